@@ -0,0 +1,541 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+)
+
+// BrokerOutboundQueue is how many packets may wait for a slow client before messages to it are dropped
+const BrokerOutboundQueue = 1000
+
+// brokerConnectTimeout is how long a new connection has to send CONNECT
+const brokerConnectTimeout = 10 * time.Second
+
+// EmbeddedBroker is a small in-process MQTT 3.1.1 broker for bench and CI use. It supports QoS 0
+// and 1 (QoS 2 publishes are accepted and delivered at QoS 1), retained messages, last wills and
+// + / # wildcards. Sessions are not persisted: every connection starts clean.
+type EmbeddedBroker struct {
+	listener  net.Listener
+	url       string
+	startedAt time.Time
+
+	clients  map[string]*brokerClient // Client ID -> connection
+	retained map[string]*packets.PublishPacket
+	mutex    sync.RWMutex
+
+	nextClient       uint64
+	messagesReceived uint64
+	messagesSent     uint64
+	messagesDropped  uint64
+}
+
+// brokerClient is one connected MQTT client
+type brokerClient struct {
+	id          string
+	conn        net.Conn
+	broker      *EmbeddedBroker
+	connectedAt time.Time
+	keepalive   time.Duration
+
+	subscriptions map[string]byte // Topic filter -> granted QoS, guarded by broker.mutex
+	will          *packets.PublishPacket
+
+	outbound  chan packets.ControlPacket
+	done      chan struct{}
+	closeOnce sync.Once
+	nextID    uint32
+}
+
+// BrokerClientInfo describes a connected client for /api/broker
+type BrokerClientInfo struct {
+	ClientID      string    `json:"client_id"`
+	Remote        string    `json:"remote"`
+	ConnectedAt   time.Time `json:"connected_at"`
+	Subscriptions []string  `json:"subscriptions"`
+}
+
+// BrokerStatus is the state of the embedded broker
+type BrokerStatus struct {
+	Enabled          bool               `json:"enabled"`
+	Listen           string             `json:"listen,omitempty"`
+	URL              string             `json:"url,omitempty"`
+	StartedAt        time.Time          `json:"started_at,omitempty"`
+	Clients          []BrokerClientInfo `json:"clients"`
+	Retained         int                `json:"retained"`
+	MessagesReceived uint64             `json:"messages_received"`
+	MessagesSent     uint64             `json:"messages_sent"`
+	MessagesDropped  uint64             `json:"messages_dropped"` // Not delivered because a client fell behind
+}
+
+// NewEmbeddedBroker creates a broker; it does not listen until Start
+func NewEmbeddedBroker() *EmbeddedBroker {
+	return &EmbeddedBroker{
+		clients:  make(map[string]*brokerClient),
+		retained: make(map[string]*packets.PublishPacket),
+	}
+}
+
+// Start listens on addr (e.g. 127.0.0.1:1883, or :0 for any free port) and accepts clients
+func (b *EmbeddedBroker) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start embedded MQTT broker: %v", err)
+	}
+	b.listener = listener
+	b.url = brokerURL(listener.Addr().String())
+	b.startedAt = time.Now()
+
+	log.Printf("🏠 Embedded MQTT broker listening on %s (%s)", listener.Addr(), b.url)
+	go b.acceptLoop()
+	return nil
+}
+
+// URL returns the address clients on this machine connect to
+func (b *EmbeddedBroker) URL() string {
+	return b.url
+}
+
+// brokerURL turns a listen address into a tcp:// URL reachable from this machine
+func brokerURL(listen string) string {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "tcp://" + listen
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "tcp://" + net.JoinHostPort(host, port)
+}
+
+// Stop closes the listener and every client connection. Wills are not published on shutdown.
+func (b *EmbeddedBroker) Stop() {
+	if b.listener == nil {
+		return
+	}
+	b.listener.Close()
+
+	b.mutex.Lock()
+	clients := make([]*brokerClient, 0, len(b.clients))
+	for _, client := range b.clients {
+		client.will = nil
+		clients = append(clients, client)
+	}
+	b.mutex.Unlock()
+
+	for _, client := range clients {
+		client.close()
+	}
+	log.Println("🏠 Embedded MQTT broker stopped")
+}
+
+func (b *EmbeddedBroker) acceptLoop() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			if !strings.Contains(err.Error(), "use of closed network connection") {
+				log.Printf("❌ Embedded broker accept failed: %v", err)
+			}
+			return
+		}
+		go b.serve(conn)
+	}
+}
+
+// serve runs one connection from CONNECT to close
+func (b *EmbeddedBroker) serve(conn net.Conn) {
+	conn.SetReadDeadline(time.Now().Add(brokerConnectTimeout))
+	packet, err := packets.ReadPacket(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	connect, ok := packet.(*packets.ConnectPacket)
+	if !ok {
+		log.Printf("⚠️ Embedded broker: %s sent %T before CONNECT", conn.RemoteAddr(), packet)
+		conn.Close()
+		return
+	}
+
+	code := connect.Validate()
+	if code != packets.Accepted {
+		connack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+		connack.ReturnCode = code
+		connack.Write(conn)
+		conn.Close()
+		log.Printf("⚠️ Embedded broker refused %s: %s", conn.RemoteAddr(), packets.ConnackReturnCodes[code])
+		return
+	}
+
+	client := &brokerClient{
+		id:            connect.ClientIdentifier,
+		conn:          conn,
+		broker:        b,
+		connectedAt:   time.Now(),
+		keepalive:     time.Duration(connect.Keepalive) * time.Second,
+		subscriptions: make(map[string]byte),
+		outbound:      make(chan packets.ControlPacket, BrokerOutboundQueue),
+		done:          make(chan struct{}),
+	}
+	if client.id == "" {
+		client.id = fmt.Sprintf("ngasim-auto-%d", atomic.AddUint64(&b.nextClient, 1))
+	}
+	if connect.WillFlag {
+		will := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+		will.TopicName = connect.WillTopic
+		will.Payload = connect.WillMessage
+		will.Qos = connect.WillQos
+		will.Retain = connect.WillRetain
+		client.will = will
+	}
+
+	// A new connection with the same client ID takes over the old one
+	b.mutex.Lock()
+	previous := b.clients[client.id]
+	b.clients[client.id] = client
+	b.mutex.Unlock()
+	if previous != nil {
+		log.Printf("🔁 Embedded broker: client %s reconnected, closing previous connection", client.id)
+		previous.close()
+	}
+
+	go client.writeLoop()
+	connack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+	connack.ReturnCode = packets.Accepted
+	client.send(connack)
+	log.Printf("🔌 Embedded broker: client %s connected from %s", client.id, conn.RemoteAddr())
+
+	graceful := client.readLoop()
+
+	b.mutex.Lock()
+	if b.clients[client.id] == client {
+		delete(b.clients, client.id)
+	}
+	will := client.will
+	b.mutex.Unlock()
+	client.close()
+
+	if !graceful && will != nil {
+		log.Printf("💀 Embedded broker: publishing will of %s on %s", client.id, will.TopicName)
+		b.publish(will)
+	}
+	log.Printf("🔌 Embedded broker: client %s disconnected", client.id)
+}
+
+// readLoop handles packets until the client disconnects; it reports whether it sent DISCONNECT
+func (c *brokerClient) readLoop() bool {
+	for {
+		if c.keepalive > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.keepalive * 3 / 2))
+		} else {
+			c.conn.SetReadDeadline(time.Time{})
+		}
+
+		packet, err := packets.ReadPacket(c.conn)
+		if err != nil {
+			return false
+		}
+
+		switch p := packet.(type) {
+		case *packets.PublishPacket:
+			if strings.ContainsAny(p.TopicName, "+#") || p.TopicName == "" {
+				log.Printf("⚠️ Embedded broker: %s published to invalid topic %q", c.id, p.TopicName)
+				return false
+			}
+			switch p.Qos {
+			case 1:
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				c.send(ack)
+			case 2:
+				rec := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
+				rec.MessageID = p.MessageID
+				c.send(rec)
+			}
+			c.broker.publish(p)
+
+		case *packets.PubrelPacket:
+			comp := packets.NewControlPacket(packets.Pubcomp).(*packets.PubcompPacket)
+			comp.MessageID = p.MessageID
+			c.send(comp)
+
+		case *packets.SubscribePacket:
+			c.subscribe(p)
+
+		case *packets.UnsubscribePacket:
+			c.broker.mutex.Lock()
+			for _, filter := range p.Topics {
+				delete(c.subscriptions, filter)
+			}
+			c.broker.mutex.Unlock()
+			ack := packets.NewControlPacket(packets.Unsuback).(*packets.UnsubackPacket)
+			ack.MessageID = p.MessageID
+			c.send(ack)
+
+		case *packets.PingreqPacket:
+			c.send(packets.NewControlPacket(packets.Pingresp))
+
+		case *packets.DisconnectPacket:
+			return true
+
+		case *packets.PubackPacket, *packets.PubrecPacket, *packets.PubcompPacket:
+			// Outbound delivery is fire-and-forget over TCP; acknowledgements need no action
+
+		default:
+			log.Printf("⚠️ Embedded broker: unexpected %T from %s", packet, c.id)
+			return false
+		}
+	}
+}
+
+// subscribe grants each filter (QoS 2 is downgraded to 1) and sends matching retained messages
+func (c *brokerClient) subscribe(p *packets.SubscribePacket) {
+	ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+	ack.MessageID = p.MessageID
+
+	var granted []string
+	c.broker.mutex.Lock()
+	for i, filter := range p.Topics {
+		if !validTopicFilter(filter) {
+			ack.ReturnCodes = append(ack.ReturnCodes, 0x80)
+			continue
+		}
+		qos := p.Qoss[i]
+		if qos > 1 {
+			qos = 1
+		}
+		c.subscriptions[filter] = qos
+		ack.ReturnCodes = append(ack.ReturnCodes, qos)
+		granted = append(granted, filter)
+	}
+
+	var retained []*packets.PublishPacket
+	for topic, message := range c.broker.retained {
+		for _, filter := range granted {
+			if topicMatches(filter, topic) {
+				retained = append(retained, message)
+				break
+			}
+		}
+	}
+	c.broker.mutex.Unlock()
+
+	c.send(ack)
+	sort.Slice(retained, func(i, j int) bool { return retained[i].TopicName < retained[j].TopicName })
+	for _, message := range retained {
+		c.deliver(message, c.grantedQoS(message.TopicName), true)
+	}
+}
+
+// grantedQoS returns the highest QoS of the client's filters matching topic
+func (c *brokerClient) grantedQoS(topic string) byte {
+	c.broker.mutex.RLock()
+	defer c.broker.mutex.RUnlock()
+
+	var qos byte
+	for filter, granted := range c.subscriptions {
+		if topicMatches(filter, topic) && granted > qos {
+			qos = granted
+		}
+	}
+	return qos
+}
+
+// publish stores retained messages and delivers a message to every matching subscriber, once per
+// client at the highest QoS it subscribed with
+func (b *EmbeddedBroker) publish(message *packets.PublishPacket) {
+	atomic.AddUint64(&b.messagesReceived, 1)
+
+	b.mutex.Lock()
+	if message.Retain {
+		if len(message.Payload) == 0 {
+			delete(b.retained, message.TopicName)
+		} else {
+			b.retained[message.TopicName] = message.Copy()
+		}
+	}
+
+	type delivery struct {
+		client *brokerClient
+		qos    byte
+	}
+	var deliveries []delivery
+	for _, client := range b.clients {
+		matched, qos := false, byte(0)
+		for filter, granted := range client.subscriptions {
+			if topicMatches(filter, message.TopicName) {
+				matched = true
+				if granted > qos {
+					qos = granted
+				}
+			}
+		}
+		if matched {
+			deliveries = append(deliveries, delivery{client, qos})
+		}
+	}
+	b.mutex.Unlock()
+
+	for _, d := range deliveries {
+		d.client.deliver(message, d.qos, false)
+	}
+}
+
+// deliver queues a copy of message for the client at min(published, granted) QoS
+func (c *brokerClient) deliver(message *packets.PublishPacket, granted byte, retained bool) {
+	out := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	out.TopicName = message.TopicName
+	out.Payload = message.Payload
+	out.Retain = retained
+	out.Qos = message.Qos
+	if out.Qos > granted {
+		out.Qos = granted
+	}
+	if out.Qos > 1 {
+		out.Qos = 1
+	}
+	if out.Qos > 0 {
+		out.MessageID = uint16(atomic.AddUint32(&c.nextID, 1)%0xffff) + 1
+	}
+	if c.send(out) {
+		atomic.AddUint64(&c.broker.messagesSent, 1)
+	} else {
+		atomic.AddUint64(&c.broker.messagesDropped, 1)
+	}
+}
+
+// send queues a packet for the client without blocking; it reports false if the client is gone
+// or too far behind
+func (c *brokerClient) send(packet packets.ControlPacket) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	select {
+	case c.outbound <- packet:
+		return true
+	default:
+		log.Printf("⚠️ Embedded broker: client %s is not keeping up, dropping %T", c.id, packet)
+		return false
+	}
+}
+
+func (c *brokerClient) writeLoop() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case packet := <-c.outbound:
+			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := packet.Write(c.conn); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// close ends the connection; safe to call more than once
+func (c *brokerClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// validTopicFilter checks wildcard placement: + and # take a whole level and # comes last
+func validTopicFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return false
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return false
+		}
+	}
+	return true
+}
+
+// topicMatches reports whether topic matches filter. Filters starting with a wildcard do not
+// match $-prefixed topics, as in MQTT 3.1.1.
+func topicMatches(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true // Also matches the parent level, e.g. a/# matches a
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+// Status reports the connected clients, retained messages and traffic counters
+func (b *EmbeddedBroker) Status() BrokerStatus {
+	status := BrokerStatus{
+		Enabled:          true,
+		URL:              b.url,
+		StartedAt:        b.startedAt,
+		Clients:          make([]BrokerClientInfo, 0),
+		MessagesReceived: atomic.LoadUint64(&b.messagesReceived),
+		MessagesSent:     atomic.LoadUint64(&b.messagesSent),
+		MessagesDropped:  atomic.LoadUint64(&b.messagesDropped),
+	}
+	if b.listener != nil {
+		status.Listen = b.listener.Addr().String()
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	status.Retained = len(b.retained)
+	for _, client := range b.clients {
+		info := BrokerClientInfo{
+			ClientID:      client.id,
+			Remote:        client.conn.RemoteAddr().String(),
+			ConnectedAt:   client.connectedAt,
+			Subscriptions: make([]string, 0, len(client.subscriptions)),
+		}
+		for filter := range client.subscriptions {
+			info.Subscriptions = append(info.Subscriptions, filter)
+		}
+		sort.Strings(info.Subscriptions)
+		status.Clients = append(status.Clients, info)
+	}
+	sort.Slice(status.Clients, func(i, j int) bool { return status.Clients[i].ClientID < status.Clients[j].ClientID })
+	return status
+}
+
+// handleBroker reports the embedded broker, or enabled=false when an external broker is used
+func (f *Fleet) handleBroker(w http.ResponseWriter, r *http.Request) {
+	status := BrokerStatus{Clients: make([]BrokerClientInfo, 0)}
+	if f.broker != nil {
+		status = f.broker.Status()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls the NgaSim HTTP API of one site
+type Client struct {
+	baseURL string
+	site    string // Empty for the site selected by NgaSim (the first one)
+	http    *http.Client
+}
+
+// NewClient creates a client for the NgaSim web server at baseURL
+func NewClient(baseURL, site string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		site:    site,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// siteURL returns the URL of a site API path, under /sites/<name> when a site was chosen
+func (c *Client) siteURL(path string) string {
+	if c.site != "" {
+		return c.baseURL + "/sites/" + url.PathEscape(c.site) + path
+	}
+	return c.baseURL + path
+}
+
+// fleetURL returns the URL of an API path served for every site at once
+func (c *Client) fleetURL(path string) string {
+	return c.baseURL + path
+}
+
+// Get fetches a JSON document
+func (c *Client) Get(u string) (interface{}, error) {
+	return c.do(http.MethodGet, u, nil)
+}
+
+// Post sends body as JSON and returns the JSON answer
+func (c *Client) Post(u string, body interface{}) (interface{}, error) {
+	return c.do(http.MethodPost, u, body)
+}
+
+// Delete sends a DELETE and returns the JSON answer
+func (c *Client) Delete(u string) (interface{}, error) {
+	return c.do(http.MethodDelete, u, nil)
+}
+
+// do performs one request. Connection failures and server errors exit with ExitUnreachable;
+// requests NgaSim refused (4xx) exit with ExitFailed.
+func (c *Client) do(method, u string, body interface{}) (interface{}, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, usageError("invalid request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, usageError("invalid URL %s: %v", u, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach NgaSim: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %v", u, err)
+	}
+
+	if resp.StatusCode >= 400 {
+		message := strings.TrimSpace(string(data))
+		if resp.StatusCode < 500 {
+			err := fmt.Errorf("%s %s: %s (HTTP %d)", method, u, message, resp.StatusCode)
+			return nil, &failure{code: ExitFailed, status: resp.StatusCode, err: err}
+		}
+		return nil, fmt.Errorf("%s %s: %s (HTTP %d)", method, u, message, resp.StatusCode)
+	}
+
+	// Unknown paths fall through to the web UI, which answers with a page
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		err := fmt.Errorf("%s %s: no such API endpoint", method, u)
+		return nil, &failure{code: ExitFailed, status: http.StatusNotFound, err: err}
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%s %s: response is not JSON: %v", method, u, err)
+	}
+	return document, nil
+}
+
+// notFound reports whether err is NgaSim answering 404 Not Found
+func notFound(err error) bool {
+	var f *failure
+	return errors.As(err, &f) && f.status == http.StatusNotFound
+}
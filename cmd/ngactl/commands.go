@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+)
+
+// Command states that are still waiting for an outcome
+var unsettledCommandStates = map[string]bool{"PENDING": true, "QUEUED": true}
+
+// parseArgs parses flags that may come before, between or after positional arguments and
+// returns the positional ones
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usageError("%s: %v", fs.Name(), err)
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// interrupted returns a channel closed on Ctrl+C, for commands that run until stopped
+func interrupted() <-chan os.Signal {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	return c
+}
+
+// fetchDevice returns one device from the site's device list
+func fetchDevice(c *Client, serial string) (map[string]interface{}, error) {
+	document, err := c.Get(c.siteURL("/api/devices"))
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items(document) {
+		if device, ok := item.(map[string]interface{}); ok && text(device["serial"]) == serial {
+			return device, nil
+		}
+	}
+	return nil, commandFailed("device %s not found", serial)
+}
+
+// deviceTable lists devices one per row
+func deviceTable(devices []interface{}) *Table {
+	table := &Table{Headers: []string{"SERIAL", "CATEGORY", "NAME", "STATUS", "LAST SEEN", "FAULTS", "LAST COMMAND", "COMMAND STATUS"}}
+	for _, device := range devices {
+		category := fieldText(device, "category")
+		if category == "" {
+			category = fieldText(device, "type")
+		}
+		table.Rows = append(table.Rows, []string{
+			fieldText(device, "serial"),
+			category,
+			fieldText(device, "name"),
+			fieldText(device, "status"),
+			age(field(device, "last_seen")),
+			fieldText(device, "active_fault_count"),
+			fieldText(device, "last_command"),
+			fieldText(device, "command_status"),
+		})
+	}
+	return table
+}
+
+func runDevices(c *Client, out *Output, args []string) error {
+	fs := flag.NewFlagSet("devices", flag.ContinueOnError)
+	watch := fs.Duration("watch", 0, "refresh interval; 0 lists once")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	stop := interrupted()
+	for {
+		document, err := c.Get(c.siteURL("/api/devices"))
+		if err != nil {
+			return err
+		}
+		if *watch > 0 && out.format == FormatTable {
+			fmt.Fprintf(out.w, "\033[H\033[2J%s  every %v (Ctrl+C to stop)\n\n", time.Now().Format("15:04:05"), *watch)
+		}
+		if err := out.Print(document, deviceTable(items(document))); err != nil {
+			return err
+		}
+		if *watch <= 0 {
+			return nil
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(*watch):
+		}
+	}
+}
+
+func runDevice(c *Client, out *Output, args []string) error {
+	positional, err := parseArgs(flag.NewFlagSet("device", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("usage: ngactl device <serial>")
+	}
+
+	device, err := fetchDevice(c, positional[0])
+	if err != nil {
+		return err
+	}
+	return out.Print(device, recordTable(device))
+}
+
+func runFaults(c *Client, out *Output, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("faults", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	document, err := c.Get(c.siteURL("/api/devices/faults"))
+	if err != nil {
+		return err
+	}
+
+	table := &Table{Headers: []string{"SERIAL", "SOURCE", "CODE", "MESSAGE", "FIRST SEEN", "LAST SEEN"}}
+	bySerial, _ := document.(map[string]interface{})
+	for _, serial := range sortedKeys(bySerial) {
+		for _, fault := range items(bySerial[serial]) {
+			if field(fault, "cleared_at") != nil {
+				continue
+			}
+			table.Rows = append(table.Rows, []string{
+				serial,
+				fieldText(fault, "source"),
+				fieldText(fault, "code"),
+				fieldText(fault, "message"),
+				age(field(fault, "first_seen")),
+				age(field(fault, "last_seen")),
+			})
+		}
+	}
+	return out.Print(document, table)
+}
+
+func runTerminal(c *Client, out *Output, args []string) error {
+	fs := flag.NewFlagSet("terminal", flag.ContinueOnError)
+	limit := fs.Int("n", 50, "entries to show")
+	follow := fs.Bool("f", false, "keep printing new entries")
+	interval := fs.Duration("interval", time.Second, "poll interval with -f")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("usage: ngactl terminal <serial> [-n 50] [-f]")
+	}
+
+	query := url.Values{"device": {positional[0]}, "limit": {strconv.Itoa(*limit)}}
+	stop := interrupted()
+	var last time.Time
+	for first := true; ; first = false {
+		document, err := c.Get(c.siteURL("/api/terminal/logs?" + query.Encode()))
+		if err != nil {
+			return err
+		}
+
+		var fresh []interface{}
+		for _, entry := range items(field(document, "entries")) {
+			if at := timeField(entry, "timestamp"); at.After(last) {
+				fresh = append(fresh, entry)
+				last = at
+			}
+		}
+
+		table := &Table{Headers: []string{"TIME", "TYPE", "DIRECTION", "MESSAGE"}}
+		for _, entry := range fresh {
+			table.Rows = append(table.Rows, []string{
+				clock(field(entry, "timestamp")),
+				fieldText(entry, "type"),
+				fieldText(entry, "direction"),
+				fieldText(entry, "message"),
+			})
+		}
+		if err := out.printStream(fresh, table, first); err != nil {
+			return err
+		}
+		if !*follow {
+			return nil
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CommandPollInterval is how often -wait checks a command's status
+const CommandPollInterval = 250 * time.Millisecond
+
+// sendCommand posts a device command and, with wait > 0, follows the status of that command
+// until the device answers. A command that is refused, fails or times out exits with ExitFailed.
+func sendCommand(c *Client, out *Output, path, serial string, body map[string]interface{}, wait time.Duration) error {
+	document, err := c.Post(c.siteURL(path), body)
+	if err != nil {
+		return err
+	}
+	response, _ := document.(map[string]interface{})
+	if response["success"] != true {
+		out.Print(document, recordTable(response))
+		return commandFailed("%s: %s", serial, text(response["error"]))
+	}
+
+	if wait > 0 {
+		status, err := waitForCommand(c, serial, text(response["command_uuid"]), wait)
+		if err != nil {
+			return err
+		}
+		response["command_status"] = status
+	}
+	if err := out.Print(response, recordTable(response)); err != nil {
+		return err
+	}
+
+	switch status := text(response["command_status"]); {
+	case wait <= 0, status == "SUCCESS":
+		return nil
+	default:
+		return commandFailed("%s: command %s", serial, status)
+	}
+}
+
+// waitForCommand polls the command commandUUID until it is no longer pending or queued, so a
+// later command to the same device cannot answer for it
+func waitForCommand(c *Client, serial, commandUUID string, wait time.Duration) (string, error) {
+	if commandUUID == "" {
+		// Demo mode simulates commands without sending them, so there is no answer to wait for
+		return "", commandFailed("%s: NgaSim returned no command UUID to wait on", serial)
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		document, err := c.Get(c.siteURL("/api/commands/" + commandUUID))
+		if err != nil {
+			return "", err
+		}
+		status := fieldText(document, "status")
+		if !unsettledCommandStates[status] {
+			return status, nil
+		}
+		if time.Now().After(deadline) {
+			return "", commandFailed("%s: command still %s after %v", serial, status, wait)
+		}
+		time.Sleep(CommandPollInterval)
+	}
+}
+
+func runSanitizer(c *Client, out *Output, args []string) error {
+	fs := flag.NewFlagSet("sanitizer", flag.ContinueOnError)
+	wait := fs.Duration("wait", 0, "wait this long for the device to answer")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return usageError("usage: ngactl sanitizer <serial> <percent> [-wait 10s]")
+	}
+	percentage, err := strconv.Atoi(strings.TrimSuffix(positional[1], "%"))
+	if err != nil || percentage < 0 || percentage > 101 {
+		return usageError("percent must be 0-101, got %q", positional[1])
+	}
+
+	return sendCommand(c, out, "/api/sanitizer/command", positional[0], map[string]interface{}{
+		"serial":     positional[0],
+		"percentage": percentage,
+	}, *wait)
+}
+
+func runPump(c *Client, out *Output, args []string) error {
+	fs := flag.NewFlagSet("pump", flag.ContinueOnError)
+	wait := fs.Duration("wait", 0, "wait this long for the device to answer")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return usageError("usage: ngactl pump <serial> <rpm>|off [-wait 10s]")
+	}
+
+	power, rpm := 1, 0
+	if strings.EqualFold(positional[1], "off") {
+		power = 0
+	} else if rpm, err = strconv.Atoi(positional[1]); err != nil || rpm <= 0 {
+		return usageError("rpm must be a positive number or off, got %q", positional[1])
+	}
+
+	return sendCommand(c, out, "/api/pump/command", positional[0], map[string]interface{}{
+		"serial": positional[0],
+		"power":  power,
+		"rpm":    rpm,
+	}, *wait)
+}
+
+func runLight(c *Client, out *Output, args []string) error {
+	fs := flag.NewFlagSet("light", flag.ContinueOnError)
+	wait := fs.Duration("wait", 0, "wait this long for the device to answer")
+	brightness := fs.Int("brightness", -1, "brightness 0-100; unchanged when not given")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 2 || len(positional) > 3 {
+		return usageError("usage: ngactl light <serial> <address> [on|off|blinking] [-brightness N] [-wait 10s]")
+	}
+	address, err := strconv.Atoi(positional[1])
+	if err != nil || address < 0 {
+		return usageError("address must be a light address, got %q", positional[1])
+	}
+
+	body := map[string]interface{}{
+		"serial":  positional[0],
+		"address": address,
+	}
+	if len(positional) == 3 {
+		body["state"] = strings.ToLower(positional[2])
+	}
+	if *brightness >= 0 {
+		body["brightness"] = *brightness
+	}
+	if len(body) == 2 {
+		return usageError("give a state (on, off, blinking) and/or -brightness")
+	}
+	return sendCommand(c, out, "/api/lights/command", positional[0], body, *wait)
+}
+
+func runEmergencyStop(c *Client, out *Output, args []string) error {
+	fs := flag.NewFlagSet("estop", flag.ContinueOnError)
+	fleet := fs.Bool("fleet", false, "stop every site, not only the selected one")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	u := c.siteURL("/api/emergency-stop")
+	if *fleet {
+		u = c.fleetURL("/api/fleet/emergency-stop")
+	}
+	document, err := c.Post(u, map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+
+	sites := map[string]interface{}{"": document}
+	table := &Table{Headers: []string{"SERIAL", "NAME", "STOPPED", "ERROR"}}
+	if *fleet {
+		sites, _ = field(document, "sites").(map[string]interface{})
+		table.Headers = append([]string{"SITE"}, table.Headers...)
+	}
+	for _, site := range sortedKeys(sites) {
+		results, _ := field(sites[site], "results").(map[string]interface{})
+		for _, serial := range sortedKeys(results) {
+			result := results[serial]
+			row := []string{serial, fieldText(result, "name"), fieldText(result, "success"), fieldText(result, "error")}
+			if *fleet {
+				row = append([]string{site}, row...)
+			}
+			table.Rows = append(table.Rows, row)
+		}
+	}
+	if err := out.Print(document, table); err != nil {
+		return err
+	}
+
+	found, stopped := fieldText(document, "sanitizers_found"), fieldText(document, "sanitizers_stopped")
+	if found != stopped {
+		return commandFailed("emergency stop reached %s of %s sanitizers", stopped, found)
+	}
+	return nil
+}
+
+func runCommands(c *Client, out *Output, args []string) error {
+	fs := flag.NewFlagSet("commands", flag.ContinueOnError)
+	status := fs.String("status", "", "only commands in this state, e.g. QUEUED")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 1 {
+		document, err := c.Get(c.siteURL("/api/commands/" + positional[0]))
+		if err != nil {
+			return err
+		}
+		record, _ := document.(map[string]interface{})
+		return out.Print(document, recordTable(record))
+	}
+
+	u := c.siteURL("/api/commands/")
+	if *status != "" {
+		u += "?status=" + strings.ToUpper(*status)
+	}
+	document, err := c.Get(u)
+	if err != nil {
+		return err
+	}
+	table := &Table{Headers: []string{"UUID", "SERIAL", "COMMAND", "STATUS", "RESPONSE", "SENT", "LATENCY MS"}}
+	for _, record := range items(document) {
+		table.Rows = append(table.Rows, []string{
+			fieldText(record, "command_uuid"),
+			fieldText(record, "device_serial"),
+			fieldText(record, "command"),
+			fieldText(record, "status"),
+			fieldText(record, "response_code"),
+			clock(field(record, "sent_at")),
+			fieldText(record, "latency_ms"),
+		})
+	}
+	return out.Print(document, table)
+}
+
+func runSites(c *Client, out *Output, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("sites", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	document, err := c.Get(c.fleetURL("/api/sites"))
+	if err != nil {
+		return err
+	}
+	table := &Table{Headers: []string{"NAME", "TITLE", "BROKER", "CONNECTED", "DEVICES", "ONLINE", "FAULTS", "QUEUED"}}
+	for _, site := range items(document) {
+		table.Rows = append(table.Rows, []string{
+			fieldText(site, "name"),
+			fieldText(site, "title"),
+			fieldText(site, "broker"),
+			fieldText(site, "connected"),
+			fieldText(site, "devices"),
+			fieldText(site, "online"),
+			fieldText(site, "active_faults"),
+			fieldText(site, "queued_commands"),
+		})
+	}
+	return out.Print(document, table)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"flag"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// JobPollInterval is how often jobs run -wait checks the execution
+const JobPollInterval = 500 * time.Millisecond
+
+// jobsURL returns the URL of a jobs API path, e.g. jobsURL(c, "42", "run")
+func jobsURL(c *Client, parts ...string) string {
+	path := "/api/jobs"
+	for _, part := range parts {
+		path += "/" + url.PathEscape(part)
+	}
+	return c.siteURL(path)
+}
+
+// listJobs fetches every job. A missing endpoint means this NgaSim runs without the job engine.
+func listJobs(c *Client) (interface{}, error) {
+	document, err := c.Get(jobsURL(c))
+	if notFound(err) {
+		return nil, commandFailed("job API not available on this NgaSim")
+	}
+	return document, err
+}
+
+// jobTable lists jobs one per row
+func jobTable(jobs []interface{}) *Table {
+	table := &Table{Headers: []string{"ID", "NAME", "ENABLED", "SCHEDULE", "NEXT RUN", "TAGS", "UPDATED"}}
+	for _, job := range jobs {
+		schedule := fieldText(job, "schedule.type")
+		for _, detail := range []string{"schedule.interval", "schedule.cron", "schedule.start_at"} {
+			if value := fieldText(job, detail); value != "" {
+				schedule += " " + value
+				break
+			}
+		}
+		var tags []string
+		for _, tag := range items(field(job, "tags")) {
+			tags = append(tags, text(tag))
+		}
+		table.Rows = append(table.Rows, []string{
+			fieldText(job, "id"),
+			fieldText(job, "name"),
+			fieldText(job, "enabled"),
+			schedule,
+			due(field(job, "next_run")),
+			strings.Join(tags, ","),
+			age(field(job, "updated_at")),
+		})
+	}
+	return table
+}
+
+// executionTable lists job executions one per row
+func executionTable(executions []interface{}) *Table {
+	table := &Table{Headers: []string{"ID", "JOB", "STATUS", "STARTED", "DURATION", "RESULTS", "ERROR"}}
+	for _, execution := range executions {
+		duration := "-"
+		if start, end := timeField(execution, "start_time"), timeField(execution, "end_time"); !start.IsZero() && !end.IsZero() {
+			duration = end.Sub(start).Round(time.Millisecond).String()
+		}
+		table.Rows = append(table.Rows, []string{
+			fieldText(execution, "id"),
+			fieldText(execution, "job_id"),
+			fieldText(execution, "status"),
+			clock(field(execution, "start_time")),
+			duration,
+			summarize(field(execution, "results")),
+			fieldText(execution, "error"),
+		})
+	}
+	return table
+}
+
+// frameTable lists what a dry run would have done, one row per action: the frame each
+// send_message would publish, as hex, and what waits and conditions amount to. Actions nested in
+// branches, parallel groups and foreach devices are numbered by their path, e.g. 0.SIMSAN001.1.
+func frameTable(execution interface{}) *Table {
+	table := &Table{Headers: []string{"ACTION", "TYPE", "DEVICE", "MESSAGE", "TOPIC", "FRAME", "ERROR"}}
+	var add func(prefix string, results []interface{})
+	add = func(prefix string, results []interface{}) {
+		for _, result := range results {
+			path := prefix + fieldText(result, "action_index")
+			switch actionType := fieldText(result, "action_type"); actionType {
+			case "device":
+				path = prefix + fieldText(result, "device_id")
+			case "send_message":
+				table.Rows = append(table.Rows, []string{path, actionType, fieldText(result, "device_id"),
+					fieldText(result, "response.message_type"), fieldText(result, "response.topic"),
+					fieldText(result, "response.frame"), fieldText(result, "error")})
+			case "wait":
+				table.Rows = append(table.Rows, []string{path, actionType, "",
+					"skip " + fieldText(result, "response.skipped_wait"), "", "", fieldText(result, "error")})
+			case "condition":
+				check := fieldText(result, "response.condition")
+				if reason := fieldText(result, "response.not_met"); reason != "" {
+					check += " (not met now: " + reason + ")"
+				} else if observed := field(result, "response.observed"); observed != nil {
+					check += " (now " + summarize(observed) + ")"
+				}
+				table.Rows = append(table.Rows, []string{path, actionType, fieldText(result, "device_id"),
+					check, "", "", fieldText(result, "error")})
+			default:
+				table.Rows = append(table.Rows, []string{path, actionType, "", "", "", "", fieldText(result, "error")})
+			}
+			add(path+".", items(field(result, "results")))
+			add(path+".on_success.", items(field(result, "on_success")))
+			add(path+".on_failure.", items(field(result, "on_failure")))
+		}
+	}
+	add("", items(field(execution, "results")))
+	return table
+}
+
+func runJobs(c *Client, out *Output, args []string) error {
+	fs := flag.NewFlagSet("jobs", flag.ContinueOnError)
+	wait := fs.Duration("wait", 0, "with run, wait this long for the execution to finish")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	verb := "list"
+	if len(positional) > 0 {
+		verb, positional = positional[0], positional[1:]
+	}
+	if verb == "list" {
+		document, err := listJobs(c)
+		if err != nil {
+			return err
+		}
+		return out.Print(document, jobTable(items(document)))
+	}
+	if len(positional) != 1 {
+		return usageError("usage: ngactl jobs %s <id>", verb)
+	}
+	id := positional[0]
+
+	switch verb {
+	case "show":
+		document, err := c.Get(jobsURL(c, id))
+		if err != nil {
+			return err
+		}
+		job, _ := document.(map[string]interface{})
+		return out.Print(document, recordTable(job))
+
+	case "executions":
+		document, err := c.Get(jobsURL(c, id, "executions"))
+		if err != nil {
+			return err
+		}
+		return out.Print(document, executionTable(items(document)))
+
+	case "enable", "disable":
+		document, err := c.Post(jobsURL(c, id, verb), nil)
+		if err != nil {
+			return err
+		}
+		job, _ := document.(map[string]interface{})
+		return out.Print(document, recordTable(job))
+
+	case "run":
+		document, err := c.Post(jobsURL(c, id, "run"), nil)
+		if err != nil {
+			return err
+		}
+		if *wait > 0 {
+			if document, err = waitForExecution(c, id, fieldText(document, "id"), *wait); err != nil {
+				return err
+			}
+		}
+		execution, _ := document.(map[string]interface{})
+		if err := out.Print(document, recordTable(execution)); err != nil {
+			return err
+		}
+		switch status := fieldText(document, "status"); status {
+		case "failed", "cancelled":
+			return commandFailed("job %s %s: %s", id, status, fieldText(document, "error"))
+		}
+		return nil
+
+	case "dry-run":
+		document, err := c.Post(jobsURL(c, id, "dry-run"), nil)
+		if err != nil {
+			return err
+		}
+		if err := out.Print(document, frameTable(document)); err != nil {
+			return err
+		}
+		if fieldText(document, "status") == "failed" {
+			return commandFailed("job %s dry run failed: %s", id, fieldText(document, "error"))
+		}
+		return nil
+	}
+	return usageError("unknown jobs command %q (list, show, run, dry-run, executions, enable, disable)", verb)
+}
+
+// waitForExecution polls the job's executions until the given one is no longer running
+func waitForExecution(c *Client, jobID, executionID string, wait time.Duration) (interface{}, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		document, err := c.Get(jobsURL(c, jobID, "executions"))
+		if err != nil {
+			return nil, err
+		}
+		for _, execution := range items(document) {
+			if fieldText(execution, "id") == executionID && fieldText(execution, "status") != "running" {
+				return execution, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, commandFailed("job %s execution %s still running after %v", jobID, executionID, wait)
+		}
+		time.Sleep(JobPollInterval)
+	}
+}
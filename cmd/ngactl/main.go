@@ -0,0 +1,144 @@
+// ngactl is the command-line client of the NgaSim HTTP API. It lists and watches devices, tails
+// device terminals, sends sanitizer, pump and light commands, triggers the emergency stop and
+// runs jobs, printing tables, JSON or CSV for shell-based test rigs.
+//
+// Exit codes:
+//
+//	0  success
+//	1  NgaSim refused the request, or a command, device or job reported failure or timed out
+//	2  usage error
+//	3  NgaSim could not be reached or failed with a server error
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Exit codes
+const (
+	ExitOK          = 0 ///< Success
+	ExitFailed      = 1 ///< Request refused, or a command, device or job failed
+	ExitUsage       = 2 ///< Bad command line
+	ExitUnreachable = 3 ///< Connection failure or server error
+)
+
+// DefaultURL is used when neither -url nor NGACTL_URL is given
+const DefaultURL = "http://localhost:8082"
+
+// failure is an error that maps to an exit code
+type failure struct {
+	code   int
+	status int // HTTP status when NgaSim refused the request
+	err    error
+}
+
+func (f *failure) Error() string { return f.err.Error() }
+
+// usageError reports a bad command line
+func usageError(format string, args ...interface{}) error {
+	return &failure{code: ExitUsage, err: fmt.Errorf(format, args...)}
+}
+
+// commandFailed reports a command that NgaSim or a device did not carry out
+func commandFailed(format string, args ...interface{}) error {
+	return &failure{code: ExitFailed, err: fmt.Errorf(format, args...)}
+}
+
+// subcommand is one ngactl verb
+type subcommand struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *Client, out *Output, args []string) error
+}
+
+var subcommands = []subcommand{
+	{"devices", "[-watch 2s]", "list devices, or keep refreshing the list", runDevices},
+	{"device", "<serial>", "show one device", runDevice},
+	{"faults", "", "list active faults", runFaults},
+	{"terminal", "<serial> [-n 50] [-f]", "tail a device terminal, -f to follow", runTerminal},
+	{"sanitizer", "<serial> <percent> [-wait 10s]", "set sanitizer output (0-101%)", runSanitizer},
+	{"pump", "<serial> <rpm>|off [-wait 10s]", "run a SpeedSet Plus pump or VSP booster, or stop it", runPump},
+	{"light", "<serial> <address> [on|off|blinking] [-brightness N] [-wait 10s]", "switch or dim a light", runLight},
+	{"estop", "[-fleet]", "emergency stop every sanitizer of the site, or of every site", runEmergencyStop},
+	{"commands", "[uuid] [-status S]", "list tracked commands, or show one", runCommands},
+	{"jobs", "[list|show|run|dry-run|executions|enable|disable] [id]", "list, inspect, run and dry run jobs", runJobs},
+	{"sites", "", "list the sites served by this NgaSim", runSites},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: ngactl [-url %s] [-site name] [-o table|json|csv] [-timeout 10s] <command> [args]\n\n", DefaultURL)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range subcommands {
+		fmt.Fprintf(os.Stderr, "  %-10s %-56s %s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nExit codes: 0 ok, 1 refused or failed, 2 usage error, 3 NgaSim unreachable or server error")
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run parses the global flags, runs one subcommand and returns the exit code
+func run(args []string) int {
+	fs := flag.NewFlagSet("ngactl", flag.ContinueOnError)
+	fs.Usage = usage
+	baseURL := fs.String("url", envOr("NGACTL_URL", DefaultURL), "NgaSim web server (env NGACTL_URL)")
+	site := fs.String("site", os.Getenv("NGACTL_SITE"), "site to address when NgaSim runs several (env NGACTL_SITE)")
+	format := fs.String("o", "table", "output format: table, json or csv")
+	timeout := fs.Duration("timeout", 10*time.Second, "HTTP request timeout")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	out, err := NewOutput(os.Stdout, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ngactl: %v\n", err)
+		return ExitUsage
+	}
+	if fs.NArg() == 0 {
+		usage()
+		return ExitUsage
+	}
+
+	name := fs.Arg(0)
+	for _, cmd := range subcommands {
+		if cmd.name == name {
+			client := NewClient(*baseURL, *site, *timeout)
+			return exitCode(cmd.run(client, out, fs.Args()[1:]))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "ngactl: unknown command %q\n\n", name)
+	usage()
+	return ExitUsage
+}
+
+// exitCode prints err and converts it to an exit code
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	fmt.Fprintf(os.Stderr, "ngactl: %v\n", err)
+
+	var f *failure
+	if errors.As(err, &f) {
+		return f.code
+	}
+	return ExitUnreachable
+}
+
+// envOr returns the environment variable, or fallback when it is unset
+func envOr(name, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats
+const (
+	FormatTable = "table" ///< Aligned columns for people
+	FormatJSON  = "json"  ///< The API document, indented
+	FormatCSV   = "csv"   ///< Header row plus one row per item
+)
+
+// Output prints API documents in the chosen format
+type Output struct {
+	w      io.Writer
+	format string
+}
+
+// Table is the tabular form of an API document, used for table and CSV output
+type Table struct {
+	Headers []string
+	Rows    [][]string
+}
+
+// NewOutput validates the format and returns an output writing to w
+func NewOutput(w io.Writer, format string) (*Output, error) {
+	switch format {
+	case FormatTable, FormatJSON, FormatCSV:
+		return &Output{w: w, format: format}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q (table, json or csv)", format)
+}
+
+// Print writes document as JSON, or table as aligned columns or CSV
+func (o *Output) Print(document interface{}, table *Table) error {
+	switch o.format {
+	case FormatJSON:
+		encoder := json.NewEncoder(o.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(document)
+
+	case FormatCSV:
+		writer := csv.NewWriter(o.w)
+		if table.Headers != nil {
+			writer.Write(table.Headers)
+		}
+		writer.WriteAll(table.Rows)
+		return writer.Error()
+	}
+
+	writer := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', 0)
+	if table.Headers != nil {
+		fmt.Fprintln(writer, strings.Join(table.Headers, "\t"))
+	}
+	for _, row := range table.Rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+// printStream prints one batch of a followed stream: JSON as one line per item, tables and CSV
+// with the header on the first batch only
+func (o *Output) printStream(batch []interface{}, table *Table, first bool) error {
+	if o.format == FormatJSON {
+		encoder := json.NewEncoder(o.w)
+		for _, item := range batch {
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !first {
+		if len(table.Rows) == 0 {
+			return nil
+		}
+		table = &Table{Rows: table.Rows}
+	}
+	return o.Print(nil, table)
+}
+
+// recordTable lists the fields of one object, sorted by name. Nested objects and lists are
+// summarized; use -o json for everything.
+func recordTable(record map[string]interface{}) *Table {
+	table := &Table{Headers: []string{"FIELD", "VALUE"}}
+	for _, key := range sortedKeys(record) {
+		table.Rows = append(table.Rows, []string{key, summarize(record[key])})
+	}
+	return table
+}
+
+// sortedKeys returns the keys of a JSON object in order
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// summarize formats a value for one table cell
+func summarize(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		return fmt.Sprintf("[%d items]", len(v))
+	case map[string]interface{}:
+		return fmt.Sprintf("{%d fields}", len(v))
+	}
+	return text(value)
+}
+
+// text formats a scalar JSON value; whole numbers print without a decimal point
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return fmt.Sprintf("%d", int64(v))
+		}
+		return fmt.Sprintf("%g", v)
+	}
+	return fmt.Sprint(value)
+}
+
+// field returns a value from a JSON object, following dotted paths such as pump.motor_rpm
+func field(object interface{}, path string) interface{} {
+	for _, name := range strings.Split(path, ".") {
+		fields, ok := object.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		object = fields[name]
+	}
+	return object
+}
+
+// fieldText is field formatted for a table cell
+func fieldText(object interface{}, path string) string {
+	return text(field(object, path))
+}
+
+// items returns a JSON array, or nil when document is not one
+func items(document interface{}) []interface{} {
+	list, _ := document.([]interface{})
+	return list
+}
+
+// timestamp parses an RFC 3339 JSON value; missing and zero times give the zero time
+func timestamp(value interface{}) time.Time {
+	s, _ := value.(string)
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil || t.Year() < 2000 {
+		return time.Time{}
+	}
+	return t
+}
+
+// timeField is a timestamp field of a JSON object
+func timeField(object interface{}, path string) time.Time {
+	return timestamp(field(object, path))
+}
+
+// age formats an RFC 3339 timestamp as time elapsed, e.g. 12s ago; zero times print as -
+func age(value interface{}) string {
+	t := timestamp(value)
+	if t.IsZero() {
+		return "-"
+	}
+	return time.Since(t).Round(time.Second).String() + " ago"
+}
+
+// due formats an RFC 3339 timestamp in its own zone with the time left, e.g.
+// 2025-06-01 08:00:00 -05:00 (in 2h0m0s); zero times print as -
+func due(value interface{}) string {
+	t := timestamp(value)
+	if t.IsZero() {
+		return "-"
+	}
+	return fmt.Sprintf("%s (in %v)", t.Format("2006-01-02 15:04:05 -07:00"), time.Until(t).Round(time.Second))
+}
+
+// clock formats an RFC 3339 timestamp as local time of day
+func clock(value interface{}) string {
+	t := timestamp(value)
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("15:04:05.000")
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+
+	"NgaSim/ned"
+	"NgaSim/ned/icl"
+	"NgaSim/ned/sanitizer"
+	"NgaSim/ned/speedsetplus"
+	"NgaSim/ned/vspbooster"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// commandEnvelopes maps MQTT topic categories to a constructor for their CommandRequestMessage.
+// Every category envelope has the same shape:
+//
+//	CommandRequestMessage { command_uuid = 1; oneof payload { ned.CommonRequestPayloads common = 2; <category> = 3; } }
+//
+// so one reflection-driven builder can fill any of them. Categories not listed here fall back
+// to ned.CommandRequestMessage, which carries common payloads only.
+var commandEnvelopes = map[string]func() proto.Message{
+	"sanitizerGen2":         func() proto.Message { return &sanitizer.CommandRequestMessage{} },
+	"speedsetplus":          func() proto.Message { return &speedsetplus.CommandRequestMessage{} },
+	"speedsetPlusGen2":      func() proto.Message { return &speedsetplus.CommandRequestMessage{} },
+	"digitalControllerGen2": func() proto.Message { return &icl.CommandRequestMessage{} },
+	VspBoosterCategory:      func() proto.Message { return &vspbooster.CommandRequestMessage{} },
+}
+
+// newCommandRequest returns an empty command envelope for a category
+func newCommandRequest(category string) proto.Message {
+	if newRequest, exists := commandEnvelopes[category]; exists {
+		return newRequest()
+	}
+	return &ned.CommandRequestMessage{}
+}
+
+// commandResponses maps MQTT topic categories to a constructor for their CommandResponseMessage
+var commandResponses = map[string]func() proto.Message{
+	"sanitizerGen2":         func() proto.Message { return &sanitizer.CommandResponseMessage{} },
+	"speedsetplus":          func() proto.Message { return &speedsetplus.CommandResponseMessage{} },
+	"speedsetPlusGen2":      func() proto.Message { return &speedsetplus.CommandResponseMessage{} },
+	"digitalControllerGen2": func() proto.Message { return &icl.CommandResponseMessage{} },
+	VspBoosterCategory:      func() proto.Message { return &vspbooster.CommandResponseMessage{} },
+}
+
+// newCommandResponse returns an empty response envelope for a category
+func newCommandResponse(category string) proto.Message {
+	if newResponse, exists := commandResponses[category]; exists {
+		return newResponse()
+	}
+	return &ned.CommandResponseMessage{}
+}
+
+// commandTopic returns the MQTT topic commands for a device are published on
+func (n *NgaSim) commandTopic(category, serial string) string {
+	return n.currentConfig().MQTT.CommandTopic(fmt.Sprintf(TopicCommandFormat, category, serial))
+}
+
+// buildCommandEnvelope wraps a request payload in the CommandRequestMessage expected by the
+// device category and stamps it with commandUUID.
+//
+// The payload may be any of:
+//   - a complete envelope for the category (only the UUID is filled in)
+//   - a payload group such as ned.CommonRequestPayloads or sanitizer.SanitizerRequestPayloads
+//   - a single request such as ned.FindMeCmdRequestPayload or
+//     sanitizer.SetSanitizerTargetPercentageRequestPayload, which is first placed in the
+//     matching oneof of its payload group
+//
+// Common payloads (FindMe, FactoryReset, telemetry configuration, ...) are therefore always
+// routed through CommonRequestPayloads, whatever the category.
+func buildCommandEnvelope(category, commandUUID string, payload proto.Message) (proto.Message, error) {
+	if payload == nil {
+		return nil, fmt.Errorf("no command payload")
+	}
+
+	request := newCommandRequest(category)
+	reflectReq := request.ProtoReflect()
+	reqDesc := reflectReq.Descriptor()
+	payloadName := payload.ProtoReflect().Descriptor().FullName()
+
+	// Already enveloped - keep the caller's message and only set the UUID
+	if payloadName == reqDesc.FullName() {
+		request = proto.Clone(payload)
+		reflectReq = request.ProtoReflect()
+	} else if !placeCommandPayload(reflectReq, payload) {
+		return nil, fmt.Errorf("%s cannot be sent to %s devices (envelope %s)",
+			payloadName, category, reqDesc.FullName())
+	}
+
+	uuidField := reqDesc.Fields().ByName("command_uuid")
+	if uuidField == nil {
+		return nil, fmt.Errorf("envelope %s has no command_uuid field", reqDesc.FullName())
+	}
+	reflectReq.Set(uuidField, protoreflect.ValueOfString(commandUUID))
+
+	return request, nil
+}
+
+// placeCommandPayload sets payload on the envelope field that accepts it, looking either at the
+// envelope fields themselves or one level down inside a payload group's oneof.
+func placeCommandPayload(request protoreflect.Message, payload proto.Message) bool {
+	payloadName := payload.ProtoReflect().Descriptor().FullName()
+	fields := request.Descriptor().Fields()
+
+	// Direct match: payload is a whole payload group (common or category specific)
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.Kind() == protoreflect.MessageKind && field.Message().FullName() == payloadName {
+			request.Set(field, protoreflect.ValueOfMessage(payload.ProtoReflect()))
+			return true
+		}
+	}
+
+	// Nested match: payload is a single request inside one of the payload groups
+	for i := 0; i < fields.Len(); i++ {
+		group := fields.Get(i)
+		if group.Kind() != protoreflect.MessageKind {
+			continue
+		}
+
+		groupFields := group.Message().Fields()
+		for j := 0; j < groupFields.Len(); j++ {
+			inner := groupFields.Get(j)
+			if inner.Kind() == protoreflect.MessageKind && inner.Message().FullName() == payloadName {
+				groupMsg := request.Mutable(group).Message()
+				groupMsg.Set(inner, protoreflect.ValueOfMessage(payload.ProtoReflect()))
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// marshalCommand builds the category envelope for payload and serializes it for publishing
+func marshalCommand(category, commandUUID string, payload proto.Message) (proto.Message, []byte, error) {
+	request, err := buildCommandEnvelope(category, commandUUID, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msgBytes, err := proto.Marshal(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal %s: %v", request.ProtoReflect().Descriptor().FullName(), err)
+	}
+
+	return request, msgBytes, nil
+}
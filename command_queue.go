@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// CommandQueueSize is the most commands held while the broker is unreachable
+const CommandQueueSize = 100
+
+// QueuedCommand is a serialized command waiting for the broker connection to come back
+type QueuedCommand struct {
+	UUID         string
+	DeviceSerial string
+	Category     string
+	Command      string
+	Payload      []byte // Complete CommandRequestMessage, replayed unchanged
+	QueuedAt     time.Time
+	ExpiresAt    time.Time
+}
+
+// CommandQueue holds commands issued while MQTT is disconnected, oldest first
+type CommandQueue struct {
+	entries  []*QueuedCommand
+	ttl      time.Duration
+	coalesce bool // Keep only the newest command of each kind per device
+	maxSize  int
+	mutex    sync.Mutex
+}
+
+// NewCommandQueue creates a queue whose commands expire after ttl; a zero ttl disables queueing
+func NewCommandQueue(ttl time.Duration, coalesce bool, maxSize int) *CommandQueue {
+	return &CommandQueue{
+		entries:  make([]*QueuedCommand, 0),
+		ttl:      ttl,
+		coalesce: coalesce,
+		maxSize:  maxSize,
+	}
+}
+
+// Configure changes the expiry and coalescing policy for commands queued from now on
+func (q *CommandQueue) Configure(ttl time.Duration, coalesce bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.ttl = ttl
+	q.coalesce = coalesce
+}
+
+// Enqueue adds a command. With coalescing on, queued commands with the same device and command
+// name are removed and returned as superseded.
+func (q *CommandQueue) Enqueue(serial, category, command, commandUUID string, payload []byte) (*QueuedCommand, []*QueuedCommand, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.ttl <= 0 {
+		return nil, nil, fmt.Errorf("MQTT is disconnected and command queueing is disabled")
+	}
+
+	var superseded []*QueuedCommand
+	if q.coalesce {
+		kept := q.entries[:0]
+		for _, entry := range q.entries {
+			if entry.DeviceSerial == serial && entry.Command == command {
+				superseded = append(superseded, entry)
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		q.entries = kept
+	}
+
+	if len(q.entries) >= q.maxSize {
+		return nil, superseded, fmt.Errorf("command queue is full (%d commands)", q.maxSize)
+	}
+
+	now := time.Now()
+	entry := &QueuedCommand{
+		UUID:         commandUUID,
+		DeviceSerial: serial,
+		Category:     category,
+		Command:      command,
+		Payload:      payload,
+		QueuedAt:     now,
+		ExpiresAt:    now.Add(q.ttl),
+	}
+	q.entries = append(q.entries, entry)
+	return entry, superseded, nil
+}
+
+// Cancel removes a queued command
+func (q *CommandQueue) Cancel(commandUUID string) (*QueuedCommand, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, entry := range q.entries {
+		if entry.UUID == commandUUID {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// Expire removes and returns commands whose time to live has passed
+func (q *CommandQueue) Expire(now time.Time) []*QueuedCommand {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var expired []*QueuedCommand
+	kept := q.entries[:0]
+	for _, entry := range q.entries {
+		if now.After(entry.ExpiresAt) {
+			expired = append(expired, entry)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	q.entries = kept
+	return expired
+}
+
+// Drain removes and returns every queued command, oldest first
+func (q *CommandQueue) Drain() []*QueuedCommand {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	drained := q.entries
+	q.entries = make([]*QueuedCommand, 0)
+	return drained
+}
+
+// Requeue puts drained commands back at the front of the queue with their original expiry
+func (q *CommandQueue) Requeue(entries []*QueuedCommand) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.entries = append(append(make([]*QueuedCommand, 0, len(entries)+len(q.entries)), entries...), q.entries...)
+}
+
+// Len returns the number of queued commands
+func (q *CommandQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.entries)
+}
+
+// commandQueueSettings reads the queue policy from the configuration
+func commandQueueSettings(cfg *LoadedConfig) (time.Duration, bool) {
+	ttl, err := time.ParseDuration(cfg.Commands.QueueTTL)
+	if err != nil {
+		log.Printf("⚠️ Invalid commands.queue_ttl %q, queueing disabled: %v", cfg.Commands.QueueTTL, err)
+		return 0, cfg.Commands.Coalesce
+	}
+	return ttl, cfg.Commands.Coalesce
+}
+
+// brokerConnected reports whether a command published now would reach the broker. paho's
+// IsConnected stays true while it is reconnecting, so the open connection is checked instead.
+func (n *NgaSim) brokerConnected() bool {
+	return n.mqtt != nil && n.mqtt.IsConnectionOpen()
+}
+
+// sendsToBroker tells whether commands go to the MQTT broker, queued while it is unreachable,
+// rather than being simulated as in demo mode or while a recorded session is replayed
+func (n *NgaSim) sendsToBroker() bool {
+	return n.mqtt != nil && !n.demoMode
+}
+
+// publishCommand sends a serialized command, or queues it for replay while the broker is
+// unreachable. It reports whether the command was queued.
+func (n *NgaSim) publishCommand(serial, category, command, commandUUID string, msgBytes []byte) (bool, error) {
+	if !n.brokerConnected() {
+		return true, n.queueCommand(serial, category, command, commandUUID, msgBytes)
+	}
+
+	n.recordCommand(serial, category, command, commandUUID, msgBytes)
+
+	topic := n.commandTopic(category, serial)
+	n.recordFrame(SessionOutbound, topic, msgBytes, command, commandUUID)
+	token := n.mqtt.Publish(topic, 1, false, msgBytes)
+	if token.Wait() && token.Error() != nil {
+		if !n.brokerConnected() {
+			// Lost the connection while publishing - hold the command for the reconnect
+			return true, n.queueCommand(serial, category, command, commandUUID, msgBytes)
+		}
+		n.logger.LogError(serial, command, fmt.Sprintf("MQTT publish failed: %v", token.Error()), commandUUID, category)
+		return false, fmt.Errorf("failed to publish command: %v", token.Error())
+	}
+	return false, nil
+}
+
+// queueCommand holds a command until the broker connection comes back
+func (n *NgaSim) queueCommand(serial, category, command, commandUUID string, msgBytes []byte) error {
+	entry, superseded, err := n.commandQueue.Enqueue(serial, category, command, commandUUID, msgBytes)
+	for _, old := range superseded {
+		n.commandTracker.Unqueue(old.UUID, CommandSuperseded)
+		n.addDeviceTerminalEntry(serial, "QUEUE",
+			fmt.Sprintf("♻️ Queued %s superseded by a newer command (UUID: %s)", old.Command, old.UUID), nil)
+	}
+	if err != nil {
+		n.logger.LogError(serial, command, err.Error(), commandUUID, category)
+		return err
+	}
+
+	n.commandTracker.Queue(commandUUID, serial, category, command, entry.QueuedAt, entry.ExpiresAt)
+
+	n.mutex.Lock()
+	if device, exists := n.devices[serial]; exists {
+		device.LastCommand = command
+		device.CommandStatus = CommandQueued
+	}
+	n.mutex.Unlock()
+
+	log.Printf("⏸️ MQTT disconnected - queued %s for %s until %s (UUID: %s)",
+		command, serial, entry.ExpiresAt.Format("15:04:05"), commandUUID)
+	n.addDeviceTerminalEntry(serial, "QUEUE",
+		fmt.Sprintf("⏸️ MQTT disconnected - %s queued, expires %s (UUID: %s)",
+			command, entry.ExpiresAt.Format("15:04:05"), commandUUID), msgBytes)
+	return nil
+}
+
+// replayCommandQueue publishes queued commands in the order they were issued. It runs after
+// each (re)connect once the response topics are subscribed.
+func (n *NgaSim) replayCommandQueue() {
+	n.expireQueuedCommands()
+
+	entries := n.commandQueue.Drain()
+	if len(entries) == 0 {
+		return
+	}
+	log.Printf("▶️ Replaying %d queued command(s)", len(entries))
+
+	for i, entry := range entries {
+		if !n.brokerConnected() {
+			// Connection dropped again - put the rest back, still in order
+			n.commandQueue.Requeue(entries[i:])
+			log.Printf("⏸️ MQTT dropped during replay, %d command(s) still queued", n.commandQueue.Len())
+			return
+		}
+
+		n.addDeviceTerminalEntry(entry.DeviceSerial, "QUEUE",
+			fmt.Sprintf("▶️ Replaying %s queued at %s (UUID: %s)",
+				entry.Command, entry.QueuedAt.Format("15:04:05"), entry.UUID), entry.Payload)
+
+		if _, err := n.publishCommand(entry.DeviceSerial, entry.Category, entry.Command, entry.UUID, entry.Payload); err != nil {
+			log.Printf("❌ Replay of %s for %s failed: %v", entry.Command, entry.DeviceSerial, err)
+		}
+	}
+}
+
+// expireQueuedCommands drops queued commands that outlived their time to live
+func (n *NgaSim) expireQueuedCommands() {
+	for _, entry := range n.commandQueue.Expire(time.Now()) {
+		record, ok := n.commandTracker.Unqueue(entry.UUID, CommandExpired)
+		if !ok {
+			continue
+		}
+		log.Printf("⌛ Queued %s for %s expired unsent (UUID: %s)", entry.Command, entry.DeviceSerial, entry.UUID)
+		n.logger.LogError(entry.DeviceSerial, entry.Command,
+			fmt.Sprintf("Expired in queue after %v", entry.ExpiresAt.Sub(entry.QueuedAt)), record.CorrelationID, entry.Category)
+		n.setQueuedCommandStatus(entry, CommandExpired)
+		n.addDeviceTerminalEntry(entry.DeviceSerial, "QUEUE",
+			fmt.Sprintf("⌛ %s expired before MQTT reconnected (UUID: %s)", entry.Command, entry.UUID), nil)
+	}
+}
+
+// cancelQueuedCommand removes a command from the queue before it is sent
+func (n *NgaSim) cancelQueuedCommand(commandUUID string) (*CommandRecord, error) {
+	entry, ok := n.commandQueue.Cancel(commandUUID)
+	if !ok {
+		return nil, fmt.Errorf("command %s is not queued", commandUUID)
+	}
+	record, _ := n.commandTracker.Unqueue(commandUUID, CommandCancelled)
+
+	log.Printf("🚫 Queued %s for %s cancelled (UUID: %s)", entry.Command, entry.DeviceSerial, commandUUID)
+	n.setQueuedCommandStatus(entry, CommandCancelled)
+	n.addDeviceTerminalEntry(entry.DeviceSerial, "QUEUE",
+		fmt.Sprintf("🚫 %s cancelled before sending (UUID: %s)", entry.Command, commandUUID), nil)
+	return record, nil
+}
+
+// deviceCommandStatus returns the status of the last command sent to a device
+func (n *NgaSim) deviceCommandStatus(serial string) string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	if device, exists := n.devices[serial]; exists {
+		return device.CommandStatus
+	}
+	return ""
+}
+
+// commandStatus returns the status of the command commandUUID, or of the device's last command
+// when there is no UUID, as for a command simulated in demo mode
+func (n *NgaSim) commandStatus(serial, commandUUID string) string {
+	if record, exists := n.commandTracker.Get(commandUUID); exists {
+		return record.Status
+	}
+	return n.deviceCommandStatus(serial)
+}
+
+// setQueuedCommandStatus updates the device's command status if entry is its last command
+func (n *NgaSim) setQueuedCommandStatus(entry *QueuedCommand, status string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if device, exists := n.devices[entry.DeviceSerial]; exists &&
+		device.LastCommand == entry.Command && device.CommandStatus == CommandQueued {
+		device.CommandStatus = status
+	}
+}
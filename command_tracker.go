@@ -0,0 +1,481 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"NgaSim/ned"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Command tracking parameters
+const (
+	CommandResponseTimeout = 10 * time.Second ///< How long a command may wait for its response
+	CommandSweepInterval   = 1 * time.Second  ///< How often pending commands are checked for timeout
+	CommandHistorySize     = 1000             ///< Number of commands kept for /api/commands
+)
+
+// Command result states reported by /api/commands and Device.CommandStatus
+const (
+	CommandPending = "PENDING" ///< Sent, waiting for a response
+	CommandSuccess = "SUCCESS" ///< Device answered OK
+	CommandFailed  = "FAILED"  ///< Device answered COMMAND_ERROR or BAD_REQUEST
+	CommandTimeout = "TIMEOUT" ///< No response within CommandResponseTimeout
+
+	CommandQueued     = "QUEUED"     ///< Waiting for the broker connection to come back
+	CommandExpired    = "EXPIRED"    ///< Dropped from the queue after its time to live
+	CommandCancelled  = "CANCELLED"  ///< Removed from the queue by an operator
+	CommandSuperseded = "SUPERSEDED" ///< Replaced in the queue by a newer command of the same kind
+)
+
+// CommandRecord follows a single command from publish to device response
+type CommandRecord struct {
+	UUID          string     `json:"command_uuid"`
+	DeviceSerial  string     `json:"device_serial"`
+	Category      string     `json:"category"`
+	Command       string     `json:"command"`
+	CorrelationID string     `json:"correlation_id"` // DeviceLogger request entry
+	Status        string     `json:"status"`
+	ResponseCode  string     `json:"response_code,omitempty"`
+	ResponseType  string     `json:"response_type,omitempty"` // Payload carried by the response, if any
+	SentAt        time.Time  `json:"sent_at"`
+	QueuedAt      *time.Time `json:"queued_at,omitempty"`  // Set when the command waited for a reconnect
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // When a queued command is dropped
+	RespondedAt   *time.Time `json:"responded_at,omitempty"`
+	LatencyMs     int64      `json:"latency_ms,omitempty"`
+	Late          bool       `json:"late,omitempty"`       // Response arrived after the timeout fired
+	Duplicates    int        `json:"duplicates,omitempty"` // QoS1 redeliveries that were ignored
+}
+
+// CommandReply is a device's answer to a command, handed to whoever awaits it
+type CommandReply struct {
+	UUID    string
+	Code    ned.ResponseCode
+	Payload proto.Message // Payload carried by the response, nil if none
+}
+
+// CommandTracker correlates outgoing commands with their responses by command_uuid
+type CommandTracker struct {
+	records map[string]*CommandRecord
+	order   []string // UUIDs oldest first, used to bound history
+	waiters map[string]chan CommandReply
+	timeout time.Duration
+	maxSize int
+	mutex   sync.RWMutex
+}
+
+// NewCommandTracker creates a tracker that times commands out after timeout
+func NewCommandTracker(timeout time.Duration, maxSize int) *CommandTracker {
+	return &CommandTracker{
+		records: make(map[string]*CommandRecord),
+		order:   make([]string, 0),
+		waiters: make(map[string]chan CommandReply),
+		timeout: timeout,
+		maxSize: maxSize,
+	}
+}
+
+// Track registers a command that has just been published
+func (ct *CommandTracker) Track(commandUUID, deviceSerial, category, command, correlationID string) *CommandRecord {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	record := &CommandRecord{
+		UUID:          commandUUID,
+		DeviceSerial:  deviceSerial,
+		Category:      category,
+		Command:       command,
+		CorrelationID: correlationID,
+		Status:        CommandPending,
+		SentAt:        time.Now(),
+	}
+
+	// A replayed command keeps the record of its time in the queue
+	if queued, exists := ct.records[commandUUID]; exists {
+		record.QueuedAt = queued.QueuedAt
+	}
+
+	ct.add(record)
+	return copyCommandRecord(record)
+}
+
+// Queue registers a command that is held until the broker connection comes back
+func (ct *CommandTracker) Queue(commandUUID, deviceSerial, category, command string, queuedAt, expiresAt time.Time) *CommandRecord {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	record := &CommandRecord{
+		UUID:         commandUUID,
+		DeviceSerial: deviceSerial,
+		Category:     category,
+		Command:      command,
+		Status:       CommandQueued,
+		QueuedAt:     &queuedAt,
+		ExpiresAt:    &expiresAt,
+	}
+
+	ct.add(record)
+	return copyCommandRecord(record)
+}
+
+// Unqueue ends a queued command with status (EXPIRED, CANCELLED or SUPERSEDED). It returns
+// false if the command is unknown or no longer queued.
+func (ct *CommandTracker) Unqueue(commandUUID, status string) (*CommandRecord, bool) {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	record, exists := ct.records[commandUUID]
+	if !exists || record.Status != CommandQueued {
+		return nil, false
+	}
+	record.Status = status
+	return copyCommandRecord(record), true
+}
+
+// add stores a record, dropping the oldest commands once history is full
+func (ct *CommandTracker) add(record *CommandRecord) {
+	if _, exists := ct.records[record.UUID]; !exists {
+		ct.order = append(ct.order, record.UUID)
+	}
+	ct.records[record.UUID] = record
+
+	for len(ct.order) > ct.maxSize {
+		delete(ct.records, ct.order[0])
+		ct.order = ct.order[1:]
+	}
+}
+
+// Resolve applies a device response to the matching command.
+// It returns the updated record, whether the response was a duplicate, and whether the
+// UUID was known at all.
+func (ct *CommandTracker) Resolve(commandUUID string, code ned.ResponseCode, responseType string) (*CommandRecord, bool, bool) {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	record, exists := ct.records[commandUUID]
+	if !exists {
+		return nil, false, false
+	}
+
+	// QoS1 may deliver the same response more than once - only the first one counts
+	if record.RespondedAt != nil {
+		record.Duplicates++
+		return copyCommandRecord(record), true, true
+	}
+
+	now := time.Now()
+	record.RespondedAt = &now
+	record.LatencyMs = now.Sub(record.SentAt).Milliseconds()
+	record.ResponseCode = responseCodeName(code)
+	record.ResponseType = responseType
+	record.Late = record.Status == CommandTimeout
+	record.Status = commandStatusFromCode(code)
+
+	return copyCommandRecord(record), false, true
+}
+
+// Await returns a channel that receives the response to a command. Call it before the command
+// is published, and StopAwait once done waiting.
+func (ct *CommandTracker) Await(commandUUID string) <-chan CommandReply {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	replies := make(chan CommandReply, 1)
+	ct.waiters[commandUUID] = replies
+	return replies
+}
+
+// StopAwait forgets the channel returned by Await
+func (ct *CommandTracker) StopAwait(commandUUID string) {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	delete(ct.waiters, commandUUID)
+}
+
+// deliver hands a response to the caller awaiting it, if any
+func (ct *CommandTracker) deliver(reply CommandReply) {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	if replies, exists := ct.waiters[reply.UUID]; exists {
+		delete(ct.waiters, reply.UUID)
+		replies <- reply
+	}
+}
+
+// ExpirePending marks commands that have waited longer than the timeout and returns them
+func (ct *CommandTracker) ExpirePending() []*CommandRecord {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	var expired []*CommandRecord
+	for _, record := range ct.records {
+		if record.Status == CommandPending && time.Since(record.SentAt) > ct.timeout {
+			record.Status = CommandTimeout
+			expired = append(expired, copyCommandRecord(record))
+		}
+	}
+	return expired
+}
+
+// Get returns the record for a command UUID
+func (ct *CommandTracker) Get(commandUUID string) (*CommandRecord, bool) {
+	ct.mutex.RLock()
+	defer ct.mutex.RUnlock()
+
+	record, exists := ct.records[commandUUID]
+	if !exists {
+		return nil, false
+	}
+	return copyCommandRecord(record), true
+}
+
+// List returns all tracked commands, newest first
+func (ct *CommandTracker) List() []*CommandRecord {
+	ct.mutex.RLock()
+	defer ct.mutex.RUnlock()
+
+	records := make([]*CommandRecord, 0, len(ct.records))
+	for _, record := range ct.records {
+		records = append(records, copyCommandRecord(record))
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].issuedAt().After(records[j].issuedAt())
+	})
+	return records
+}
+
+// issuedAt is when the command was queued, or sent if it never waited
+func (record *CommandRecord) issuedAt() time.Time {
+	if record.QueuedAt != nil {
+		return *record.QueuedAt
+	}
+	return record.SentAt
+}
+
+// copyCommandRecord returns a snapshot that is safe to use outside the tracker lock
+func copyCommandRecord(record *CommandRecord) *CommandRecord {
+	snapshot := *record
+	return &snapshot
+}
+
+// commandStatusFromCode maps a device ResponseCode to a command result
+func commandStatusFromCode(code ned.ResponseCode) string {
+	if code == ned.ResponseCode_RESPONSE_OK {
+		return CommandSuccess
+	}
+	// RESPONSE_COMMAND_ERROR, RESPONSE_BAD_REQUEST and anything unrecognised
+	return CommandFailed
+}
+
+// responseCodeName returns the proto enum name for a ResponseCode
+func responseCodeName(code ned.ResponseCode) string {
+	if name, exists := ned.ResponseCode_name[int32(code)]; exists {
+		return name
+	}
+	return fmt.Sprintf("RESPONSE_CODE_%d", code)
+}
+
+// decodeCommandResponse parses a category CommandResponseMessage and pulls out the fields
+// needed for correlation: the command UUID, the response code and the payload, if any.
+func decodeCommandResponse(category string, payload []byte) (string, ned.ResponseCode, proto.Message, error) {
+	response := newCommandResponse(category)
+	if err := proto.Unmarshal(payload, response); err != nil {
+		return "", ned.ResponseCode_RESPONSE_UNKNOWN, nil, fmt.Errorf("failed to decode %s: %v",
+			response.ProtoReflect().Descriptor().FullName(), err)
+	}
+
+	reflectResp := response.ProtoReflect()
+	fields := reflectResp.Descriptor().Fields()
+
+	commandUUID := ""
+	if field := fields.ByName("command_uuid"); field != nil {
+		commandUUID = reflectResp.Get(field).String()
+	}
+
+	code := ned.ResponseCode_RESPONSE_UNKNOWN
+	if field := fields.ByName("response_code"); field != nil {
+		code = ned.ResponseCode(reflectResp.Get(field).Enum())
+	}
+
+	var responsePayload proto.Message
+	if inner := oneofPayload(reflectResp); inner != nil {
+		responsePayload = inner.Interface()
+	}
+	return commandUUID, code, responsePayload, nil
+}
+
+// oneofPayload follows the set oneof members down from an envelope (envelope -> payload group
+// -> payload) and returns the payload message, e.g. ned.GetDeviceInformationResponsePayload;
+// nil when the message carries no payload.
+func oneofPayload(msg protoreflect.Message) protoreflect.Message {
+	oneofs := msg.Descriptor().Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		field := msg.WhichOneof(oneofs.Get(i))
+		if field == nil || field.Kind() != protoreflect.MessageKind {
+			continue
+		}
+		inner := msg.Get(field).Message()
+		if nested := oneofPayload(inner); nested != nil {
+			return nested
+		}
+		return inner
+	}
+	return nil
+}
+
+// protoTypeName returns the full proto name of msg, or "" for nil
+func protoTypeName(msg proto.Message) string {
+	if msg == nil {
+		return ""
+	}
+	return string(msg.ProtoReflect().Descriptor().FullName())
+}
+
+// recordCommand logs an outgoing command and starts tracking its response
+func (n *NgaSim) recordCommand(serial, category, command, commandUUID string, msgBytes []byte) {
+	correlationID := n.logger.LogRequest(serial, command, msgBytes, category, "protobuf_command")
+	n.commandTracker.Track(commandUUID, serial, category, command, correlationID)
+
+	n.mutex.Lock()
+	if device, exists := n.devices[serial]; exists {
+		device.LastCommand = command
+		device.CommandStatus = CommandPending
+	}
+	n.mutex.Unlock()
+}
+
+// handleCommandResponse processes cmd/<category>/<serial>/res messages and matches them to
+// the command that produced them.
+func (n *NgaSim) handleCommandResponse(category, deviceSerial string, payload []byte) {
+	commandUUID, code, responsePayload, err := decodeCommandResponse(category, payload)
+	if err != nil {
+		log.Printf("❌ Command response from %s: %v", deviceSerial, err)
+		n.logger.LogError(deviceSerial, "CommandResponse", err.Error(), "", category)
+		return
+	}
+
+	record, duplicate, known := n.commandTracker.Resolve(commandUUID, code, protoTypeName(responsePayload))
+	if !duplicate && code == ned.ResponseCode_RESPONSE_OK && responsePayload != nil {
+		n.mergeDevicePayload(deviceSerial, responsePayload)
+	}
+
+	if !known {
+		log.Printf("⚠️ Response %s from %s for unknown command %s", responseCodeName(code), deviceSerial, commandUUID)
+		n.logger.LogResponse(deviceSerial, "CommandResponse", payload, "", category, responseCodeName(code), "uncorrelated")
+		n.addDeviceTerminalEntry(deviceSerial, "RESPONSE",
+			fmt.Sprintf("📥 %s for unknown command (UUID: %s)", responseCodeName(code), commandUUID), payload)
+		return
+	}
+
+	if duplicate {
+		log.Printf("🔁 Duplicate response for %s from %s ignored (%d so far)", commandUUID, deviceSerial, record.Duplicates)
+		return
+	}
+	n.commandTracker.deliver(CommandReply{UUID: commandUUID, Code: code, Payload: responsePayload})
+
+	n.logger.LogResponse(deviceSerial, record.Command, payload, record.CorrelationID, category, record.ResponseCode)
+	if record.Status == CommandFailed {
+		n.logger.LogError(deviceSerial, record.Command,
+			fmt.Sprintf("Device rejected command: %s", record.ResponseCode), record.CorrelationID, category)
+	}
+
+	n.mutex.Lock()
+	if device, exists := n.devices[deviceSerial]; exists && device.LastCommand == record.Command {
+		device.CommandStatus = record.Status
+	}
+	n.mutex.Unlock()
+
+	icon := "✅"
+	if record.Status != CommandSuccess {
+		icon = "❌"
+	}
+	lateNote := ""
+	if record.Late {
+		lateNote = " (after timeout)"
+	}
+	n.addDeviceTerminalEntry(deviceSerial, "RESPONSE",
+		fmt.Sprintf("%s %s: %s in %dms%s (UUID: %s)", icon, record.Command, record.ResponseCode,
+			record.LatencyMs, lateNote, commandUUID), payload)
+	log.Printf("%s Command %s for %s: %s in %dms%s", icon, record.Command, deviceSerial,
+		record.ResponseCode, record.LatencyMs, lateNote)
+}
+
+// watchCommandTimeouts periodically fails commands that never got a response and drops
+// queued commands that expired before MQTT came back
+func (n *NgaSim) watchCommandTimeouts() {
+	ticker := time.NewTicker(CommandSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n.expireQueuedCommands()
+
+		for _, record := range n.commandTracker.ExpirePending() {
+			log.Printf("⏰ Command %s for %s timed out (UUID: %s)", record.Command, record.DeviceSerial, record.UUID)
+			n.logger.LogError(record.DeviceSerial, record.Command,
+				fmt.Sprintf("No response within %v", CommandResponseTimeout), record.CorrelationID, record.Category)
+
+			n.mutex.Lock()
+			if device, exists := n.devices[record.DeviceSerial]; exists && device.LastCommand == record.Command {
+				device.CommandStatus = CommandTimeout
+			}
+			n.mutex.Unlock()
+
+			n.addDeviceTerminalEntry(record.DeviceSerial, "TIMEOUT",
+				fmt.Sprintf("⏰ %s: no response after %v (UUID: %s)", record.Command, CommandResponseTimeout, record.UUID), nil)
+		}
+	}
+}
+
+// handleCommandStatus serves /api/commands/{uuid}, or the recent command list without a UUID
+// (?status=QUEUED filters it). DELETE /api/commands/{uuid} cancels a queued command.
+func (n *NgaSim) handleCommandStatus(w http.ResponseWriter, r *http.Request) {
+	commandUUID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/commands"), "/")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if commandUUID == "" {
+		records := n.commandTracker.List()
+		if status := r.URL.Query().Get("status"); status != "" {
+			filtered := make([]*CommandRecord, 0)
+			for _, record := range records {
+				if strings.EqualFold(record.Status, status) {
+					filtered = append(filtered, record)
+				}
+			}
+			records = filtered
+		}
+		json.NewEncoder(w).Encode(records)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		record, err := n.cancelQueuedCommand(commandUUID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		json.NewEncoder(w).Encode(record)
+		return
+	}
+
+	record, exists := n.commandTracker.Get(commandUUID)
+	if !exists {
+		http.Error(w, fmt.Sprintf("Command '%s' not found", commandUUID), http.StatusNotFound)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(record); err != nil {
+		http.Error(w, fmt.Sprintf("Error encoding JSON: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,640 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultConfigFile is read when -config is not given; a missing default file is not an error
+const DefaultConfigFile = "ngasim.toml"
+
+// Config holds the runtime settings of NgaSim. Values come from built-in defaults, then the
+// TOML config file, then NGASIM_* environment variables, then command line flags.
+type Config struct {
+	MQTT      MQTTConfig      `toml:"mqtt" json:"mqtt"`
+	HTTP      HTTPConfig      `toml:"http" json:"http"`
+	Logging   LoggingConfig   `toml:"logging" json:"logging"`
+	Poller    PollerConfig    `toml:"poller" json:"poller"`
+	Jobs      JobsConfig      `toml:"jobs" json:"jobs"`
+	Commands  CommandsConfig  `toml:"commands" json:"commands"`
+	Broker    BrokerConfig    `toml:"broker" json:"broker"`
+	Simulator SimulatorConfig `toml:"simulator" json:"simulator"`
+	Recording RecordingConfig `toml:"recording" json:"recording"`
+	Replay    ReplayConfig    `toml:"replay" json:"replay"`
+
+	Sites []SiteConfig `toml:"-" json:"sites,omitempty"` // Resolved [[sites]] tables; empty for a single site
+}
+
+// MQTTConfig holds the broker connection and topic layout
+type MQTTConfig struct {
+	Broker        string `toml:"broker" json:"broker"`                 // e.g. tcp://169.254.1.1:1883
+	ClientID      string `toml:"client_id" json:"client_id"`           // MQTT client identifier
+	AsyncPrefix   string `toml:"async_prefix" json:"async_prefix"`     // Prefix of device-published topics
+	CommandPrefix string `toml:"command_prefix" json:"command_prefix"` // Prefix of command request/response topics
+
+	Username    string        `toml:"username" json:"username,omitempty"`         // Broker username
+	Password    string        `toml:"password" json:"-"`                          // Broker password, never reported
+	SecretsFile string        `toml:"secrets_file" json:"secrets_file,omitempty"` // TOML file with username/password
+	TLS         MQTTTLSConfig `toml:"tls" json:"tls"`
+}
+
+// HTTPConfig holds the web server settings
+type HTTPConfig struct {
+	Listen string `toml:"listen" json:"listen"` // Listen address, e.g. :8082
+}
+
+// LoggingConfig holds log destinations and verbosity
+type LoggingConfig struct {
+	TerminalLog string `toml:"terminal_log" json:"terminal_log"` // Terminal tee file
+	Level       string `toml:"level" json:"level"`               // DEBUG, INFO, WARN or ERROR (reloadable)
+}
+
+// PollerConfig holds how the C poller subprocess is started
+type PollerConfig struct {
+	Path string `toml:"path" json:"path"` // Poller executable
+	Sudo bool   `toml:"sudo" json:"sudo"` // Run the poller through sudo
+}
+
+// JobsConfig lists job definition files and how their schedules are evaluated
+type JobsConfig struct {
+	Files     []string `toml:"files" json:"files"`           // Job files or directories of them (reloadable)
+	Timezone  string   `toml:"timezone" json:"timezone"`     // IANA zone for cron schedules, empty for the local zone (reloadable)
+	CatchUp   string   `toml:"catch_up" json:"catch_up"`     // Runs missed while down: skip, once or all (reloadable)
+	StateFile string   `toml:"state_file" json:"state_file"` // Last scheduled runs, kept across restarts for catch-up; empty to not keep them
+
+	DeviceTags map[string][]string `toml:"device_tags" json:"device_tags,omitempty"` // Tags by device serial, for foreach (reloadable, file only)
+}
+
+// CommandsConfig holds how commands issued while MQTT is disconnected are queued (reloadable)
+type CommandsConfig struct {
+	QueueTTL string `toml:"queue_ttl" json:"queue_ttl"` // How long a queued command waits, e.g. 5m; 0 disables queueing
+	Coalesce bool   `toml:"coalesce" json:"coalesce"`   // Keep only the newest queued command of each kind per device
+}
+
+// BrokerConfig holds the embedded MQTT broker used on a bench or in CI without mosquitto
+type BrokerConfig struct {
+	Embedded bool   `toml:"embedded" json:"embedded"` // Run an in-process broker; mqtt.broker defaults to it
+	Listen   string `toml:"listen" json:"listen"`     // Listen address, e.g. 127.0.0.1:1883 or :1883 for other hosts
+}
+
+// SimulatorConfig lists virtual devices that connect to the broker and behave like hardware
+type SimulatorConfig struct {
+	Devices         []string `toml:"devices" json:"devices"`                   // category or category:count, e.g. sanitizerGen2:2
+	TelemetryPeriod string   `toml:"telemetry_period" json:"telemetry_period"` // Until a device is told otherwise, e.g. 5s
+	Scenarios       []string `toml:"scenarios" json:"scenarios"`               // Fault scenario files (reloadable)
+}
+
+// RecordingConfig holds where raw MQTT sessions are recorded
+type RecordingConfig struct {
+	Enabled bool   `toml:"enabled" json:"enabled"` // Record from startup; otherwise start with POST /api/recording
+	Dir     string `toml:"dir" json:"dir"`         // Directory of session files
+}
+
+// ReplayConfig replays a recorded session instead of connecting to a broker
+type ReplayConfig struct {
+	File  string `toml:"file" json:"file"`   // Session file to replay at startup, empty for live operation
+	Speed string `toml:"speed" json:"speed"` // Multiplier of the recorded pace, 0 for as fast as possible, or "step"
+}
+
+// SiteConfig is one pool pad managed by this NgaSim, with its own broker connection and poller.
+// Settings missing from a [[sites]] table are inherited from the top-level [mqtt] and [poller]
+// sections; the client ID and terminal log get the site name appended so sites never collide.
+type SiteConfig struct {
+	Name        string       `toml:"name" json:"name"`
+	Title       string       `toml:"title" json:"title,omitempty"`
+	MQTT        MQTTConfig   `toml:"mqtt" json:"mqtt"`
+	Poller      PollerConfig `toml:"poller" json:"poller"`
+	TerminalLog string       `toml:"terminal_log" json:"terminal_log"`
+}
+
+// DefaultSiteName names the implicit site used when no [[sites]] are configured
+const DefaultSiteName = "default"
+
+// validSiteName matches names that can be used in /sites/<name>/ URLs
+var validSiteName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// DefaultConfig returns the settings NgaSim used before it was configurable
+func DefaultConfig() *Config {
+	return &Config{
+		MQTT: MQTTConfig{
+			Broker:        "tcp://169.254.1.1:1883",
+			ClientID:      "NgaSim-WebUI",
+			AsyncPrefix:   "async",
+			CommandPrefix: "cmd",
+		},
+		HTTP: HTTPConfig{
+			Listen: ":8082",
+		},
+		Logging: LoggingConfig{
+			TerminalLog: "ngasim_terminal.log",
+			Level:       "INFO",
+		},
+		Poller: PollerConfig{
+			Path: "./poller",
+			Sudo: true,
+		},
+		Jobs: JobsConfig{
+			CatchUp: CatchUpSkip,
+		},
+		Commands: CommandsConfig{
+			QueueTTL: "5m",
+			Coalesce: true,
+		},
+		Broker: BrokerConfig{
+			Listen: "127.0.0.1:1883",
+		},
+		Simulator: SimulatorConfig{
+			TelemetryPeriod: "5s",
+		},
+		Recording: RecordingConfig{
+			Dir: "sessions",
+		},
+		Replay: ReplayConfig{
+			Speed: "1",
+		},
+	}
+}
+
+// BaseURL returns the URL operators can reach the web server on from this machine
+func (c HTTPConfig) BaseURL() string {
+	host, port := "localhost", strings.TrimPrefix(c.Listen, ":")
+	if i := strings.LastIndex(c.Listen, ":"); i > 0 {
+		host, port = c.Listen[:i], c.Listen[i+1:]
+	}
+	return fmt.Sprintf("http://%s:%s", host, port)
+}
+
+// configSetting describes one setting that can be overridden from the environment or a flag
+type configSetting struct {
+	Key    string // Dotted TOML key, e.g. mqtt.broker
+	Env    string // Environment variable
+	Flag   string // Command line flag, empty if none
+	Usage  string
+	Reload bool // Applied on SIGHUP without a restart
+	Secret bool // Value is never logged
+	field  func(c *Config) interface{}
+}
+
+// configSettings is the table of every overridable setting
+var configSettings = []configSetting{
+	{Key: "mqtt.broker", Env: "NGASIM_MQTT_BROKER", Flag: "broker", Usage: "MQTT broker URL",
+		field: func(c *Config) interface{} { return &c.MQTT.Broker }},
+	{Key: "mqtt.client_id", Env: "NGASIM_MQTT_CLIENT_ID", Flag: "client-id", Usage: "MQTT client identifier",
+		field: func(c *Config) interface{} { return &c.MQTT.ClientID }},
+	{Key: "mqtt.async_prefix", Env: "NGASIM_ASYNC_PREFIX", Flag: "async-prefix", Usage: "prefix of device topics",
+		field: func(c *Config) interface{} { return &c.MQTT.AsyncPrefix }},
+	{Key: "mqtt.command_prefix", Env: "NGASIM_COMMAND_PREFIX", Flag: "command-prefix", Usage: "prefix of command topics",
+		field: func(c *Config) interface{} { return &c.MQTT.CommandPrefix }},
+	{Key: "mqtt.username", Env: "NGASIM_MQTT_USERNAME", Flag: "mqtt-username", Usage: "MQTT username",
+		field: func(c *Config) interface{} { return &c.MQTT.Username }},
+	{Key: "mqtt.password", Env: "NGASIM_MQTT_PASSWORD", Usage: "MQTT password", Secret: true,
+		field: func(c *Config) interface{} { return &c.MQTT.Password }},
+	{Key: "mqtt.secrets_file", Env: "NGASIM_MQTT_SECRETS_FILE", Flag: "mqtt-secrets", Usage: "TOML file with MQTT username and password",
+		field: func(c *Config) interface{} { return &c.MQTT.SecretsFile }},
+	{Key: "mqtt.tls.ca_file", Env: "NGASIM_MQTT_CA_FILE", Flag: "mqtt-ca", Usage: "PEM CA bundle for the MQTT broker",
+		field: func(c *Config) interface{} { return &c.MQTT.TLS.CAFile }},
+	{Key: "mqtt.tls.cert_file", Env: "NGASIM_MQTT_CERT_FILE", Flag: "mqtt-cert", Usage: "PEM MQTT client certificate",
+		field: func(c *Config) interface{} { return &c.MQTT.TLS.CertFile }},
+	{Key: "mqtt.tls.key_file", Env: "NGASIM_MQTT_KEY_FILE", Flag: "mqtt-key", Usage: "PEM MQTT client private key",
+		field: func(c *Config) interface{} { return &c.MQTT.TLS.KeyFile }},
+	{Key: "mqtt.tls.server_name", Env: "NGASIM_MQTT_SERVER_NAME", Flag: "mqtt-server-name", Usage: "name to verify on the broker certificate",
+		field: func(c *Config) interface{} { return &c.MQTT.TLS.ServerName }},
+	{Key: "mqtt.tls.insecure_skip_verify", Env: "NGASIM_MQTT_INSECURE", Flag: "mqtt-insecure", Usage: "skip broker certificate verification (lab use only)",
+		field: func(c *Config) interface{} { return &c.MQTT.TLS.InsecureSkipVerify }},
+	{Key: "http.listen", Env: "NGASIM_HTTP_LISTEN", Flag: "listen", Usage: "web server listen address",
+		field: func(c *Config) interface{} { return &c.HTTP.Listen }},
+	{Key: "logging.terminal_log", Env: "NGASIM_TERMINAL_LOG", Flag: "terminal-log", Usage: "terminal log file",
+		field: func(c *Config) interface{} { return &c.Logging.TerminalLog }},
+	{Key: "logging.level", Env: "NGASIM_LOG_LEVEL", Flag: "log-level", Usage: "device log level (DEBUG, INFO, WARN, ERROR)", Reload: true,
+		field: func(c *Config) interface{} { return &c.Logging.Level }},
+	{Key: "poller.path", Env: "NGASIM_POLLER_PATH", Flag: "poller", Usage: "poller executable",
+		field: func(c *Config) interface{} { return &c.Poller.Path }},
+	{Key: "poller.sudo", Env: "NGASIM_POLLER_SUDO", Flag: "poller-sudo", Usage: "run the poller through sudo",
+		field: func(c *Config) interface{} { return &c.Poller.Sudo }},
+	{Key: "jobs.files", Env: "NGASIM_JOB_FILES", Flag: "jobs", Usage: "comma separated job files or directories of them", Reload: true,
+		field: func(c *Config) interface{} { return &c.Jobs.Files }},
+	{Key: "jobs.timezone", Env: "NGASIM_JOB_TIMEZONE", Flag: "job-timezone", Usage: "IANA timezone for job schedules, e.g. America/Chicago (default local)", Reload: true,
+		field: func(c *Config) interface{} { return &c.Jobs.Timezone }},
+	{Key: "jobs.catch_up", Env: "NGASIM_JOB_CATCH_UP", Flag: "job-catch-up", Usage: "scheduled job runs missed while down: skip, once or all", Reload: true,
+		field: func(c *Config) interface{} { return &c.Jobs.CatchUp }},
+	{Key: "jobs.state_file", Env: "NGASIM_JOB_STATE_FILE", Flag: "job-state", Usage: "file keeping last scheduled job runs across restarts",
+		field: func(c *Config) interface{} { return &c.Jobs.StateFile }},
+	{Key: "commands.queue_ttl", Env: "NGASIM_COMMAND_QUEUE_TTL", Flag: "command-queue-ttl", Usage: "how long commands wait for an MQTT reconnect (0 disables queueing)", Reload: true,
+		field: func(c *Config) interface{} { return &c.Commands.QueueTTL }},
+	{Key: "commands.coalesce", Env: "NGASIM_COMMAND_COALESCE", Flag: "command-coalesce", Usage: "keep only the newest queued command of each kind per device", Reload: true,
+		field: func(c *Config) interface{} { return &c.Commands.Coalesce }},
+	{Key: "broker.embedded", Env: "NGASIM_BROKER_EMBEDDED", Flag: "embedded-broker", Usage: "run an in-process MQTT broker and connect to it",
+		field: func(c *Config) interface{} { return &c.Broker.Embedded }},
+	{Key: "broker.listen", Env: "NGASIM_BROKER_LISTEN", Flag: "broker-listen", Usage: "embedded MQTT broker listen address",
+		field: func(c *Config) interface{} { return &c.Broker.Listen }},
+	{Key: "simulator.devices", Env: "NGASIM_SIMULATOR_DEVICES", Flag: "simulate", Usage: "comma separated virtual devices, e.g. sanitizerGen2:2,vspBoosterGen2",
+		field: func(c *Config) interface{} { return &c.Simulator.Devices }},
+	{Key: "simulator.telemetry_period", Env: "NGASIM_SIMULATOR_PERIOD", Flag: "simulate-period", Usage: "virtual device telemetry period",
+		field: func(c *Config) interface{} { return &c.Simulator.TelemetryPeriod }},
+	{Key: "simulator.scenarios", Env: "NGASIM_SIMULATOR_SCENARIOS", Flag: "scenarios", Usage: "comma separated fault scenario files", Reload: true,
+		field: func(c *Config) interface{} { return &c.Simulator.Scenarios }},
+	{Key: "recording.enabled", Env: "NGASIM_RECORD", Flag: "record", Usage: "record every MQTT frame to a session file from startup",
+		field: func(c *Config) interface{} { return &c.Recording.Enabled }},
+	{Key: "recording.dir", Env: "NGASIM_RECORDING_DIR", Flag: "record-dir", Usage: "directory of recorded session files", Reload: true,
+		field: func(c *Config) interface{} { return &c.Recording.Dir }},
+	{Key: "replay.file", Env: "NGASIM_REPLAY", Flag: "replay", Usage: "replay a recorded session file instead of connecting to a broker",
+		field: func(c *Config) interface{} { return &c.Replay.File }},
+	{Key: "replay.speed", Env: "NGASIM_REPLAY_SPEED", Flag: "replay-speed", Usage: "replay pace: a multiplier, 0 for as fast as possible, or step", Reload: true,
+		field: func(c *Config) interface{} { return &c.Replay.Speed }},
+}
+
+// set parses value into the setting's field
+func (s configSetting) set(c *Config, value string) error {
+	switch field := s.field(c).(type) {
+	case *string:
+		*field = value
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", s.Key, err)
+		}
+		*field = b
+	case *[]string:
+		*field = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*field = append(*field, item)
+			}
+		}
+	}
+	return nil
+}
+
+// boolSettingFlag is the flag of a bool setting. It keeps the text like the other setting flags
+// until loadConfig applies it, but a bare -flag means true as with any Go bool flag.
+type boolSettingFlag struct {
+	value *string
+}
+
+func (f boolSettingFlag) String() string {
+	if f.value == nil {
+		return ""
+	}
+	return *f.value
+}
+
+func (f boolSettingFlag) Set(value string) error {
+	*f.value = value
+	return nil
+}
+
+func (f boolSettingFlag) IsBoolFlag() bool { return true }
+
+// LoadedConfig is the effective configuration together with where each value came from
+type LoadedConfig struct {
+	*Config
+	File     string            `json:"file,omitempty"` // Config file read, empty if none
+	Sources  map[string]string `json:"sources"`        // Key -> default, file, env, flag or embedded
+	LoadedAt time.Time         `json:"loaded_at"`
+	Site     string            `json:"site,omitempty"` // Site this view belongs to, see forSite
+
+	args  []string         // Command line, kept so a reload applies the same flags
+	sites []toml.Primitive // Undecoded [[sites]] tables, resolved once env and flags are applied
+	meta  toml.MetaData
+}
+
+// LoadConfig builds the configuration from defaults, the config file, the environment and args
+// (the command line without the program name)
+func LoadConfig(args []string) (*LoadedConfig, error) {
+	return loadConfig(flag.NewFlagSet("ngasim", flag.ContinueOnError), args)
+}
+
+// loadConfig is LoadConfig with the configuration flags added to fs, so a command line mode can
+// define flags of its own alongside them
+func loadConfig(fs *flag.FlagSet, args []string) (*LoadedConfig, error) {
+	configFile := fs.String("config", "", "config file (default "+DefaultConfigFile+" if present)")
+	flagValues := make(map[string]*string)
+	for _, s := range configSettings {
+		if s.Flag == "" {
+			continue
+		}
+		usage := s.Usage + " (env " + s.Env + ")"
+		if _, isBool := s.field(&Config{}).(*bool); isBool {
+			flagValues[s.Key] = new(string)
+			fs.Var(boolSettingFlag{flagValues[s.Key]}, s.Flag, usage)
+		} else {
+			flagValues[s.Key] = fs.String(s.Flag, "", usage)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	loaded := &LoadedConfig{
+		Config:   DefaultConfig(),
+		Sources:  make(map[string]string),
+		LoadedAt: time.Now(),
+		args:     args,
+	}
+	for _, s := range configSettings {
+		loaded.Sources[s.Key] = "default"
+	}
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("NGASIM_CONFIG")
+	}
+	if path == "" {
+		if _, err := os.Stat(DefaultConfigFile); err == nil {
+			path = DefaultConfigFile
+		}
+	}
+	if path != "" {
+		meta, err := toml.DecodeFile(path, loaded.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+		}
+		var sites struct {
+			Sites []toml.Primitive `toml:"sites"`
+		}
+		if loaded.meta, err = toml.DecodeFile(path, &sites); err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+		}
+		loaded.sites = sites.Sites
+		var unknown []toml.Key
+		for _, key := range meta.Undecoded() {
+			if key[0] != "sites" {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			log.Printf("⚠️ Unknown keys in %s: %v", path, unknown)
+		}
+		for _, s := range configSettings {
+			if meta.IsDefined(strings.Split(s.Key, ".")...) {
+				loaded.Sources[s.Key] = "file"
+			}
+		}
+		loaded.File = path
+	}
+
+	for _, s := range configSettings {
+		if value, ok := os.LookupEnv(s.Env); ok {
+			if err := s.set(loaded.Config, value); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", s.Env, err)
+			}
+			loaded.Sources[s.Key] = "env"
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range configSettings {
+			if s.Flag == f.Name && flagErr == nil {
+				if flagErr = s.set(loaded.Config, *flagValues[s.Key]); flagErr == nil {
+					loaded.Sources[s.Key] = "flag"
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, fmt.Errorf("invalid -flag: %v", flagErr)
+	}
+
+	if _, err := parseLogLevel(loaded.Logging.Level); err != nil {
+		return nil, err
+	}
+	if _, err := time.ParseDuration(loaded.Commands.QueueTTL); err != nil {
+		return nil, fmt.Errorf("invalid commands.queue_ttl: %v", err)
+	}
+	if _, err := parseSimulatorDevices(loaded.Simulator.Devices); err != nil {
+		return nil, fmt.Errorf("invalid simulator.devices: %v", err)
+	}
+	if period, err := time.ParseDuration(loaded.Simulator.TelemetryPeriod); err != nil || period < MinTelemetryPeriod {
+		return nil, fmt.Errorf("invalid simulator.telemetry_period %q (at least %v)", loaded.Simulator.TelemetryPeriod, MinTelemetryPeriod)
+	}
+	if loaded.Broker.Embedded && loaded.Sources["mqtt.broker"] == "default" {
+		// Connect to our own broker unless a broker was named explicitly
+		loaded.MQTT.Broker = brokerURL(loaded.Broker.Listen)
+		loaded.Sources["mqtt.broker"] = "embedded"
+	}
+	if err := loaded.resolveSites(); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// resolveSites decodes each [[sites]] table over the effective top-level settings
+func (c *LoadedConfig) resolveSites() error {
+	seen := make(map[string]bool)
+	for i, primitive := range c.sites {
+		site := SiteConfig{MQTT: c.MQTT, Poller: c.Poller}
+		site.MQTT.ClientID = ""
+		if err := c.meta.PrimitiveDecode(primitive, &site); err != nil {
+			return fmt.Errorf("invalid [[sites]] entry %d: %v", i+1, err)
+		}
+
+		if !validSiteName.MatchString(site.Name) {
+			return fmt.Errorf("[[sites]] entry %d: name %q must be letters, digits, - or _", i+1, site.Name)
+		}
+		if seen[site.Name] {
+			return fmt.Errorf("[[sites]] entry %d: duplicate site name %q", i+1, site.Name)
+		}
+		seen[site.Name] = true
+
+		if site.MQTT.ClientID == "" {
+			site.MQTT.ClientID = c.MQTT.ClientID + "-" + site.Name
+		}
+		if site.TerminalLog == "" {
+			ext := filepath.Ext(c.Logging.TerminalLog)
+			site.TerminalLog = strings.TrimSuffix(c.Logging.TerminalLog, ext) + "-" + site.Name + ext
+		}
+		c.Sites = append(c.Sites, site)
+	}
+
+	var unknown []toml.Key
+	for _, key := range c.meta.Undecoded() {
+		if key[0] == "sites" {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		log.Printf("⚠️ Unknown keys in [[sites]]: %v", unknown)
+	}
+	return nil
+}
+
+// useEmbeddedBroker points every broker URL that names the embedded broker's listen address at
+// the address it actually bound, which differs when listening on port 0
+func (c *LoadedConfig) useEmbeddedBroker(url string) {
+	configured := brokerURL(c.Broker.Listen)
+	if c.MQTT.Broker == configured {
+		c.MQTT.Broker = url
+	}
+	for i := range c.Sites {
+		if c.Sites[i].MQTT.Broker == configured {
+			c.Sites[i].MQTT.Broker = url
+		}
+	}
+}
+
+// siteNames lists the configured sites, or the implicit default site
+func (c *LoadedConfig) siteNames() []string {
+	if len(c.Sites) == 0 {
+		return []string{DefaultSiteName}
+	}
+	names := make([]string, 0, len(c.Sites))
+	for _, site := range c.Sites {
+		names = append(names, site.Name)
+	}
+	return names
+}
+
+// forSite returns the configuration one site runs with: the shared settings plus the site's
+// broker, poller, terminal log and job state file
+func (c *LoadedConfig) forSite(name string) (*LoadedConfig, error) {
+	view := *c
+	view.Config = &Config{}
+	*view.Config = *c.Config
+	view.Site = name
+
+	if len(c.Sites) == 0 && name == DefaultSiteName {
+		return &view, nil
+	}
+	for _, site := range c.Sites {
+		if site.Name == name {
+			view.MQTT = site.MQTT
+			view.Poller = site.Poller
+			view.Logging.TerminalLog = site.TerminalLog
+			if c.Jobs.StateFile != "" {
+				ext := filepath.Ext(c.Jobs.StateFile)
+				view.Jobs.StateFile = strings.TrimSuffix(c.Jobs.StateFile, ext) + "-" + site.Name + ext
+			}
+			return &view, nil
+		}
+	}
+	return nil, fmt.Errorf("site %q is not configured", name)
+}
+
+// parseLogLevel converts a configured level name to a LogLevel
+func parseLogLevel(name string) (LogLevel, error) {
+	for _, level := range []LogLevel{LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError} {
+		if strings.EqualFold(name, level.String()) {
+			return level, nil
+		}
+	}
+	return LogLevelInfo, fmt.Errorf("unknown log level %q (want DEBUG, INFO, WARN or ERROR)", name)
+}
+
+// currentConfig returns the effective configuration
+func (n *NgaSim) currentConfig() *LoadedConfig {
+	n.configMutex.RLock()
+	defer n.configMutex.RUnlock()
+	return n.config
+}
+
+// onConfigReload registers a function called with the new configuration after each reload
+func (n *NgaSim) onConfigReload(hook func(cfg *LoadedConfig)) {
+	n.configMutex.Lock()
+	defer n.configMutex.Unlock()
+	n.reloadHooks = append(n.reloadHooks, hook)
+}
+
+// applyLogLevel sets the device logger threshold from the configuration
+func (n *NgaSim) applyLogLevel(cfg *LoadedConfig) {
+	level, err := parseLogLevel(cfg.Logging.Level)
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+		return
+	}
+	if n.logger != nil {
+		n.logger.SetLevel(level)
+	}
+}
+
+// reloadConfig applies the reloadable settings of a freshly loaded configuration. Changes to any
+// other setting are reported and take effect on the next restart.
+func (n *NgaSim) reloadConfig(fresh *LoadedConfig) error {
+	current := n.currentConfig()
+
+	var err error
+	if current.Site != "" {
+		if fresh, err = fresh.forSite(current.Site); err != nil {
+			return err
+		}
+	}
+
+	// Start from the running values and take only what can change without a restart
+	next := &LoadedConfig{
+		Config:   &Config{},
+		File:     fresh.File,
+		Sources:  make(map[string]string),
+		LoadedAt: fresh.LoadedAt,
+		Site:     current.Site,
+		args:     current.args,
+	}
+	*next.Config = *current.Config
+	for _, s := range configSettings {
+		next.Sources[s.Key] = current.Sources[s.Key]
+		oldValue := reflect.ValueOf(s.field(current.Config)).Elem().Interface()
+		newValue := reflect.ValueOf(s.field(fresh.Config)).Elem().Interface()
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		shownOld, shownNew := oldValue, newValue
+		if s.Secret {
+			shownOld, shownNew = "(secret)", "(secret)"
+		}
+		if !s.Reload {
+			log.Printf("⚠️ Config %s changed to %v; restart to apply", s.Key, shownNew)
+			continue
+		}
+		reflect.ValueOf(s.field(next.Config)).Elem().Set(reflect.ValueOf(newValue))
+		next.Sources[s.Key] = fresh.Sources[s.Key]
+		log.Printf("🔄 Config %s: %v → %v", s.Key, shownOld, shownNew)
+	}
+	// Device tags are a file-only table, so they have no entry in configSettings
+	if !reflect.DeepEqual(current.Jobs.DeviceTags, fresh.Jobs.DeviceTags) {
+		next.Jobs.DeviceTags = fresh.Jobs.DeviceTags
+		log.Printf("🔄 Config jobs.device_tags: %d devices tagged", len(fresh.Jobs.DeviceTags))
+	}
+
+	n.configMutex.Lock()
+	n.config = next
+	hooks := append([]func(*LoadedConfig){}, n.reloadHooks...)
+	n.configMutex.Unlock()
+
+	for _, hook := range hooks {
+		hook(next)
+	}
+	return nil
+}
+
+// handleConfig returns the effective configuration and the source of each value
+func (n *NgaSim) handleConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := n.currentConfig()
+
+	reloadable := []string{}
+	for _, s := range configSettings {
+		if s.Reload {
+			reloadable = append(reloadable, s.Key)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"config":     cfg.Config,
+		"file":       cfg.File,
+		"sources":    cfg.Sources,
+		"loaded_at":  cfg.LoadedAt,
+		"reloadable": reloadable,
+	})
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"NgaSim/ned/icl"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// DctTelemetry is the controller-level telemetry of a digital controller (DCT)
+type DctTelemetry struct {
+	Power              int32     `json:"power"`               // W, 0 - 330
+	Current            int32     `json:"current"`             // mA, 0 - 25000
+	Voltage            float64   `json:"voltage"`             // VAC, 11.9 - 16.1
+	BoardTemp          float64   `json:"board_temp"`          // °C
+	DeratingPercentage int32     `json:"derating_percentage"` // 100 means no derating
+	RSSI               int32     `json:"rssi"`
+	Updated            time.Time `json:"updated"`
+}
+
+// DeviceLight is a light connected to a digital controller. Each light is tracked as its own
+// entity under the controller, merged from telemetry, status, information and error messages.
+type DeviceLight struct {
+	Address         int32                  `json:"address"`
+	Model           string                 `json:"model,omitempty"`
+	FirmwareVersion string                 `json:"firmware_version,omitempty"`
+	SerialNumber    string                 `json:"serial_number,omitempty"`
+	ControlType     string                 `json:"control_type,omitempty"`
+	Brightness      int32                  `json:"brightness"`
+	MaxBrightness   int32                  `json:"max_brightness"`
+	DriveMode       map[string]interface{} `json:"drive_mode,omitempty"`
+	Available       bool                   `json:"available"`
+
+	Temperature        float64   `json:"temperature"`         // °C
+	DeratingPercentage int32     `json:"derating_percentage"` // 100 means no derating
+	TelemetryUpdated   time.Time `json:"telemetry_updated,omitempty"`
+
+	Faults      []string  `json:"faults,omitempty"` // Active fault codes raised for this light
+	AddedAt     time.Time `json:"added_at"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// lightSource is the fault Source used for errors raised by the light at address
+func lightSource(address int32) string {
+	return fmt.Sprintf("light %d", address)
+}
+
+// light returns the light at address, adding it to the device if it is new
+func (d *Device) light(address int32, now time.Time) *DeviceLight {
+	for _, light := range d.Lights {
+		if light.Address == address {
+			return light
+		}
+	}
+	light := &DeviceLight{Address: address, AddedAt: now}
+	d.Lights = append(d.Lights, light)
+	sort.Slice(d.Lights, func(i, j int) bool { return d.Lights[i].Address < d.Lights[j].Address })
+	return light
+}
+
+// applyLightInformation records identity details of a light
+func (d *Device) applyLightInformation(info *icl.LightDeviceInformation, now time.Time) {
+	light := d.light(info.GetAddress(), now)
+	light.Model = info.GetModel()
+	light.FirmwareVersion = info.GetFirmwareVersion()
+	light.SerialNumber = info.GetSerialNumber()
+	light.LastUpdated = now
+}
+
+// applyLightStatus records the state of one light
+func (d *Device) applyLightStatus(status *icl.LightStatus, now time.Time) {
+	light := d.light(status.GetAddress(), now)
+	light.ControlType = status.GetControlType().String()
+	light.Brightness = status.GetBrightness()
+	light.MaxBrightness = status.GetMaxBrightness()
+	light.Available = status.GetIsAvailable()
+	light.DriveMode = nil
+	if driveMode := status.GetDriveMode(); driveMode != nil {
+		light.DriveMode, _ = protoMessageToMap(driveMode)
+	}
+	light.LastUpdated = now
+}
+
+// applyDctStatus records the DCT capacity and the state of every light it reports
+func (d *Device) applyDctStatus(status *icl.DctStatus, now time.Time) {
+	d.DctWattageCapacity = status.GetDctWattageCapacity()
+
+	for _, lightStatus := range status.GetLightsStatus() {
+		d.applyLightStatus(lightStatus, now)
+	}
+}
+
+// applyDctTelemetry stores controller telemetry and the temperature and derating of each light
+func (d *Device) applyDctTelemetry(telemetry *icl.TelemetryMessage, dct *DctTelemetry) {
+	d.Dct = dct
+
+	for _, lightTelemetry := range telemetry.GetLightsTelemetry() {
+		light := d.light(lightTelemetry.GetAddress(), dct.Updated)
+		light.Temperature = deciDegrees(lightTelemetry.GetLightTemperature())
+		light.DeratingPercentage = lightTelemetry.GetLightDeratingPercentage()
+		light.TelemetryUpdated = dct.Updated
+	}
+}
+
+// refreshLightFaults copies the device's active faults onto the lights that raised them
+func (d *Device) refreshLightFaults() {
+	for _, light := range d.Lights {
+		light.Faults = nil
+		source := lightSource(light.Address)
+		for _, fault := range d.ActiveFaults() {
+			if fault.Source == source {
+				light.Faults = append(light.Faults, fault.Code)
+			}
+		}
+	}
+}
+
+// mergePayload folds a decoded response or status payload into the device. Payloads that carry
+// nothing the device tracks beyond StatusDetail are ignored.
+func (d *Device) mergePayload(msg proto.Message, now time.Time) bool {
+	switch payload := msg.(type) {
+	case *icl.GetDctStatusResponse:
+		d.applyDctStatus(payload.GetDctStatus(), now)
+	case *icl.GetLightStatusResponse:
+		d.applyLightStatus(payload.GetLightStatus(), now)
+	case *icl.GetDctInformationResponse:
+		for _, info := range payload.GetLightsInformation() {
+			d.applyLightInformation(info, now)
+		}
+	case *icl.GetLightInformationResponse:
+		d.applyLightInformation(payload.GetLightInformation(), now)
+	default:
+		return false
+	}
+	return true
+}
+
+// mergeDevicePayload applies a successful command response payload to the device it came from
+func (sim *NgaSim) mergeDevicePayload(deviceSerial string, msg proto.Message) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	device, exists := sim.devices[deviceSerial]
+	if !exists {
+		return
+	}
+
+	if device.mergePayload(msg, time.Now()) {
+		log.Printf("🔄 Merged %s into device %s", protoTypeName(msg), deviceSerial)
+	}
+}
+
+// dctTelemetryUnits annotates DCT telemetry fields for display
+var dctTelemetryUnits = map[string]string{
+	"power":                         "W",
+	"current":                       "mA",
+	"voltage":                       "VAC",
+	"rssi":                          "dBm",
+	"dct_power_derating_percentage": "%",
+}
+
+// decodeDctTelemetry decodes icl.TelemetryMessage from a digital controller and its lights
+func (sim *NgaSim) decodeDctTelemetry(deviceSerial string, payload []byte) (*TelemetryResult, error) {
+	telemetry := &icl.TelemetryMessage{}
+	if err := proto.Unmarshal(payload, telemetry); err != nil {
+		return nil, fmt.Errorf("failed to parse as icl TelemetryMessage: %v", err)
+	}
+
+	dct := &DctTelemetry{
+		Power:              telemetry.GetPower(),
+		Current:            telemetry.GetCurrent(),
+		Voltage:            telemetry.GetVoltage(),
+		BoardTemp:          deciDegrees(telemetry.GetBoardTemperature()),
+		DeratingPercentage: telemetry.GetDctPowerDeratingPercentage(),
+		RSSI:               telemetry.GetRssi(),
+		Updated:            time.Now(),
+	}
+
+	parsed := parseProtoMessage(telemetry, "digitalControllerGen2", payload, dctTelemetryUnits)
+	scaleParsedField(parsed, "board_temperature", 0.1, "°C")
+
+	return &TelemetryResult{
+		Summary: fmt.Sprintf("← DCT: %dW | %dmA | %.1fVAC | board %.1f°C | %d lights | RSSI: %ddBm",
+			dct.Power, dct.Current, dct.Voltage, dct.BoardTemp, len(telemetry.GetLightsTelemetry()), dct.RSSI),
+		Parsed: parsed,
+		Apply: func() {
+			sim.updateDeviceFromDctTelemetry(deviceSerial, telemetry, dct)
+		},
+	}, nil
+}
+
+// updateDeviceFromDctTelemetry stores controller and light telemetry, auto-creating the device
+func (sim *NgaSim) updateDeviceFromDctTelemetry(deviceSerial string, telemetry *icl.TelemetryMessage, dct *DctTelemetry) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	device, exists := sim.devices[deviceSerial]
+	if !exists {
+		device = &Device{
+			ID:       deviceSerial,
+			Serial:   deviceSerial,
+			Name:     fmt.Sprintf("DCT-%s", deviceSerial),
+			Type:     "digitalControllerGen2",
+			Category: "digitalControllerGen2",
+			Status:   DeviceOnline,
+			LastSeen: time.Now(),
+		}
+		sim.devices[deviceSerial] = device
+		log.Printf("✅ Auto-created DCT device from telemetry: %s", deviceSerial)
+	}
+
+	device.applyDctTelemetry(telemetry, dct)
+
+	// Keep the generic fields in step for the dashboard and older API clients
+	device.Power = int(dct.Power)
+	device.Temp = dct.BoardTemp
+
+	device.noteTelemetry(time.Now())
+	sim.markDeviceSeen(device)
+}
@@ -28,6 +28,13 @@ type Device struct {
 	Temp  float64 `json:"temperature,omitempty"`
 	Power int     `json:"power,omitempty"`
 
+	// SpeedSet Plus pump telemetry (RPM, Power and Temp above are filled from it too)
+	Pump *PumpTelemetry `json:"pump,omitempty"`
+
+	// Digital controller telemetry (Power and Temp above are filled from it too); per-light
+	// state lives in Lights
+	Dct *DctTelemetry `json:"dct,omitempty"`
+
 	// Sanitizer fields
 	PowerLevel  int     `json:"power_level,omitempty"`  // 0-101%
 	Salinity    int     `json:"salinity,omitempty"`     // ppm
@@ -71,6 +78,50 @@ type Device struct {
 	CommandStatus  string          `json:"command_status"`  // SUCCESS/FAILED/PENDING
 	HumanName      string          `json:"human_name"`      // Friendly display name
 	ConnectionTime time.Time       `json:"connection_time"` // When device first connected
+
+	// Telemetry topic
+	Telemetry        map[string]interface{} `json:"telemetry,omitempty"`         // Last protobuf telemetry message, raw field values
+	TelemetryUpdated time.Time              `json:"telemetry_updated,omitempty"` // When it arrived
+
+	// Error and status topics
+	Faults           []*DeviceFault         `json:"faults,omitempty"`         // Active faults plus recently cleared ones
+	ActiveFaultCount int                    `json:"active_fault_count"`       // Number of faults still raised
+	StatusDetail     map[string]interface{} `json:"status_detail,omitempty"`  // Last decoded status message
+	StatusUpdated    time.Time              `json:"status_updated,omitempty"` // When the status message arrived
+
+	// Info topic
+	Configuration        map[string]interface{} `json:"configuration,omitempty"`         // Last pushed configuration
+	ConfigurationUpdated time.Time              `json:"configuration_updated,omitempty"` // When it was pushed
+	DctWattageCapacity   int32                  `json:"dct_wattage_capacity,omitempty"`  // Digital controller capacity
+	Lights               []*DeviceLight         `json:"lights,omitempty"`                // Lights on a digital controller
+
+	// Liveness tracking
+	StatusReason       string    `json:"status_reason,omitempty"`        // Why Status last changed
+	StatusChanged      time.Time `json:"status_changed,omitempty"`       // When Status last changed
+	TelemetryPeriodSec float64   `json:"telemetry_period_sec,omitempty"` // Observed telemetry period
+	lastTelemetry      time.Time // Previous telemetry sample, for the period estimate
+}
+
+// PumpTelemetry holds the latest SpeedSet Plus telemetry, converted to display units
+type PumpTelemetry struct {
+	MotorRPM           int32     `json:"motor_rpm"`            // Current motor speed
+	DemandRPM          int32     `json:"demand_rpm"`           // Speed requested by the user
+	MotorCurrent       int32     `json:"motor_current"`        // A rms
+	Torque             int32     `json:"torque"`               // Nm
+	InverterInputPower int32     `json:"inverter_input_power"` // W
+	OutputPower        int32     `json:"output_power"`         // W, inverter output
+	MotorInputPower    int32     `json:"motor_input_power"`    // W
+	DCBusVoltage       int32     `json:"dc_bus_voltage"`       // V
+	MotorLineVoltage   int32     `json:"motor_line_voltage"`   // V
+	AmbientTemp        float64   `json:"ambient_temp"`         // °C (device sends deci-degrees)
+	IPMTemp            float64   `json:"ipm_temp"`             // °C (device sends deci-degrees)
+	Humidity           int32     `json:"humidity"`             // %, optional sensor
+	VibrationX         int32     `json:"vibration_x"`          // mg, optional sensor
+	VibrationY         int32     `json:"vibration_y"`          // mg, optional sensor
+	VibrationZ         int32     `json:"vibration_z"`          // mg, optional sensor
+	TotalFaults        int32     `json:"total_faults"`         // Faults counted by the drive
+	RSSI               int32     `json:"rssi"`                 // dBm
+	Updated            time.Time `json:"updated"`
 }
 
 // TerminalEntry represents a single terminal log entry for a device
@@ -130,6 +181,9 @@ func (pcr *ProtobufCommandRegistry) discoverCommands() {
 	// Discover sanitizer commands
 	pcr.discoverSanitizerCommands()
 
+	// Discover VSP booster pump commands
+	pcr.discoverVspBoosterCommands()
+
 	// TODO: Add other device types (pumps, heaters, etc.) as needed
 
 	log.Printf("Command discovery complete. Found commands for %d device categories", len(pcr.commandsByCategory))
@@ -172,6 +226,63 @@ func (pcr *ProtobufCommandRegistry) discoverSanitizerCommands() {
 	log.Printf("Discovered %d commands for sanitizerGen2 devices", len(sanitizerCommands))
 }
 
+// discoverVspBoosterCommands analyzes the VSP booster protobuf structures
+func (pcr *ProtobufCommandRegistry) discoverVspBoosterCommands() {
+	boosterCommands := []CommandInfo{
+		{
+			Name:        "set_vsp_booster_control_command",
+			DisplayName: "Set Motor Control",
+			Description: "Start or stop the booster pump at a demand RPM",
+			Category:    VspBoosterCategory,
+			IsQuery:     false,
+			Fields: []CommandField{
+				{
+					Name:        "power",
+					Type:        "int32",
+					Description: "Motor control (0 = off, 1 = on)",
+					Required:    true,
+					Min:         VspBoosterPowerOff,
+					Max:         VspBoosterPowerOn,
+				},
+				{
+					Name:        "set_demand_rpm",
+					Type:        "int32",
+					Description: "Demand speed in RPM",
+					Required:    true,
+					Min:         0,
+				},
+			},
+		},
+		{
+			Name:        "get_status",
+			DisplayName: "Get Device Status",
+			Description: "Retrieve motor power state and demand RPM",
+			Category:    VspBoosterCategory,
+			IsQuery:     true,
+			Fields:      []CommandField{},
+		},
+		{
+			Name:        "get_device_information",
+			DisplayName: "Get Device Information",
+			Description: "Retrieve motor board serial number and firmware version",
+			Category:    VspBoosterCategory,
+			IsQuery:     true,
+			Fields:      []CommandField{},
+		},
+		{
+			Name:        "get_active_errors",
+			DisplayName: "Get Active Errors",
+			Description: "Retrieve the errors the booster is currently raising",
+			Category:    VspBoosterCategory,
+			IsQuery:     true,
+			Fields:      []CommandField{},
+		},
+	}
+
+	pcr.commandsByCategory[VspBoosterCategory] = boosterCommands
+	log.Printf("Discovered %d commands for %s devices", len(boosterCommands), VspBoosterCategory)
+}
+
 // GetCommandsForCategory returns available commands for a device category
 func (pcr *ProtobufCommandRegistry) GetCommandsForCategory(category string) ([]CommandInfo, bool) {
 	pcr.mutex.RLock()
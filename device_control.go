@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"NgaSim/ned/icl"
+	"NgaSim/ned/speedsetplus"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+// DctCategory is the NED category of the digital controller (DCT) that drives pool lights
+const DctCategory = "digitalControllerGen2"
+
+// lightControlTypes maps the light states accepted by /api/lights/command to LightControlType
+var lightControlTypes = map[string]icl.LightControlType{
+	"on":       icl.LightControlType_LIGHT_CONTROL_ON,
+	"off":      icl.LightControlType_LIGHT_CONTROL_OFF,
+	"blinking": icl.LightControlType_LIGHT_CONTROL_BLINKING,
+}
+
+// deviceCategory returns the MQTT category of a known device
+func (n *NgaSim) deviceCategory(serial string) (string, error) {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	device, exists := n.devices[serial]
+	if !exists {
+		return "", fmt.Errorf("device not found: %s", serial)
+	}
+	if device.Category != "" {
+		return device.Category, nil
+	}
+	return device.Type, nil
+}
+
+// sendDeviceCommand stamps payload with a new command UUID, wraps it in the category envelope and
+// publishes it, noting it on the device terminal. It returns the command UUID.
+func (n *NgaSim) sendDeviceCommand(serial, category, command, summary string, payload proto.Message) (string, error) {
+	commandUUID := uuid.New().String()
+
+	_, msgBytes, err := marshalCommand(category, commandUUID, payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal protobuf command: %v", err)
+	}
+
+	n.mutex.Lock()
+	if device, exists := n.devices[serial]; exists {
+		device.LastCommandTime = time.Now()
+	}
+	n.mutex.Unlock()
+	n.addDeviceTerminalEntry(serial, "COMMAND", "→ "+summary, nil)
+
+	queued, err := n.publishCommand(serial, category, command, commandUUID, msgBytes)
+	if err != nil {
+		return "", err
+	}
+	if queued {
+		return commandUUID, nil
+	}
+
+	n.addDeviceTerminalEntry(serial, "MQTT_CMD",
+		fmt.Sprintf("📡 MQTT command sent: %s (UUID: %s)", summary, commandUUID), msgBytes)
+	log.Printf("✅ MQTT protobuf command sent successfully: %s -> %s (UUID: %s)", serial, summary, commandUUID)
+	return commandUUID, nil
+}
+
+// sendPumpCommand starts or stops a SpeedSet Plus pump or VSP booster at the demand RPM and
+// returns the command UUID
+func (n *NgaSim) sendPumpCommand(serial string, power, rpm int) (string, error) {
+	category, err := n.deviceCategory(serial)
+	if err != nil {
+		return "", err
+	}
+	if power != VspBoosterPowerOff && power != VspBoosterPowerOn {
+		return "", fmt.Errorf("invalid power: %d (must be %d or %d)", power, VspBoosterPowerOff, VspBoosterPowerOn)
+	}
+	if rpm < 0 {
+		return "", fmt.Errorf("invalid rpm: %d (must not be negative)", rpm)
+	}
+
+	switch category {
+	case VspBoosterCategory:
+		return n.sendVspBoosterCommand(serial, power, rpm)
+
+	case "speedsetplus", "speedsetPlusGen2":
+		log.Printf("⚡ Sending SpeedSet Plus command: %s -> power=%d, %d RPM", serial, power, rpm)
+		payload := &speedsetplus.SetSpeedsetPlusControlCommandRequestPayload{
+			Power:        int32(power),
+			SetDemandRpm: int32(rpm),
+		}
+		return n.sendDeviceCommand(serial, category, "SetVspControlCommand",
+			fmt.Sprintf("Set pump power=%d at %d RPM", power, rpm), payload)
+	}
+	return "", fmt.Errorf("%s is a %s, not a pump", serial, category)
+}
+
+// sendLightCommand changes one light on a digital controller and returns the command UUID.
+// Brightness is left alone when nil.
+func (n *NgaSim) sendLightCommand(serial string, address int32, state string, brightness *int32) (string, error) {
+	category, err := n.deviceCategory(serial)
+	if err != nil {
+		return "", err
+	}
+	if category != DctCategory {
+		return "", fmt.Errorf("%s is a %s, not a light controller", serial, category)
+	}
+
+	patch := &icl.LightConfigurationPatch{Address: address}
+	summary := fmt.Sprintf("Set light %d", address)
+	if state != "" {
+		controlType, known := lightControlTypes[strings.ToLower(state)]
+		if !known {
+			return "", fmt.Errorf("invalid light state %q (on, off or blinking)", state)
+		}
+		patch.Fields = append(patch.Fields, &icl.LightConfigurationPatch_Field{
+			FieldType: &icl.LightConfigurationPatch_Field_ControlType{ControlType: controlType},
+		})
+		summary += " " + strings.ToLower(state)
+	}
+	if brightness != nil {
+		if *brightness < 0 || *brightness > 100 {
+			return "", fmt.Errorf("invalid brightness: %d (must be 0-100)", *brightness)
+		}
+		patch.Fields = append(patch.Fields, &icl.LightConfigurationPatch_Field{
+			FieldType: &icl.LightConfigurationPatch_Field_Brightness{Brightness: *brightness},
+		})
+		summary += fmt.Sprintf(" brightness=%d%%", *brightness)
+	}
+	if len(patch.Fields) == 0 {
+		return "", fmt.Errorf("nothing to change: give a state and/or a brightness")
+	}
+
+	log.Printf("💡 Sending light command: %s -> %s", serial, summary)
+	payload := &icl.SetLightConfigurationRequest{LightPatch: []*icl.LightConfigurationPatch{patch}}
+	return n.sendDeviceCommand(serial, category, "SetDct20Lights", summary, payload)
+}
+
+// handlePumpCommand starts or stops a pump: {"serial": ..., "power": 0|1, "rpm": ...}. SpeedSet
+// Plus pumps and VSP boosters are both accepted.
+func (n *NgaSim) handlePumpCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Serial string `json:"serial"`
+		Power  int    `json:"power"`
+		RPM    int    `json:"rpm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	commandUUID, err := n.sendPumpCommand(request.Serial, request.Power, request.RPM)
+
+	response := map[string]interface{}{
+		"success": err == nil,
+		"serial":  request.Serial,
+		"power":   request.Power,
+		"rpm":     request.RPM,
+	}
+	n.writeCommandResponse(w, request.Serial, commandUUID, response, err)
+}
+
+// handleLightCommand changes a light on a digital controller:
+// {"serial": ..., "address": 1, "state": "on|off|blinking", "brightness": 0-100}
+func (n *NgaSim) handleLightCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Serial     string `json:"serial"`
+		Address    int32  `json:"address"`
+		State      string `json:"state"`
+		Brightness *int32 `json:"brightness"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	commandUUID, err := n.sendLightCommand(request.Serial, request.Address, request.State, request.Brightness)
+
+	response := map[string]interface{}{
+		"success": err == nil,
+		"serial":  request.Serial,
+		"address": request.Address,
+	}
+	if request.State != "" {
+		response["state"] = request.State
+	}
+	if request.Brightness != nil {
+		response["brightness"] = *request.Brightness
+	}
+	n.writeCommandResponse(w, request.Serial, commandUUID, response, err)
+}
+
+// writeCommandResponse adds the command UUID and status, or the error, to a command response
+func (n *NgaSim) writeCommandResponse(w http.ResponseWriter, serial, commandUUID string, response map[string]interface{}, err error) {
+	if status := n.commandStatus(serial, commandUUID); status != "" {
+		response["command_status"] = status // PENDING, or QUEUED while MQTT is disconnected
+	}
+	if commandUUID != "" {
+		response["command_uuid"] = commandUUID
+	}
+	if err != nil {
+		response["error"] = err.Error()
+		log.Printf("❌ Command failed: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(response)
+}
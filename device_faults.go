@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"NgaSim/ned/icl"
+	"NgaSim/ned/sanitizer"
+	"NgaSim/ned/speedsetplus"
+	"NgaSim/ned/vspbooster"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MaxClearedFaults is how many cleared faults a device keeps for history
+const MaxClearedFaults = 20
+
+// DeviceFault is one error a device has raised on its error topic.
+// A fault stays active until an error message arrives without it; it is then kept, with its
+// cleared time, as history.
+type DeviceFault struct {
+	Key       string     `json:"key"`              // Source + code, stable across reports
+	Code      string     `json:"code"`             // Enum name, e.g. SANITIZER_ERROR_LOW_SALT
+	CodeValue int32      `json:"code_value"`       // Raw enum value
+	Message   string     `json:"message"`          // Human readable text provided by the device
+	Source    string     `json:"source,omitempty"` // Sub-unit raising the fault, e.g. "light 3"
+	FirstSeen time.Time  `json:"first_seen"`
+	LastSeen  time.Time  `json:"last_seen"`
+	ClearedAt *time.Time `json:"cleared_at,omitempty"`
+}
+
+// Active reports whether the device is still raising the fault
+func (f *DeviceFault) Active() bool {
+	return f.ClearedAt == nil
+}
+
+// reportedFault is a single entry of a decoded error message
+type reportedFault struct {
+	Code      string
+	CodeValue int32
+	Message   string
+	Source    string
+}
+
+func (r reportedFault) key() string {
+	if r.Source == "" {
+		return r.Code
+	}
+	return r.Source + "/" + r.Code
+}
+
+// errorDecoders turn an async/<category>/<serial>/error payload into the device's full list of
+// active faults. An empty list means the device is back to normal.
+var errorDecoders = map[string]func([]byte) ([]reportedFault, error){
+	"sanitizerGen2":         decodeSanitizerErrors,
+	"speedsetplus":          decodeSpeedsetPlusErrors,
+	"speedsetPlusGen2":      decodeSpeedsetPlusErrors,
+	"digitalControllerGen2": decodeLightErrors,
+	VspBoosterCategory:      decodeVspBoosterErrors,
+}
+
+func decodeSanitizerErrors(payload []byte) ([]reportedFault, error) {
+	msg := &sanitizer.DeviceErrorMessage{}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode sanitizer.DeviceErrorMessage: %v", err)
+	}
+
+	var faults []reportedFault
+	for _, e := range msg.GetActiveErrors().GetErrorList() {
+		faults = append(faults, reportedFault{
+			Code:      e.GetErrorCode().String(),
+			CodeValue: int32(e.GetErrorCode()),
+			Message:   e.GetErrorMessage(),
+		})
+	}
+	return faults, nil
+}
+
+func decodeSpeedsetPlusErrors(payload []byte) ([]reportedFault, error) {
+	msg := &speedsetplus.DeviceErrorMessage{}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode speedsetPlus.DeviceErrorMessage: %v", err)
+	}
+
+	var faults []reportedFault
+	for _, e := range msg.GetActiveErrors().GetErrorList() {
+		faults = append(faults, reportedFault{
+			Code:      e.GetErrorCode().String(),
+			CodeValue: int32(e.GetErrorCode()),
+			Message:   e.GetErrorMessage(),
+		})
+	}
+	return faults, nil
+}
+
+// decodeLightErrors handles the DCT, which reports errors per connected light
+func decodeLightErrors(payload []byte) ([]reportedFault, error) {
+	msg := &icl.ActiveErrors{}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode icl.ActiveErrors: %v", err)
+	}
+	return lightFaults(msg), nil
+}
+
+// lightFaults flattens the per-light error lists of a DCT into faults sourced by light address
+func lightFaults(msg *icl.ActiveErrors) []reportedFault {
+	var faults []reportedFault
+	for _, light := range msg.GetLightsErrors() {
+		for _, e := range light.GetLightErrors() {
+			faults = append(faults, reportedFault{
+				Code:      e.GetErrorCode().String(),
+				CodeValue: int32(e.GetErrorCode()),
+				Message:   e.GetErrorMessage(),
+				Source:    lightSource(light.GetLightAddress()),
+			})
+		}
+	}
+	return faults
+}
+
+// statusMessages maps categories to the message carried on async/<category>/<serial>/sts
+var statusMessages = map[string]func() proto.Message{
+	"sanitizerGen2":         func() proto.Message { return &sanitizer.GetSanitizerStatusResponsePayload{} },
+	"speedsetplus":          func() proto.Message { return &speedsetplus.GetSpeedsetPlusStatusResponsePayload{} },
+	"speedsetPlusGen2":      func() proto.Message { return &speedsetplus.GetSpeedsetPlusStatusResponsePayload{} },
+	"digitalControllerGen2": func() proto.Message { return &icl.GetDctStatusResponse{} },
+	VspBoosterCategory:      func() proto.Message { return &vspbooster.GetVspBoosterStatusResponsePayload{} },
+}
+
+// decodeDeviceStatus decodes a status payload, returning the message and a generic map of the
+// status it carries for the API and cards. The map is the status itself, e.g. SanitizerStatus
+// rather than the response wrapping it, which is how InfoMessage pushes carry status too.
+func decodeDeviceStatus(category string, payload []byte) (proto.Message, map[string]interface{}, error) {
+	newStatus, exists := statusMessages[category]
+	if !exists {
+		return nil, nil, fmt.Errorf("no status message known for category %s", category)
+	}
+
+	msg := newStatus()
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode %s: %v", msg.ProtoReflect().Descriptor().FullName(), err)
+	}
+
+	status, err := protoMessageToMap(wrappedStatus(msg))
+	if err != nil {
+		return nil, nil, err
+	}
+	return msg, status, nil
+}
+
+// wrappedStatus returns the status a status response carries in its only field (status,
+// dct_status), or the response itself if it has another shape
+func wrappedStatus(response proto.Message) proto.Message {
+	reflected := response.ProtoReflect()
+	fields := reflected.Descriptor().Fields()
+	if fields.Len() != 1 || fields.Get(0).Kind() != protoreflect.MessageKind || fields.Get(0).IsList() {
+		return response
+	}
+	return reflected.Get(fields.Get(0)).Message().Interface()
+}
+
+// applyFaults reconciles the device's fault set with a freshly reported list of active errors.
+// It returns the faults that were newly raised and those that cleared.
+func (d *Device) applyFaults(reported []reportedFault, now time.Time) (raised, cleared []*DeviceFault) {
+	seen := make(map[string]bool)
+
+	for _, r := range reported {
+		key := r.key()
+		seen[key] = true
+
+		if fault := d.activeFault(key); fault != nil {
+			fault.LastSeen = now
+			fault.Message = r.Message
+			continue
+		}
+
+		fault := &DeviceFault{
+			Key:       key,
+			Code:      r.Code,
+			CodeValue: r.CodeValue,
+			Message:   r.Message,
+			Source:    r.Source,
+			FirstSeen: now,
+			LastSeen:  now,
+		}
+		d.Faults = append(d.Faults, fault)
+		raised = append(raised, fault)
+	}
+
+	for _, fault := range d.Faults {
+		if fault.Active() && !seen[fault.Key] {
+			clearedAt := now
+			fault.ClearedAt = &clearedAt
+			cleared = append(cleared, fault)
+		}
+	}
+
+	d.trimClearedFaults()
+	d.ActiveFaultCount = len(d.ActiveFaults())
+	d.refreshLightFaults()
+	return raised, cleared
+}
+
+// activeFault returns the active fault with the given key, if any
+func (d *Device) activeFault(key string) *DeviceFault {
+	for _, fault := range d.Faults {
+		if fault.Key == key && fault.Active() {
+			return fault
+		}
+	}
+	return nil
+}
+
+// ActiveFaults returns the faults the device is currently raising
+func (d *Device) ActiveFaults() []*DeviceFault {
+	var active []*DeviceFault
+	for _, fault := range d.Faults {
+		if fault.Active() {
+			active = append(active, fault)
+		}
+	}
+	return active
+}
+
+// trimClearedFaults drops the oldest cleared faults beyond MaxClearedFaults
+func (d *Device) trimClearedFaults() {
+	clearedCount := len(d.Faults) - len(d.ActiveFaults())
+	if clearedCount <= MaxClearedFaults {
+		return
+	}
+
+	kept := make([]*DeviceFault, 0, len(d.Faults))
+	for _, fault := range d.Faults {
+		if !fault.Active() && clearedCount > MaxClearedFaults {
+			clearedCount--
+			continue
+		}
+		kept = append(kept, fault)
+	}
+	d.Faults = kept
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"NgaSim/ned/icl"
+	"NgaSim/ned/sanitizer"
+	"NgaSim/ned/speedsetplus"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// infoUpdate is a decoded info message: a summary for the terminal and the change to apply
+// to the device
+type infoUpdate struct {
+	Summary string
+	Apply   func(device *Device, now time.Time)
+}
+
+// infoDecoders turn an async/<category>/<serial>/info payload (the category InfoMessage) into
+// a device update
+var infoDecoders = map[string]func([]byte) (*infoUpdate, error){
+	"sanitizerGen2":         decodeSanitizerInfo,
+	"speedsetplus":          decodeSpeedsetPlusInfo,
+	"speedsetPlusGen2":      decodeSpeedsetPlusInfo,
+	"digitalControllerGen2": decodeDctInfo,
+	VspBoosterCategory:      decodeVspBoosterInfo,
+}
+
+func decodeSanitizerInfo(payload []byte) (*infoUpdate, error) {
+	msg := &sanitizer.InfoMessage{}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode sanitizer.InfoMessage: %v", err)
+	}
+
+	info := msg.GetPayload()
+	switch {
+	case info.GetStatus() != nil:
+		return statusInfoUpdate(info.GetStatus())
+	case info.GetConfiguration() != nil:
+		return configurationInfoUpdate(info.GetConfiguration())
+	}
+	return nil, fmt.Errorf("sanitizer.InfoMessage carries no payload")
+}
+
+func decodeSpeedsetPlusInfo(payload []byte) (*infoUpdate, error) {
+	msg := &speedsetplus.InfoMessage{}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode speedsetPlus.InfoMessage: %v", err)
+	}
+
+	info := msg.GetPayload()
+	switch {
+	case info.GetStatus() != nil:
+		return statusInfoUpdate(info.GetStatus())
+	case info.GetConfiguration() != nil:
+		return configurationInfoUpdate(info.GetConfiguration())
+	}
+	return nil, fmt.Errorf("speedsetPlus.InfoMessage carries no payload")
+}
+
+func decodeDctInfo(payload []byte) (*infoUpdate, error) {
+	msg := &icl.InfoMessage{}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode icl.InfoMessage: %v", err)
+	}
+
+	info := msg.GetPayload()
+	if added := info.GetLightAdded(); added != nil {
+		lightInfo := added.GetLightInformation()
+		return &infoUpdate{
+			Summary: fmt.Sprintf("💡 Light added at address %d (%s, fw %s)",
+				lightInfo.GetAddress(), lightInfo.GetModel(), lightInfo.GetFirmwareVersion()),
+			Apply: func(device *Device, now time.Time) {
+				device.applyLightInformation(lightInfo, now)
+			},
+		}, nil
+	}
+
+	if changed := info.GetDctStatusChanged(); changed != nil {
+		dctStatus := changed.GetDctStatus()
+		statusMap, err := protoMessageToMap(dctStatus)
+		if err != nil {
+			return nil, err
+		}
+		return &infoUpdate{
+			Summary: fmt.Sprintf("💡 DCT status changed: %d lights, %dW capacity",
+				len(dctStatus.GetLightsStatus()), dctStatus.GetDctWattageCapacity()),
+			Apply: func(device *Device, now time.Time) {
+				device.applyDctStatus(dctStatus, now)
+				device.StatusDetail = statusMap
+				device.StatusUpdated = now
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("icl.InfoMessage carries no payload")
+}
+
+// statusInfoUpdate stores a pushed status the same way a status topic message is stored
+func statusInfoUpdate(status proto.Message) (*infoUpdate, error) {
+	statusMap, err := protoMessageToMap(status)
+	if err != nil {
+		return nil, err
+	}
+	return &infoUpdate{
+		Summary: fmt.Sprintf("📋 Status pushed: %v", statusMap),
+		Apply: func(device *Device, now time.Time) {
+			device.StatusDetail = statusMap
+			device.StatusUpdated = now
+		},
+	}, nil
+}
+
+// configurationInfoUpdate replaces the device's known configuration
+func configurationInfoUpdate(configuration proto.Message) (*infoUpdate, error) {
+	configMap, err := protoMessageToMap(configuration)
+	if err != nil {
+		return nil, err
+	}
+	return &infoUpdate{
+		Summary: fmt.Sprintf("⚙️ Configuration changed: %v", configMap),
+		Apply: func(device *Device, now time.Time) {
+			device.Configuration = configMap
+			device.ConfigurationUpdated = now
+		},
+	}, nil
+}
+
+// protoMessageToMap converts a protobuf message to a generic map using proto field names
+func protoMessageToMap(msg proto.Message) (map[string]interface{}, error) {
+	jsonBytes, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s to JSON: %v", msg.ProtoReflect().Descriptor().FullName(), err)
+	}
+
+	result := make(map[string]interface{})
+	if err := json.Unmarshal(jsonBytes, &result); err != nil {
+		return nil, fmt.Errorf("failed to convert %s to map: %v", msg.ProtoReflect().Descriptor().FullName(), err)
+	}
+	return result, nil
+}
+
+// handleDeviceInfo processes unsolicited info pushes and merges them into device state
+func (sim *NgaSim) handleDeviceInfo(category, deviceSerial string, payload []byte) {
+	log.Printf("Device info from %s (category: %s): %d bytes", deviceSerial, category, len(payload))
+
+	decode, exists := infoDecoders[category]
+	if !exists {
+		log.Printf("⚠️ No info decoder for category %s: %x", category, payload)
+		return
+	}
+
+	update, err := decode(payload)
+	if err != nil {
+		log.Printf("❌ Info message from %s: %v", deviceSerial, err)
+		sim.addDeviceTerminalEntry(deviceSerial, "INFO", fmt.Sprintf("❌ Undecodable info message: %v", err), payload)
+		return
+	}
+
+	sim.mutex.Lock()
+	device, exists := sim.devices[deviceSerial]
+	if exists {
+		update.Apply(device, time.Now())
+		sim.markDeviceSeen(device)
+	}
+	sim.mutex.Unlock()
+
+	if !exists {
+		log.Printf("⚠️ Info from unknown device %s ignored: %s", deviceSerial, update.Summary)
+		return
+	}
+
+	log.Printf("ℹ️ %s: %s", deviceSerial, update.Summary)
+	sim.addDeviceTerminalEntry(deviceSerial, "INFO", update.Summary, payload)
+}
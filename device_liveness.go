@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"NgaSim/ned"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Device connection states
+const (
+	DeviceOnline  = "ONLINE"  ///< Traffic seen within StaleAfterPeriods telemetry periods
+	DeviceStale   = "STALE"   ///< Quiet for a while, but not yet given up on
+	DeviceOffline = "OFFLINE" ///< Last will received, or quiet for OfflineAfterPeriods periods
+)
+
+// Liveness sweeper parameters
+const (
+	DefaultTelemetryPeriod = 10 * time.Second ///< Assumed period until telemetry has been observed
+	MinTelemetryPeriod     = 1 * time.Second  ///< Floor for the observed period, guards against bursts
+	StaleAfterPeriods      = 3                ///< Missed periods before a device is STALE
+	OfflineAfterPeriods    = 10               ///< Missed periods before a device is OFFLINE
+	LivenessSweepInterval  = 2 * time.Second  ///< How often LastSeen is checked
+)
+
+// TelemetryPeriod returns the device's observed telemetry period, or the default before one
+// has been measured
+func (d *Device) TelemetryPeriod() time.Duration {
+	if d.TelemetryPeriodSec <= 0 {
+		return DefaultTelemetryPeriod
+	}
+	return time.Duration(d.TelemetryPeriodSec * float64(time.Second))
+}
+
+// noteTelemetry updates the observed telemetry period from the gap since the previous sample.
+// Gaps longer than the offline threshold are outages, not the device's period, and are ignored.
+func (d *Device) noteTelemetry(now time.Time) {
+	if !d.lastTelemetry.IsZero() {
+		interval := now.Sub(d.lastTelemetry)
+		if interval < MinTelemetryPeriod {
+			interval = MinTelemetryPeriod
+		}
+
+		if d.TelemetryPeriodSec <= 0 {
+			d.TelemetryPeriodSec = interval.Seconds()
+		} else if interval < OfflineAfterPeriods*d.TelemetryPeriod() {
+			// Smooth out jitter so one late sample does not shift the thresholds much
+			d.TelemetryPeriodSec = 0.75*d.TelemetryPeriodSec + 0.25*interval.Seconds()
+		}
+	}
+	d.lastTelemetry = now
+}
+
+// markDeviceSeen records traffic from a device and brings it back ONLINE if needed.
+// Caller must hold the NgaSim mutex.
+func (sim *NgaSim) markDeviceSeen(device *Device) {
+	device.LastSeen = time.Now()
+	if device.Status != DeviceOnline {
+		sim.setDeviceStatus(device, DeviceOnline, "traffic received")
+	}
+}
+
+// setDeviceStatus changes a device's connection state and records the transition in the device
+// terminal and the global terminal log. Caller must hold the NgaSim mutex.
+func (sim *NgaSim) setDeviceStatus(device *Device, status, reason string) {
+	if device.Status == status {
+		return
+	}
+
+	previous := device.Status
+	device.Status = status
+	device.StatusReason = reason
+	device.StatusChanged = time.Now()
+
+	icon := "🟢"
+	switch status {
+	case DeviceStale:
+		icon = "🟡"
+	case DeviceOffline:
+		icon = "🔴"
+	}
+	message := fmt.Sprintf("%s %s → %s (%s)", icon, previous, status, reason)
+	log.Printf("%s Device %s: %s → %s (%s)", icon, device.Serial, previous, status, reason)
+
+	appendLiveTerminal(device, TerminalEntry{
+		Timestamp: device.StatusChanged,
+		Type:      "CONNECTION",
+		Message:   message,
+	})
+
+	if sim.terminalLogger != nil {
+		sim.terminalLogger.LogEvent(device.Serial, status, message)
+	}
+}
+
+// handleDeviceDisconnected processes the last will a device's MQTT client leaves with the broker
+func (sim *NgaSim) handleDeviceDisconnected(category, deviceSerial string, payload []byte) {
+	log.Printf("Device disconnected from %s (category: %s): %d bytes", deviceSerial, category, len(payload))
+
+	// The payload carries no fields; decoding only confirms it is what we expect
+	if err := proto.Unmarshal(payload, &ned.DisconnectedMessagePayload{}); err != nil {
+		log.Printf("⚠️ Unexpected disconnected payload from %s: %v", deviceSerial, err)
+	}
+
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	device, exists := sim.devices[deviceSerial]
+	if !exists {
+		log.Printf("⚠️ Disconnect from unknown device %s ignored", deviceSerial)
+		return
+	}
+
+	sim.setDeviceStatus(device, DeviceOffline, "last will: disconnected from broker")
+}
+
+// watchDeviceLiveness periodically marks devices STALE or OFFLINE when they go quiet
+func (sim *NgaSim) watchDeviceLiveness() {
+	ticker := time.NewTicker(LivenessSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sim.sweepDeviceLiveness()
+	}
+}
+
+// sweepDeviceLiveness compares each device's LastSeen with its telemetry period
+func (sim *NgaSim) sweepDeviceLiveness() {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	for _, device := range sim.devices {
+		if device.LastSeen.IsZero() {
+			continue
+		}
+
+		quiet := time.Since(device.LastSeen)
+		period := device.TelemetryPeriod()
+
+		switch {
+		case quiet > OfflineAfterPeriods*period:
+			sim.setDeviceStatus(device, DeviceOffline,
+				fmt.Sprintf("no traffic for %v", quiet.Round(time.Second)))
+		case quiet > StaleAfterPeriods*period && device.Status == DeviceOnline:
+			sim.setDeviceStatus(device, DeviceStale,
+				fmt.Sprintf("no traffic for %v", quiet.Round(time.Second)))
+		}
+	}
+}
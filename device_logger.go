@@ -59,6 +59,7 @@ type DeviceLogger struct {
 	mutex      sync.RWMutex
 	filename   string
 	registry   *ProtobufCommandRegistry // For command introspection
+	minLevel   LogLevel                 // Entries below this level are dropped
 }
 
 // LogFilter represents criteria for filtering log entries
@@ -157,6 +158,10 @@ func (dl *DeviceLogger) addEntry(entry *DeviceLogEntry) {
 	dl.mutex.Lock()
 	defer dl.mutex.Unlock()
 
+	if entry.Level < dl.minLevel {
+		return
+	}
+
 	dl.entries = append(dl.entries, entry)
 
 	// Keep only the most recent entries
@@ -165,6 +170,17 @@ func (dl *DeviceLogger) addEntry(entry *DeviceLogEntry) {
 	}
 }
 
+// SetLevel drops future entries below level
+func (dl *DeviceLogger) SetLevel(level LogLevel) {
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+
+	if dl.minLevel != level {
+		log.Printf("📝 Device log level: %s → %s", dl.minLevel, level)
+	}
+	dl.minLevel = level
+}
+
 // GetEntries returns filtered log entries
 func (dl *DeviceLogger) GetEntries(filter LogFilter) []*DeviceLogEntry {
 	dl.mutex.RLock()
@@ -36,11 +36,15 @@ func (n *NgaSim) handleGoDemo(w http.ResponseWriter, r *http.Request) {
 		Version        string
 		Devices        []*Device
 		DeviceCommands map[string]DeviceCommands
+		Site           string
+		Sites          []SiteSummary // Site switcher, empty with a single site
 	}{
 		Title:          "NgaSim Pool Controller - Go Demo",
 		Version:        NgaSimVersion,
 		Devices:        devices,
 		DeviceCommands: deviceCommands,
+		Site:           n.site,
+		Sites:          n.siteLinks(),
 	}
 
 	w.Header().Set("Content-Type", "text/html")
@@ -111,7 +115,7 @@ func (n *NgaSim) handleExit(w http.ResponseWriter, r *http.Request) {
 	go func() {
 		time.Sleep(100 * time.Millisecond) // Give response time to send
 		log.Println("🧹 Starting graceful shutdown...")
-		n.cleanup()
+		n.shutdown()
 		os.Exit(0)
 	}()
 }
@@ -134,6 +138,28 @@ func (n *NgaSim) handleAPI(w http.ResponseWriter, r *http.Request) {
 	log.Printf("📤 Sent %d devices to API client", len(devices))
 }
 
+// handleDeviceFaults returns the active and recently cleared faults of every device, keyed by serial
+func (n *NgaSim) handleDeviceFaults(w http.ResponseWriter, r *http.Request) {
+	log.Println("📡 API request: /api/devices/faults")
+
+	n.mutex.RLock()
+	faults := make(map[string][]DeviceFault)
+	for serial, device := range n.devices {
+		for _, fault := range device.Faults {
+			faults[serial] = append(faults[serial], *fault)
+		}
+	}
+	n.mutex.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if err := json.NewEncoder(w).Encode(faults); err != nil {
+		http.Error(w, fmt.Sprintf("Error encoding JSON: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
 // handleSanitizerCommand handles sanitizer command requests
 func (n *NgaSim) handleSanitizerCommand(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -162,7 +188,7 @@ func (n *NgaSim) handleSanitizerCommand(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Send the command
-	err := n.sendSanitizerCommand(request.Serial, "sanitizerGen2", request.Percentage)
+	commandUUID, err := n.sendSanitizerCommand(request.Serial, "sanitizerGen2", request.Percentage)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -172,6 +198,12 @@ func (n *NgaSim) handleSanitizerCommand(w http.ResponseWriter, r *http.Request)
 		"serial":     request.Serial,
 		"percentage": request.Percentage,
 	}
+	if status := n.commandStatus(request.Serial, commandUUID); status != "" {
+		response["command_status"] = status // PENDING, or QUEUED while MQTT is disconnected
+	}
+	if commandUUID != "" {
+		response["command_uuid"] = commandUUID
+	}
 
 	if err != nil {
 		response["error"] = err.Error()
@@ -183,6 +215,65 @@ func (n *NgaSim) handleSanitizerCommand(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleVspBoosterCommand handles VSP booster start/stop requests
+func (n *NgaSim) handleVspBoosterCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Println("🌀 VSP booster command request received")
+
+	var request struct {
+		Serial string `json:"serial"`
+		Power  int    `json:"power"`
+		RPM    int    `json:"rpm"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("🎯 Command: Set %s power=%d at %d RPM", request.Serial, request.Power, request.RPM)
+
+	if request.Power != VspBoosterPowerOff && request.Power != VspBoosterPowerOn {
+		http.Error(w, "Power must be 0 or 1", http.StatusBadRequest)
+		return
+	}
+	if request.RPM < 0 {
+		http.Error(w, "RPM must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	commandUUID, err := n.sendVspBoosterCommand(request.Serial, request.Power, request.RPM)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	response := map[string]interface{}{
+		"success": err == nil,
+		"serial":  request.Serial,
+		"power":   request.Power,
+		"rpm":     request.RPM,
+	}
+	if status := n.commandStatus(request.Serial, commandUUID); status != "" {
+		response["command_status"] = status
+	}
+	if commandUUID != "" {
+		response["command_uuid"] = commandUUID
+	}
+
+	if err != nil {
+		response["error"] = err.Error()
+		log.Printf("❌ Command failed: %v", err)
+	} else {
+		log.Printf("✅ Command sent successfully: %s -> power=%d, %d RPM", request.Serial, request.Power, request.RPM)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
 // handleSanitizerStates handles sanitizer states API
 func (n *NgaSim) handleSanitizerStates(w http.ResponseWriter, r *http.Request) {
 	log.Println("📊 Sanitizer states request received")
@@ -222,6 +313,15 @@ func (n *NgaSim) handleEmergencyStop(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("🛑 Emergency stop request received")
 
+	response := n.emergencyStop()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(response)
+}
+
+// emergencyStop commands every sanitizer of this site to 0% and reports the result per device
+func (n *NgaSim) emergencyStop() map[string]interface{} {
 	// Stop all sanitizers
 	n.mutex.RLock()
 	sanitizers := make([]*Device, 0)
@@ -244,7 +344,7 @@ func (n *NgaSim) handleEmergencyStop(w http.ResponseWriter, r *http.Request) {
 	for _, sanitizer := range sanitizers {
 		log.Printf("🛑 Emergency stopping sanitizer: %s (%s)", sanitizer.Name, sanitizer.Serial)
 
-		err := n.sendSanitizerCommand(sanitizer.Serial, "sanitizerGen2", 0)
+		_, err := n.sendSanitizerCommand(sanitizer.Serial, "sanitizerGen2", 0)
 		success := err == nil
 		if success {
 			successCount++
@@ -267,9 +367,6 @@ func (n *NgaSim) handleEmergencyStop(w http.ResponseWriter, r *http.Request) {
 			[]byte(`{"emergency_stop":true,"target_percentage":0}`))
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
 	response := map[string]interface{}{
 		"success":            successCount > 0,
 		"message":            fmt.Sprintf("Emergency stop executed on %d/%d sanitizers", successCount, len(sanitizers)),
@@ -278,8 +375,9 @@ func (n *NgaSim) handleEmergencyStop(w http.ResponseWriter, r *http.Request) {
 		"results":            results,
 	}
 
-	json.NewEncoder(w).Encode(response)
-	log.Printf("🛑 Emergency stop completed: %d/%d sanitizers successfully commanded to 0%%", successCount, len(sanitizers))
+	log.Printf("🛑 Emergency stop completed on site %s: %d/%d sanitizers successfully commanded to 0%%",
+		n.site, successCount, len(sanitizers))
+	return response
 }
 
 // handleUISpecAPI serves the UI specification as JSON
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JobFilePatterns are the files loaded from a directory listed in jobs.files
+var JobFilePatterns = []string{"*.yaml", "*.yml", "*.json"}
+
+// DefaultExecutionLimit is how many executions /api/jobs/{id}/executions returns without ?limit=
+const DefaultExecutionLimit = 50
+
+// JobStatus is a job as the API shows it: its definition and when its schedule runs it next
+type JobStatus struct {
+	*Job
+	NextRun *time.Time `json:"next_run,omitempty"`
+}
+
+// jobStatus adds the next scheduled run to a job
+func (n *NgaSim) jobStatus(job *Job) *JobStatus {
+	status := &JobStatus{Job: job}
+	if next := n.jobEngine.NextRun(job.ID); !next.IsZero() {
+		status.NextRun = &next
+	}
+	return status
+}
+
+// expandJobFiles resolves jobs.files entries: files are kept and directories are replaced by
+// the job files they contain, in name order
+func expandJobFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading job file: %v", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		var found []string
+		for _, pattern := range JobFilePatterns {
+			matches, _ := filepath.Glob(filepath.Join(path, pattern))
+			found = append(found, matches...)
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// loadJobFiles reads every job from the files and directories in jobs.files
+func loadJobFiles(paths []string) ([]*Job, error) {
+	files, err := expandJobFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*Job
+	for _, file := range files {
+		parsed, err := readJobFile(file)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, parsed...)
+	}
+	return jobs, nil
+}
+
+// loadJobs replaces the engine's file jobs with those in jobs.files. Called at startup and on
+// every configuration reload, so edited job files are picked up by SIGHUP.
+func (n *NgaSim) loadJobs(cfg *LoadedConfig) {
+	jobs, err := loadJobFiles(cfg.Jobs.Files)
+	if err == nil {
+		err = n.jobEngine.ReplaceFileJobs(jobs)
+	}
+	if err != nil {
+		log.Printf("⚠️ Job files not loaded, keeping current jobs: %v", err)
+		return
+	}
+
+	if len(jobs) > 0 {
+		ids := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+		}
+		log.Printf("📋 Jobs loaded from %s: %s", strings.Join(cfg.Jobs.Files, ", "), strings.Join(ids, ", "))
+	}
+}
+
+// configureJobScheduler applies jobs.timezone, jobs.catch_up and jobs.state_file. Called at
+// startup and on every configuration reload.
+func (n *NgaSim) configureJobScheduler(cfg *LoadedConfig) {
+	location := time.Local
+	if cfg.Jobs.Timezone != "" {
+		loaded, err := time.LoadLocation(cfg.Jobs.Timezone)
+		if err != nil {
+			log.Printf("⚠️ Invalid jobs.timezone %q, using local time: %v", cfg.Jobs.Timezone, err)
+		} else {
+			location = loaded
+		}
+	}
+
+	catchUp := cfg.Jobs.CatchUp
+	switch catchUp {
+	case CatchUpSkip, CatchUpOnce, CatchUpAll:
+	default:
+		log.Printf("⚠️ Invalid jobs.catch_up %q (want skip, once or all), missed runs are skipped", catchUp)
+		catchUp = CatchUpSkip
+	}
+
+	n.jobEngine.scheduler.Configure(location, catchUp, cfg.Jobs.StateFile)
+}
+
+// decodeJob reads a job definition from a request body
+func decodeJob(r *http.Request) (*Job, error) {
+	var job Job
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&job); err != nil {
+		return nil, fmt.Errorf("Invalid JSON: %v", err)
+	}
+	return &job, nil
+}
+
+// handleJobs serves the jobs API:
+//
+//	GET    /api/jobs                            list jobs with their next_run (?tag= keeps jobs with that tag)
+//	POST   /api/jobs                            create a job
+//	GET    /api/jobs/{id}                       one job
+//	PUT    /api/jobs/{id}                       replace a job's definition
+//	DELETE /api/jobs/{id}                       delete a job
+//	POST   /api/jobs/{id}/enable                enable a job (/disable disables it)
+//	POST   /api/jobs/{id}/run                   start a run; answers 202 with the running execution
+//	POST   /api/jobs/{id}/dry-run               build every frame the job would send, without sending
+//	GET    /api/jobs/{id}/executions            past runs, newest first (?limit=, default 50)
+//	GET    /api/jobs/{id}/executions/{exec id}  one run
+func (n *NgaSim) handleJobs(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs"), "/"), "/")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if parts[0] == "" {
+		n.handleJobList(w, r)
+		return
+	}
+
+	jobID := parts[0]
+	job, exists := n.jobEngine.GetJob(jobID)
+	if !exists {
+		http.Error(w, fmt.Sprintf("Job '%s' not found", jobID), http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 1 {
+		n.handleJob(w, r, job)
+		return
+	}
+
+	action := parts[1]
+	if action == "executions" {
+		n.handleJobExecutions(w, r, jobID, parts[2:])
+		return
+	}
+	if len(parts) > 2 {
+		http.Error(w, fmt.Sprintf("Unknown job endpoint '%s'", r.URL.Path), http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch action {
+	case "enable", "disable":
+		job, err := n.jobEngine.SetJobEnabled(jobID, action == "enable")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("📋 Job %s %sd", jobID, action)
+		json.NewEncoder(w).Encode(job)
+
+	case "run":
+		execution, err := n.jobEngine.StartJob(jobID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("▶️ Job %s started (%s)", jobID, execution.ID)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(execution)
+
+	case "dry-run":
+		execution, err := n.jobEngine.DryRunJob(jobID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("🧪 Job %s dry run %s", jobID, execution.Status)
+		json.NewEncoder(w).Encode(execution)
+
+	default:
+		http.Error(w, fmt.Sprintf("Unknown job action '%s'", action), http.StatusNotFound)
+	}
+}
+
+// handleJobList lists jobs (GET) or creates one (POST)
+func (n *NgaSim) handleJobList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		tag := r.URL.Query().Get("tag")
+		jobs := make([]*JobStatus, 0)
+		for _, job := range n.jobEngine.GetJobs() {
+			if tag == "" || slices.Contains(job.Tags, tag) {
+				jobs = append(jobs, n.jobStatus(job))
+			}
+		}
+		sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+		json.NewEncoder(w).Encode(jobs)
+
+	case http.MethodPost:
+		job, err := decodeJob(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, exists := n.jobEngine.GetJob(job.ID); exists {
+			http.Error(w, fmt.Sprintf("Job '%s' already exists", job.ID), http.StatusConflict)
+			return
+		}
+		if err := n.jobEngine.CreateJob(job); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("📋 Job %s created", job.ID)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(n.jobStatus(job))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleJob returns (GET), replaces (PUT) or deletes (DELETE) one job
+func (n *NgaSim) handleJob(w http.ResponseWriter, r *http.Request, job *Job) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(n.jobStatus(job))
+
+	case http.MethodPut:
+		update, err := decodeJob(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if update.ID != "" && update.ID != job.ID {
+			http.Error(w, fmt.Sprintf("Job ID '%s' does not match '%s'", update.ID, job.ID), http.StatusBadRequest)
+			return
+		}
+		if err := n.jobEngine.UpdateJob(job.ID, update); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("📋 Job %s updated", job.ID)
+		json.NewEncoder(w).Encode(n.jobStatus(update))
+
+	case http.MethodDelete:
+		deleted, err := n.jobEngine.DeleteJob(job.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("🗑️ Job %s deleted", job.ID)
+		json.NewEncoder(w).Encode(deleted)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleJobExecutions lists the runs of a job, or returns one by ID
+func (n *NgaSim) handleJobExecutions(w http.ResponseWriter, r *http.Request, jobID string, rest []string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(rest) > 0 && rest[0] != "" {
+		execution, exists := n.jobEngine.GetExecution(rest[0])
+		if !exists || execution.JobID != jobID {
+			http.Error(w, fmt.Sprintf("Execution '%s' of job '%s' not found", rest[0], jobID), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(execution)
+		return
+	}
+
+	limit := DefaultExecutionLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "limit must be a non-negative number (0 for all)", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	json.NewEncoder(w).Encode(n.jobEngine.GetExecutions(jobID, limit))
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Condition types a condition action can check
+const (
+	ConditionDeviceOnline   = "device_online"    ///< The device's status is ONLINE
+	ConditionDeviceOffline  = "device_offline"   ///< The device's status is OFFLINE
+	ConditionFieldEquals    = "field_equals"     ///< field_path == value
+	ConditionFieldNotEquals = "field_not_equals" ///< field_path != value
+	ConditionFieldGreater   = "field_greater"    ///< field_path > value, or >= with that operator
+	ConditionFieldLess      = "field_less"       ///< field_path < value, or <= with that operator
+	ConditionFieldCompare   = "field_compare"    ///< field_path compared to value with any operator
+	ConditionTimeRange      = "time_range"       ///< The time of day in jobs.timezone is from start_time to end_time
+)
+
+// JobConditionPollInterval is how often an unmet condition is checked again until its timeout
+const JobConditionPollInterval = 500 * time.Millisecond
+
+// conditionOperators lists the operators each field condition accepts, its default first.
+// field_compare has no default: its operator must be given.
+var conditionOperators = map[string][]string{
+	ConditionFieldEquals:    {"=="},
+	ConditionFieldNotEquals: {"!="},
+	ConditionFieldGreater:   {">", ">="},
+	ConditionFieldLess:      {"<", "<="},
+	ConditionFieldCompare:   {"==", "!=", ">", "<", ">=", "<="},
+}
+
+// timeOfDayLayouts are the accepted forms of start_time and end_time
+var timeOfDayLayouts = []string{"15:04", "15:04:05"}
+
+// conditionCheck is the outcome of checking a condition once
+type conditionCheck struct {
+	met      bool
+	observed interface{}
+	expected interface{}
+	reason   string // Why the condition does not hold
+}
+
+// conditionOperator returns the operator a field condition compares with
+func conditionOperator(condition *JobCondition) (string, error) {
+	allowed := conditionOperators[condition.Type]
+	if condition.Operator == "" {
+		if condition.Type == ConditionFieldCompare {
+			return "", fmt.Errorf("operator is required for %s", condition.Type)
+		}
+		return allowed[0], nil
+	}
+	if !slices.Contains(allowed, condition.Operator) {
+		return "", fmt.Errorf("operator %q does not fit %s (use %s)", condition.Operator, condition.Type, strings.Join(allowed, ", "))
+	}
+	return condition.Operator, nil
+}
+
+// parseTimeOfDay reads "HH:MM" or "HH:MM:SS" as the time since midnight
+func parseTimeOfDay(text string) (time.Duration, error) {
+	for _, layout := range timeOfDayLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute +
+				time.Duration(parsed.Second())*time.Second, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time of day %q (want HH:MM or HH:MM:SS)", text)
+}
+
+// validateCondition checks that a condition names what its type needs
+func validateCondition(condition *JobCondition) error {
+	switch condition.Type {
+	case ConditionDeviceOnline, ConditionDeviceOffline:
+		if condition.DeviceID == "" {
+			return fmt.Errorf("device_id is required for %s", condition.Type)
+		}
+
+	case ConditionFieldEquals, ConditionFieldNotEquals, ConditionFieldGreater, ConditionFieldLess, ConditionFieldCompare:
+		if condition.DeviceID == "" {
+			return fmt.Errorf("device_id is required for %s", condition.Type)
+		}
+		if condition.FieldPath == "" {
+			return fmt.Errorf("field_path is required for %s", condition.Type)
+		}
+		if _, err := conditionOperator(condition); err != nil {
+			return err
+		}
+		if condition.Value == nil {
+			return fmt.Errorf("value is required for %s", condition.Type)
+		}
+
+	case ConditionTimeRange:
+		if condition.StartTime == "" || condition.EndTime == "" {
+			return fmt.Errorf("start_time and end_time are required for %s", condition.Type)
+		}
+		start, err := parseTimeOfDay(condition.StartTime)
+		if err != nil {
+			return fmt.Errorf("start_time: %v", err)
+		}
+		end, err := parseTimeOfDay(condition.EndTime)
+		if err != nil {
+			return fmt.Errorf("end_time: %v", err)
+		}
+		if start == end {
+			return fmt.Errorf("start_time and end_time are both %s", condition.StartTime)
+		}
+
+	default:
+		return fmt.Errorf("unknown condition type %q", condition.Type)
+	}
+
+	if condition.Timeout != "" {
+		timeout, err := time.ParseDuration(condition.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid condition timeout: %v", err)
+		}
+		if timeout < 0 {
+			return fmt.Errorf("condition timeout must not be negative")
+		}
+	}
+	return nil
+}
+
+// executeCondition checks action.Condition against the live device registry, again every
+// JobConditionPollInterval until it holds or its timeout passes; without a timeout it is
+// checked once. The last observation is kept in the result, and the error says why the
+// condition did not hold.
+func (je *JobEngine) executeCondition(action *JobAction, result *ActionResult) error {
+	condition := action.Condition
+	var timeout time.Duration
+	if condition.Timeout != "" {
+		parsed, err := time.ParseDuration(condition.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid condition timeout: %v", err)
+		}
+		timeout = parsed
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		check := je.checkCondition(condition)
+		result.Response = conditionResponse(condition, check)
+		if check.met {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			if timeout > 0 {
+				return fmt.Errorf("%s (waited %v)", check.reason, timeout)
+			}
+			return fmt.Errorf("%s", check.reason)
+		}
+		time.Sleep(shorterWait(JobConditionPollInterval, remaining))
+	}
+}
+
+// conditionResponse is what an action's result shows of a condition check
+func conditionResponse(condition *JobCondition, check conditionCheck) map[string]interface{} {
+	response := map[string]interface{}{
+		"condition": condition.Type,
+		"met":       check.met,
+		"observed":  check.observed,
+		"expected":  check.expected,
+	}
+	if condition.DeviceID != "" {
+		response["device_id"] = condition.DeviceID
+	}
+	if condition.FieldPath != "" {
+		response["field_path"] = condition.FieldPath
+	}
+	return response
+}
+
+// checkCondition evaluates a condition once
+func (je *JobEngine) checkCondition(condition *JobCondition) conditionCheck {
+	switch condition.Type {
+	case ConditionTimeRange:
+		return je.checkTimeRange(condition, time.Now().In(je.scheduler.Location()))
+
+	case ConditionDeviceOnline, ConditionDeviceOffline:
+		want := DeviceOnline
+		if condition.Type == ConditionDeviceOffline {
+			want = DeviceOffline
+		}
+		check := conditionCheck{expected: want}
+		state, err := je.deviceState(condition.DeviceID)
+		if err != nil {
+			check.reason = err.Error()
+			return check
+		}
+		check.observed = state["status"]
+		check.met = check.observed == want
+		check.reason = fmt.Sprintf("%s is %v, not %s", condition.DeviceID, check.observed, want)
+		return check
+
+	default:
+		check := conditionCheck{expected: condition.Value}
+		operator, err := conditionOperator(condition)
+		if err != nil {
+			check.reason = err.Error()
+			return check
+		}
+		state, err := je.deviceState(condition.DeviceID)
+		if err != nil {
+			check.reason = err.Error()
+			return check
+		}
+		check.observed, err = resolveFieldPath(state, condition.FieldPath)
+		if err != nil {
+			check.reason = fmt.Sprintf("%s: %v", condition.DeviceID, err)
+			return check
+		}
+		check.met, err = compareValues(check.observed, operator, condition.Value)
+		if err != nil {
+			check.reason = fmt.Sprintf("%s %s: %v", condition.DeviceID, condition.FieldPath, err)
+			return check
+		}
+		check.reason = fmt.Sprintf("%s %s is %s, not %s %s", condition.DeviceID, condition.FieldPath,
+			formatJobValue(check.observed), operator, formatJobValue(condition.Value))
+		return check
+	}
+}
+
+// checkTimeRange tests whether now falls from start_time up to end_time. A range whose end is
+// before its start runs past midnight, e.g. 22:00 to 06:00.
+func (je *JobEngine) checkTimeRange(condition *JobCondition, now time.Time) conditionCheck {
+	check := conditionCheck{
+		observed: now.Format("15:04:05 MST"),
+		expected: condition.StartTime + "-" + condition.EndTime,
+	}
+	start, err := parseTimeOfDay(condition.StartTime)
+	if err != nil {
+		check.reason = err.Error()
+		return check
+	}
+	end, err := parseTimeOfDay(condition.EndTime)
+	if err != nil {
+		check.reason = err.Error()
+		return check
+	}
+
+	wallClock := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute +
+		time.Duration(now.Second())*time.Second
+	if start < end {
+		check.met = wallClock >= start && wallClock < end
+	} else {
+		check.met = wallClock >= start || wallClock < end
+	}
+	check.reason = fmt.Sprintf("%s is outside %s-%s", check.observed, condition.StartTime, condition.EndTime)
+	return check
+}
+
+// deviceState reads a device from the device connection
+func (je *JobEngine) deviceState(deviceID string) (map[string]interface{}, error) {
+	if je.deviceComm == nil {
+		return nil, fmt.Errorf("no device connection to read %s", deviceID)
+	}
+	return je.deviceComm.DeviceState(deviceID)
+}
+
+// resolveFieldPath follows a dotted path through decoded JSON, object fields by name and list
+// items by index, e.g. telemetry.ppm_salt or lights.0.status
+func resolveFieldPath(value interface{}, path string) (interface{}, error) {
+	parts := strings.Split(path, ".")
+	current := value
+	for i, part := range parts {
+		where := "the top level"
+		if i > 0 {
+			where = strings.Join(parts[:i], ".")
+		}
+
+		switch node := current.(type) {
+		case map[string]interface{}:
+			child, exists := node[part]
+			if !exists {
+				keys := make([]string, 0, len(node))
+				for key := range node {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				return nil, fmt.Errorf("no field %q in %s (fields: %s)", part, where, strings.Join(keys, ", "))
+			}
+			current = child
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("no item %q in %s, which has %d items", part, where, len(node))
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("%s is %s, which has no field %q", where, formatJobValue(current), part)
+		}
+	}
+	return current, nil
+}
+
+// compareValues applies operator to actual and expected. Numbers, and strings holding numbers
+// such as 64-bit protobuf integers, compare by value; anything else can only be tested for
+// equality, by its text.
+func compareValues(actual interface{}, operator string, expected interface{}) (bool, error) {
+	a, actualIsNumber := conditionNumber(actual)
+	e, expectedIsNumber := conditionNumber(expected)
+	if actualIsNumber && expectedIsNumber {
+		switch operator {
+		case "==":
+			return a == e, nil
+		case "!=":
+			return a != e, nil
+		case ">":
+			return a > e, nil
+		case "<":
+			return a < e, nil
+		case ">=":
+			return a >= e, nil
+		case "<=":
+			return a <= e, nil
+		}
+		return false, fmt.Errorf("unknown operator %q", operator)
+	}
+
+	switch operator {
+	case "==":
+		return formatJobValue(actual) == formatJobValue(expected), nil
+	case "!=":
+		return formatJobValue(actual) != formatJobValue(expected), nil
+	}
+	return false, fmt.Errorf("cannot compare %s %s %s, only numbers are ordered",
+		formatJobValue(actual), operator, formatJobValue(expected))
+}
+
+// conditionNumber reads a JSON or YAML number, or a string holding one
+func conditionNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return parsed, err == nil
+	}
+	return 0, false
+}
+
+// formatJobValue renders a value for comparisons, failure reasons and ${variables} inside
+// text: strings as they are, anything else as JSON
+func formatJobValue(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"NgaSim/ned"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+// MQTTDeviceCommunicator sends job messages to devices over a site's MQTT connection, the same
+// way the control API does, and waits for each device's response
+type MQTTDeviceCommunicator struct {
+	sim     *NgaSim
+	timeout time.Duration
+}
+
+// NewMQTTDeviceCommunicator creates a communicator for one site that waits timeout for responses
+func NewMQTTDeviceCommunicator(sim *NgaSim, timeout time.Duration) *MQTTDeviceCommunicator {
+	return &MQTTDeviceCommunicator{sim: sim, timeout: timeout}
+}
+
+// SendMessage publishes message to the device in its category envelope and returns the
+// correlated response. A response code other than OK is returned along with an error. Job
+// commands are not queued while the broker is unreachable; the action fails so its retry policy
+// applies instead of a late replay.
+func (c *MQTTDeviceCommunicator) SendMessage(serial string, message proto.Message) (*CommandReply, error) {
+	n := c.sim
+	category, err := n.deviceCategory(serial)
+	if err != nil {
+		return nil, err
+	}
+	if !n.brokerConnected() {
+		return nil, fmt.Errorf("not connected to the MQTT broker")
+	}
+
+	command := string(message.ProtoReflect().Descriptor().Name())
+	commandUUID := uuid.New().String()
+	_, msgBytes, err := marshalCommand(category, commandUUID, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s: %v", command, err)
+	}
+
+	// Await before publishing so a fast device cannot answer unseen
+	replies := n.commandTracker.Await(commandUUID)
+	defer n.commandTracker.StopAwait(commandUUID)
+
+	n.addDeviceTerminalEntry(serial, "COMMAND", "→ Job "+command, nil)
+	queued, err := n.publishCommand(serial, category, command, commandUUID, msgBytes)
+	if err != nil {
+		return nil, err
+	}
+	if queued {
+		// The link dropped after the check above; withdraw the command so the action's retry
+		// policy is the only thing that sends it again
+		n.cancelQueuedCommand(commandUUID)
+		return nil, fmt.Errorf("MQTT disconnected while sending %s (UUID: %s)", command, commandUUID)
+	}
+	n.addDeviceTerminalEntry(serial, "MQTT_CMD",
+		fmt.Sprintf("📡 MQTT command sent: %s (UUID: %s)", command, commandUUID), msgBytes)
+	log.Printf("📤 Job command sent: %s -> %s (UUID: %s)", serial, command, commandUUID)
+
+	timeout := time.NewTimer(c.timeout)
+	defer timeout.Stop()
+
+	select {
+	case reply := <-replies:
+		if reply.Code != ned.ResponseCode_RESPONSE_OK {
+			return &reply, fmt.Errorf("%s answered %s to %s", serial, responseCodeName(reply.Code), command)
+		}
+		return &reply, nil
+	case <-timeout.C:
+		return nil, fmt.Errorf("no response from %s to %s within %v (UUID: %s)", serial, command, c.timeout, commandUUID)
+	}
+}
+
+// CommandFrame builds the envelope and bytes SendMessage would publish for message, without
+// publishing it. A device NgaSim has not seen gets the category of the message's proto package.
+func (c *MQTTDeviceCommunicator) CommandFrame(serial string, message proto.Message) (*CommandFrame, error) {
+	n := c.sim
+	category, err := n.deviceCategory(serial)
+	if err != nil {
+		category = messageCategories[message.ProtoReflect().Descriptor().ParentFile().Package()]
+		if category == "" {
+			return nil, err
+		}
+	}
+
+	commandUUID := uuid.New().String()
+	envelope, msgBytes, err := marshalCommand(category, commandUUID, message)
+	if err != nil {
+		return nil, err
+	}
+	return &CommandFrame{
+		Topic:    n.commandTopic(category, serial),
+		Category: category,
+		UUID:     commandUUID,
+		Envelope: envelope,
+		Bytes:    msgBytes,
+	}, nil
+}
+
+// DeviceState returns the device's fields as JSON names them in /api/devices, without its
+// terminal history, plus its jobs.device_tags as "tags"
+func (c *MQTTDeviceCommunicator) DeviceState(serial string) (map[string]interface{}, error) {
+	n := c.sim
+	n.mutex.RLock()
+	device, exists := n.devices[serial]
+	var data []byte
+	var err error
+	if exists {
+		data, err = json.Marshal(device)
+	}
+	n.mutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("device not found: %s", serial)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", serial, err)
+	}
+
+	state := make(map[string]interface{})
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", serial, err)
+	}
+	delete(state, "live_terminal")
+
+	tags := make([]interface{}, 0)
+	for _, tag := range n.currentConfig().Jobs.DeviceTags[serial] {
+		tags = append(tags, tag)
+	}
+	state["tags"] = tags
+	return state, nil
+}
+
+// Devices returns the state of every known device, by serial number
+func (c *MQTTDeviceCommunicator) Devices() []map[string]interface{} {
+	n := c.sim
+	n.mutex.RLock()
+	serials := make([]string, 0, len(n.devices))
+	for serial := range n.devices {
+		serials = append(serials, serial)
+	}
+	n.mutex.RUnlock()
+	sort.Strings(serials)
+
+	devices := make([]map[string]interface{}, 0, len(serials))
+	for _, serial := range serials {
+		// A device removed since the list was taken is left out
+		if state, err := c.DeviceState(serial); err == nil {
+			devices = append(devices, state)
+		}
+	}
+	return devices
+}
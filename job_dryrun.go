@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DryRunJob runs a job without sending anything. send_message actions build the frame they
+// would publish and report its topic, envelope and bytes; waits are skipped; conditions are
+// checked once and taken as met, so the run follows the path on which every device answers OK.
+// foreach selects from the devices NgaSim knows now. A dry run does not stop at a frame it
+// cannot build, is not kept with the job's executions, and works on disabled jobs too.
+func (je *JobEngine) DryRunJob(jobID string) (*JobExecution, error) {
+	job, exists := je.GetJob(jobID)
+	if !exists {
+		return nil, fmt.Errorf("job %s not found", jobID)
+	}
+
+	execution := &JobExecution{
+		ID:        fmt.Sprintf("dryrun_%d", time.Now().UnixNano()),
+		JobID:     job.ID,
+		StartTime: time.Now(),
+		Status:    JobRunning,
+		Results:   make([]ActionResult, 0),
+		Context:   map[string]interface{}{"trigger": JobTriggerDryRun},
+	}
+	je.runExecution(job, execution, true)
+	return je.snapshotExecution(execution), nil
+}
+
+// dryRunAction stands in for a send_message, wait or condition action in a dry run
+func (je *JobEngine) dryRunAction(action *JobAction, result *ActionResult) error {
+	switch action.Type {
+	case "send_message":
+		msg, err := je.buildMessage(action)
+		if err != nil {
+			return err
+		}
+		if je.deviceComm == nil {
+			return fmt.Errorf("no device connection to address %s", action.MessageType)
+		}
+		frame, err := je.deviceComm.CommandFrame(action.DeviceID, msg)
+		if err != nil {
+			return fmt.Errorf("failed to build frame: %v", err)
+		}
+		result.Response = map[string]interface{}{
+			"dry_run":       true,
+			"message_type":  action.MessageType,
+			"topic":         frame.Topic,
+			"category":      frame.Category,
+			"command_uuid":  frame.UUID,
+			"envelope_type": protoTypeName(frame.Envelope),
+			"envelope":      je.protoToMap(frame.Envelope),
+			"size":          len(frame.Bytes),
+			"frame":         hex.EncodeToString(frame.Bytes),
+		}
+		log.Printf("🧪 Dry run %s → %s (%d bytes): %x", action.MessageType, frame.Topic, len(frame.Bytes), frame.Bytes)
+
+	case "wait":
+		result.Response = map[string]interface{}{"dry_run": true, "skipped_wait": action.WaitDuration}
+
+	case "condition":
+		check := je.checkCondition(action.Condition)
+		result.Response = conditionResponse(action.Condition, check)
+		result.Response["dry_run"] = true
+		if !check.met {
+			result.Response["not_met"] = check.reason
+		}
+
+	default:
+		return fmt.Errorf("unknown action type: %s", action.Type)
+	}
+	return nil
+}
@@ -4,26 +4,58 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 	"gopkg.in/yaml.v2"
 )
 
+// Job execution states
+const (
+	JobRunning   = "running"   ///< Actions are being carried out
+	JobCompleted = "completed" ///< Every action succeeded
+	JobFailed    = "failed"    ///< An action failed
+	JobCancelled = "cancelled" ///< Stopped before the last action
+)
+
+// What started a job execution, kept in its context under "trigger"
+const (
+	JobTriggerManual   = "manual"   ///< Run through the API or ExecuteJob
+	JobTriggerSchedule = "schedule" ///< Started by the job's schedule
+	JobTriggerDryRun   = "dry_run"  ///< A dry run, which sends nothing and is not kept
+)
+
+// JobExecutionHistorySize is how many executions are kept for /api/jobs/{id}/executions
+const JobExecutionHistorySize = 500
+
 // JobAction represents a single action to perform on a device
 type JobAction struct {
-	Type         string                 `json:"type" yaml:"type"` // "send_message", "wait", "condition"
-	DeviceID     string                 `json:"device_id" yaml:"device_id"`
-	MessageType  string                 `json:"message_type" yaml:"message_type"`
-	Parameters   map[string]interface{} `json:"parameters" yaml:"parameters"`
+	Type         string                 `json:"type" yaml:"type"`                   // "send_message", "wait", "condition", "parallel", "foreach"
+	DeviceID     string                 `json:"device_id" yaml:"device_id"`         // May use ${variables}; inside foreach, defaults to the current device
+	MessageType  string                 `json:"message_type" yaml:"message_type"`   // e.g. sanitizer.SetSanitizerTargetPercentageRequestPayload
+	Parameters   map[string]interface{} `json:"parameters" yaml:"parameters"`       // String values may use ${variables}
 	WaitDuration string                 `json:"wait_duration" yaml:"wait_duration"` // e.g., "5s", "1m"
 	Condition    *JobCondition          `json:"condition" yaml:"condition"`
-	OnSuccess    []JobAction            `json:"on_success" yaml:"on_success"`
-	OnFailure    []JobAction            `json:"on_failure" yaml:"on_failure"`
+	Actions      []JobAction            `json:"actions,omitempty" yaml:"actions"`   // The group of a parallel action, or what foreach runs per device
+	Devices      *DeviceSelector        `json:"devices,omitempty" yaml:"devices"`   // Devices a foreach action runs its actions for
+	Parallel     bool                   `json:"parallel,omitempty" yaml:"parallel"` // foreach: run all devices at once instead of one by one
+	SaveAs       string                 `json:"save_as,omitempty" yaml:"save_as"`   // Context variable the action's response is kept in
+	OnSuccess    []JobAction            `json:"on_success" yaml:"on_success"`       // Run after the action succeeds
+	OnFailure    []JobAction            `json:"on_failure" yaml:"on_failure"`       // Run after the action fails; the job goes on if they succeed
 	Retry        *RetryConfig           `json:"retry" yaml:"retry"`
 	Tags         []string               `json:"tags" yaml:"tags"`
 }
 
+// DeviceSelector picks the devices a foreach action runs for. Every criterion given must match.
+type DeviceSelector struct {
+	Category string `json:"category,omitempty" yaml:"category"` // Device category or type, e.g. sanitizerGen2
+	Tag      string `json:"tag,omitempty" yaml:"tag"`           // A tag given to the device in jobs.device_tags
+	Model    string `json:"model,omitempty" yaml:"model"`       // Announced model_id, e.g. sanitizer-gen2
+}
+
 // JobCondition represents a condition to check before proceeding
 type JobCondition struct {
 	Type      string      `json:"type" yaml:"type"` // "field_equals", "field_greater", "device_online", etc.
@@ -31,7 +63,9 @@ type JobCondition struct {
 	FieldPath string      `json:"field_path" yaml:"field_path"` // e.g., "telemetry.temperature"
 	Operator  string      `json:"operator" yaml:"operator"`     // "==", "!=", ">", "<", ">=", "<="
 	Value     interface{} `json:"value" yaml:"value"`
-	Timeout   string      `json:"timeout" yaml:"timeout"` // e.g., "30s"
+	Timeout   string      `json:"timeout" yaml:"timeout"`                 // e.g., "30s"; polled until then, checked once if empty
+	StartTime string      `json:"start_time,omitempty" yaml:"start_time"` // time_range start, "HH:MM" in jobs.timezone
+	EndTime   string      `json:"end_time,omitempty" yaml:"end_time"`     // time_range end, before start to run past midnight
 }
 
 // RetryConfig defines retry behavior for actions
@@ -52,14 +86,17 @@ type Job struct {
 	Tags        []string    `json:"tags" yaml:"tags"`
 	CreatedAt   time.Time   `json:"created_at" yaml:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at" yaml:"updated_at"`
+	Source      string      `json:"source,omitempty" yaml:"-"` // Job file it was loaded from, empty if created through the API
 }
 
 // Schedule defines when a job should run
 type Schedule struct {
-	Type     string `json:"type" yaml:"type"`         // "once", "interval", "cron"
-	Interval string `json:"interval" yaml:"interval"` // e.g., "1h", "30m"
-	Cron     string `json:"cron" yaml:"cron"`         // cron expression
-	StartAt  string `json:"start_at" yaml:"start_at"` // ISO 8601 timestamp
+	Type     string `json:"type" yaml:"type"`                   // "once", "interval", "cron"
+	Interval string `json:"interval" yaml:"interval"`           // e.g., "1h", "30m"
+	Cron     string `json:"cron" yaml:"cron"`                   // cron expression
+	StartAt  string `json:"start_at" yaml:"start_at"`           // ISO 8601 timestamp
+	Timezone string `json:"timezone,omitempty" yaml:"timezone"` // IANA zone for cron and start_at, default jobs.timezone
+	CatchUp  string `json:"catch_up,omitempty" yaml:"catch_up"` // "skip", "once" or "all", default jobs.catch_up
 }
 
 // JobExecution represents a single execution instance of a job
@@ -84,6 +121,11 @@ type ActionResult struct {
 	Error         string                 `json:"error,omitempty"`
 	Response      map[string]interface{} `json:"response,omitempty"`
 	RetryAttempts int                    `json:"retry_attempts"`
+	DeviceID      string                 `json:"device_id,omitempty"`  // Device the action went to
+	Recovered     bool                   `json:"recovered,omitempty"`  // Failed, but its on_failure actions succeeded so the job went on
+	Results       []ActionResult         `json:"results,omitempty"`    // Actions of a parallel group, or one entry per foreach device
+	OnSuccess     []ActionResult         `json:"on_success,omitempty"` // Actions run because this one succeeded
+	OnFailure     []ActionResult         `json:"on_failure,omitempty"` // Actions run because this one failed
 }
 
 // JobEngine manages and executes automation jobs
@@ -92,19 +134,25 @@ type JobEngine struct {
 	executions map[string]*JobExecution
 	mutex      sync.RWMutex
 	scheduler  *JobScheduler
-	deviceComm *DeviceCommunicator
+	deviceComm DeviceCommunicator
+	reflection *ProtobufReflectionEngine
 	logger     *DeviceLogger
 	registry   *ProtobufCommandRegistry
 	stopChan   chan struct{}
 	running    bool
 }
 
-// NewJobEngine creates a new job automation engine
-func NewJobEngine(deviceComm *DeviceCommunicator, logger *DeviceLogger, registry *ProtobufCommandRegistry) *JobEngine {
+// NewJobEngine creates a new job automation engine. send_message actions build their messages
+// with reflection and go out through deviceComm; without one they fail.
+func NewJobEngine(deviceComm DeviceCommunicator, reflection *ProtobufReflectionEngine, logger *DeviceLogger, registry *ProtobufCommandRegistry) *JobEngine {
+	if reflection == nil {
+		reflection = NewProtobufReflectionEngine()
+	}
 	engine := &JobEngine{
 		jobs:       make(map[string]*Job),
 		executions: make(map[string]*JobExecution),
 		deviceComm: deviceComm,
+		reflection: reflection,
 		logger:     logger,
 		registry:   registry,
 		stopChan:   make(chan struct{}),
@@ -114,29 +162,157 @@ func NewJobEngine(deviceComm *DeviceCommunicator, logger *DeviceLogger, registry
 	return engine
 }
 
+// Start runs scheduled jobs until Stop is called
+func (je *JobEngine) Start() {
+	je.mutex.Lock()
+	defer je.mutex.Unlock()
+
+	if je.running {
+		return
+	}
+	je.running = true
+	go je.scheduler.run(je.stopChan)
+}
+
+// Stop ends scheduling; executions already started run to the end
+func (je *JobEngine) Stop() {
+	je.mutex.Lock()
+	defer je.mutex.Unlock()
+
+	if !je.running {
+		return
+	}
+	je.running = false
+	close(je.stopChan)
+	je.stopChan = make(chan struct{})
+}
+
+// NextRun returns when a job's schedule runs it next, or the zero time if it does not
+func (je *JobEngine) NextRun(jobID string) time.Time {
+	return je.scheduler.NextRun(jobID)
+}
+
 // LoadJobsFromFile loads jobs from a JSON or YAML file
 func (je *JobEngine) LoadJobsFromFile(filename string) error {
+	jobs, err := readJobFile(filename)
+	if err != nil {
+		return err
+	}
+
+	// Load jobs into engine
+	for _, job := range jobs {
+		if err := je.AddJob(job); err != nil {
+			return fmt.Errorf("error adding job %s: %v", job.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// readJobFile parses a JSON or YAML list of jobs and marks them with the file they came from
+func readJobFile(filename string) ([]*Job, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return fmt.Errorf("error reading job file: %v", err)
+		return nil, fmt.Errorf("error reading job file: %v", err)
 	}
 
-	var jobs []Job
+	var jobs []*Job
 
 	// Try JSON first, then YAML
 	if err := json.Unmarshal(data, &jobs); err != nil {
 		if err := yaml.Unmarshal(data, &jobs); err != nil {
-			return fmt.Errorf("error parsing job file (tried JSON and YAML): %v", err)
+			return nil, fmt.Errorf("error parsing job file %s (tried JSON and YAML): %v", filename, err)
 		}
 	}
 
-	// Load jobs into engine
 	for _, job := range jobs {
-		if err := je.AddJob(&job); err != nil {
-			return fmt.Errorf("error adding job %s: %v", job.ID, err)
+		job.Source = filename
+		normalizeActions(job.Actions)
+	}
+	return jobs, nil
+}
+
+// normalizeActions converts the map[interface{}]interface{} values YAML decodes nested maps to
+// into map[string]interface{}, as JSON decodes them, so both can be sent and served as JSON
+func normalizeActions(actions []JobAction) {
+	for i := range actions {
+		action := &actions[i]
+		if action.Parameters != nil {
+			action.Parameters = normalizeYAML(action.Parameters).(map[string]interface{})
+		}
+		if action.Condition != nil {
+			action.Condition.Value = normalizeYAML(action.Condition.Value)
+		}
+		normalizeActions(action.Actions)
+		normalizeActions(action.OnSuccess)
+		normalizeActions(action.OnFailure)
+	}
+}
+
+// normalizeYAML returns value with every nested map keyed by strings
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			normalized[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return normalized
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	}
+	return value
+}
+
+// ReplaceFileJobs swaps every job loaded from a file for jobs, which were read again from the
+// job files. Jobs created through the API are kept, as is the execution history. Nothing changes
+// when a job is invalid or its ID is taken.
+func (je *JobEngine) ReplaceFileJobs(jobs []*Job) error {
+	sources := make(map[string]string)
+	for _, job := range jobs {
+		if err := je.validateJob(job); err != nil {
+			return fmt.Errorf("%s: job %s: %v", job.Source, job.ID, err)
+		}
+		if previous, exists := sources[job.ID]; exists {
+			return fmt.Errorf("%s: job %s already defined in %s", job.Source, job.ID, previous)
 		}
+		sources[job.ID] = job.Source
 	}
 
+	je.mutex.Lock()
+	defer je.mutex.Unlock()
+
+	for _, job := range jobs {
+		if existing, exists := je.jobs[job.ID]; exists && existing.Source == "" {
+			return fmt.Errorf("%s: job %s is already defined through the API", job.Source, job.ID)
+		}
+	}
+
+	for id, job := range je.jobs {
+		if job.Source != "" {
+			je.scheduler.UnscheduleJob(id)
+			delete(je.jobs, id)
+		}
+	}
+	now := time.Now()
+	for _, job := range jobs {
+		if job.CreatedAt.IsZero() {
+			job.CreatedAt = now
+		}
+		job.UpdatedAt = now
+		je.jobs[job.ID] = job
+		if job.Enabled && job.Schedule != nil {
+			je.scheduler.ScheduleJob(job)
+		}
+	}
 	return nil
 }
 
@@ -145,6 +321,80 @@ func (je *JobEngine) AddJob(job *Job) error {
 	je.mutex.Lock()
 	defer je.mutex.Unlock()
 
+	return je.storeJob(job)
+}
+
+// CreateJob adds a job defined through the API; its ID must not be taken
+func (je *JobEngine) CreateJob(job *Job) error {
+	je.mutex.Lock()
+	defer je.mutex.Unlock()
+
+	if _, exists := je.jobs[job.ID]; exists {
+		return fmt.Errorf("job %s already exists", job.ID)
+	}
+	job.Source = ""
+	return je.storeJob(job)
+}
+
+// UpdateJob replaces the definition of an existing job, keeping its ID, creation time and source
+func (je *JobEngine) UpdateJob(jobID string, job *Job) error {
+	je.mutex.Lock()
+	defer je.mutex.Unlock()
+
+	existing, exists := je.jobs[jobID]
+	if !exists {
+		return fmt.Errorf("job %s not found", jobID)
+	}
+	job.ID = jobID
+	job.CreatedAt = existing.CreatedAt
+	job.Source = existing.Source
+	return je.storeJob(job)
+}
+
+// SetJobEnabled enables or disables a job and returns it
+func (je *JobEngine) SetJobEnabled(jobID string, enabled bool) (*Job, error) {
+	je.mutex.Lock()
+	defer je.mutex.Unlock()
+
+	existing, exists := je.jobs[jobID]
+	if !exists {
+		return nil, fmt.Errorf("job %s not found", jobID)
+	}
+
+	// Stored jobs are never modified in place, so readers can hold on to them
+	job := *existing
+	job.Enabled = enabled
+	if err := je.storeJob(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// DeleteJob removes a job; executions already started run to the end
+func (je *JobEngine) DeleteJob(jobID string) (*Job, error) {
+	je.mutex.Lock()
+	defer je.mutex.Unlock()
+
+	job, exists := je.jobs[jobID]
+	if !exists {
+		return nil, fmt.Errorf("job %s not found", jobID)
+	}
+	je.scheduler.UnscheduleJob(jobID)
+	delete(je.jobs, jobID)
+	return job, nil
+}
+
+// GetJob returns one job
+func (je *JobEngine) GetJob(jobID string) (*Job, bool) {
+	je.mutex.RLock()
+	defer je.mutex.RUnlock()
+
+	job, exists := je.jobs[jobID]
+	return job, exists
+}
+
+// storeJob validates, stamps and (re)schedules a job. Caller must hold the engine mutex.
+func (je *JobEngine) storeJob(job *Job) error {
 	// Validate job
 	if err := je.validateJob(job); err != nil {
 		return err
@@ -157,6 +407,7 @@ func (je *JobEngine) AddJob(job *Job) error {
 	}
 	job.UpdatedAt = now
 
+	je.scheduler.UnscheduleJob(job.ID)
 	je.jobs[job.ID] = job
 
 	// Schedule if enabled
@@ -181,26 +432,39 @@ func (je *JobEngine) validateJob(job *Job) error {
 		return fmt.Errorf("job must have at least one action")
 	}
 
-	// Validate actions
-	for i, action := range job.Actions {
-		if err := je.validateAction(&action); err != nil {
-			return fmt.Errorf("action %d: %v", i, err)
+	if job.Schedule != nil {
+		if _, _, err := compileSchedule(job.Schedule, time.Local, time.Now(), time.Time{}); err != nil {
+			return fmt.Errorf("schedule: %v", err)
 		}
 	}
 
+	return je.validateActions(job.Actions, false)
+}
+
+// validateActions validates a list of actions and the actions nested in them. Inside a foreach
+// (inForeach) actions may leave device_id out to use the current device.
+func (je *JobEngine) validateActions(actions []JobAction, inForeach bool) error {
+	for i := range actions {
+		if err := je.validateAction(&actions[i], inForeach); err != nil {
+			return fmt.Errorf("action %d: %v", i, err)
+		}
+	}
 	return nil
 }
 
 // validateAction validates a single action
-func (je *JobEngine) validateAction(action *JobAction) error {
+func (je *JobEngine) validateAction(action *JobAction, inForeach bool) error {
 	switch action.Type {
 	case "send_message":
-		if action.DeviceID == "" {
+		if action.DeviceID == "" && !inForeach {
 			return fmt.Errorf("device_id is required for send_message action")
 		}
 		if action.MessageType == "" {
 			return fmt.Errorf("message_type is required for send_message action")
 		}
+		if err := je.validateMessage(action); err != nil {
+			return err
+		}
 	case "wait":
 		if action.WaitDuration == "" {
 			return fmt.Errorf("wait_duration is required for wait action")
@@ -212,80 +476,236 @@ func (je *JobEngine) validateAction(action *JobAction) error {
 		if action.Condition == nil {
 			return fmt.Errorf("condition is required for condition action")
 		}
+		condition := *action.Condition
+		if condition.DeviceID == "" && inForeach {
+			condition.DeviceID = "${device.serial}"
+		}
+		if err := validateCondition(&condition); err != nil {
+			return fmt.Errorf("condition: %v", err)
+		}
+	case "parallel":
+		if len(action.Actions) == 0 {
+			return fmt.Errorf("actions are required for parallel action")
+		}
+		if err := je.validateActions(action.Actions, inForeach); err != nil {
+			return fmt.Errorf("parallel %v", err)
+		}
+	case "foreach":
+		if action.Devices == nil || *action.Devices == (DeviceSelector{}) {
+			return fmt.Errorf("devices (category, tag or model) are required for foreach action")
+		}
+		if len(action.Actions) == 0 {
+			return fmt.Errorf("actions are required for foreach action")
+		}
+		if err := je.validateActions(action.Actions, true); err != nil {
+			return fmt.Errorf("foreach %v", err)
+		}
 	default:
 		return fmt.Errorf("unknown action type: %s", action.Type)
 	}
 
+	if action.Retry != nil && (action.Type == "parallel" || action.Type == "foreach") {
+		return fmt.Errorf("retry is not supported on %s actions, give it to the actions inside", action.Type)
+	}
+	if err := je.validateActions(action.OnSuccess, inForeach); err != nil {
+		return fmt.Errorf("on_success %v", err)
+	}
+	if err := je.validateActions(action.OnFailure, inForeach); err != nil {
+		return fmt.Errorf("on_failure %v", err)
+	}
+	return nil
+}
+
+// validateMessage resolves a send_message action's message_type in the protobuf registry and
+// builds it from the parameters, so unknown fields, values of the wrong type and unknown enum
+// names are reported when the job is loaded rather than when it runs. Values holding
+// ${variables} are only known at run time; their field names are checked, their values are not.
+func (je *JobEngine) validateMessage(action *JobAction) error {
+	msg, err := je.reflection.CreateMessage(action.MessageType)
+	if err != nil {
+		return fmt.Errorf("message_type: %v%s", err, similarMessages(action.MessageType))
+	}
+	if parameters, ok := withoutVariables(action.Parameters).(map[string]interface{}); ok {
+		if err := je.populateMessage(msg, parameters); err != nil {
+			return fmt.Errorf("parameters: %v", err)
+		}
+	}
+
+	// Messages of a device package must fit that category's envelope, common ned ones any
+	category := messageCategories[msg.ProtoReflect().Descriptor().ParentFile().Package()]
+	if _, err := buildCommandEnvelope(category, "", msg); err != nil {
+		return fmt.Errorf("message_type: %v", err)
+	}
 	return nil
 }
 
-// ExecuteJob executes a job immediately
+// withoutVariables returns a copy of parameters in which values holding ${variables} are nil,
+// which PopulateMessage skips, and list items holding them are left out
+func withoutVariables(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if jobVariable.MatchString(v) {
+			return nil
+		}
+	case map[string]interface{}:
+		stripped := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			stripped[key] = withoutVariables(item)
+		}
+		return stripped
+	case []interface{}:
+		stripped := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if item = withoutVariables(item); item != nil {
+				stripped = append(stripped, item)
+			}
+		}
+		return stripped
+	}
+	return value
+}
+
+// ExecuteJob executes a job immediately. Disabled jobs run too: enabled only governs the schedule.
 func (je *JobEngine) ExecuteJob(jobID string) (*JobExecution, error) {
-	je.mutex.RLock()
-	job, exists := je.jobs[jobID]
-	je.mutex.RUnlock()
+	job, err := je.findJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	execution := je.startExecution(job, JobTriggerManual)
+	je.runExecution(job, execution, false)
+	return je.snapshotExecution(execution), nil
+}
 
+// StartJob starts a job in the background and returns its running execution. Like ExecuteJob it
+// runs disabled jobs, which are left for a manual trigger.
+func (je *JobEngine) StartJob(jobID string) (*JobExecution, error) {
+	job, err := je.findJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	execution := je.startExecution(job, JobTriggerManual)
+	snapshot := je.snapshotExecution(execution)
+	go je.runExecution(job, execution, false)
+	return snapshot, nil
+}
+
+// findJob returns a job, or an error when there is none
+func (je *JobEngine) findJob(jobID string) (*Job, error) {
+	job, exists := je.GetJob(jobID)
 	if !exists {
 		return nil, fmt.Errorf("job %s not found", jobID)
 	}
+	return job, nil
+}
+
+// runnableJob returns an enabled job, for the scheduler
+func (je *JobEngine) runnableJob(jobID string) (*Job, error) {
+	job, err := je.findJob(jobID)
+	if err != nil {
+		return nil, err
+	}
 
 	if !job.Enabled {
 		return nil, fmt.Errorf("job %s is disabled", jobID)
 	}
 
-	return je.executeJobInternal(job)
+	return job, nil
 }
 
-// executeJobInternal performs the actual job execution
-func (je *JobEngine) executeJobInternal(job *Job) (*JobExecution, error) {
+// startExecution registers a running execution of job, dropping the oldest finished
+// executions beyond JobExecutionHistorySize
+func (je *JobEngine) startExecution(job *Job, trigger string) *JobExecution {
 	execution := &JobExecution{
 		ID:        fmt.Sprintf("exec_%d", time.Now().UnixNano()),
 		JobID:     job.ID,
 		StartTime: time.Now(),
-		Status:    "running",
+		Status:    JobRunning,
 		Results:   make([]ActionResult, 0),
-		Context:   make(map[string]interface{}),
+		Context:   map[string]interface{}{"trigger": trigger},
 	}
 
 	// Store execution
 	je.mutex.Lock()
+	defer je.mutex.Unlock()
+
 	je.executions[execution.ID] = execution
-	je.mutex.Unlock()
+	if excess := len(je.executions) - JobExecutionHistorySize; excess > 0 {
+		var finished []*JobExecution
+		for _, previous := range je.executions {
+			if previous.Status != JobRunning {
+				finished = append(finished, previous)
+			}
+		}
+		sort.Slice(finished, func(i, j int) bool { return finished[i].StartTime.Before(finished[j].StartTime) })
+		for i := 0; i < excess && i < len(finished); i++ {
+			delete(je.executions, finished[i].ID)
+		}
+	}
 
-	// Execute actions in sequence
-	for i, action := range job.Actions {
-		result := je.executeAction(&action, i, execution)
-		execution.Results = append(execution.Results, result)
+	return execution
+}
+
+// runExecution performs the actions of a started execution in sequence, until one fails
+// without recovering through its on_failure actions. A dry run goes on past failures, so it
+// reports every frame it could not build.
+func (je *JobEngine) runExecution(job *Job, execution *JobExecution, dryRun bool) {
+	scope := &jobScope{execution: execution, vars: make(map[string]interface{}), topLevel: true, dryRun: dryRun}
+	for i := range job.Actions {
+		result := je.runStep(&job.Actions[i], i, scope)
+		failed := !result.Success && !result.Recovered
 
-		if !result.Success {
-			execution.Status = "failed"
+		je.mutex.Lock()
+		execution.Results = append(execution.Results, result)
+		if failed && execution.Status != JobFailed {
+			execution.Status = JobFailed
 			execution.Error = result.Error
+		}
+		je.mutex.Unlock()
+
+		if failed && !dryRun {
 			break
 		}
 	}
 
+	je.mutex.Lock()
 	execution.EndTime = time.Now()
-	if execution.Status == "running" {
-		execution.Status = "completed"
+	if execution.Status == JobRunning {
+		execution.Status = JobCompleted
 	}
-
-	return execution, nil
+	je.mutex.Unlock()
 }
 
-// executeAction executes a single action
-func (je *JobEngine) executeAction(action *JobAction, index int, execution *JobExecution) ActionResult {
+// executeAction executes a single action, without its on_success and on_failure actions, once
+// its ${variables} are filled in from scope
+func (je *JobEngine) executeAction(action *JobAction, index int, scope *jobScope) ActionResult {
 	result := ActionResult{
 		ActionIndex: index,
 		ActionType:  action.Type,
 		StartTime:   time.Now(),
 	}
 
-	var err error
+	action, err := je.expandAction(action, scope)
+	if err != nil {
+		result.EndTime = time.Now()
+		result.Error = err.Error()
+		return result
+	}
+	result.DeviceID = action.DeviceID
+	if action.Condition != nil {
+		result.DeviceID = action.Condition.DeviceID
+	}
+
 	maxAttempts := 1
 	interval := time.Second
 
-	if action.Retry != nil {
-		maxAttempts = action.Retry.MaxAttempts
+	// A dry run sends nothing, so there is nothing to try again
+	if action.Retry != nil && !scope.dryRun {
+		// A retry block without max_attempts still makes the first attempt
+		if action.Retry.MaxAttempts > 1 {
+			maxAttempts = action.Retry.MaxAttempts
+		}
 		if action.Retry.Interval != "" {
 			if d, parseErr := time.ParseDuration(action.Retry.Interval); parseErr == nil {
 				interval = d
@@ -297,13 +717,19 @@ func (je *JobEngine) executeAction(action *JobAction, index int, execution *JobE
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		result.RetryAttempts = attempt - 1
 
-		switch action.Type {
-		case "send_message":
-			err = je.executeSendMessage(action, execution, &result)
-		case "wait":
+		switch {
+		case scope.dryRun && action.Type != "parallel" && action.Type != "foreach":
+			err = je.dryRunAction(action, &result)
+		case action.Type == "send_message":
+			err = je.executeSendMessage(action, &result)
+		case action.Type == "wait":
 			err = je.executeWait(action)
-		case "condition":
-			err = je.executeCondition(action, execution)
+		case action.Type == "condition":
+			err = je.executeCondition(action, &result)
+		case action.Type == "parallel":
+			err = je.executeParallel(action, scope, &result)
+		case action.Type == "foreach":
+			err = je.executeForeach(action, scope, &result)
 		default:
 			err = fmt.Errorf("unknown action type: %s", action.Type)
 		}
@@ -326,49 +752,51 @@ func (je *JobEngine) executeAction(action *JobAction, index int, execution *JobE
 	if err != nil {
 		result.Error = err.Error()
 	}
+	je.keepResponse(action, scope, &result)
 
 	return result
 }
 
-// executeSendMessage executes a send_message action
-func (je *JobEngine) executeSendMessage(action *JobAction, execution *JobExecution, result *ActionResult) error {
-	// Create protobuf message
-	// // 	msg, err := je.registry.CreateMessage(action.MessageType)
-	//	if err != nil {
-	//		return fmt.Errorf("failed to create message: %v", err)
-	//	}
-
-	// Populate message fields from parameters
-	//	if err := je.populateMessage(msg, action.Parameters); err != nil {
-	//		return fmt.Errorf("failed to populate message: %v", err)
-	//	}
-
-	// Send message and get response (placeholder implementation)
-	// response, err := je.deviceComm.SendMessage(action.DeviceID, msg)
-	//	response := msg // For now, just echo the message back
-	//	err = nil
-	//	if err != nil {
-	//		return fmt.Errorf("failed to send message: %v", err)
-	//	}
-
-	// Store response in result and execution context
-	// 	if response != nil {
-	// 		result.Response = je.protoToMap(response)
-	// 		execution.Context[fmt.Sprintf("response_%d", result.ActionIndex)] = result.Response
-	// 	}
-
-	// TODO: Implement actual message sending when protobuf issues are resolved
-	fmt.Printf("📤 Would send message: %s to device: %s\n", action.MessageType, action.DeviceID)
-
-	// Store a placeholder response
-	result.Response = map[string]interface{}{
-		"message_type": action.MessageType,
-		"device_id":    action.DeviceID,
-		"status":       "simulated",
+// executeSendMessage builds action.MessageType from its parameters, sends it to the device and
+// waits for the response, which is kept in the result
+func (je *JobEngine) executeSendMessage(action *JobAction, result *ActionResult) error {
+	msg, err := je.buildMessage(action)
+	if err != nil {
+		return err
+	}
+
+	if je.deviceComm == nil {
+		return fmt.Errorf("no device connection to send %s", action.MessageType)
+	}
+	reply, err := je.deviceComm.SendMessage(action.DeviceID, msg)
+	if reply != nil {
+		result.Response = map[string]interface{}{
+			"command_uuid":  reply.UUID,
+			"response_code": responseCodeName(reply.Code),
+		}
+		if reply.Payload != nil {
+			result.Response["payload_type"] = protoTypeName(reply.Payload)
+			result.Response["payload"] = je.protoToMap(reply.Payload)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send message: %v", err)
 	}
 	return nil
 }
 
+// buildMessage creates a send_message action's message_type and fills it from its parameters
+func (je *JobEngine) buildMessage(action *JobAction) (proto.Message, error) {
+	msg, err := je.reflection.CreateMessage(action.MessageType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create message: %v", err)
+	}
+	if err := je.populateMessage(msg, action.Parameters); err != nil {
+		return nil, fmt.Errorf("failed to populate message: %v", err)
+	}
+	return msg, nil
+}
+
 // executeWait executes a wait action
 func (je *JobEngine) executeWait(action *JobAction) error {
 	duration, err := time.ParseDuration(action.WaitDuration)
@@ -380,24 +808,25 @@ func (je *JobEngine) executeWait(action *JobAction) error {
 	return nil
 }
 
-// executeCondition executes a condition action
-func (je *JobEngine) executeCondition(action *JobAction, execution *JobExecution) error {
-	// Implementation for condition evaluation
-	// This would check device state, field values, etc.
-	return nil
-}
-
 // populateMessage populates a protobuf message with parameters
-func (je *JobEngine) populateMessage(msg interface{}, params map[string]interface{}) error {
-	// Implementation for setting protobuf message fields from parameters
-	// This would use reflection to set field values
-	return nil
+func (je *JobEngine) populateMessage(msg proto.Message, params map[string]interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	return je.reflection.PopulateMessage(msg, params)
 }
 
-// protoToMap converts a protobuf message to a map
-func (je *JobEngine) protoToMap(msg interface{}) map[string]interface{} {
-	// Implementation for converting protobuf to map
-	return make(map[string]interface{})
+// protoToMap converts a protobuf message to a map keyed by proto field names, with enums by name
+func (je *JobEngine) protoToMap(msg proto.Message) map[string]interface{} {
+	result := make(map[string]interface{})
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err == nil {
+		err = json.Unmarshal(data, &result)
+	}
+	if err != nil {
+		result["error"] = fmt.Sprintf("cannot convert %s: %v", protoTypeName(msg), err)
+	}
+	return result
 }
 
 // GetJobs returns all jobs
@@ -412,41 +841,77 @@ func (je *JobEngine) GetJobs() map[string]*Job {
 	return result
 }
 
-// GetExecutions returns job executions with optional filtering
+// GetExecutions returns job executions with optional filtering, newest first. A limit of 0
+// returns them all.
 func (je *JobEngine) GetExecutions(jobID string, limit int) []*JobExecution {
 	je.mutex.RLock()
 	defer je.mutex.RUnlock()
 
-	var executions []*JobExecution
+	executions := make([]*JobExecution, 0)
 	for _, exec := range je.executions {
 		if jobID == "" || exec.JobID == jobID {
-			executions = append(executions, exec)
+			executions = append(executions, copyExecution(exec))
 		}
 	}
 
-	// Sort by start time (newest first) and limit
-	// Implementation for sorting and limiting would go here
+	sort.Slice(executions, func(i, j int) bool { return executions[i].StartTime.After(executions[j].StartTime) })
+	if limit > 0 && len(executions) > limit {
+		executions = executions[:limit]
+	}
 
 	return executions
 }
 
-// JobScheduler handles scheduling of jobs
-type JobScheduler struct {
-	engine *JobEngine
+// GetExecution returns one execution
+func (je *JobEngine) GetExecution(executionID string) (*JobExecution, bool) {
+	je.mutex.RLock()
+	defer je.mutex.RUnlock()
+
+	execution, exists := je.executions[executionID]
+	if !exists {
+		return nil, false
+	}
+	return copyExecution(execution), true
 }
 
-// NewJobScheduler creates a new job scheduler
-func NewJobScheduler(engine *JobEngine) *JobScheduler {
-	return &JobScheduler{engine: engine}
+// snapshotExecution copies an execution that may still be running
+func (je *JobEngine) snapshotExecution(execution *JobExecution) *JobExecution {
+	je.mutex.RLock()
+	defer je.mutex.RUnlock()
+
+	return copyExecution(execution)
 }
 
-// ScheduleJob schedules a job based on its schedule configuration
-func (js *JobScheduler) ScheduleJob(job *Job) {
-	// Implementation for job scheduling
-	// This would handle cron expressions, intervals, etc.
+// copyExecution copies an execution so it can be read while the original runs on. Caller must
+// hold the engine mutex.
+func copyExecution(execution *JobExecution) *JobExecution {
+	snapshot := *execution
+	snapshot.Results = make([]ActionResult, len(execution.Results))
+	copy(snapshot.Results, execution.Results)
+	snapshot.Context = make(map[string]interface{}, len(execution.Context))
+	for key, value := range execution.Context {
+		snapshot.Context[key] = value
+	}
+	return &snapshot
 }
 
 // DeviceCommunicator interface for sending messages to devices
 type DeviceCommunicator interface {
-	SendMessage(deviceID string, message interface{}) (interface{}, error)
+	// SendMessage sends a command payload to a device and returns its response
+	SendMessage(deviceID string, message proto.Message) (*CommandReply, error)
+	// DeviceState returns a device as the devices API shows it, for conditions to test
+	DeviceState(deviceID string) (map[string]interface{}, error)
+	// Devices returns the state of every known device, for foreach to select from
+	Devices() []map[string]interface{}
+	// CommandFrame builds the frame SendMessage would publish, for dry runs
+	CommandFrame(deviceID string, message proto.Message) (*CommandFrame, error)
+}
+
+// CommandFrame is a command as it goes out on MQTT: the category envelope and its bytes
+type CommandFrame struct {
+	Topic    string
+	Category string
+	UUID     string
+	Envelope proto.Message
+	Bytes    []byte
 }
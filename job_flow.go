@@ -0,0 +1,391 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jobVariable matches a ${name} or ${name.field.0} reference in an action
+var jobVariable = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// jobScope is what one sequence of actions runs with. Variables set in the scope (the foreach
+// device, the sequence's last_response) hide execution context entries of the same name;
+// nested sequences get a copy, so parallel actions and foreach devices do not see each other's.
+type jobScope struct {
+	execution *JobExecution
+	vars      map[string]interface{}
+	device    string // Device actions without a device_id go to, inside foreach
+	topLevel  bool   // The job's own actions, whose responses are kept as response_<index>
+	dryRun    bool   // Build frames instead of sending them, see DryRunJob
+}
+
+// child returns a scope for a nested sequence of actions
+func (s *jobScope) child() *jobScope {
+	vars := make(map[string]interface{}, len(s.vars))
+	for name, value := range s.vars {
+		vars[name] = value
+	}
+	return &jobScope{execution: s.execution, vars: vars, device: s.device, dryRun: s.dryRun}
+}
+
+// variables returns what ${...} references in scope can name: the execution context, then the
+// scope's own variables
+func (je *JobEngine) variables(scope *jobScope) map[string]interface{} {
+	je.mutex.RLock()
+	vars := make(map[string]interface{}, len(scope.execution.Context)+len(scope.vars))
+	for name, value := range scope.execution.Context {
+		vars[name] = value
+	}
+	je.mutex.RUnlock()
+
+	for name, value := range scope.vars {
+		vars[name] = value
+	}
+	return vars
+}
+
+// interpolate replaces ${...} references in strings, also inside maps and lists, by values from
+// vars. A string that is a single reference takes the value itself, so numbers and objects keep
+// their type; references inside longer text are written in as text.
+func interpolate(value interface{}, vars map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		match := jobVariable.FindStringSubmatchIndex(v)
+		if match == nil {
+			return v, nil
+		}
+		if match[0] == 0 && match[1] == len(v) {
+			return lookupVariable(vars, v[match[2]:match[3]])
+		}
+
+		var err error
+		text := jobVariable.ReplaceAllStringFunc(v, func(reference string) string {
+			resolved, lookupErr := lookupVariable(vars, reference[2:len(reference)-1])
+			if lookupErr != nil {
+				if err == nil {
+					err = lookupErr
+				}
+				return reference
+			}
+			return formatJobValue(resolved)
+		})
+		return text, err
+
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := interpolate(item, vars)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			expanded[key] = resolved
+		}
+		return expanded, nil
+
+	case []interface{}:
+		expanded := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := interpolate(item, vars)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %v", i, err)
+			}
+			expanded[i] = resolved
+		}
+		return expanded, nil
+	}
+	return value, nil
+}
+
+// lookupVariable resolves the dotted path inside a ${...} reference
+func lookupVariable(vars map[string]interface{}, path string) (interface{}, error) {
+	value, err := resolveFieldPath(vars, strings.TrimSpace(path))
+	if err != nil {
+		return nil, fmt.Errorf("${%s}: %v", path, err)
+	}
+	return value, nil
+}
+
+// interpolateText fills in the ${...} references of a string field
+func interpolateText(text string, vars map[string]interface{}) (string, error) {
+	resolved, err := interpolate(text, vars)
+	if err != nil {
+		return "", err
+	}
+	return formatJobValue(resolved), nil
+}
+
+// expandAction returns a copy of action with its ${variables} filled in and, inside a foreach,
+// the current device as its device_id when it has none. Nested actions are left as they are;
+// they are expanded when they run.
+func (je *JobEngine) expandAction(action *JobAction, scope *jobScope) (*JobAction, error) {
+	expanded := *action
+	vars := je.variables(scope)
+
+	if expanded.DeviceID == "" && expanded.Type == "send_message" {
+		expanded.DeviceID = scope.device
+	}
+	var err error
+	if expanded.DeviceID, err = interpolateText(expanded.DeviceID, vars); err != nil {
+		return nil, fmt.Errorf("device_id: %v", err)
+	}
+
+	if expanded.Parameters != nil {
+		parameters, err := interpolate(expanded.Parameters, vars)
+		if err != nil {
+			return nil, fmt.Errorf("parameters: %v", err)
+		}
+		expanded.Parameters = parameters.(map[string]interface{})
+	}
+
+	if action.Condition != nil {
+		condition := *action.Condition
+		if condition.DeviceID == "" && condition.Type != ConditionTimeRange {
+			condition.DeviceID = scope.device
+		}
+		if condition.DeviceID, err = interpolateText(condition.DeviceID, vars); err != nil {
+			return nil, fmt.Errorf("condition device_id: %v", err)
+		}
+		if condition.FieldPath, err = interpolateText(condition.FieldPath, vars); err != nil {
+			return nil, fmt.Errorf("condition field_path: %v", err)
+		}
+		if condition.Value, err = interpolate(condition.Value, vars); err != nil {
+			return nil, fmt.Errorf("condition value: %v", err)
+		}
+		expanded.Condition = &condition
+	}
+	return &expanded, nil
+}
+
+// keepResponse makes an action's response available to the actions after it: under its
+// save_as name, as response_<index> for the job's own actions, and for send_message as
+// last_response
+func (je *JobEngine) keepResponse(action *JobAction, scope *jobScope, result *ActionResult) {
+	if result.Response == nil {
+		return
+	}
+
+	je.mutex.Lock()
+	defer je.mutex.Unlock()
+
+	context := scope.execution.Context
+	if action.SaveAs != "" {
+		context[action.SaveAs] = result.Response
+		scope.vars[action.SaveAs] = result.Response
+	}
+	if scope.topLevel {
+		context[fmt.Sprintf("response_%d", result.ActionIndex)] = result.Response
+	}
+	if action.Type == "send_message" {
+		if scope.topLevel {
+			context["last_response"] = result.Response
+		} else {
+			scope.vars["last_response"] = result.Response
+		}
+	}
+}
+
+// runStep runs an action, then its on_success or on_failure actions. Those see the action's
+// outcome as ${result.success}, ${result.error} and ${result.response}. An action recovers when
+// its on_failure actions succeed; an action whose on_success actions fail has failed.
+func (je *JobEngine) runStep(action *JobAction, index int, scope *jobScope) ActionResult {
+	result := je.executeAction(action, index, scope)
+
+	branch := action.OnSuccess
+	if !result.Success {
+		branch = action.OnFailure
+	}
+	if len(branch) == 0 {
+		return result
+	}
+
+	branchScope := scope.child()
+	branchScope.vars["result"] = map[string]interface{}{
+		"success":  result.Success,
+		"error":    result.Error,
+		"response": result.Response,
+	}
+	results, err := je.runActions(branch, branchScope)
+
+	if result.Success {
+		result.OnSuccess = results
+		if err != nil {
+			result.Success = false
+			result.Error = fmt.Sprintf("on_success %v", err)
+		}
+	} else {
+		result.OnFailure = results
+		if err == nil {
+			result.Recovered = true
+		} else {
+			result.Error = fmt.Sprintf("%s; on_failure %v", result.Error, err)
+		}
+	}
+	result.EndTime = time.Now()
+	return result
+}
+
+// runActions runs actions one after another until one fails without recovering; a dry run
+// goes on to the end and returns the first failure
+func (je *JobEngine) runActions(actions []JobAction, scope *jobScope) ([]ActionResult, error) {
+	results := make([]ActionResult, 0, len(actions))
+	var failure error
+	for i := range actions {
+		result := je.runStep(&actions[i], i, scope)
+		results = append(results, result)
+		if !result.Success && !result.Recovered {
+			if failure == nil {
+				failure = fmt.Errorf("action %d: %s", i, result.Error)
+			}
+			if !scope.dryRun {
+				break
+			}
+		}
+	}
+	return results, failure
+}
+
+// executeParallel runs the actions of a parallel group at the same time and fails if any of
+// them fails
+func (je *JobEngine) executeParallel(action *JobAction, scope *jobScope, result *ActionResult) error {
+	result.Results = make([]ActionResult, len(action.Actions))
+
+	var wg sync.WaitGroup
+	for i := range action.Actions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result.Results[i] = je.runStep(&action.Actions[i], i, scope.child())
+		}(i)
+	}
+	wg.Wait()
+
+	var failures []string
+	for i, child := range result.Results {
+		if !child.Success && !child.Recovered {
+			failures = append(failures, fmt.Sprintf("action %d: %s", i, child.Error))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d parallel actions failed: %s", len(failures), len(action.Actions), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// executeForeach runs the foreach actions for every device the selector matches, each device
+// as ${device}, one by one or all at once. A device that fails does not stop the others; the
+// response lists which devices succeeded and why the others failed.
+func (je *JobEngine) executeForeach(action *JobAction, scope *jobScope, result *ActionResult) error {
+	if je.deviceComm == nil {
+		return fmt.Errorf("no device connection to select devices from")
+	}
+	var devices []map[string]interface{}
+	for _, device := range je.deviceComm.Devices() {
+		if action.Devices.matches(device) {
+			devices = append(devices, device)
+		}
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf("no devices match %s", action.Devices)
+	}
+
+	result.Results = make([]ActionResult, len(devices))
+	runDevice := func(i int) {
+		serial, _ := devices[i]["serial"].(string)
+		iteration := scope.child()
+		iteration.device = serial
+		iteration.vars["device"] = devices[i]
+
+		entry := ActionResult{ActionIndex: i, ActionType: "device", DeviceID: serial, StartTime: time.Now()}
+		results, err := je.runActions(action.Actions, iteration)
+		entry.EndTime = time.Now()
+		entry.Results = results
+		entry.Success = err == nil
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		result.Results[i] = entry
+	}
+
+	if action.Parallel {
+		var wg sync.WaitGroup
+		for i := range devices {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				runDevice(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range devices {
+			runDevice(i)
+		}
+	}
+
+	// Kept as decoded JSON would be, so ${...} references can index the lists
+	serials := make([]interface{}, 0, len(devices))
+	succeeded := make([]interface{}, 0, len(devices))
+	failed := make(map[string]interface{})
+	var failures []string
+	for _, entry := range result.Results {
+		serials = append(serials, entry.DeviceID)
+		if entry.Success {
+			succeeded = append(succeeded, entry.DeviceID)
+		} else {
+			failed[entry.DeviceID] = entry.Error
+			failures = append(failures, fmt.Sprintf("%s: %s", entry.DeviceID, entry.Error))
+		}
+	}
+	result.Response = map[string]interface{}{
+		"devices":   serials,
+		"succeeded": succeeded,
+		"failed":    failed,
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d devices failed: %s", len(failures), len(devices), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// matches tells whether a device, as DeviceCommunicator.Devices returns it, is selected
+func (s *DeviceSelector) matches(device map[string]interface{}) bool {
+	category, _ := device["category"].(string)
+	deviceType, _ := device["type"].(string)
+	if s.Category != "" && !strings.EqualFold(s.Category, category) && !strings.EqualFold(s.Category, deviceType) {
+		return false
+	}
+
+	model, _ := device["model_id"].(string)
+	if s.Model != "" && !strings.EqualFold(s.Model, model) {
+		return false
+	}
+
+	if s.Tag != "" {
+		tags, _ := device["tags"].([]interface{})
+		for _, tag := range tags {
+			if tag == s.Tag {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// String describes the selector for messages, e.g. "category=sanitizerGen2 tag=spa"
+func (s *DeviceSelector) String() string {
+	var criteria []string
+	if s.Category != "" {
+		criteria = append(criteria, "category="+s.Category)
+	}
+	if s.Tag != "" {
+		criteria = append(criteria, "tag="+s.Tag)
+	}
+	if s.Model != "" {
+		criteria = append(criteria, "model="+s.Model)
+	}
+	return strings.Join(criteria, " ")
+}
@@ -0,0 +1,641 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Catch-up policies for runs missed while NgaSim was down or the host was asleep
+const (
+	CatchUpSkip = "skip" ///< Missed runs are dropped; the job waits for its next time
+	CatchUpOnce = "once" ///< One run makes up for any number of missed runs
+	CatchUpAll  = "all"  ///< Every missed run is made up, oldest first, up to JobMaxCatchUpRuns
+)
+
+// JobMisfireGrace is how late a scheduled run may start before it counts as missed
+const JobMisfireGrace = time.Minute
+
+// JobMaxCatchUpRuns caps the runs made up at once with catch_up = "all"
+const JobMaxCatchUpRuns = 24
+
+// JobSchedulerMaxSleep bounds how long the scheduler sleeps. Go timers stop while the host is
+// suspended and ignore wall clock changes, so the clock is looked at again at least this often.
+const JobSchedulerMaxSleep = time.Minute
+
+// cronSearchYears is how far ahead the next time of a cron expression is looked for
+const cronSearchYears = 5
+
+// jobSchedule computes the run times of one job
+type jobSchedule interface {
+	// next returns the first run time after t, or the zero time when there is none
+	next(t time.Time) time.Time
+}
+
+// onceSchedule runs a job at start_at only
+type onceSchedule struct {
+	at time.Time
+}
+
+func (s onceSchedule) next(t time.Time) time.Time {
+	if s.at.After(t) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+// intervalSchedule runs a job at first and every interval after it. Intervals are elapsed
+// time, so "2h" stays two hours apart across a daylight saving change.
+type intervalSchedule struct {
+	first time.Time
+	every time.Duration
+}
+
+func (s intervalSchedule) next(t time.Time) time.Time {
+	if t.Before(s.first) {
+		return s.first
+	}
+	return s.first.Add((t.Sub(s.first)/s.every + 1) * s.every)
+}
+
+// cronSchedule runs a job when the wall clock in its location matches a standard 5-field cron
+// expression. Times skipped when clocks go forward run at the moment they jump, as cron does; a
+// time that happens twice when they go back runs once.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // Bit n set when value n matches
+	domAny, dowAny                bool   // Field starts with *, so the day matches on the other field only
+	location                      *time.Location
+	notBefore                     time.Time // start_at, if given
+}
+
+// cronField describes one field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // Names of the values from min on, e.g. JAN for month 1
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+// cronMacros are the @ shorthands cron accepts
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCron parses "minute hour day-of-month month day-of-week". Fields take *, numbers, names
+// (JAN-DEC, SUN-SAT, 7 is also Sunday), ranges, lists and /steps, as in crontab(5). When both
+// day fields are restricted a day matching either runs, as cron does.
+func parseCron(expr string, location *time.Location) (*cronSchedule, error) {
+	if macro, ok := cronMacros[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("want 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+
+	values := make([]uint64, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", cronFields[i].name, err)
+		}
+		values[i] = set
+	}
+
+	// Sunday is both 0 and 7
+	if values[4]&(1<<7) != 0 {
+		values[4] = values[4]&^(1<<7) | 1
+	}
+
+	return &cronSchedule{
+		minute:   values[0],
+		hour:     values[1],
+		dom:      values[2],
+		month:    values[3],
+		dow:      values[4],
+		domAny:   strings.HasPrefix(fields[2], "*"),
+		dowAny:   strings.HasPrefix(fields[4], "*"),
+		location: location,
+	}, nil
+}
+
+// parseCronField returns the values one comma separated field matches, as a bit set
+func parseCronField(text string, field cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(text, ",") {
+		span, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+		}
+
+		low, high := field.min, field.max
+		if span != "*" {
+			first, last, isRange := strings.Cut(span, "-")
+			var err error
+			if low, err = field.value(first); err != nil {
+				return 0, err
+			}
+			switch {
+			case isRange:
+				if high, err = field.value(last); err != nil {
+					return 0, err
+				}
+			case !hasStep:
+				high = low
+			}
+		}
+		if low > high {
+			return 0, fmt.Errorf("range %q runs backwards", span)
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+// value converts a number or name in the field to its value
+func (field cronField) value(text string) (int, error) {
+	for i, name := range field.names {
+		if strings.EqualFold(text, name) {
+			return field.min + i, nil
+		}
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", text)
+	}
+	if value < field.min || value > field.max {
+		return 0, fmt.Errorf("%d is outside %d-%d", value, field.min, field.max)
+	}
+	return value, nil
+}
+
+// dayMatches reports whether the cron expression runs on the given date
+func (c *cronSchedule) dayMatches(date time.Time) bool {
+	if c.month&(1<<date.Month()) == 0 {
+		return false
+	}
+	dom := c.dom&(1<<date.Day()) != 0
+	dow := c.dow&(1<<date.Weekday()) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next walks the days from t in the schedule's location and returns the first matching wall
+// clock time after t. Times are built with time.Date, so a repeated wall time resolves to one
+// instant; a skipped one is moved to the daylight saving change.
+func (c *cronSchedule) next(t time.Time) time.Time {
+	if t.Before(c.notBefore) {
+		t = c.notBefore.Add(-time.Nanosecond)
+	}
+	local := t.In(c.location)
+
+	for day := 0; day < cronSearchYears*366; day++ {
+		// Noon is never skipped by a daylight saving change, so the date steps cleanly
+		date := time.Date(local.Year(), local.Month(), local.Day()+day, 12, 0, 0, 0, c.location)
+		if !c.dayMatches(date) {
+			continue
+		}
+		for hours := c.hour; hours != 0; hours &= hours - 1 {
+			hour := bits.TrailingZeros64(hours)
+			for minutes := c.minute; minutes != 0; minutes &= minutes - 1 {
+				minute := bits.TrailingZeros64(minutes)
+				candidate := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, c.location)
+				if wall := candidate.Hour()*60 + candidate.Minute(); wall != hour*60+minute {
+					// The clocks skipped this time; run when they jump
+					start, end := candidate.ZoneBounds()
+					if candidate = start; wall < hour*60+minute {
+						candidate = end
+					}
+				}
+				if candidate.After(t) {
+					return candidate
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// startAtLayouts are the start_at formats besides RFC 3339; they are read in the job's timezone
+var startAtLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// parseStartAt reads an RFC 3339 timestamp, or a local date and time in location
+func parseStartAt(text string, location *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, nil
+	}
+	for _, layout := range startAtLayouts {
+		if t, err := time.ParseInLocation(layout, text, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid start_at %q, want e.g. 2025-06-01T08:00:00-05:00 or 2025-06-01 08:00", text)
+}
+
+// scheduleLocation returns the timezone a schedule is evaluated in
+func scheduleLocation(s *Schedule, fallback *time.Location) (*time.Location, error) {
+	if s.Timezone == "" {
+		return fallback, nil
+	}
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", s.Timezone, err)
+	}
+	return location, nil
+}
+
+// compileSchedule turns a job's schedule into run times. An interval without start_at counts
+// from lastRun, the last time the schedule ran the job, or from now if it never did.
+func compileSchedule(s *Schedule, fallback *time.Location, now, lastRun time.Time) (jobSchedule, *time.Location, error) {
+	location, err := scheduleLocation(s, fallback)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch s.CatchUp {
+	case "", CatchUpSkip, CatchUpOnce, CatchUpAll:
+	default:
+		return nil, nil, fmt.Errorf("unknown catch_up %q (skip, once or all)", s.CatchUp)
+	}
+
+	var startAt time.Time
+	if s.StartAt != "" {
+		if startAt, err = parseStartAt(s.StartAt, location); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch s.Type {
+	case "once":
+		if startAt.IsZero() {
+			return nil, nil, fmt.Errorf("start_at is required for a once schedule")
+		}
+		return onceSchedule{at: startAt}, location, nil
+
+	case "interval":
+		every, err := time.ParseDuration(s.Interval)
+		if err != nil || every <= 0 {
+			return nil, nil, fmt.Errorf("interval must be a positive duration such as 30m, got %q", s.Interval)
+		}
+		first := startAt
+		if first.IsZero() {
+			first = now.Add(every)
+			if !lastRun.IsZero() {
+				first = lastRun.Add(every)
+			}
+		}
+		return intervalSchedule{first: first, every: every}, location, nil
+
+	case "cron":
+		cron, err := parseCron(s.Cron, location)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid cron %q: %v", s.Cron, err)
+		}
+		cron.notBefore = startAt
+		if cron.next(now).IsZero() {
+			return nil, nil, fmt.Errorf("cron %q matches no date in the next %d years", s.Cron, cronSearchYears)
+		}
+		return cron, location, nil
+
+	case "":
+		return nil, nil, fmt.Errorf("schedule type is required (once, interval or cron)")
+	default:
+		return nil, nil, fmt.Errorf("unknown schedule type %q (once, interval or cron)", s.Type)
+	}
+}
+
+// scheduledJob is an enabled job waiting for its next run
+type scheduledJob struct {
+	job      *Job
+	schedule jobSchedule
+	location *time.Location
+	next     time.Time // Zero when the schedule has no more runs
+}
+
+// JobScheduler runs enabled jobs at the times their schedules give. One goroutine sleeps until
+// the earliest next run; ScheduleJob and UnscheduleJob wake it to look again.
+type JobScheduler struct {
+	engine    *JobEngine
+	mutex     sync.Mutex
+	entries   map[string]*scheduledJob
+	running   map[string]bool      // Jobs whose scheduled run has not finished
+	lastRuns  map[string]time.Time // Latest run time handled per job, saved to statePath
+	restore   map[string]bool      // Jobs whose last run was read from statePath and not yet scheduled
+	location  *time.Location
+	catchUp   string
+	statePath string
+	dirty     bool // lastRuns changed since it was saved
+	wake      chan struct{}
+}
+
+// NewJobScheduler creates a new job scheduler
+func NewJobScheduler(engine *JobEngine) *JobScheduler {
+	return &JobScheduler{
+		engine:   engine,
+		entries:  make(map[string]*scheduledJob),
+		running:  make(map[string]bool),
+		lastRuns: make(map[string]time.Time),
+		restore:  make(map[string]bool),
+		location: time.Local,
+		catchUp:  CatchUpSkip,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Configure sets the default timezone and catch-up policy and the file last run times are kept
+// in. When the timezone changes, scheduled jobs are planned again in it. A new state file is
+// read, so runs missed before a restart are caught up.
+func (js *JobScheduler) Configure(location *time.Location, catchUp, statePath string) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	moved := location.String() != js.location.String()
+	js.location = location
+	js.catchUp = catchUp
+	if statePath != js.statePath {
+		js.statePath = statePath
+		js.loadState()
+	}
+
+	if moved {
+		now := time.Now()
+		for _, entry := range js.entries {
+			js.plan(entry, now)
+		}
+		js.wakeUp()
+	}
+}
+
+// loadState reads the last run times kept in statePath. Caller must hold the mutex.
+func (js *JobScheduler) loadState() {
+	if js.statePath == "" {
+		return
+	}
+	data, err := os.ReadFile(js.statePath)
+	if os.IsNotExist(err) {
+		return
+	}
+	lastRuns := make(map[string]time.Time)
+	if err == nil {
+		err = json.Unmarshal(data, &lastRuns)
+	}
+	if err != nil {
+		log.Printf("⚠️ Job schedule state %s not read, missed runs will not be caught up: %v", js.statePath, err)
+		return
+	}
+
+	for id, last := range lastRuns {
+		js.lastRuns[id] = last
+		js.restore[id] = true
+	}
+	log.Printf("⏰ Last runs of %d scheduled jobs read from %s", len(lastRuns), js.statePath)
+}
+
+// saveState writes the last run times to statePath, through a temporary file so a crash never
+// leaves it half written
+func (js *JobScheduler) saveState() {
+	js.mutex.Lock()
+	if !js.dirty || js.statePath == "" {
+		js.mutex.Unlock()
+		return
+	}
+	path := js.statePath
+	data, err := json.MarshalIndent(js.lastRuns, "", "  ")
+	js.dirty = false
+	js.mutex.Unlock()
+
+	if err == nil {
+		temp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+		if err = os.WriteFile(temp, data, 0644); err == nil {
+			err = os.Rename(temp, path)
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️ Job schedule state not saved to %s: %v", path, err)
+	}
+}
+
+// ScheduleJob schedules a job based on its schedule configuration
+func (js *JobScheduler) ScheduleJob(job *Job) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	entry := &scheduledJob{job: job}
+	js.entries[job.ID] = entry
+	js.plan(entry, time.Now())
+	if !entry.next.IsZero() {
+		log.Printf("⏰ Job %s next runs at %s", job.ID, entry.next.Format(time.RFC3339))
+	}
+	js.wakeUp()
+}
+
+// UnscheduleJob stops future runs of a job
+func (js *JobScheduler) UnscheduleJob(jobID string) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	delete(js.entries, jobID)
+	js.wakeUp()
+}
+
+// NextRun returns when the schedule runs a job next, in the job's timezone, or the zero time
+// when the job is not scheduled or its schedule is over
+func (js *JobScheduler) NextRun(jobID string) time.Time {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	entry, exists := js.entries[jobID]
+	if !exists || entry.next.IsZero() {
+		return time.Time{}
+	}
+	return entry.next.In(entry.location)
+}
+
+// Location returns the default timezone, jobs.timezone
+func (js *JobScheduler) Location() *time.Location {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+	return js.location
+}
+
+// plan computes an entry's next run. A job whose last run was just read from the state file
+// continues from that run, so the times missed while NgaSim was down are due at once; any other
+// job starts from now. A once job that never ran and whose start_at has passed is due at once
+// too, so the catch-up policy decides whether it still runs. Caller must hold the mutex.
+func (js *JobScheduler) plan(entry *scheduledJob, now time.Time) {
+	id := entry.job.ID
+	lastRun := js.lastRuns[id]
+	schedule, location, err := compileSchedule(entry.job.Schedule, js.location, now, lastRun)
+	if err != nil {
+		log.Printf("⚠️ Job %s not scheduled: %v", id, err)
+		entry.schedule, entry.next = nil, time.Time{}
+		return
+	}
+
+	from := now
+	if js.restore[id] && lastRun.Before(now) {
+		from = lastRun
+	}
+	delete(js.restore, id)
+
+	entry.schedule, entry.location = schedule, location
+	entry.next = schedule.next(from)
+	if once, ok := schedule.(onceSchedule); ok && lastRun.IsZero() && !once.at.After(from) {
+		entry.next = once.at
+	}
+}
+
+// wakeUp makes the scheduler goroutine look at the entries again. Caller must hold the mutex.
+func (js *JobScheduler) wakeUp() {
+	select {
+	case js.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run starts due jobs until stop is closed
+func (js *JobScheduler) run(stop <-chan struct{}) {
+	log.Println("⏰ Job scheduler started")
+	for {
+		now := time.Now()
+		js.mutex.Lock()
+		sleep := js.startDue(now)
+		js.mutex.Unlock()
+		js.saveState()
+
+		timer := time.NewTimer(sleep)
+		select {
+		case <-stop:
+			timer.Stop()
+			log.Println("⏰ Job scheduler stopped")
+			return
+		case <-js.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// startDue starts every job whose next run has come, applying the catch-up policy to runs that
+// are more than JobMisfireGrace late, and returns how long to sleep until the next one. Caller
+// must hold the mutex.
+func (js *JobScheduler) startDue(now time.Time) time.Duration {
+	sleep := JobSchedulerMaxSleep
+	for id, entry := range js.entries {
+		if entry.next.IsZero() {
+			continue
+		}
+		if wait := entry.next.Sub(now); wait > 0 {
+			sleep = shorterWait(sleep, wait)
+			continue
+		}
+
+		// Collect the due times; when far behind, stop counting missed runs one by one
+		var missed, onTime []time.Time
+		handled := now
+		next := entry.next
+		for !next.IsZero() && !next.After(now) {
+			if now.Sub(next) > JobMisfireGrace {
+				missed = append(missed, next)
+			} else {
+				onTime = append(onTime, next)
+			}
+			if len(missed) > JobMaxCatchUpRuns {
+				handled, next = now, entry.schedule.next(now)
+				break
+			}
+			handled = next
+			next = entry.schedule.next(next)
+		}
+		entry.next = next
+		js.lastRuns[id] = handled
+		js.dirty = true
+
+		catchUp := entry.job.Schedule.CatchUp
+		if catchUp == "" {
+			catchUp = js.catchUp
+		}
+		var runs []time.Time
+		switch {
+		case catchUp == CatchUpAll:
+			runs = append(runs, missed[:min(len(missed), JobMaxCatchUpRuns)]...)
+		case catchUp == CatchUpOnce && len(missed) > 0 && len(onTime) == 0:
+			runs = append(runs, missed[len(missed)-1])
+		}
+		if len(onTime) > 0 {
+			runs = append(runs, onTime[len(onTime)-1])
+		}
+		if len(missed) > 0 {
+			log.Printf("⏰ Job %s missed %d run(s) since %s (catch_up %s)", id, len(missed), missed[0].Format(time.RFC3339), catchUp)
+		}
+
+		if len(runs) > 0 {
+			if js.running[id] {
+				log.Printf("⏭️ Job %s is still running, skipping its run at %s", id, runs[0].Format(time.RFC3339))
+			} else {
+				js.running[id] = true
+				go js.runJob(entry.job, runs)
+			}
+		}
+		if !next.IsZero() {
+			sleep = shorterWait(sleep, next.Sub(now))
+		}
+	}
+	return sleep
+}
+
+// shorterWait returns the shorter of two waits
+func shorterWait(a, b time.Duration) time.Duration {
+	if b < a {
+		return b
+	}
+	return a
+}
+
+// runJob carries out a job's scheduled runs one after another
+func (js *JobScheduler) runJob(job *Job, runs []time.Time) {
+	defer func() {
+		js.mutex.Lock()
+		delete(js.running, job.ID)
+		js.mutex.Unlock()
+	}()
+
+	for _, at := range runs {
+		// The job may have been disabled or deleted while earlier runs went on
+		current, err := js.engine.runnableJob(job.ID)
+		if err != nil {
+			return
+		}
+		execution := js.engine.startExecution(current, JobTriggerSchedule)
+		log.Printf("⏰ Job %s started (%s), scheduled for %s", job.ID, execution.ID, at.Format(time.RFC3339))
+		js.engine.runExecution(current, execution, false)
+	}
+}
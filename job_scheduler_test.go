@@ -0,0 +1,253 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// startScheduled plans a job as the scheduler does when NgaSim starts at now, after lastRun
+// was read from the state file if it is set, starts the runs due at now and returns how many
+// executions they made along with the job's scheduler entry
+func startScheduled(t *testing.T, schedule *Schedule, catchUp string, lastRun, now time.Time) (int, *scheduledJob) {
+	t.Helper()
+	engine := NewJobEngine(nil, nil, nil, nil)
+	job := &Job{
+		ID:       "scheduled",
+		Name:     "scheduled",
+		Enabled:  true,
+		Schedule: schedule,
+		Actions:  []JobAction{{Type: "wait", WaitDuration: "1ms"}},
+	}
+	engine.jobs[job.ID] = job
+
+	js := engine.scheduler
+	entry := &scheduledJob{job: job}
+	js.mutex.Lock()
+	js.location = time.UTC
+	js.catchUp = catchUp
+	if !lastRun.IsZero() {
+		js.lastRuns[job.ID] = lastRun
+		js.restore[job.ID] = true
+	}
+	js.entries[job.ID] = entry
+	js.plan(entry, now)
+	js.startDue(now)
+	js.mutex.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		js.mutex.Lock()
+		running := js.running[job.ID]
+		js.mutex.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("scheduled runs still going after 5s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return len(engine.GetExecutions(job.ID, 0)), entry
+}
+
+func mustTime(t *testing.T, text string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestOnceScheduleCatchUp(t *testing.T) {
+	now := mustTime(t, "2025-06-01T05:30:00Z")
+	tests := []struct {
+		name     string
+		startAt  string
+		catchUp  string
+		lastRun  string
+		wantRuns int
+		wantNext string
+	}{
+		{name: "missed while down, skip", startAt: "2025-06-01T03:00:00Z", catchUp: CatchUpSkip, wantRuns: 0},
+		{name: "missed while down, once", startAt: "2025-06-01T03:00:00Z", catchUp: CatchUpOnce, wantRuns: 1},
+		{name: "missed while down, all", startAt: "2025-06-01T03:00:00Z", catchUp: CatchUpAll, wantRuns: 1},
+		{name: "just due", startAt: "2025-06-01T05:29:30Z", catchUp: CatchUpSkip, wantRuns: 1},
+		{name: "already ran", startAt: "2025-06-01T03:00:00Z", catchUp: CatchUpAll, lastRun: "2025-06-01T03:00:00Z", wantRuns: 0},
+		{name: "still ahead", startAt: "2025-06-01T07:00:00Z", catchUp: CatchUpAll, wantRuns: 0, wantNext: "2025-06-01T07:00:00Z"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var lastRun time.Time
+			if test.lastRun != "" {
+				lastRun = mustTime(t, test.lastRun)
+			}
+			runs, entry := startScheduled(t, &Schedule{Type: "once", StartAt: test.startAt}, test.catchUp, lastRun, now)
+			if runs != test.wantRuns {
+				t.Errorf("runs = %d, want %d", runs, test.wantRuns)
+			}
+			var wantNext time.Time
+			if test.wantNext != "" {
+				wantNext = mustTime(t, test.wantNext)
+			}
+			if !entry.next.Equal(wantNext) {
+				t.Errorf("next = %v, want %v", entry.next, wantNext)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name string
+		cron string
+		zone string
+		from string
+		want []string // Successive runs after from
+	}{
+		{
+			name: "spring forward runs when the clocks jump",
+			cron: "30 2 * * *", zone: "America/New_York", from: "2025-03-08T12:00:00-05:00",
+			want: []string{"2025-03-09T03:00:00-04:00", "2025-03-10T02:30:00-04:00"},
+		},
+		{
+			name: "fall back runs the repeated time once",
+			cron: "30 1 * * *", zone: "America/New_York", from: "2025-11-01T12:00:00-04:00",
+			want: []string{"2025-11-02T01:30:00-04:00", "2025-11-03T01:30:00-05:00"},
+		},
+		{
+			name: "wall clock kept across a daylight saving change",
+			cron: "0 8 * * *", zone: "Europe/Berlin", from: "2025-03-29T12:00:00+01:00",
+			want: []string{"2025-03-30T08:00:00+02:00", "2025-03-31T08:00:00+02:00"},
+		},
+		{
+			name: "day of month or day of week",
+			cron: "0 9 10 * FRI", zone: "UTC", from: "2025-06-01T00:00:00Z",
+			want: []string{"2025-06-06T09:00:00Z", "2025-06-10T09:00:00Z", "2025-06-13T09:00:00Z"},
+		},
+		{
+			name: "day of month only when day of week is *",
+			cron: "0 9 10 * *", zone: "UTC", from: "2025-06-01T00:00:00Z",
+			want: []string{"2025-06-10T09:00:00Z", "2025-07-10T09:00:00Z"},
+		},
+		{
+			name: "day of week only when day of month is *",
+			cron: "0 9 * * 1-5", zone: "UTC", from: "2025-06-06T10:00:00Z",
+			want: []string{"2025-06-09T09:00:00Z", "2025-06-10T09:00:00Z"},
+		},
+		{
+			name: "sunday as 7",
+			cron: "0 0 * * 7", zone: "UTC", from: "2025-06-02T00:00:00Z",
+			want: []string{"2025-06-08T00:00:00Z", "2025-06-15T00:00:00Z"},
+		},
+		{
+			name: "step over every value",
+			cron: "*/20 * * * *", zone: "UTC", from: "2025-06-01T10:05:00Z",
+			want: []string{"2025-06-01T10:20:00Z", "2025-06-01T10:40:00Z", "2025-06-01T11:00:00Z"},
+		},
+		{
+			name: "step over a range",
+			cron: "0 8-18/5 * * *", zone: "UTC", from: "2025-06-01T00:00:00Z",
+			want: []string{"2025-06-01T08:00:00Z", "2025-06-01T13:00:00Z", "2025-06-01T18:00:00Z", "2025-06-02T08:00:00Z"},
+		},
+		{
+			name: "macro",
+			cron: "@yearly", zone: "UTC", from: "2025-06-01T00:00:00Z",
+			want: []string{"2026-01-01T00:00:00Z"},
+		},
+		{
+			name: "never matches",
+			cron: "0 0 30 2 *", zone: "UTC", from: "2025-06-01T00:00:00Z",
+			want: []string{""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			location, err := time.LoadLocation(test.zone)
+			if err != nil {
+				t.Skipf("timezone %s not available: %v", test.zone, err)
+			}
+			cron, err := parseCron(test.cron, location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := mustTime(t, test.from)
+			for i, want := range test.want {
+				at = cron.next(at)
+				if want == "" {
+					if !at.IsZero() {
+						t.Fatalf("run %d = %v, want none", i, at)
+					}
+					continue
+				}
+				if wantTime := mustTime(t, want); !at.Equal(wantTime) {
+					t.Fatalf("run %d = %v, want %v", i, at.In(location), wantTime.In(location))
+				}
+			}
+		})
+	}
+}
+
+func TestCronErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+	}{
+		{"too few fields", Schedule{Type: "cron", Cron: "0 8 * *"}},
+		{"value out of range", Schedule{Type: "cron", Cron: "61 * * * *"}},
+		{"range backwards", Schedule{Type: "cron", Cron: "0 18-8 * * *"}},
+		{"zero step", Schedule{Type: "cron", Cron: "*/0 * * * *"}},
+		{"never matches", Schedule{Type: "cron", Cron: "0 0 30 2 *"}},
+		{"unknown catch_up", Schedule{Type: "cron", Cron: "0 8 * * *", CatchUp: "later"}},
+	}
+	now := mustTime(t, "2025-06-01T00:00:00Z")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := compileSchedule(&test.schedule, time.UTC, now, time.Time{}); err == nil {
+				t.Errorf("cron %q compiled, want an error", test.schedule.Cron)
+			}
+		})
+	}
+}
+
+func TestCatchUpPolicies(t *testing.T) {
+	hourly := &Schedule{Type: "interval", Interval: "1h", StartAt: "2025-06-01T00:00:00Z"}
+	cron := &Schedule{Type: "cron", Cron: "0 * * * *"}
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		catchUp  string
+		lastRun  string
+		now      string
+		wantRuns int
+		wantNext string
+	}{
+		// 03:00, 04:00 and 05:00 were missed while NgaSim was down
+		{"missed, skip", hourly, CatchUpSkip, "2025-06-01T02:00:00Z", "2025-06-01T05:30:00Z", 0, "2025-06-01T06:00:00Z"},
+		{"missed, once", hourly, CatchUpOnce, "2025-06-01T02:00:00Z", "2025-06-01T05:30:00Z", 1, "2025-06-01T06:00:00Z"},
+		{"missed, all", hourly, CatchUpAll, "2025-06-01T02:00:00Z", "2025-06-01T05:30:00Z", 3, "2025-06-01T06:00:00Z"},
+		{"missed, cron all", cron, CatchUpAll, "2025-06-01T02:00:00Z", "2025-06-01T05:30:00Z", 3, "2025-06-01T06:00:00Z"},
+
+		// 03:00 and 04:00 were missed, 05:00 is on time
+		{"on time, skip", hourly, CatchUpSkip, "2025-06-01T02:00:00Z", "2025-06-01T05:00:30Z", 1, "2025-06-01T06:00:00Z"},
+		{"on time, once", hourly, CatchUpOnce, "2025-06-01T02:00:00Z", "2025-06-01T05:00:30Z", 1, "2025-06-01T06:00:00Z"},
+		{"on time, all", hourly, CatchUpAll, "2025-06-01T02:00:00Z", "2025-06-01T05:00:30Z", 3, "2025-06-01T06:00:00Z"},
+
+		// Two days down: all makes up no more than JobMaxCatchUpRuns
+		{"far behind, all", hourly, CatchUpAll, "2025-06-01T02:00:00Z", "2025-06-03T02:30:00Z", JobMaxCatchUpRuns, "2025-06-03T03:00:00Z"},
+
+		// Nothing missed
+		{"not due", hourly, CatchUpAll, "2025-06-01T05:00:00Z", "2025-06-01T05:30:00Z", 0, "2025-06-01T06:00:00Z"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runs, entry := startScheduled(t, test.schedule, test.catchUp, mustTime(t, test.lastRun), mustTime(t, test.now))
+			if runs != test.wantRuns {
+				t.Errorf("runs = %d, want %d", runs, test.wantRuns)
+			}
+			if want := mustTime(t, test.wantNext); !entry.next.Equal(want) {
+				t.Errorf("next = %v, want %v", entry.next, want)
+			}
+		})
+	}
+}
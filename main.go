@@ -11,6 +11,7 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -24,6 +25,7 @@ import (
 	"time"
 
 	"NgaSim/ned" // Import protobuf definitions
+	"NgaSim/ned/sanitizer"
 
 	"github.com/google/uuid"
 
@@ -117,36 +119,82 @@ type NgaSim struct {
 	devices             map[string]*Device
 	mutex               sync.RWMutex
 	mqtt                mqtt.Client
-	server              *http.Server
 	pollerCmd           *exec.Cmd
 	logger              *DeviceLogger
 	commandRegistry     *ProtobufCommandRegistry
 	sanitizerController *SanitizerController
 
 	// New fields for dynamic protobuf system
-	reflectionEngine *ProtobufReflectionEngine // Dynamic protobuf discovery
-	terminalLogger   *TerminalLogger           // Terminal display with file tee
-	popupGenerator   *PopupUIGenerator         // Dynamic popup UI generator
+	reflectionEngine  *ProtobufReflectionEngine // Dynamic protobuf discovery
+	terminalLogger    *TerminalLogger           // Terminal display with file tee
+	popupGenerator    *PopupUIGenerator         // Dynamic popup UI generator
+	commandTracker    *CommandTracker           // Command/response correlation by UUID
+	telemetryDecoders *TelemetryDecoderRegistry // Per-category telemetry decoding
 
 	// Add this missing field:
 	deviceCommands map[string][]string // Device command mappings
+
+	// Runtime configuration; replaced as a whole on SIGHUP
+	config      *LoadedConfig
+	configMutex sync.RWMutex
+	reloadHooks []func(cfg *LoadedConfig)
+
+	commandQueue *CommandQueue // Commands held while MQTT is disconnected
+	demoMode     bool          // The broker was unreachable at startup; commands are simulated
+
+	mqttInfo mqttConnectionInfo // Broker connection, auth and TLS state for diagnostics
+
+	// Multi-site operation: each site is its own NgaSim inside one Fleet
+	site  string // Site name, DefaultSiteName when only one site is configured
+	fleet *Fleet // Fleet this site belongs to, nil outside a fleet
+
+	simulator *Simulator // Virtual devices, nil unless simulator.devices is set
+
+	jobEngine *JobEngine // Automation jobs from jobs.files and the jobs API
+
+	recorder    *SessionRecorder // Raw MQTT session recording
+	replay      *SessionReplay   // Recorded session being replayed, nil if none was started
+	replayMutex sync.Mutex
 }
 
-// MQTT connection parameters
+// MQTT Topics for device discovery, below the configured async prefix (default "async")
 const (
-	MQTTBroker   = "tcp://169.254.1.1:1883" ///< MQTT broker address
-	MQTTClientID = "NgaSim-WebUI"           ///< MQTT client identifier
+	TopicAnnounce     = "+/+/anc"          ///< Device announcement topic pattern
+	TopicInfo         = "+/+/info"         ///< Device information topic pattern
+	TopicTelemetry    = "+/+/dt"           ///< Device telemetry topic pattern
+	TopicError        = "+/+/error"        ///< Device error topic pattern
+	TopicStatus       = "+/+/sts"          ///< Device status topic pattern
+	TopicDisconnected = "+/+/disconnected" ///< Device last-will topic pattern
 )
 
-// MQTT Topics for device discovery
+// MQTT Topics for commands, below the configured command prefix (default "cmd")
 const (
-	TopicAnnounce  = "async/+/+/anc"   ///< Device announcement topic pattern
-	TopicInfo      = "async/+/+/info"  ///< Device information topic pattern
-	TopicTelemetry = "async/+/+/dt"    ///< Device telemetry topic pattern
-	TopicError     = "async/+/+/error" ///< Device error topic pattern
-	TopicStatus    = "async/+/+/sts"   ///< Device status topic pattern
+	TopicCommandFormat         = "%s/%s/req" ///< Command request topic (category, serial)
+	TopicCommandResponse       = "+/+/res"   ///< Command response topic pattern
+	TopicCommandResponseFormat = "%s/%s/res" ///< Command response topic of one device (category, serial)
 )
 
+// AsyncTopic places a device topic pattern below the async prefix
+func (c MQTTConfig) AsyncTopic(pattern string) string {
+	return c.AsyncPrefix + "/" + pattern
+}
+
+// CommandTopic places a command topic below the command prefix
+func (c MQTTConfig) CommandTopic(pattern string) string {
+	return c.CommandPrefix + "/" + pattern
+}
+
+// splitDeviceTopic extracts category, serial and message type from the last three levels of
+// a <prefix>/<category>/<serial>/<type> topic. The prefix may span several levels.
+func splitDeviceTopic(topic string) (category, deviceSerial, messageType string, ok bool) {
+	parts := strings.Split(topic, "/")
+	if len(parts) < 4 {
+		return "", "", "", false
+	}
+	n := len(parts)
+	return parts[n-3], parts[n-2], parts[n-1], true
+}
+
 // connectMQTT establishes connection to the MQTT broker and configures message handling.
 // This function demonstrates several important Go networking and concurrency patterns that
 // are essential for IoT device communication in production environments.
@@ -175,18 +223,28 @@ const (
 //
 // Returns nil on success, error on failure. Caller must check the error!
 func (sim *NgaSim) connectMQTT() error {
-	log.Printf("🔌 Connecting to MQTT broker: %s", MQTTBroker)
+	mqttConfig := sim.currentConfig().MQTT
+	log.Printf("🔌 Connecting to MQTT broker: %s", mqttConfig.Broker)
 
 	// Create MQTT client options - this is the configuration object
 	// Note: mqtt.NewClientOptions() returns a pointer to ClientOptions struct
 	opts := mqtt.NewClientOptions()
 
 	// Configure connection parameters
-	opts.AddBroker(MQTTBroker)          // Where to connect (tcp://169.254.1.1:1883)
-	opts.SetClientID(MQTTClientID)      // Unique identifier for this client
-	opts.SetCleanSession(true)          // Don't remember state from previous connections
-	opts.SetAutoReconnect(true)         // Automatically reconnect if connection breaks
-	opts.SetKeepAlive(30 * time.Second) // Send ping every 30 seconds to detect dead connections
+	opts.AddBroker(mqttConfig.Broker)     // Where to connect (default tcp://169.254.1.1:1883)
+	opts.SetClientID(mqttConfig.ClientID) // Unique identifier for this client
+	opts.SetCleanSession(true)            // Don't remember state from previous connections
+	opts.SetAutoReconnect(true)           // Automatically reconnect if connection breaks
+	opts.SetKeepAlive(30 * time.Second)   // Send ping every 30 seconds to detect dead connections
+
+	// TLS (ssl://, tls://, mqtts:// brokers) and username/password authentication
+	if err := sim.configureMQTTSecurity(opts, mqttConfig); err != nil {
+		return fmt.Errorf("invalid MQTT security settings: %v", err)
+	}
+	sim.mqttInfo.update(func(d *MQTTDiagnostics) {
+		d.Broker = mqttConfig.Broker
+		d.ClientID = mqttConfig.ClientID
+	})
 
 	// Set up event handlers using function pointers
 	// These functions will be called automatically by the MQTT library when events occur
@@ -194,6 +252,10 @@ func (sim *NgaSim) connectMQTT() error {
 	// Called when connection is lost (network issues, broker restart, etc.)
 	opts.SetConnectionLostHandler(func(client mqtt.Client, err error) {
 		log.Printf("🔥 MQTT connection lost: %v", err)
+		sim.mqttInfo.update(func(d *MQTTDiagnostics) {
+			d.LastError = err.Error()
+			d.LastErrorAt = time.Now()
+		})
 		log.Println("   Auto-reconnect will attempt to restore connection...")
 		// Note: No need to manually reconnect due to SetAutoReconnect(true)
 	})
@@ -201,11 +263,15 @@ func (sim *NgaSim) connectMQTT() error {
 	// Called when connection is successfully established
 	// This is where we subscribe to device topics since we need an active connection first
 	opts.SetOnConnectHandler(func(client mqtt.Client) {
-		log.Printf("✅ Connected to MQTT broker: %s", MQTTBroker)
+		log.Printf("✅ Connected to MQTT broker: %s", mqttConfig.Broker)
+		sim.mqttInfo.update(func(d *MQTTDiagnostics) { d.LastConnected = time.Now() })
 		log.Println("🔔 Subscribing to device announcement and telemetry topics...")
 
 		// Subscribe to device topics now that we're connected
 		sim.subscribeToTopics()
+
+		// Send whatever was issued while we were disconnected; Publish must not block this handler
+		go sim.replayCommandQueue()
 	})
 
 	// Create the actual MQTT client using our configuration
@@ -219,7 +285,13 @@ func (sim *NgaSim) connectMQTT() error {
 	// Wait for connection attempt to complete and check for errors
 	// This blocks until connection succeeds or fails
 	if token.Wait() && token.Error() != nil {
-		return fmt.Errorf("failed to connect to MQTT broker %s: %v", MQTTBroker, token.Error())
+		sim.mqttInfo.update(func(d *MQTTDiagnostics) {
+			d.LastError = token.Error().Error()
+			d.LastErrorAt = time.Now()
+		})
+		// Without a client NgaSim runs in demo mode rather than queueing for a broker it never reached
+		sim.mqtt = nil
+		return fmt.Errorf("failed to connect to MQTT broker %s: %v", mqttConfig.Broker, token.Error())
 	}
 
 	log.Printf("🎉 MQTT client initialized successfully")
@@ -233,7 +305,12 @@ func (sim *NgaSim) connectMQTT() error {
 func (sim *NgaSim) startPoller() error {
 	log.Println("Starting C poller subprocess...")
 
-	sim.pollerCmd = exec.Command("sudo", "./poller")
+	pollerConfig := sim.currentConfig().Poller
+	if pollerConfig.Sudo {
+		sim.pollerCmd = exec.Command("sudo", pollerConfig.Path)
+	} else {
+		sim.pollerCmd = exec.Command(pollerConfig.Path)
+	}
 
 	// Start the poller in the background
 	if err := sim.pollerCmd.Start(); err != nil {
@@ -313,12 +390,27 @@ func (sim *NgaSim) stopPoller() {
 func (sim *NgaSim) cleanup() {
 	log.Println("Performing cleanup...")
 
+	// Start no more scheduled jobs
+	sim.jobEngine.Stop()
+
 	// Stop poller first
 	sim.stopPoller()
 
 	// Kill any orphaned poller processes
 	sim.killOrphanedPollers()
 
+	// Drop the virtual devices before the site's own connection
+	if sim.simulator != nil {
+		log.Println("Stopping virtual devices...")
+		sim.simulator.Stop()
+	}
+
+	// Close the session file while its last frames are still being written
+	sim.stopRecording()
+	if replay := sim.currentReplay(); replay != nil {
+		replay.Stop()
+	}
+
 	// Disconnect MQTT
 	if sim.mqtt != nil && sim.mqtt.IsConnected() {
 		log.Println("Disconnecting from MQTT...")
@@ -385,7 +477,16 @@ func (sim *NgaSim) killOrphanedPollers() {
 
 // subscribeToTopics subscribes to device announcement and telemetry topics
 func (sim *NgaSim) subscribeToTopics() {
-	topics := []string{TopicAnnounce, TopicTelemetry, TopicStatus, TopicError}
+	mqttConfig := sim.currentConfig().MQTT
+	topics := []string{
+		mqttConfig.AsyncTopic(TopicAnnounce),
+		mqttConfig.AsyncTopic(TopicInfo),
+		mqttConfig.AsyncTopic(TopicTelemetry),
+		mqttConfig.AsyncTopic(TopicStatus),
+		mqttConfig.AsyncTopic(TopicError),
+		mqttConfig.AsyncTopic(TopicDisconnected),
+		mqttConfig.CommandTopic(TopicCommandResponse),
+	}
 
 	for _, topic := range topics {
 		if token := sim.mqtt.Subscribe(topic, 1, sim.messageHandler); token.Wait() && token.Error() != nil {
@@ -399,66 +500,25 @@ func (sim *NgaSim) subscribeToTopics() {
 // handleDeviceTelemetry processes device telemetry messages
 func (n *NgaSim) handleDeviceTelemetry(topic string, payload []byte) {
 	// Parse topic to extract device info
-	parts := strings.Split(topic, "/")
-	if len(parts) < 4 {
+	category, deviceSerial, _, ok := splitDeviceTopic(topic)
+	if !ok {
 		log.Printf("Invalid topic format: %s", topic)
 		return
 	}
 
-	category := parts[1]
-	deviceSerial := parts[2]
-
 	log.Printf("Device telemetry from %s (category: %s): %d bytes", deviceSerial, category, len(payload))
 
-	// Try to parse as sanitizer telemetry first
-	if category == "sanitizerGen2" {
-		telemetry := &ned.TelemetryMessage{}
-		if err := proto.Unmarshal(payload, telemetry); err == nil {
-			log.Printf("======== Sanitizer Telemetry ========")
-			log.Printf("serial: %s", deviceSerial)
-			log.Printf("rssi: %d dBm", telemetry.GetRssi())
-			log.Printf("ppm_salt: %d ppm", telemetry.GetPpmSalt())
-			log.Printf("percentage_output: %d%%", telemetry.GetPercentageOutput())
-			log.Printf("accelerometer: x=%d, y=%d, z=%d", telemetry.GetAccelerometerX(), telemetry.GetAccelerometerY(), telemetry.GetAccelerometerZ())
-			log.Printf("line_input_voltage: %d V", telemetry.GetLineInputVoltage())
-			log.Printf("is_cell_flow_reversed: %t", telemetry.GetIsCellFlowReversed())
-			log.Printf("========================================")
-
-			// Get device for status info (need device to check PendingPercentage)
-			n.mutex.RLock()
-			device, exists := n.devices[deviceSerial]
-			n.mutex.RUnlock()
-
-			// Add to device terminal with more detailed info
-			statusInfo := "normal"
-			if exists && device.PendingPercentage != 0 && telemetry.GetPercentageOutput() != device.PendingPercentage {
-				statusInfo = fmt.Sprintf("ramping to %d%%", device.PendingPercentage)
-			}
-
-			n.addDeviceTerminalEntry(deviceSerial, "TELEMETRY",
-				fmt.Sprintf("← Current power: %d%% (%s) | Salt: %dppm | RSSI: %ddBm",
-					telemetry.GetPercentageOutput(), statusInfo, telemetry.GetPpmSalt(), telemetry.GetRssi()), payload)
-
-			n.updateDeviceFromSanitizerTelemetry(deviceSerial, telemetry)
-
-			return
-
-		} else {
-			log.Printf("Failed to parse as sanitizer TelemetryMessage: %v", err)
-		}
-	}
-
-	// Try to parse as JSON (fallback)
-	var telemetryData map[string]interface{}
-	if err := json.Unmarshal(payload, &telemetryData); err == nil {
-		n.updateDeviceFromTelemetry(deviceSerial, telemetryData)
-
-		// Add to device terminal
-		n.addDeviceTerminalEntry(deviceSerial, "TELEMETRY", "Telemetry received (JSON)", payload)
+	// Each category registers its own decoder; unknown categories fall back to legacy JSON
+	result, err := n.telemetryDecoders.Decode(category, deviceSerial, payload)
+	if err != nil {
+		log.Printf("Could not parse telemetry message: %v (%x)", err, payload)
 		return
 	}
 
-	log.Printf("Could not parse telemetry message: %x", payload)
+	// Update the device first so auto-created devices also get the terminal entry
+	result.Apply()
+	n.storeTelemetry(deviceSerial, result.Parsed)
+	n.addParsedTerminalEntry(deviceSerial, "TELEMETRY", result.Summary, payload, result.Parsed)
 }
 
 // messageHandler processes incoming MQTT messages and routes them to appropriate handlers.
@@ -488,38 +548,37 @@ func (n *NgaSim) handleDeviceTelemetry(topic string, payload []byte) {
 //
 // Message types handled:
 //   - "anc" (announce): Device saying "I exist!" with basic info
+//   - "info": Device pushing a status or configuration change without being asked
 //   - "dt" (data/telemetry): Device sending sensor readings
 //   - "sts" (status): Device reporting operational status
 //   - "error": Device reporting error conditions
+//   - "disconnected": Device last will, published by the broker when the device drops
+//   - "res": Command response on cmd/category/serial/res
 //
 // Error handling philosophy: This is a callback function called by the MQTT library.
 // If we can't parse a message, we log the problem and abandon THAT message, but
 // continue processing other messages. We don't return an error because that would
 // break the entire MQTT message processing pipeline.
 func (sim *NgaSim) messageHandler(client mqtt.Client, msg mqtt.Message) {
-	topic := msg.Topic()
-	payload := msg.Payload()
+	sim.recordFrame(SessionInbound, msg.Topic(), msg.Payload(), "", "")
+	sim.handleMessage(msg.Topic(), msg.Payload())
+}
 
+// handleMessage routes one device or command-response message by its topic. Messages come
+// from the broker through messageHandler, or from a recorded session being replayed.
+func (sim *NgaSim) handleMessage(topic string, payload []byte) {
 	log.Printf("📡 Received MQTT message on topic: %s", topic)
 
 	// Parse topic to extract device information
-	// Topic format: async/category/serial/type
+	// Topic format: <prefix>/category/serial/type, the prefix being "async" by default
 	// Example: "async/sanitizerGen2/1234567890ABCDEF00/anc"
-	parts := strings.Split(topic, "/")
-	if len(parts) < 4 {
+	// The last three levels are always category, serial and message type (anc, dt, sts, error)
+	category, deviceSerial, messageType, ok := splitDeviceTopic(topic)
+	if !ok {
 		log.Printf("❌ Invalid topic format: %s (expected: async/category/serial/type)", topic)
 		return // Fail fast - can't process malformed topics
 	}
 
-	// Extract structured information from topic
-	// parts[0] = "async" (protocol prefix - always the same)
-	// parts[1] = device category (sanitizerGen2, digitalControllerGen2, etc.)
-	// parts[2] = device serial number (unique identifier)
-	// parts[3] = message type (anc, dt, sts, error)
-	category := parts[1]
-	deviceSerial := parts[2]
-	messageType := parts[3]
-
 	log.Printf("   📋 Parsed: category=%s, serial=%s, type=%s, payload=%d bytes",
 		category, deviceSerial, messageType, len(payload))
 
@@ -530,6 +589,10 @@ func (sim *NgaSim) messageHandler(client mqtt.Client, msg mqtt.Message) {
 		// Device announcement - "Hello, I'm here!"
 		sim.handleDeviceAnnounce(topic, payload)
 
+	case "info":
+		// Device info - unsolicited status/configuration pushes
+		sim.handleDeviceInfo(category, deviceSerial, payload)
+
 	case "dt":
 		// Device telemetry - sensor readings, status data
 		sim.handleDeviceTelemetry(topic, payload)
@@ -542,6 +605,14 @@ func (sim *NgaSim) messageHandler(client mqtt.Client, msg mqtt.Message) {
 		// Device error - something went wrong
 		sim.handleDeviceError(category, deviceSerial, payload)
 
+	case "disconnected":
+		// Device last will - the broker lost the device's connection
+		sim.handleDeviceDisconnected(category, deviceSerial, payload)
+
+	case "res":
+		// Command response - cmd/category/serial/res, matched to the request by command_uuid
+		sim.handleCommandResponse(category, deviceSerial, payload)
+
 	default:
 		// Unknown message type - log for debugging but don't crash
 		log.Printf("⚠️  Unknown message type: %s (topic: %s)", messageType, topic)
@@ -582,17 +653,13 @@ func (sim *NgaSim) messageHandler(client mqtt.Client, msg mqtt.Message) {
 func (n *NgaSim) handleDeviceAnnounce(topic string, payload []byte) {
 	// Phase 1: Parse MQTT topic to extract device metadata
 	// This parsing happens outside any locks for better performance
-	parts := strings.Split(topic, "/")
-	if len(parts) < 4 { // Magic number 4 = expected parts (async/category/serial/type)
+	// The message type would be "anc" (announce) - we already know this from routing
+	category, deviceSerial, _, ok := splitDeviceTopic(topic)
+	if !ok {
 		log.Printf("❌ Invalid announce topic format: %s (expected: async/category/serial/type)", topic)
 		return // Early return - can't process malformed topics
 	}
 
-	// Extract device identification from topic structure
-	category := parts[1]     // Device category (sanitizerGen2, digitalControllerGen2, etc.)
-	deviceSerial := parts[2] // Unique device identifier
-	// parts[3] would be "anc" (announce) - we already know this from routing
-
 	log.Printf("📢 Device announce from %s (category: %s): %d bytes", deviceSerial, category, len(payload))
 
 	// Phase 2: Try to parse as protobuf GetDeviceInformationResponsePayload (preferred format)
@@ -692,8 +759,7 @@ func (sim *NgaSim) updateDeviceFromAnnounce(deviceID string, data map[string]int
 		device.Serial = serial
 	}
 
-	device.Status = "ONLINE"
-	device.LastSeen = time.Now()
+	sim.markDeviceSeen(device)
 
 	log.Printf("Updated device %s: type=%s, name=%s", deviceID, device.Type, device.Name)
 }
@@ -701,17 +767,75 @@ func (sim *NgaSim) updateDeviceFromAnnounce(deviceID string, data map[string]int
 // handleDeviceStatus processes device status messages
 func (sim *NgaSim) handleDeviceStatus(category, deviceSerial string, payload []byte) {
 	log.Printf("Device status from %s (category: %s): %d bytes", deviceSerial, category, len(payload))
-	// TODO: Implement status message parsing
+
+	msg, status, err := decodeDeviceStatus(category, payload)
+	if err != nil {
+		log.Printf("⚠️ Status from %s not decoded: %v", deviceSerial, err)
+		return
+	}
+
+	sim.mutex.Lock()
+	device, exists := sim.devices[deviceSerial]
+	if exists {
+		device.StatusDetail = status
+		device.StatusUpdated = time.Now()
+		device.mergePayload(msg, device.StatusUpdated)
+		sim.markDeviceSeen(device)
+	}
+	sim.mutex.Unlock()
+
+	if !exists {
+		log.Printf("⚠️ Status from unknown device %s ignored", deviceSerial)
+		return
+	}
+
+	sim.addDeviceTerminalEntry(deviceSerial, "STATUS", fmt.Sprintf("📋 Status: %v", status), payload)
 }
 
-// handleDeviceError processes device error messages
+// handleDeviceError processes device error messages.
+// Each message carries the complete list of active errors, so faults missing from it have cleared.
 func (sim *NgaSim) handleDeviceError(category, deviceSerial string, payload []byte) {
-	log.Printf("Device error from %s (category: %s): %d bytes - %s", deviceSerial, category, len(payload), string(payload))
-	// TODO: Implement error message parsing
+	log.Printf("Device error from %s (category: %s): %d bytes", deviceSerial, category, len(payload))
+
+	decode, exists := errorDecoders[category]
+	if !exists {
+		log.Printf("⚠️ No error decoder for category %s: %x", category, payload)
+		return
+	}
+
+	reported, err := decode(payload)
+	if err != nil {
+		log.Printf("❌ Error message from %s: %v", deviceSerial, err)
+		sim.addDeviceTerminalEntry(deviceSerial, "ERROR", fmt.Sprintf("❌ Undecodable error message: %v", err), payload)
+		return
+	}
+
+	sim.mutex.Lock()
+	device, exists := sim.devices[deviceSerial]
+	var raised, cleared []*DeviceFault
+	if exists {
+		raised, cleared = device.applyFaults(reported, time.Now())
+		sim.markDeviceSeen(device)
+	}
+	sim.mutex.Unlock()
+
+	if !exists {
+		log.Printf("⚠️ Errors from unknown device %s ignored (%d reported)", deviceSerial, len(reported))
+		return
+	}
+
+	for _, fault := range raised {
+		log.Printf("🚨 %s raised %s: %s", deviceSerial, fault.Key, fault.Message)
+		sim.addDeviceTerminalEntry(deviceSerial, "ERROR", fmt.Sprintf("🚨 %s raised: %s", fault.Key, fault.Message), payload)
+	}
+	for _, fault := range cleared {
+		log.Printf("✅ %s cleared %s after %v", deviceSerial, fault.Key, fault.ClearedAt.Sub(fault.FirstSeen).Round(time.Second))
+		sim.addDeviceTerminalEntry(deviceSerial, "ERROR", fmt.Sprintf("✅ %s cleared", fault.Key), payload)
+	}
 }
 
 // updateDeviceFromSanitizerTelemetry updates device with sanitizer telemetry data
-func (sim *NgaSim) updateDeviceFromSanitizerTelemetry(deviceSerial string, telemetry *ned.TelemetryMessage) {
+func (sim *NgaSim) updateDeviceFromSanitizerTelemetry(deviceSerial string, telemetry *sanitizer.TelemetryMessage) {
 	sim.mutex.Lock()
 	defer sim.mutex.Unlock()
 
@@ -760,8 +884,8 @@ func (sim *NgaSim) updateDeviceFromSanitizerTelemetry(deviceSerial string, telem
 		}
 	}
 
-	device.Status = "ONLINE"
-	device.LastSeen = time.Now()
+	device.noteTelemetry(time.Now())
+	sim.markDeviceSeen(device)
 }
 
 // updateDeviceFromTelemetry updates device with telemetry data
@@ -818,7 +942,8 @@ func (sim *NgaSim) updateDeviceFromTelemetry(deviceID string, data map[string]in
 		}
 	}
 
-	device.LastSeen = time.Now()
+	device.noteTelemetry(time.Now())
+	sim.markDeviceSeen(device)
 	log.Printf("Updated telemetry for device %s", deviceID)
 }
 
@@ -865,8 +990,7 @@ func (sim *NgaSim) updateDeviceFromProtobufAnnounce(category, deviceSerial strin
 	}
 	device.Serial = deviceSerial
 
-	device.Status = "ONLINE"
-	device.LastSeen = time.Now()
+	sim.markDeviceSeen(device)
 
 	log.Printf("Device %s fully updated: ProductName='%s', Category='%s', Model='%s', FirmwareVer='%s'",
 		deviceSerial, device.ProductName, device.Category, device.ModelId, device.FirmwareVersion)
@@ -901,8 +1025,7 @@ func (sim *NgaSim) updateDeviceFromJSONAnnounce(deviceSerial string, data map[st
 		device.Serial = serial
 	}
 
-	device.Status = "ONLINE"
-	device.LastSeen = time.Now()
+	sim.markDeviceSeen(device)
 
 	log.Printf("Updated device %s from JSON: type=%s, name=%s", deviceSerial, device.Type, device.Name)
 }
@@ -931,7 +1054,7 @@ func (sim *NgaSim) updateDeviceFromJSONAnnounce(deviceSerial string, data map[st
 // This makes the system more robust in production environments.
 //
 // Returns a fully configured NgaSim instance ready to be started with Start().
-func NewNgaSim() *NgaSim {
+func NewNgaSim(cfg *LoadedConfig) *NgaSim {
 	log.Println("🏗️ Initializing NgaSim components...")
 
 	// Create reflection engine for automatic protobuf discovery
@@ -950,13 +1073,13 @@ func NewNgaSim() *NgaSim {
 
 	// Create terminal logger with file tee (logs to both screen and file)
 	// Buffer size of 1000 means we keep the last 1000 log entries in memory for the web interface
-	terminalLogger, err := NewTerminalLogger("ngasim_terminal.log", 1000)
+	terminalLogger, err := NewTerminalLogger(cfg.Logging.TerminalLog, 1000)
 	if err != nil {
 		log.Printf("⚠️ Warning: Terminal logger creation failed: %v", err)
 		log.Println("   System will continue without terminal logging to file")
 		terminalLogger = nil // Set to nil so other components know it's unavailable
 	} else {
-		log.Println("✅ Terminal logger initialized with file tee to ngasim_terminal.log")
+		log.Printf("✅ Terminal logger initialized with file tee to %s", cfg.Logging.TerminalLog)
 	}
 
 	// Create the main NgaSim struct
@@ -968,6 +1091,10 @@ func NewNgaSim() *NgaSim {
 		reflectionEngine: reflectionEngine,             // Protobuf introspection
 		terminalLogger:   terminalLogger,               // Live terminal feed
 		deviceCommands:   make(map[string][]string),    // Device capability mapping
+		commandTracker:   NewCommandTracker(CommandResponseTimeout, CommandHistorySize),
+		recorder:         NewSessionRecorder(),
+		config:           cfg, // Effective runtime configuration
+		site:             cfg.Site,
 		// Other fields (mutex, mqtt, server, etc.) automatically get zero values
 		// which is exactly what we want for uninitialized components
 	}
@@ -981,6 +1108,31 @@ func NewNgaSim() *NgaSim {
 		log.Println("⚠️ Popup UI generator disabled (terminal logger unavailable)")
 	}
 
+	// Apply the device log level now and whenever the configuration is reloaded
+	ngaSim.applyLogLevel(cfg)
+	ngaSim.onConfigReload(ngaSim.applyLogLevel)
+
+	// Queue commands issued while MQTT is down; the policy follows configuration reloads
+	queueTTL, coalesce := commandQueueSettings(cfg)
+	ngaSim.commandQueue = NewCommandQueue(queueTTL, coalesce, CommandQueueSize)
+	ngaSim.onConfigReload(func(cfg *LoadedConfig) {
+		ngaSim.commandQueue.Configure(commandQueueSettings(cfg))
+	})
+
+	// Load automation jobs now and again whenever the configuration is reloaded; their
+	// schedules start running with the site
+	ngaSim.jobEngine = NewJobEngine(NewMQTTDeviceCommunicator(ngaSim, CommandResponseTimeout), reflectionEngine,
+		ngaSim.logger, ngaSim.commandRegistry)
+	ngaSim.configureJobScheduler(cfg)
+	ngaSim.onConfigReload(ngaSim.configureJobScheduler)
+	ngaSim.loadJobs(cfg)
+	ngaSim.onConfigReload(ngaSim.loadJobs)
+
+	// Register telemetry decoders for every supported product line
+	ngaSim.telemetryDecoders = NewTelemetryDecoderRegistry()
+	ngaSim.registerDefaultTelemetryDecoders(ngaSim.telemetryDecoders)
+	log.Printf("✅ Telemetry decoders registered for %v", ngaSim.telemetryDecoders.Categories())
+
 	// Initialize sanitizer controller (always needed for sanitizer devices)
 	ngaSim.sanitizerController = NewSanitizerController(ngaSim)
 	log.Println("✅ Sanitizer controller initialized")
@@ -996,27 +1148,9 @@ func NewNgaSim() *NgaSim {
 	return ngaSim
 }
 
-// Start initializes and launches all NgaSim components including the comprehensive web server.
-// This function demonstrates Go's HTTP server patterns and is the main application orchestrator.
-//
-// Key Go concepts demonstrated:
-//   - http.ServeMux: Go's built-in HTTP request router (like Apache mod_rewrite but simpler)
-//   - http.HandleFunc: Registers handler functions for specific URL patterns
-//   - Method receivers: Functions attached to the NgaSim struct can access all its data
-//   - Goroutines for servers: HTTP server runs in background without blocking main thread
-//   - Error handling: Graceful fallback when components fail to initialize
-//
-// Web server architecture:
-//   - Single HTTP server listening on port 8082
-//   - Multiple route categories: main pages, API endpoints, static assets, protobuf interfaces
-//   - RESTful API design: /api/ prefix for programmatic access
-//   - Static asset serving: /static/ prefix for CSS, JS, images, documentation
-//
-// The server provides four main interface categories:
-//  1. Human interfaces: Web pages for operators and technicians
-//  2. API endpoints: JSON endpoints for programmatic control
-//  3. Protobuf interfaces: Dynamic device control based on discovered capabilities
-//  4. Static documentation: Specifications, diagrams, and technical docs
+// Start launches the device side of one site: the MQTT connection (or demo devices when the
+// broker is unreachable), the liveness watcher, the poller and the command timeout sweep.
+// The web server is started by the Fleet, which serves every site's routes.
 //
 // Error handling follows the "graceful degradation" pattern - if optional components
 // (like MQTT or protobuf reflection) fail, the system continues with reduced functionality
@@ -1024,28 +1158,82 @@ func NewNgaSim() *NgaSim {
 //
 // Returns error only on catastrophic failure, nil on successful startup.
 func (n *NgaSim) Start() error {
-	log.Println("Starting NgaSim v" + NgaSimVersion)
+	log.Printf("Starting NgaSim v%s site %q", NgaSimVersion, n.site)
 
-	// Phase 1: Initialize MQTT communication (with fallback to demo mode)
-	// This is the "device discovery engine" - connects to pool devices
-	log.Println("Connecting to MQTT broker...")
-	if err := n.connectMQTT(); err != nil {
+	cfg := n.currentConfig()
+	if cfg.Recording.Enabled && cfg.Replay.File == "" {
+		if _, err := n.startRecording(""); err != nil {
+			log.Printf("⚠️ Recording not started: %v", err)
+		}
+	}
+
+	// Initialize MQTT communication (with fallback to demo mode)
+	// This is the "device discovery engine" - connects to pool devices.
+	// A recorded session being replayed stands in for the broker, poller and devices.
+	if cfg.Replay.File != "" {
+		log.Println("📼 Replay mode - not connecting to the MQTT broker")
+		if _, err := n.startReplay(cfg.Replay.File, cfg.Replay.Speed); err != nil {
+			return fmt.Errorf("failed to replay %s: %v", cfg.Replay.File, err)
+		}
+	} else if err := n.connectMQTT(); err != nil {
 		log.Printf("MQTT connection failed: %v", err)
 		log.Println("Falling back to demo mode...")
+		n.demoMode = true
 		n.createDemoDevices() // Create fake devices for development/testing
 	} else {
 		log.Println("MQTT connected successfully - waiting for device announcements...")
 
+		// Watch for devices that go quiet without leaving a last will
+		go n.watchDeviceLiveness()
+
 		// Start the C poller to wake up devices (sends broadcast packets)
 		// Think of this as "knocking on doors" to get devices to announce themselves
-		if err := n.startPoller(); err != nil {
+		// Real devices never reach the embedded broker, so there is nothing to wake on a bench
+		if n.usesEmbeddedBroker() {
+			log.Println("🏠 Using the embedded broker - poller not started")
+		} else if err := n.startPoller(); err != nil {
 			log.Printf("Failed to start poller: %v", err)
 			log.Println("Device discovery may not work properly")
 			// Note: We continue anyway - manual device addition might still work
 		}
+
+		// Virtual devices announce themselves on the same broker
+		n.startSimulator()
 	}
 
-	// Phase 2: Create the HTTP request router
+	// Fail commands whose responses never arrive
+	go n.watchCommandTimeouts()
+
+	// Run scheduled jobs
+	n.jobEngine.Start()
+
+	// Test the protobuf reflection system
+	// This validates that our automatic device discovery is working
+	n.testProtobufSystem()
+
+	return nil
+}
+
+// routes builds the site's HTTP request router.
+//
+// Key Go concepts demonstrated:
+//   - http.ServeMux: Go's built-in HTTP request router (like Apache mod_rewrite but simpler)
+//   - http.HandleFunc: Registers handler functions for specific URL patterns
+//   - Method receivers: Functions attached to the NgaSim struct can access all its data
+//
+// Web server architecture:
+//   - Multiple route categories: main pages, API endpoints, static assets, protobuf interfaces
+//   - RESTful API design: /api/ prefix for programmatic access
+//   - Static asset serving: /static/ prefix for CSS, JS, images, documentation
+//
+// The router provides four main interface categories:
+//  1. Human interfaces: Web pages for operators and technicians
+//  2. API endpoints: JSON endpoints for programmatic control
+//  3. Protobuf interfaces: Dynamic device control based on discovered capabilities
+//  4. Static documentation: Specifications, diagrams, and technical docs
+//
+// The Fleet mounts the router under /sites/<name>/ and at the root for the selected site.
+func (n *NgaSim) routes() *http.ServeMux {
 	// ServeMux is Go's built-in URL router - maps URL patterns to handler functions
 	// Think of it as a telephone switchboard directing calls to the right department
 	mux := http.NewServeMux()
@@ -1062,19 +1250,35 @@ func (n *NgaSim) Start() error {
 	// ==================== API ROUTES (JSON endpoints) ====================
 	// These return JSON data for programmatic access (mobile apps, scripts, etc.)
 
-	mux.HandleFunc("/api/exit", n.handleExit)                          // Gracefully shut down application
-	mux.HandleFunc("/api/devices", n.handleAPI)                        // Get list of all discovered devices
-	mux.HandleFunc("/api/sanitizer/command", n.handleSanitizerCommand) // Send commands to sanitizer devices
-	mux.HandleFunc("/api/sanitizer/states", n.handleSanitizerStates)   // Get sanitizer status information
-	mux.HandleFunc("/api/power-levels", n.handlePowerLevels)           // Get available power level options
-	mux.HandleFunc("/api/emergency-stop", n.handleEmergencyStop)       // Emergency stop all pool equipment
-	mux.HandleFunc("/api/ui/spec", n.handleUISpecAPI)                  // Get UI specification for dynamic interfaces
+	mux.HandleFunc("/api/exit", n.handleExit)                             // Gracefully shut down application
+	mux.HandleFunc("/api/devices", n.handleAPI)                           // Get list of all discovered devices
+	mux.HandleFunc("/api/devices/faults", n.handleDeviceFaults)           // Active and cleared faults per device
+	mux.HandleFunc("/api/sanitizer/command", n.handleSanitizerCommand)    // Send commands to sanitizer devices
+	mux.HandleFunc("/api/sanitizer/states", n.handleSanitizerStates)      // Get sanitizer status information
+	mux.HandleFunc("/api/vsp-booster/command", n.handleVspBoosterCommand) // Start/stop VSP booster pumps
+	mux.HandleFunc("/api/pump/command", n.handlePumpCommand)              // Start/stop SpeedSet Plus pumps and boosters
+	mux.HandleFunc("/api/lights/command", n.handleLightCommand)           // Switch or dim a light on a digital controller
+	mux.HandleFunc("/api/power-levels", n.handlePowerLevels)              // Get available power level options
+	mux.HandleFunc("/api/emergency-stop", n.handleEmergencyStop)          // Emergency stop all pool equipment
+	mux.HandleFunc("/api/ui/spec", n.handleUISpecAPI)                     // Get UI specification for dynamic interfaces
+	mux.HandleFunc("/api/config", n.handleConfig)                         // Effective configuration and where each value came from
+	mux.HandleFunc("/api/mqtt", n.handleMQTTDiagnostics)                  // Broker connection, auth and negotiated TLS session
+	mux.HandleFunc("/api/simulator", n.handleSimulator)                   // Virtual devices and their internal state
+	mux.HandleFunc("/api/scenarios", n.handleScenarios)                   // Fault scenarios (GET) or start one (POST)
+	mux.HandleFunc("/api/scenario-runs/", n.handleScenarioRuns)           // Scenario run by ID (list without one); DELETE stops it
+	mux.HandleFunc("/api/recording", n.handleRecording)                   // Recording status (GET), start (POST) or stop (DELETE)
+	mux.HandleFunc("/api/sessions/", n.handleSessions)                    // Recorded session by name (list without one)
+	mux.HandleFunc("/api/replay", n.handleReplay)                         // Replay status (GET), start (POST) or stop (DELETE)
+	mux.HandleFunc("/api/replay/", n.handleReplayControl)                 // POST step or speed of the running replay
+	mux.HandleFunc("/api/jobs", n.handleJobs)                             // List jobs (GET) or create one (POST)
+	mux.HandleFunc("/api/jobs/", n.handleJobs)                            // Job by ID: GET/PUT/DELETE, POST enable, disable or run; executions
 
 	// ==================== DEVICE COMMAND API ROUTES ====================
 	// These provide automatic command discovery based on protobuf reflection
 
 	mux.HandleFunc("/api/device-commands/", n.handleDeviceCommands)   // Get commands for specific device category
 	mux.HandleFunc("/api/device-commands", n.handleAllDeviceCommands) // Get all available commands across all device types
+	mux.HandleFunc("/api/commands/", n.handleCommandStatus)           // Command result by UUID (list without one); DELETE cancels a queued command
 
 	// ==================== STATIC ASSET ROUTES ====================
 	// These serve documentation, diagrams, and specifications
@@ -1108,63 +1312,16 @@ func (n *NgaSim) Start() error {
 		log.Println("⚠️ Protobuf popup routes disabled (popupGenerator not available)")
 	}
 
-	// Phase 3: Create and configure the HTTP server
-	// Server struct contains all the HTTP server configuration
-	n.server = &http.Server{
-		Addr:    ":8082", // Listen on all interfaces, port 8082
-		Handler: mux,     // Use our route multiplexer to handle requests
-		// Note: Go's HTTP server has sensible defaults for timeouts, etc.
-	}
-
-	// Phase 4: Test the protobuf reflection system
-	// This validates that our automatic device discovery is working
-	n.testProtobufSystem()
-
-	// Phase 5: Start the HTTP server in a goroutine
-	// The goroutine is CRITICAL - without it, ListenAndServe() would block forever
-	// and the main() function would never reach the "select {}" statement
-	go func() {
-		log.Println("Web server starting on :8082")
-
-		// ListenAndServe() blocks until the server shuts down
-		// It returns http.ErrServerClosed on normal shutdown, or an actual error on failure
-		if err := n.server.ListenAndServe(); err != http.ErrServerClosed {
-			log.Printf("Server error: %v", err)
-		}
-		// When this goroutine exits, the HTTP server has stopped
-	}()
-
-	// Phase 6: Log all available endpoints for the operator
-	// This creates a "menu" of what the system can do
-	log.Println("🚀 NgaSim started successfully!")
-	log.Println("")
-	log.Println("📍 Available Interfaces:")
-	log.Println("   🌐 Main Interface:    http://localhost:8082")
-	log.Println("   🎮 JS Demo:           http://localhost:8082/js-demo")
-	log.Println("   🏠 Original Home:     http://localhost:8082/old")
-	log.Println("   📊 Demo Page:         http://localhost:8082/demo")
-	log.Println("")
-	log.Println("🔗 API Endpoints:")
-	log.Println("   📊 Device List:       http://localhost:8082/api/devices")
-	log.Println("   🧪 Sanitizer Cmd:     http://localhost:8082/api/sanitizer/command")
-	log.Println("   ⚡ Power Levels:      http://localhost:8082/api/power-levels")
-	log.Println("   🛑 Emergency Stop:    http://localhost:8082/api/emergency-stop")
-	log.Println("   🔧 Exit App:          http://localhost:8082/api/exit")
-	log.Println("")
-	log.Println("📋 Documentation:")
-	log.Println("   📄 UI Spec (TOML):    http://localhost:8082/static/ui-spec.toml")
-	log.Println("   🎨 Wireframe (SVG):   http://localhost:8082/static/wireframe.svg")
-
-	return nil // Success! Everything started correctly
+	return mux
 }
 
 // main is the entry point for the NgaSim Pool Controller application.
 // It performs the complete startup sequence and manages application lifecycle:
 //
-//  1. Creates a new NgaSim instance (the main controller object)
+//  1. Creates a Fleet with one NgaSim instance (the main controller object) per site
 //  2. Sets up graceful shutdown handling using Go's signal system
-//  3. Connects to the MQTT broker at 169.254.1.1:1883 for device communication
-//  4. Starts the web server on port 8082 for the management dashboard
+//  3. Connects to the configured MQTT broker (169.254.1.1:1883 by default) for device communication
+//  4. Starts the web server (port 8082 by default) for the management dashboard
 //  5. Runs indefinitely until terminated by interrupt signal (Ctrl+C)
 //
 // Key Go concepts demonstrated:
@@ -1177,16 +1334,38 @@ func (n *NgaSim) Start() error {
 // even if the program crashes or is terminated unexpectedly. This ensures MQTT
 // connections are closed and the C poller subprocess is properly killed.
 func main() {
+	// ngasim send ... publishes one command and exits instead of starting the simulator
+	if len(os.Args) > 1 && os.Args[1] == SendMode {
+		if err := runSend(os.Args[2:]); err != nil && err != flag.ErrHelp {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
 	log.Println("=== NgaSim Pool Controller Simulator ===")
 	log.Printf("Version: %s", NgaSimVersion)
 	log.Println("Starting up...")
 
-	// Create NgaSim instance
-	nga := NewNgaSim()
+	// Load settings from ngasim.toml (or -config), NGASIM_* environment variables and flags
+	cfg, err := LoadConfig(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		log.Fatalf("❌ Invalid configuration: %v", err)
+	}
+	if cfg.File != "" {
+		log.Printf("📄 Configuration loaded from %s", cfg.File)
+	}
+
+	// Create one NgaSim per site ([[sites]] in the config, or a single default site)
+	fleet, err := NewFleet(cfg)
+	if err != nil {
+		log.Fatalf("❌ Invalid site configuration: %v", err)
+	}
 
 	// CRITICAL: This defer ensures cleanup happens no matter how main() exits
 	// It's like a C++ destructor but more reliable - runs even on crashes
-	defer nga.cleanup()
+	defer fleet.cleanup()
 
 	// Set up graceful shutdown handling
 	c := make(chan os.Signal, 1)
@@ -1196,33 +1375,53 @@ func main() {
 	go func() {
 		<-c // Block until signal received
 		log.Println("\n🛑 Interrupt received, shutting down gracefully...")
-		nga.cleanup()
+		fleet.cleanup()
 		os.Exit(0)
 	}()
 
-	// Start the NgaSim system
-	if err := nga.Start(); err != nil {
+	// Reload log level and job files on SIGHUP
+	go fleet.watchConfigReload()
+
+	// Start every site and the web server
+	if err := fleet.Start(); err != nil {
 		log.Fatalf("❌ Failed to start NgaSim: %v", err)
 	}
 
+	baseURL := cfg.HTTP.BaseURL()
 	log.Println("🚀 NgaSim started successfully!")
 	log.Println("")
 	log.Println("📍 Available Interfaces:")
-	log.Println("   🌐 Main Interface:    http://localhost:8082")
-	log.Println("   🎮 JS Demo:           http://localhost:8082/js-demo")
-	log.Println("   🏠 Original Home:     http://localhost:8082/old")
-	log.Println("   📊 Demo Page:         http://localhost:8082/demo")
+	log.Printf("   🌐 Main Interface:    %s", baseURL)
+	log.Printf("   🎮 JS Demo:           %s/js-demo", baseURL)
+	log.Printf("   🏠 Original Home:     %s/old", baseURL)
+	log.Printf("   📊 Demo Page:         %s/demo", baseURL)
 	log.Println("")
 	log.Println("🔗 API Endpoints:")
-	log.Println("   📊 Device List:       http://localhost:8082/api/devices")
-	log.Println("   🧪 Sanitizer Cmd:     http://localhost:8082/api/sanitizer/command")
-	log.Println("   ⚡ Power Levels:      http://localhost:8082/api/power-levels")
-	log.Println("   🛑 Emergency Stop:    http://localhost:8082/api/emergency-stop")
-	log.Println("   🔧 Exit App:          http://localhost:8082/api/exit")
+	log.Printf("   📊 Device List:       %s/api/devices", baseURL)
+	log.Printf("   🧪 Sanitizer Cmd:     %s/api/sanitizer/command", baseURL)
+	log.Printf("   🌀 Pump Cmd:          %s/api/pump/command", baseURL)
+	log.Printf("   💡 Light Cmd:         %s/api/lights/command", baseURL)
+	log.Printf("   ⚡ Power Levels:      %s/api/power-levels", baseURL)
+	log.Printf("   🛑 Emergency Stop:    %s/api/emergency-stop", baseURL)
+	log.Printf("   🔧 Exit App:          %s/api/exit", baseURL)
+	log.Printf("   ⚙️ Config:            %s/api/config", baseURL)
+	log.Printf("   🔒 MQTT Diagnostics:  %s/api/mqtt", baseURL)
+	log.Printf("   🏠 Embedded Broker:   %s/api/broker", baseURL)
+	log.Printf("   🤖 Simulator:         %s/api/simulator", baseURL)
+	log.Printf("   🎬 Fault Scenarios:   %s/api/scenarios", baseURL)
+	log.Printf("   ⏺️ Recording:         %s/api/recording", baseURL)
+	log.Printf("   📼 Replay:            %s/api/replay", baseURL)
+	log.Printf("   📋 Jobs:              %s/api/jobs", baseURL)
+	log.Println("")
+	log.Println("🌐 Sites:")
+	log.Printf("   🗺️ Fleet View:        %s/fleet", baseURL)
+	for _, name := range cfg.siteNames() {
+		log.Printf("   🏊 %-18s %s/sites/%s/", name+":", baseURL, name)
+	}
 	log.Println("")
 	log.Println("📋 Documentation:")
-	log.Println("   📄 UI Spec (TOML):    http://localhost:8082/static/ui-spec.toml")
-	log.Println("   🎨 Wireframe (SVG):   http://localhost:8082/static/wireframe.svg")
+	log.Printf("   📄 UI Spec (TOML):    %s/static/ui-spec.toml", baseURL)
+	log.Printf("   🎨 Wireframe (SVG):   %s/static/wireframe.svg", baseURL)
 	log.Println("")
 	log.Println("Press Ctrl+C to exit")
 
@@ -1396,13 +1595,14 @@ func (n *NgaSim) createDemoDevices() {
 	log.Printf("Created %d demo devices (multiple per type for sorting test)", len(demoDevices))
 }
 
-// Enhanced sendSanitizerCommand with continuous 0% safety mode
-func (n *NgaSim) sendSanitizerCommand(serial, category string, percentage int) error {
+// Enhanced sendSanitizerCommand with continuous 0% safety mode. It returns the command UUID, or
+// "" when the command is simulated in demo mode.
+func (n *NgaSim) sendSanitizerCommand(serial, category string, percentage int) (string, error) {
 	log.Printf("🧪 Sending sanitizer command: %s -> %d%%", serial, percentage)
 
 	// Validate percentage range
 	if percentage < 0 || percentage > 101 {
-		return fmt.Errorf("invalid percentage: %d (must be 0-101)", percentage)
+		return "", fmt.Errorf("invalid percentage: %d (must be 0-101)", percentage)
 	}
 
 	// Find the device
@@ -1411,7 +1611,7 @@ func (n *NgaSim) sendSanitizerCommand(serial, category string, percentage int) e
 	n.mutex.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("device not found: %s", serial)
+		return "", fmt.Errorf("device not found: %s", serial)
 	}
 
 	// Update device state to show pending command
@@ -1425,16 +1625,16 @@ func (n *NgaSim) sendSanitizerCommand(serial, category string, percentage int) e
 		fmt.Sprintf("→ Set power level to %d%%", percentage),
 		[]byte(fmt.Sprintf(`{"command":"set_power","percentage":%d}`, percentage)))
 
-	// If MQTT is connected, send the real command
-	if n.mqtt != nil && n.mqtt.IsConnected() {
-		err := n.sendMQTTSanitizerCommand(serial, category, percentage)
+	// With a broker, send the real command (queued until reconnect if the link is down)
+	if n.sendsToBroker() {
+		commandUUID, err := n.sendMQTTSanitizerCommand(serial, category, percentage)
 
 		// ENHANCED: For 0% (safety/emergency) commands, start continuous sending
 		if percentage == 0 {
 			go n.startContinuous0PercentMode(serial, category)
 		}
 
-		return err
+		return commandUUID, err
 	}
 
 	// Demo mode - simulate the command execution
@@ -1461,7 +1661,7 @@ func (n *NgaSim) sendSanitizerCommand(serial, category string, percentage int) e
 		n.mutex.Unlock()
 	}()
 
-	return nil
+	return "", nil
 }
 
 // startContinuous0PercentMode sends 0% commands every 5 seconds until device reaches 0%
@@ -1504,7 +1704,7 @@ func (n *NgaSim) startContinuous0PercentMode(serial, category string) {
 			// Device not at 0% yet, send another 0% command
 			log.Printf("🔄 Continuous 0%% mode: %s still at %d%%, sending another 0%% command", serial, currentLevel)
 
-			if err := n.sendMQTTSanitizerCommand(serial, category, 0); err != nil {
+			if _, err := n.sendMQTTSanitizerCommand(serial, category, 0); err != nil {
 				log.Printf("❌ Failed to send continuous 0%% command to %s: %v", serial, err)
 				// Don't stop on single failure - keep trying
 			}
@@ -1518,55 +1718,48 @@ func (n *NgaSim) startContinuous0PercentMode(serial, category string) {
 	}
 }
 
-// sendMQTTSanitizerCommand sends a sanitizer command via MQTT using proper protobuf + UUID
-func (n *NgaSim) sendMQTTSanitizerCommand(serial, category string, percentage int) error {
+// sendMQTTSanitizerCommand sends a sanitizer command via MQTT using proper protobuf + UUID and
+// returns the UUID
+func (n *NgaSim) sendMQTTSanitizerCommand(serial, category string, percentage int) (string, error) {
 	log.Printf("📡 Sending MQTT sanitizer command: %s -> %d%%", serial, percentage)
 
 	// Generate UUID for command correlation (CRITICAL: This prevents import removal!)
 	commandUUID := uuid.New().String()
 
 	// Create the inner sanitizer command
-	saltCmd := &ned.SetSanitizerTargetPercentageRequestPayload{
+	saltCmd := &sanitizer.SetSanitizerTargetPercentageRequestPayload{
 		TargetPercentage: int32(percentage),
 	}
 
 	// Wrap it in SanitizerRequestPayloads using the oneof pattern
-	wrapper := &ned.SanitizerRequestPayloads{
-		RequestType: &ned.SanitizerRequestPayloads_SetSanitizerOutputPercentage{
+	wrapper := &sanitizer.SanitizerRequestPayloads{
+		RequestType: &sanitizer.SanitizerRequestPayloads_SetSanitizerOutputPercentage{
 			SetSanitizerOutputPercentage: saltCmd,
 		},
 	}
 
-	// Serialize the protobuf wrapper
-	msgBytes, err := proto.Marshal(wrapper)
+	// Envelope it in the category CommandRequestMessage carrying the UUID and serialize
+	_, msgBytes, err := marshalCommand(category, commandUUID, wrapper)
 	if err != nil {
-		return fmt.Errorf("failed to marshal protobuf command: %v", err)
+		return "", fmt.Errorf("failed to marshal protobuf command: %v", err)
 	}
 
-	// Construct the MQTT topic for sending commands
-	// Topic format: async/category/serial/cmd
-	topic := fmt.Sprintf("async/%s/%s/cmd", category, serial)
+	// Log the command, start waiting for its response and send it on cmd/category/serial/req.
+	// While MQTT is down the command is queued and replayed on reconnect instead.
+	queued, err := n.publishCommand(serial, category, "SetSanitizerTargetPercentage", commandUUID, msgBytes)
+	if err != nil {
+		return "", err
+	}
+	if queued {
+		return commandUUID, nil
+	}
 
 	// Log successful command transmission to device terminal
 	n.addDeviceTerminalEntry(serial, "MQTT_CMD",
 		fmt.Sprintf("📡 MQTT command sent: Set power to %d%% (UUID: %s)", percentage, commandUUID), msgBytes)
 
-	// Use UUID as correlation ID instead of random string
-	correlationID := commandUUID
-
-	// Log the command for debugging
-	n.logger.LogRequest(serial, "SetSanitizerTargetPercentage", msgBytes, category, "sanitizer", "protobuf_command")
-
-	// Send the command via MQTT
-	token := n.mqtt.Publish(topic, 1, false, msgBytes)
-	if token.Wait() && token.Error() != nil {
-		n.logger.LogError(serial, "SetSanitizerTargetPercentage",
-			fmt.Sprintf("MQTT publish failed: %v", token.Error()), correlationID, category)
-		return fmt.Errorf("failed to publish command: %v", token.Error())
-	}
-
 	log.Printf("✅ MQTT protobuf command sent successfully: %s -> %d%% (UUID: %s)", serial, percentage, commandUUID)
-	return nil
+	return commandUUID, nil
 }
 
 // handleAllDeviceCommands returns all available device commands
@@ -1644,6 +1837,12 @@ func (n *NgaSim) clearDeviceTerminal(deviceSerial string) bool {
 
 // Enhanced addDeviceTerminalEntry with protobuf parsing
 func (n *NgaSim) addDeviceTerminalEntry(deviceSerial, entryType, message string, rawData []byte) {
+	n.addParsedTerminalEntry(deviceSerial, entryType, message, rawData, nil)
+}
+
+// addParsedTerminalEntry adds a device terminal entry with an already decoded protobuf view.
+// Without one, announce and telemetry entries fall back to the basic byte-level parser.
+func (n *NgaSim) addParsedTerminalEntry(deviceSerial, entryType, message string, rawData []byte, parsed *ParsedProtobufMessage) {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
 
@@ -1663,45 +1862,55 @@ func (n *NgaSim) addDeviceTerminalEntry(deviceSerial, entryType, message string,
 		Data:      string(rawData),
 	}
 
-	// Try to parse protobuf data based on entry type
-	switch strings.ToUpper(entryType) {
-	case "ANNOUNCE":
-		if parsed, err := parser.ParseAnnounceMessage(rawData, deviceSerial); err == nil {
-			entry.ParsedProtobuf = parsed
-			// Update message with parsed info
-			if len(parsed.Fields) > 0 {
-				entry.Message = fmt.Sprintf("Device announced: %s (%d fields parsed)",
-					device.Name, len(parsed.Fields))
+	// Use the decoder's view when there is one, otherwise parse based on entry type
+	if parsed != nil {
+		entry.ParsedProtobuf = parsed
+	} else {
+		switch strings.ToUpper(entryType) {
+		case "ANNOUNCE":
+			if parsed, err := parser.ParseAnnounceMessage(rawData, deviceSerial); err == nil {
+				entry.ParsedProtobuf = parsed
+				// Update message with parsed info
+				if len(parsed.Fields) > 0 {
+					entry.Message = fmt.Sprintf("Device announced: %s (%d fields parsed)",
+						device.Name, len(parsed.Fields))
+				}
+			} else {
+				log.Printf("Failed to parse announce protobuf: %v", err)
 			}
-		} else {
-			log.Printf("Failed to parse announce protobuf: %v", err)
-		}
 
-	case "TELEMETRY":
-		if parsed, err := parser.ParseTelemetryMessage(rawData, deviceSerial); err == nil {
-			entry.ParsedProtobuf = parsed
-			// Update message with key telemetry info
-			if len(parsed.Fields) > 0 {
-				entry.Message = fmt.Sprintf("Telemetry received (%d fields parsed)",
-					len(parsed.Fields))
+		case "TELEMETRY":
+			if parsed, err := parser.ParseTelemetryMessage(rawData, deviceSerial); err == nil {
+				entry.ParsedProtobuf = parsed
+				// Update message with key telemetry info
+				if len(parsed.Fields) > 0 {
+					entry.Message = fmt.Sprintf("Telemetry received (%d fields parsed)",
+						len(parsed.Fields))
+				}
+			} else {
+				log.Printf("Failed to parse telemetry protobuf: %v", err)
 			}
-		} else {
-			log.Printf("Failed to parse telemetry protobuf: %v", err)
 		}
 	}
 
 	// Add to device's live terminal
+	appendLiveTerminal(device, entry)
+
+	// Also log to global terminal if available
+	if n.terminalLogger != nil {
+		n.terminalLogger.LogProtobufMessage(entryType, deviceSerial, "DEVICE", message, rawData)
+	}
+}
+
+// appendLiveTerminal adds an entry to a device's live terminal, keeping only the last 50.
+// Caller must hold the NgaSim mutex.
+func appendLiveTerminal(device *Device, entry TerminalEntry) {
 	device.LiveTerminal = append(device.LiveTerminal, entry)
 
 	// Keep only last 50 entries per device
 	if len(device.LiveTerminal) > 50 {
 		device.LiveTerminal = device.LiveTerminal[len(device.LiveTerminal)-50:]
 	}
-
-	// Also log to global terminal if available
-	if n.terminalLogger != nil {
-		n.terminalLogger.LogProtobufMessage(entryType, deviceSerial, "DEVICE", message, rawData)
-	}
 }
 
 // getSortedDevices returns devices sorted by serial number
@@ -1765,6 +1974,8 @@ func (sim *NgaSim) discoverDeviceCapabilities(deviceType string) map[string]inte
 		// Reflection discovers controller commands automatically
 	case "speedsetplus":
 		// Reflection discovers pump commands automatically
+	case VspBoosterCategory:
+		// Reflection discovers booster pump commands automatically
 	default:
 		// Unknown device - reflection still discovers basic capabilities
 	}
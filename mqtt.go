@@ -1 +1,285 @@
 package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTTTLSConfig holds the TLS settings used for ssl://, tls://, mqtts:// and wss:// brokers
+type MQTTTLSConfig struct {
+	CAFile             string `toml:"ca_file" json:"ca_file,omitempty"`                 // PEM CA bundle, system roots when empty
+	CertFile           string `toml:"cert_file" json:"cert_file,omitempty"`             // PEM client certificate
+	KeyFile            string `toml:"key_file" json:"key_file,omitempty"`               // PEM client private key
+	ServerName         string `toml:"server_name" json:"server_name,omitempty"`         // Overrides the name verified against the broker certificate
+	InsecureSkipVerify bool   `toml:"insecure_skip_verify" json:"insecure_skip_verify"` // Lab use only
+}
+
+// configured reports whether any TLS setting was given
+func (c MQTTTLSConfig) configured() bool {
+	return c.CAFile != "" || c.CertFile != "" || c.KeyFile != "" || c.ServerName != "" || c.InsecureSkipVerify
+}
+
+// mqttSecrets is the layout of the MQTT secrets file
+type mqttSecrets struct {
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+}
+
+// isTLSBroker reports whether the broker URL scheme makes paho use TLS
+func isTLSBroker(broker string) bool {
+	uri, err := url.Parse(broker)
+	if err != nil {
+		return false
+	}
+	switch uri.Scheme {
+	case "ssl", "tls", "mqtts", "mqtt+ssl", "tcps", "wss":
+		return true
+	}
+	return false
+}
+
+// buildTLSConfig loads the CA bundle and client key pair named in the configuration
+func buildTLSConfig(c MQTTTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, fmt.Errorf("client certificate needs both cert_file and key_file")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.InsecureSkipVerify {
+		log.Println("⚠️ MQTT TLS certificate verification is DISABLED (insecure_skip_verify) - lab use only")
+	}
+	return tlsConfig, nil
+}
+
+// loadMQTTSecrets reads username and password from a TOML secrets file
+func loadMQTTSecrets(path string) (*mqttSecrets, error) {
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0o077 != 0 {
+		log.Printf("⚠️ MQTT secrets file %s is readable by other users (mode %v)", path, info.Mode().Perm())
+	}
+
+	secrets := &mqttSecrets{}
+	if _, err := toml.DecodeFile(path, secrets); err != nil {
+		return nil, fmt.Errorf("failed to read MQTT secrets file %s: %v", path, err)
+	}
+	return secrets, nil
+}
+
+// mqttCredentials returns the credentials to connect with. Values in the secrets file win over
+// the config file and environment; the file is re-read on every connect so a rotated password
+// is picked up on the next reconnect.
+func mqttCredentials(c MQTTConfig) (username, password string, err error) {
+	username, password = c.Username, c.Password
+	if c.SecretsFile == "" {
+		return username, password, nil
+	}
+
+	secrets, err := loadMQTTSecrets(c.SecretsFile)
+	if err != nil {
+		return "", "", err
+	}
+	if secrets.Username != "" {
+		username = secrets.Username
+	}
+	if secrets.Password != "" {
+		password = secrets.Password
+	}
+	return username, password, nil
+}
+
+// MQTTPeerCertificate summarises one certificate presented by the broker
+type MQTTPeerCertificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	DNSNames  []string  `json:"dns_names,omitempty"`
+}
+
+// MQTTTLSState is the negotiated TLS session of the current broker connection
+type MQTTTLSState struct {
+	Version            string                `json:"version"`
+	CipherSuite        string                `json:"cipher_suite"`
+	ServerName         string                `json:"server_name"`
+	NegotiatedProtocol string                `json:"negotiated_protocol,omitempty"`
+	DidResume          bool                  `json:"did_resume"`
+	ClientCertificate  bool                  `json:"client_certificate"`
+	InsecureSkipVerify bool                  `json:"insecure_skip_verify"`
+	PeerCertificates   []MQTTPeerCertificate `json:"peer_certificates"`
+	HandshakeAt        time.Time             `json:"handshake_at"`
+}
+
+// MQTTDiagnostics describes the broker connection for /api/mqtt
+type MQTTDiagnostics struct {
+	Broker        string        `json:"broker"`
+	ClientID      string        `json:"client_id"`
+	Connected     bool          `json:"connected"`
+	Auth          string        `json:"auth"` // none or password
+	Username      string        `json:"username,omitempty"`
+	TLS           *MQTTTLSState `json:"tls,omitempty"`
+	LastConnected time.Time     `json:"last_connected,omitempty"`
+	LastError     string        `json:"last_error,omitempty"`
+	LastErrorAt   time.Time     `json:"last_error_at,omitempty"`
+}
+
+// mqttConnectionInfo tracks the broker connection for diagnostics
+type mqttConnectionInfo struct {
+	mutex sync.Mutex
+	diag  MQTTDiagnostics
+}
+
+func (i *mqttConnectionInfo) update(change func(d *MQTTDiagnostics)) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	change(&i.diag)
+}
+
+func (i *mqttConnectionInfo) snapshot() MQTTDiagnostics {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.diag
+}
+
+// tlsVersionName returns a readable name for a TLS protocol version
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+// newMQTTTLSState converts a negotiated connection state for diagnostics
+func newMQTTTLSState(state tls.ConnectionState, tlsConfig *tls.Config) *MQTTTLSState {
+	result := &MQTTTLSState{
+		Version:            tlsVersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		ServerName:         state.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		DidResume:          state.DidResume,
+		ClientCertificate:  len(tlsConfig.Certificates) > 0,
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify,
+		HandshakeAt:        time.Now(),
+	}
+	for _, cert := range state.PeerCertificates {
+		result.PeerCertificates = append(result.PeerCertificates, MQTTPeerCertificate{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			DNSNames:  cert.DNSNames,
+		})
+	}
+	return result
+}
+
+// configureMQTTSecurity applies TLS and credentials to the client options. TLS brokers are
+// dialled here rather than by paho so the negotiated session can be shown in diagnostics.
+func (sim *NgaSim) configureMQTTSecurity(opts *mqtt.ClientOptions, c MQTTConfig) error {
+	if _, _, err := mqttCredentials(c); err != nil {
+		return err
+	}
+	opts.SetCredentialsProvider(func() (string, string) {
+		username, password, err := mqttCredentials(c)
+		if err != nil {
+			log.Printf("❌ %v", err)
+		}
+		auth := "none"
+		if username != "" || password != "" {
+			auth = "password"
+		}
+		sim.mqttInfo.update(func(d *MQTTDiagnostics) {
+			d.Auth = auth
+			d.Username = username
+		})
+		return username, password
+	})
+
+	if !isTLSBroker(c.Broker) {
+		if c.TLS.configured() {
+			return fmt.Errorf("TLS settings given but broker %s is not a TLS URL (use ssl://, tls:// or mqtts://)", c.Broker)
+		}
+		return nil
+	}
+
+	tlsConfig, err := buildTLSConfig(c.TLS)
+	if err != nil {
+		return err
+	}
+	opts.SetTLSConfig(tlsConfig)
+
+	if strings.HasPrefix(c.Broker, "wss") {
+		// paho dials websockets itself; the handshake is not visible to diagnostics
+		return nil
+	}
+
+	opts.SetCustomOpenConnectionFn(func(uri *url.URL, options mqtt.ClientOptions) (net.Conn, error) {
+		dialer := &net.Dialer{Timeout: options.ConnectTimeout}
+		conn, err := tls.DialWithDialer(dialer, "tcp", uri.Host, options.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		state := newMQTTTLSState(conn.ConnectionState(), options.TLSConfig)
+		if state.ServerName == "" {
+			state.ServerName = uri.Hostname() // No SNI is sent for IP addresses
+		}
+		sim.mqttInfo.update(func(d *MQTTDiagnostics) { d.TLS = state })
+		log.Printf("🔒 MQTT TLS established: %s, %s, server %s, client cert: %v",
+			state.Version, state.CipherSuite, state.ServerName, state.ClientCertificate)
+		return conn, nil
+	})
+	return nil
+}
+
+// handleMQTTDiagnostics reports the broker connection, authentication and TLS session
+func (n *NgaSim) handleMQTTDiagnostics(w http.ResponseWriter, r *http.Request) {
+	diag := n.mqttInfo.snapshot()
+	diag.Connected = n.mqtt != nil && n.mqtt.IsConnected()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(diag)
+}
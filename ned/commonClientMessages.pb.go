@@ -1,5 +1,3 @@
-//go:build !exclude_duplicates
-
 //**************************************************************
 //Common Gen2 Client Messages Defined
 //**************************************************************
@@ -1,5 +1,3 @@
-//go:build exclude_duplicates
-
 //**************************************************************
 //Protobuf Message InfiniteWaterColorDCT proto file
 //- command/response definition
@@ -11,9 +9,10 @@
 // 	protoc        v3.12.4
 // source: digitalControllerTransformer.proto
 
-package ned
+package icl
 
 import (
+	ned "NgaSim/ned"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -642,27 +641,86 @@ func (*InfiniteWaterColorDCTInfoPayloads_DctStatusChanged) isInfiniteWaterColorD
 
 // a command wrapper message with a type field
 // MQTT topic: 'cmd/<category>/<serial number>/req'
+type CommandRequestMessage struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	CommandUuid string                 `protobuf:"bytes,1,opt,name=command_uuid,json=commandUuid,proto3" json:"command_uuid,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*CommandRequestMessage_Common
+	//	*CommandRequestMessage_Icl
+	Payload       isCommandRequestMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
+func (x *CommandRequestMessage) Reset() {
+	*x = CommandRequestMessage{}
+	mi := &file_digitalControllerTransformer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
+func (x *CommandRequestMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
 func (*CommandRequestMessage) ProtoMessage() {}
 
+func (x *CommandRequestMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_digitalControllerTransformer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
 
 // Deprecated: Use CommandRequestMessage.ProtoReflect.Descriptor instead.
 func (*CommandRequestMessage) Descriptor() ([]byte, []int) {
 	return file_digitalControllerTransformer_proto_rawDescGZIP(), []int{3}
 }
 
+func (x *CommandRequestMessage) GetCommandUuid() string {
+	if x != nil {
+		return x.CommandUuid
+	}
+	return ""
+}
 
+func (x *CommandRequestMessage) GetPayload() isCommandRequestMessage_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
 
+func (x *CommandRequestMessage) GetCommon() *ned.CommonRequestPayloads {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandRequestMessage_Common); ok {
+			return x.Common
+		}
+	}
+	return nil
+}
 
+func (x *CommandRequestMessage) GetIcl() *DCTRequests {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandRequestMessage_Icl); ok {
+			return x.Icl
+		}
+	}
+	return nil
+}
 
 type isCommandRequestMessage_Payload interface {
 	isCommandRequestMessage_Payload()
 }
 
 type CommandRequestMessage_Common struct {
-	Common *CommonRequestPayloads `protobuf:"bytes,2,opt,name=common,proto3,oneof"`
+	Common *ned.CommonRequestPayloads `protobuf:"bytes,2,opt,name=common,proto3,oneof"`
 }
 
 type CommandRequestMessage_Icl struct {
@@ -676,28 +734,95 @@ func (*CommandRequestMessage_Icl) isCommandRequestMessage_Payload() {}
 // a response wrapper message with a message type field
 // and the required response code
 // MQTT topic: 'cmd/<category>/<serial number>/res'
+type CommandResponseMessage struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	CommandUuid string                 `protobuf:"bytes,1,opt,name=command_uuid,json=commandUuid,proto3" json:"command_uuid,omitempty"`
+	// required
+	ResponseCode ned.ResponseCode `protobuf:"varint,2,opt,name=response_code,json=responseCode,proto3,enum=ned.ResponseCode" json:"response_code,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*CommandResponseMessage_Common
+	//	*CommandResponseMessage_Icl
+	Payload       isCommandResponseMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
+func (x *CommandResponseMessage) Reset() {
+	*x = CommandResponseMessage{}
+	mi := &file_digitalControllerTransformer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
+func (x *CommandResponseMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
 func (*CommandResponseMessage) ProtoMessage() {}
 
+func (x *CommandResponseMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_digitalControllerTransformer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
 
 // Deprecated: Use CommandResponseMessage.ProtoReflect.Descriptor instead.
 func (*CommandResponseMessage) Descriptor() ([]byte, []int) {
 	return file_digitalControllerTransformer_proto_rawDescGZIP(), []int{4}
 }
 
+func (x *CommandResponseMessage) GetCommandUuid() string {
+	if x != nil {
+		return x.CommandUuid
+	}
+	return ""
+}
 
+func (x *CommandResponseMessage) GetResponseCode() ned.ResponseCode {
+	if x != nil {
+		return x.ResponseCode
+	}
+	return ned.ResponseCode_RESPONSE_UNKNOWN
+}
 
+func (x *CommandResponseMessage) GetPayload() isCommandResponseMessage_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
 
+func (x *CommandResponseMessage) GetCommon() *ned.CommonResponsePayloads {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandResponseMessage_Common); ok {
+			return x.Common
+		}
+	}
+	return nil
+}
 
+func (x *CommandResponseMessage) GetIcl() *InfiniteWaterColorDCTResponsePayloads {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandResponseMessage_Icl); ok {
+			return x.Icl
+		}
+	}
+	return nil
+}
 
 type isCommandResponseMessage_Payload interface {
 	isCommandResponseMessage_Payload()
 }
 
 type CommandResponseMessage_Common struct {
-	Common *CommonResponsePayloads `protobuf:"bytes,3,opt,name=common,proto3,oneof"`
+	Common *ned.CommonResponsePayloads `protobuf:"bytes,3,opt,name=common,proto3,oneof"`
 }
 
 type CommandResponseMessage_Icl struct {
@@ -2878,9 +3003,9 @@ var file_digitalControllerTransformer_proto_goTypes = []any{
 	(*GetActiveErrorsResponse)(nil),                             // 41: icl.GetActiveErrorsResponse
 	(*LightConfigurationPatch_Field)(nil),                       // 42: icl.LightConfigurationPatch.Field
 	(*SetLightMaxBrightnessRequest_LightMaximumBrightness)(nil), // 43: icl.SetLightMaxBrightnessRequest.LightMaximumBrightness
-	(*CommonRequestPayloads)(nil),                               // 44: ned.CommonRequestPayloads
-	(ResponseCode)(0),                                           // 45: ned.ResponseCode
-	(*CommonResponsePayloads)(nil),                              // 46: ned.CommonResponsePayloads
+	(*ned.CommonRequestPayloads)(nil),                           // 44: ned.CommonRequestPayloads
+	(ned.ResponseCode)(0),                                       // 45: ned.ResponseCode
+	(*ned.CommonResponsePayloads)(nil),                          // 46: ned.CommonResponsePayloads
 }
 var file_digitalControllerTransformer_proto_depIdxs = []int32{
 	19, // 0: icl.DCTRequests.set_dct20_lights:type_name -> icl.SetLightConfigurationRequest
@@ -2947,7 +3072,6 @@ func file_digitalControllerTransformer_proto_init() {
 	if File_digitalControllerTransformer_proto != nil {
 		return
 	}
-	file_commonClientMessages_proto_init()
 	file_digitalControllerTransformer_proto_msgTypes[0].OneofWrappers = []any{
 		(*DCTRequests_SetDct20Lights)(nil),
 		(*DCTRequests_GetDct20Status)(nil),
@@ -5,13 +5,14 @@
 
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.9
-// 	protoc        v3.21.12
+// 	protoc-gen-go v1.36.10
+// 	protoc        v3.12.4
 // source: sanitizer.proto
 
-package ned
+package sanitizer
 
 import (
+	ned "NgaSim/ned"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -544,6 +545,198 @@ func (*SanitizerInfoPayloads_Status) isSanitizerInfoPayloads_AnnounceType() {}
 
 func (*SanitizerInfoPayloads_Configuration) isSanitizerInfoPayloads_AnnounceType() {}
 
+type CommandRequestMessage struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	CommandUuid string                 `protobuf:"bytes,1,opt,name=command_uuid,json=commandUuid,proto3" json:"command_uuid,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*CommandRequestMessage_Common
+	//	*CommandRequestMessage_Sanitizer
+	Payload       isCommandRequestMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandRequestMessage) Reset() {
+	*x = CommandRequestMessage{}
+	mi := &file_sanitizer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandRequestMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandRequestMessage) ProtoMessage() {}
+
+func (x *CommandRequestMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_sanitizer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandRequestMessage.ProtoReflect.Descriptor instead.
+func (*CommandRequestMessage) Descriptor() ([]byte, []int) {
+	return file_sanitizer_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CommandRequestMessage) GetCommandUuid() string {
+	if x != nil {
+		return x.CommandUuid
+	}
+	return ""
+}
+
+func (x *CommandRequestMessage) GetPayload() isCommandRequestMessage_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *CommandRequestMessage) GetCommon() *ned.CommonRequestPayloads {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandRequestMessage_Common); ok {
+			return x.Common
+		}
+	}
+	return nil
+}
+
+func (x *CommandRequestMessage) GetSanitizer() *SanitizerRequestPayloads {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandRequestMessage_Sanitizer); ok {
+			return x.Sanitizer
+		}
+	}
+	return nil
+}
+
+type isCommandRequestMessage_Payload interface {
+	isCommandRequestMessage_Payload()
+}
+
+type CommandRequestMessage_Common struct {
+	Common *ned.CommonRequestPayloads `protobuf:"bytes,2,opt,name=common,proto3,oneof"`
+}
+
+type CommandRequestMessage_Sanitizer struct {
+	Sanitizer *SanitizerRequestPayloads `protobuf:"bytes,3,opt,name=sanitizer,proto3,oneof"`
+}
+
+func (*CommandRequestMessage_Common) isCommandRequestMessage_Payload() {}
+
+func (*CommandRequestMessage_Sanitizer) isCommandRequestMessage_Payload() {}
+
+// a response wrapper message with a message type field
+// and the required response code
+// MQTT topic: 'cmd/<category>/<serial number>/res'
+type CommandResponseMessage struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	CommandUuid string                 `protobuf:"bytes,1,opt,name=command_uuid,json=commandUuid,proto3" json:"command_uuid,omitempty"`
+	// required
+	ResponseCode ned.ResponseCode `protobuf:"varint,2,opt,name=response_code,json=responseCode,proto3,enum=ned.ResponseCode" json:"response_code,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*CommandResponseMessage_Common
+	//	*CommandResponseMessage_Sanitizer
+	Payload       isCommandResponseMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandResponseMessage) Reset() {
+	*x = CommandResponseMessage{}
+	mi := &file_sanitizer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandResponseMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandResponseMessage) ProtoMessage() {}
+
+func (x *CommandResponseMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_sanitizer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandResponseMessage.ProtoReflect.Descriptor instead.
+func (*CommandResponseMessage) Descriptor() ([]byte, []int) {
+	return file_sanitizer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CommandResponseMessage) GetCommandUuid() string {
+	if x != nil {
+		return x.CommandUuid
+	}
+	return ""
+}
+
+func (x *CommandResponseMessage) GetResponseCode() ned.ResponseCode {
+	if x != nil {
+		return x.ResponseCode
+	}
+	return ned.ResponseCode_RESPONSE_UNKNOWN
+}
+
+func (x *CommandResponseMessage) GetPayload() isCommandResponseMessage_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *CommandResponseMessage) GetCommon() *ned.CommonResponsePayloads {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandResponseMessage_Common); ok {
+			return x.Common
+		}
+	}
+	return nil
+}
+
+func (x *CommandResponseMessage) GetSanitizer() *SanitizerResponsePayloads {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandResponseMessage_Sanitizer); ok {
+			return x.Sanitizer
+		}
+	}
+	return nil
+}
+
+type isCommandResponseMessage_Payload interface {
+	isCommandResponseMessage_Payload()
+}
+
+type CommandResponseMessage_Common struct {
+	Common *ned.CommonResponsePayloads `protobuf:"bytes,3,opt,name=common,proto3,oneof"`
+}
+
+type CommandResponseMessage_Sanitizer struct {
+	Sanitizer *SanitizerResponsePayloads `protobuf:"bytes,4,opt,name=sanitizer,proto3,oneof"`
+}
+
+func (*CommandResponseMessage_Common) isCommandResponseMessage_Payload() {}
+
+func (*CommandResponseMessage_Sanitizer) isCommandResponseMessage_Payload() {}
+
 // an Info message wrapper
 // MQTT  topic: 'async/<category>/<serial number>/info'
 // Info messages can be a variety of message types
@@ -556,7 +749,7 @@ type InfoMessage struct {
 
 func (x *InfoMessage) Reset() {
 	*x = InfoMessage{}
-	mi := &file_sanitizer_proto_msgTypes[3]
+	mi := &file_sanitizer_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -568,7 +761,7 @@ func (x *InfoMessage) String() string {
 func (*InfoMessage) ProtoMessage() {}
 
 func (x *InfoMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[3]
+	mi := &file_sanitizer_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -581,7 +774,7 @@ func (x *InfoMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use InfoMessage.ProtoReflect.Descriptor instead.
 func (*InfoMessage) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{3}
+	return file_sanitizer_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *InfoMessage) GetPayload() *SanitizerInfoPayloads {
@@ -610,7 +803,7 @@ type TelemetryMessage struct {
 
 func (x *TelemetryMessage) Reset() {
 	*x = TelemetryMessage{}
-	mi := &file_sanitizer_proto_msgTypes[4]
+	mi := &file_sanitizer_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -622,7 +815,7 @@ func (x *TelemetryMessage) String() string {
 func (*TelemetryMessage) ProtoMessage() {}
 
 func (x *TelemetryMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[4]
+	mi := &file_sanitizer_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -635,7 +828,7 @@ func (x *TelemetryMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TelemetryMessage.ProtoReflect.Descriptor instead.
 func (*TelemetryMessage) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{4}
+	return file_sanitizer_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *TelemetryMessage) GetRssi() int32 {
@@ -704,7 +897,7 @@ type SanitizerStatus struct {
 
 func (x *SanitizerStatus) Reset() {
 	*x = SanitizerStatus{}
-	mi := &file_sanitizer_proto_msgTypes[5]
+	mi := &file_sanitizer_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -716,7 +909,7 @@ func (x *SanitizerStatus) String() string {
 func (*SanitizerStatus) ProtoMessage() {}
 
 func (x *SanitizerStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[5]
+	mi := &file_sanitizer_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -729,7 +922,7 @@ func (x *SanitizerStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SanitizerStatus.ProtoReflect.Descriptor instead.
 func (*SanitizerStatus) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{5}
+	return file_sanitizer_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *SanitizerStatus) GetTargetPercentage() int32 {
@@ -755,7 +948,7 @@ type SanitizerConfiguration struct {
 
 func (x *SanitizerConfiguration) Reset() {
 	*x = SanitizerConfiguration{}
-	mi := &file_sanitizer_proto_msgTypes[6]
+	mi := &file_sanitizer_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -767,7 +960,7 @@ func (x *SanitizerConfiguration) String() string {
 func (*SanitizerConfiguration) ProtoMessage() {}
 
 func (x *SanitizerConfiguration) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[6]
+	mi := &file_sanitizer_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -780,7 +973,7 @@ func (x *SanitizerConfiguration) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SanitizerConfiguration.ProtoReflect.Descriptor instead.
 func (*SanitizerConfiguration) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{6}
+	return file_sanitizer_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *SanitizerConfiguration) GetCellReversalDuration() int32 {
@@ -801,7 +994,7 @@ type SetSanitizerTargetPercentageRequestPayload struct {
 
 func (x *SetSanitizerTargetPercentageRequestPayload) Reset() {
 	*x = SetSanitizerTargetPercentageRequestPayload{}
-	mi := &file_sanitizer_proto_msgTypes[7]
+	mi := &file_sanitizer_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -813,7 +1006,7 @@ func (x *SetSanitizerTargetPercentageRequestPayload) String() string {
 func (*SetSanitizerTargetPercentageRequestPayload) ProtoMessage() {}
 
 func (x *SetSanitizerTargetPercentageRequestPayload) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[7]
+	mi := &file_sanitizer_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -826,7 +1019,7 @@ func (x *SetSanitizerTargetPercentageRequestPayload) ProtoReflect() protoreflect
 
 // Deprecated: Use SetSanitizerTargetPercentageRequestPayload.ProtoReflect.Descriptor instead.
 func (*SetSanitizerTargetPercentageRequestPayload) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{7}
+	return file_sanitizer_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *SetSanitizerTargetPercentageRequestPayload) GetTargetPercentage() int32 {
@@ -845,7 +1038,7 @@ type GetSanitizerDeviceInformationRequestPayload struct {
 
 func (x *GetSanitizerDeviceInformationRequestPayload) Reset() {
 	*x = GetSanitizerDeviceInformationRequestPayload{}
-	mi := &file_sanitizer_proto_msgTypes[8]
+	mi := &file_sanitizer_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -857,7 +1050,7 @@ func (x *GetSanitizerDeviceInformationRequestPayload) String() string {
 func (*GetSanitizerDeviceInformationRequestPayload) ProtoMessage() {}
 
 func (x *GetSanitizerDeviceInformationRequestPayload) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[8]
+	mi := &file_sanitizer_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -870,7 +1063,7 @@ func (x *GetSanitizerDeviceInformationRequestPayload) ProtoReflect() protoreflec
 
 // Deprecated: Use GetSanitizerDeviceInformationRequestPayload.ProtoReflect.Descriptor instead.
 func (*GetSanitizerDeviceInformationRequestPayload) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{8}
+	return file_sanitizer_proto_rawDescGZIP(), []int{10}
 }
 
 type GetSanitizerDeviceInformationResponsePayload struct {
@@ -884,7 +1077,7 @@ type GetSanitizerDeviceInformationResponsePayload struct {
 
 func (x *GetSanitizerDeviceInformationResponsePayload) Reset() {
 	*x = GetSanitizerDeviceInformationResponsePayload{}
-	mi := &file_sanitizer_proto_msgTypes[9]
+	mi := &file_sanitizer_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -896,7 +1089,7 @@ func (x *GetSanitizerDeviceInformationResponsePayload) String() string {
 func (*GetSanitizerDeviceInformationResponsePayload) ProtoMessage() {}
 
 func (x *GetSanitizerDeviceInformationResponsePayload) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[9]
+	mi := &file_sanitizer_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -909,7 +1102,7 @@ func (x *GetSanitizerDeviceInformationResponsePayload) ProtoReflect() protorefle
 
 // Deprecated: Use GetSanitizerDeviceInformationResponsePayload.ProtoReflect.Descriptor instead.
 func (*GetSanitizerDeviceInformationResponsePayload) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{9}
+	return file_sanitizer_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *GetSanitizerDeviceInformationResponsePayload) GetCellSerialNumber() string {
@@ -942,7 +1135,7 @@ type GetSanitizerStatusRequestPayload struct {
 
 func (x *GetSanitizerStatusRequestPayload) Reset() {
 	*x = GetSanitizerStatusRequestPayload{}
-	mi := &file_sanitizer_proto_msgTypes[10]
+	mi := &file_sanitizer_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -954,7 +1147,7 @@ func (x *GetSanitizerStatusRequestPayload) String() string {
 func (*GetSanitizerStatusRequestPayload) ProtoMessage() {}
 
 func (x *GetSanitizerStatusRequestPayload) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[10]
+	mi := &file_sanitizer_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -967,7 +1160,7 @@ func (x *GetSanitizerStatusRequestPayload) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetSanitizerStatusRequestPayload.ProtoReflect.Descriptor instead.
 func (*GetSanitizerStatusRequestPayload) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{10}
+	return file_sanitizer_proto_rawDescGZIP(), []int{12}
 }
 
 type GetSanitizerStatusResponsePayload struct {
@@ -979,7 +1172,7 @@ type GetSanitizerStatusResponsePayload struct {
 
 func (x *GetSanitizerStatusResponsePayload) Reset() {
 	*x = GetSanitizerStatusResponsePayload{}
-	mi := &file_sanitizer_proto_msgTypes[11]
+	mi := &file_sanitizer_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -991,7 +1184,7 @@ func (x *GetSanitizerStatusResponsePayload) String() string {
 func (*GetSanitizerStatusResponsePayload) ProtoMessage() {}
 
 func (x *GetSanitizerStatusResponsePayload) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[11]
+	mi := &file_sanitizer_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1004,7 +1197,7 @@ func (x *GetSanitizerStatusResponsePayload) ProtoReflect() protoreflect.Message
 
 // Deprecated: Use GetSanitizerStatusResponsePayload.ProtoReflect.Descriptor instead.
 func (*GetSanitizerStatusResponsePayload) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{11}
+	return file_sanitizer_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *GetSanitizerStatusResponsePayload) GetStatus() *SanitizerStatus {
@@ -1023,7 +1216,7 @@ type GetSanitizerConfigurationRequestPayload struct {
 
 func (x *GetSanitizerConfigurationRequestPayload) Reset() {
 	*x = GetSanitizerConfigurationRequestPayload{}
-	mi := &file_sanitizer_proto_msgTypes[12]
+	mi := &file_sanitizer_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1035,7 +1228,7 @@ func (x *GetSanitizerConfigurationRequestPayload) String() string {
 func (*GetSanitizerConfigurationRequestPayload) ProtoMessage() {}
 
 func (x *GetSanitizerConfigurationRequestPayload) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[12]
+	mi := &file_sanitizer_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1048,7 +1241,7 @@ func (x *GetSanitizerConfigurationRequestPayload) ProtoReflect() protoreflect.Me
 
 // Deprecated: Use GetSanitizerConfigurationRequestPayload.ProtoReflect.Descriptor instead.
 func (*GetSanitizerConfigurationRequestPayload) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{12}
+	return file_sanitizer_proto_rawDescGZIP(), []int{14}
 }
 
 type GetSanitizerConfigurationResponsePayload struct {
@@ -1060,7 +1253,7 @@ type GetSanitizerConfigurationResponsePayload struct {
 
 func (x *GetSanitizerConfigurationResponsePayload) Reset() {
 	*x = GetSanitizerConfigurationResponsePayload{}
-	mi := &file_sanitizer_proto_msgTypes[13]
+	mi := &file_sanitizer_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1072,7 +1265,7 @@ func (x *GetSanitizerConfigurationResponsePayload) String() string {
 func (*GetSanitizerConfigurationResponsePayload) ProtoMessage() {}
 
 func (x *GetSanitizerConfigurationResponsePayload) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[13]
+	mi := &file_sanitizer_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1085,7 +1278,7 @@ func (x *GetSanitizerConfigurationResponsePayload) ProtoReflect() protoreflect.M
 
 // Deprecated: Use GetSanitizerConfigurationResponsePayload.ProtoReflect.Descriptor instead.
 func (*GetSanitizerConfigurationResponsePayload) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{13}
+	return file_sanitizer_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *GetSanitizerConfigurationResponsePayload) GetConfiguration() *SanitizerConfiguration {
@@ -1104,7 +1297,7 @@ type SetSanitizerConfigurationRequestPayload struct {
 
 func (x *SetSanitizerConfigurationRequestPayload) Reset() {
 	*x = SetSanitizerConfigurationRequestPayload{}
-	mi := &file_sanitizer_proto_msgTypes[14]
+	mi := &file_sanitizer_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1116,7 +1309,7 @@ func (x *SetSanitizerConfigurationRequestPayload) String() string {
 func (*SetSanitizerConfigurationRequestPayload) ProtoMessage() {}
 
 func (x *SetSanitizerConfigurationRequestPayload) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[14]
+	mi := &file_sanitizer_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1129,7 +1322,7 @@ func (x *SetSanitizerConfigurationRequestPayload) ProtoReflect() protoreflect.Me
 
 // Deprecated: Use SetSanitizerConfigurationRequestPayload.ProtoReflect.Descriptor instead.
 func (*SetSanitizerConfigurationRequestPayload) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{14}
+	return file_sanitizer_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *SetSanitizerConfigurationRequestPayload) GetConfiguration() *SanitizerConfiguration {
@@ -1149,7 +1342,7 @@ type OverrideFlowSensorTypeRequestPayload struct {
 
 func (x *OverrideFlowSensorTypeRequestPayload) Reset() {
 	*x = OverrideFlowSensorTypeRequestPayload{}
-	mi := &file_sanitizer_proto_msgTypes[15]
+	mi := &file_sanitizer_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1161,7 +1354,7 @@ func (x *OverrideFlowSensorTypeRequestPayload) String() string {
 func (*OverrideFlowSensorTypeRequestPayload) ProtoMessage() {}
 
 func (x *OverrideFlowSensorTypeRequestPayload) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[15]
+	mi := &file_sanitizer_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1174,7 +1367,7 @@ func (x *OverrideFlowSensorTypeRequestPayload) ProtoReflect() protoreflect.Messa
 
 // Deprecated: Use OverrideFlowSensorTypeRequestPayload.ProtoReflect.Descriptor instead.
 func (*OverrideFlowSensorTypeRequestPayload) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{15}
+	return file_sanitizer_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *OverrideFlowSensorTypeRequestPayload) GetFlowSensorType() FlowSensorType {
@@ -1192,7 +1385,7 @@ type GetSanitizerActiveErrorsRequestPayload struct {
 
 func (x *GetSanitizerActiveErrorsRequestPayload) Reset() {
 	*x = GetSanitizerActiveErrorsRequestPayload{}
-	mi := &file_sanitizer_proto_msgTypes[16]
+	mi := &file_sanitizer_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1204,7 +1397,7 @@ func (x *GetSanitizerActiveErrorsRequestPayload) String() string {
 func (*GetSanitizerActiveErrorsRequestPayload) ProtoMessage() {}
 
 func (x *GetSanitizerActiveErrorsRequestPayload) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[16]
+	mi := &file_sanitizer_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1217,7 +1410,7 @@ func (x *GetSanitizerActiveErrorsRequestPayload) ProtoReflect() protoreflect.Mes
 
 // Deprecated: Use GetSanitizerActiveErrorsRequestPayload.ProtoReflect.Descriptor instead.
 func (*GetSanitizerActiveErrorsRequestPayload) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{16}
+	return file_sanitizer_proto_rawDescGZIP(), []int{18}
 }
 
 type GetSanitizerActiveErrorsResponsePayload struct {
@@ -1229,7 +1422,7 @@ type GetSanitizerActiveErrorsResponsePayload struct {
 
 func (x *GetSanitizerActiveErrorsResponsePayload) Reset() {
 	*x = GetSanitizerActiveErrorsResponsePayload{}
-	mi := &file_sanitizer_proto_msgTypes[17]
+	mi := &file_sanitizer_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1241,7 +1434,7 @@ func (x *GetSanitizerActiveErrorsResponsePayload) String() string {
 func (*GetSanitizerActiveErrorsResponsePayload) ProtoMessage() {}
 
 func (x *GetSanitizerActiveErrorsResponsePayload) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[17]
+	mi := &file_sanitizer_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1254,7 +1447,7 @@ func (x *GetSanitizerActiveErrorsResponsePayload) ProtoReflect() protoreflect.Me
 
 // Deprecated: Use GetSanitizerActiveErrorsResponsePayload.ProtoReflect.Descriptor instead.
 func (*GetSanitizerActiveErrorsResponsePayload) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{17}
+	return file_sanitizer_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *GetSanitizerActiveErrorsResponsePayload) GetActiveErrors() *ActiveErrors {
@@ -1275,7 +1468,7 @@ type DeviceErrorMessage struct {
 
 func (x *DeviceErrorMessage) Reset() {
 	*x = DeviceErrorMessage{}
-	mi := &file_sanitizer_proto_msgTypes[18]
+	mi := &file_sanitizer_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1287,7 +1480,7 @@ func (x *DeviceErrorMessage) String() string {
 func (*DeviceErrorMessage) ProtoMessage() {}
 
 func (x *DeviceErrorMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[18]
+	mi := &file_sanitizer_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1300,7 +1493,7 @@ func (x *DeviceErrorMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeviceErrorMessage.ProtoReflect.Descriptor instead.
 func (*DeviceErrorMessage) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{18}
+	return file_sanitizer_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *DeviceErrorMessage) GetActiveErrors() *ActiveErrors {
@@ -1320,7 +1513,7 @@ type SanitizerError struct {
 
 func (x *SanitizerError) Reset() {
 	*x = SanitizerError{}
-	mi := &file_sanitizer_proto_msgTypes[19]
+	mi := &file_sanitizer_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1332,7 +1525,7 @@ func (x *SanitizerError) String() string {
 func (*SanitizerError) ProtoMessage() {}
 
 func (x *SanitizerError) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[19]
+	mi := &file_sanitizer_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1345,7 +1538,7 @@ func (x *SanitizerError) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SanitizerError.ProtoReflect.Descriptor instead.
 func (*SanitizerError) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{19}
+	return file_sanitizer_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *SanitizerError) GetErrorCode() SanitizerErrorCode {
@@ -1372,7 +1565,7 @@ type ActiveErrors struct {
 
 func (x *ActiveErrors) Reset() {
 	*x = ActiveErrors{}
-	mi := &file_sanitizer_proto_msgTypes[20]
+	mi := &file_sanitizer_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1384,7 +1577,7 @@ func (x *ActiveErrors) String() string {
 func (*ActiveErrors) ProtoMessage() {}
 
 func (x *ActiveErrors) ProtoReflect() protoreflect.Message {
-	mi := &file_sanitizer_proto_msgTypes[20]
+	mi := &file_sanitizer_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1397,7 +1590,7 @@ func (x *ActiveErrors) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ActiveErrors.ProtoReflect.Descriptor instead.
 func (*ActiveErrors) Descriptor() ([]byte, []int) {
-	return file_sanitizer_proto_rawDescGZIP(), []int{20}
+	return file_sanitizer_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *ActiveErrors) GetErrorList() []*SanitizerError {
@@ -1432,7 +1625,18 @@ const file_sanitizer_proto_rawDesc = "" +
 	"\x15SanitizerInfoPayloads\x124\n" +
 	"\x06status\x18\x01 \x01(\v2\x1a.sanitizer.SanitizerStatusH\x00R\x06status\x12I\n" +
 	"\rconfiguration\x18\x02 \x01(\v2!.sanitizer.SanitizerConfigurationH\x00R\rconfigurationB\x0f\n" +
-	"\rannounce_type\"I\n" +
+	"\rannounce_type\"\xc0\x01\n" +
+	"\x15CommandRequestMessage\x12!\n" +
+	"\fcommand_uuid\x18\x01 \x01(\tR\vcommandUuid\x124\n" +
+	"\x06common\x18\x02 \x01(\v2\x1a.ned.CommonRequestPayloadsH\x00R\x06common\x12C\n" +
+	"\tsanitizer\x18\x03 \x01(\v2#.sanitizer.SanitizerRequestPayloadsH\x00R\tsanitizerB\t\n" +
+	"\apayload\"\xfb\x01\n" +
+	"\x16CommandResponseMessage\x12!\n" +
+	"\fcommand_uuid\x18\x01 \x01(\tR\vcommandUuid\x126\n" +
+	"\rresponse_code\x18\x02 \x01(\x0e2\x11.ned.ResponseCodeR\fresponseCode\x125\n" +
+	"\x06common\x18\x03 \x01(\v2\x1b.ned.CommonResponsePayloadsH\x00R\x06common\x12D\n" +
+	"\tsanitizer\x18\x04 \x01(\v2$.sanitizer.SanitizerResponsePayloadsH\x00R\tsanitizerB\t\n" +
+	"\apayload\"I\n" +
 	"\vInfoMessage\x12:\n" +
 	"\apayload\x18\x01 \x01(\v2 .sanitizer.SanitizerInfoPayloadsR\apayload\"\xca\x02\n" +
 	"\x10TelemetryMessage\x12\x12\n" +
@@ -1493,7 +1697,7 @@ const file_sanitizer_proto_rawDesc = "" +
 	"\x17SANITIZER_ERROR_NO_FLOW\x10\x01\x12\x1c\n" +
 	"\x18SANITIZER_ERROR_LOW_SALT\x10\x02\x12\x1d\n" +
 	"\x19SANITIZER_ERROR_HIGH_SALT\x10\x03\x12\x1f\n" +
-	"\x1bSANITIZER_ERROR_CELL_TILTED\x10\x04B\x11Z\x0fpooltester3/nedb\x06proto3"
+	"\x1bSANITIZER_ERROR_CELL_TILTED\x10\x04B\x1aZ\x18pooltester3_20250916/nedb\x06proto3"
 
 var (
 	file_sanitizer_proto_rawDescOnce sync.Once
@@ -1508,7 +1712,7 @@ func file_sanitizer_proto_rawDescGZIP() []byte {
 }
 
 var file_sanitizer_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
-var file_sanitizer_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
+var file_sanitizer_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
 var file_sanitizer_proto_goTypes = []any{
 	(CellType)(0),                                        // 0: sanitizer.CellType
 	(FlowSensorType)(0),                                  // 1: sanitizer.FlowSensorType
@@ -1516,55 +1720,65 @@ var file_sanitizer_proto_goTypes = []any{
 	(*SanitizerRequestPayloads)(nil),                     // 3: sanitizer.SanitizerRequestPayloads
 	(*SanitizerResponsePayloads)(nil),                    // 4: sanitizer.SanitizerResponsePayloads
 	(*SanitizerInfoPayloads)(nil),                        // 5: sanitizer.SanitizerInfoPayloads
-	(*InfoMessage)(nil),                                  // 6: sanitizer.InfoMessage
-	(*TelemetryMessage)(nil),                             // 7: sanitizer.TelemetryMessage
-	(*SanitizerStatus)(nil),                              // 8: sanitizer.SanitizerStatus
-	(*SanitizerConfiguration)(nil),                       // 9: sanitizer.SanitizerConfiguration
-	(*SetSanitizerTargetPercentageRequestPayload)(nil),   // 10: sanitizer.SetSanitizerTargetPercentageRequestPayload
-	(*GetSanitizerDeviceInformationRequestPayload)(nil),  // 11: sanitizer.GetSanitizerDeviceInformationRequestPayload
-	(*GetSanitizerDeviceInformationResponsePayload)(nil), // 12: sanitizer.GetSanitizerDeviceInformationResponsePayload
-	(*GetSanitizerStatusRequestPayload)(nil),             // 13: sanitizer.GetSanitizerStatusRequestPayload
-	(*GetSanitizerStatusResponsePayload)(nil),            // 14: sanitizer.GetSanitizerStatusResponsePayload
-	(*GetSanitizerConfigurationRequestPayload)(nil),      // 15: sanitizer.GetSanitizerConfigurationRequestPayload
-	(*GetSanitizerConfigurationResponsePayload)(nil),     // 16: sanitizer.GetSanitizerConfigurationResponsePayload
-	(*SetSanitizerConfigurationRequestPayload)(nil),      // 17: sanitizer.SetSanitizerConfigurationRequestPayload
-	(*OverrideFlowSensorTypeRequestPayload)(nil),         // 18: sanitizer.OverrideFlowSensorTypeRequestPayload
-	(*GetSanitizerActiveErrorsRequestPayload)(nil),       // 19: sanitizer.GetSanitizerActiveErrorsRequestPayload
-	(*GetSanitizerActiveErrorsResponsePayload)(nil),      // 20: sanitizer.GetSanitizerActiveErrorsResponsePayload
-	(*DeviceErrorMessage)(nil),                           // 21: sanitizer.DeviceErrorMessage
-	(*SanitizerError)(nil),                               // 22: sanitizer.SanitizerError
-	(*ActiveErrors)(nil),                                 // 23: sanitizer.ActiveErrors
+	(*CommandRequestMessage)(nil),                        // 6: sanitizer.CommandRequestMessage
+	(*CommandResponseMessage)(nil),                       // 7: sanitizer.CommandResponseMessage
+	(*InfoMessage)(nil),                                  // 8: sanitizer.InfoMessage
+	(*TelemetryMessage)(nil),                             // 9: sanitizer.TelemetryMessage
+	(*SanitizerStatus)(nil),                              // 10: sanitizer.SanitizerStatus
+	(*SanitizerConfiguration)(nil),                       // 11: sanitizer.SanitizerConfiguration
+	(*SetSanitizerTargetPercentageRequestPayload)(nil),   // 12: sanitizer.SetSanitizerTargetPercentageRequestPayload
+	(*GetSanitizerDeviceInformationRequestPayload)(nil),  // 13: sanitizer.GetSanitizerDeviceInformationRequestPayload
+	(*GetSanitizerDeviceInformationResponsePayload)(nil), // 14: sanitizer.GetSanitizerDeviceInformationResponsePayload
+	(*GetSanitizerStatusRequestPayload)(nil),             // 15: sanitizer.GetSanitizerStatusRequestPayload
+	(*GetSanitizerStatusResponsePayload)(nil),            // 16: sanitizer.GetSanitizerStatusResponsePayload
+	(*GetSanitizerConfigurationRequestPayload)(nil),      // 17: sanitizer.GetSanitizerConfigurationRequestPayload
+	(*GetSanitizerConfigurationResponsePayload)(nil),     // 18: sanitizer.GetSanitizerConfigurationResponsePayload
+	(*SetSanitizerConfigurationRequestPayload)(nil),      // 19: sanitizer.SetSanitizerConfigurationRequestPayload
+	(*OverrideFlowSensorTypeRequestPayload)(nil),         // 20: sanitizer.OverrideFlowSensorTypeRequestPayload
+	(*GetSanitizerActiveErrorsRequestPayload)(nil),       // 21: sanitizer.GetSanitizerActiveErrorsRequestPayload
+	(*GetSanitizerActiveErrorsResponsePayload)(nil),      // 22: sanitizer.GetSanitizerActiveErrorsResponsePayload
+	(*DeviceErrorMessage)(nil),                           // 23: sanitizer.DeviceErrorMessage
+	(*SanitizerError)(nil),                               // 24: sanitizer.SanitizerError
+	(*ActiveErrors)(nil),                                 // 25: sanitizer.ActiveErrors
+	(*ned.CommonRequestPayloads)(nil),                    // 26: ned.CommonRequestPayloads
+	(ned.ResponseCode)(0),                                // 27: ned.ResponseCode
+	(*ned.CommonResponsePayloads)(nil),                   // 28: ned.CommonResponsePayloads
 }
 var file_sanitizer_proto_depIdxs = []int32{
-	10, // 0: sanitizer.SanitizerRequestPayloads.set_sanitizer_output_percentage:type_name -> sanitizer.SetSanitizerTargetPercentageRequestPayload
-	11, // 1: sanitizer.SanitizerRequestPayloads.get_device_information:type_name -> sanitizer.GetSanitizerDeviceInformationRequestPayload
-	13, // 2: sanitizer.SanitizerRequestPayloads.get_status:type_name -> sanitizer.GetSanitizerStatusRequestPayload
-	15, // 3: sanitizer.SanitizerRequestPayloads.get_configuration:type_name -> sanitizer.GetSanitizerConfigurationRequestPayload
-	17, // 4: sanitizer.SanitizerRequestPayloads.set_configuration:type_name -> sanitizer.SetSanitizerConfigurationRequestPayload
-	18, // 5: sanitizer.SanitizerRequestPayloads.override_flow_sensor_type:type_name -> sanitizer.OverrideFlowSensorTypeRequestPayload
-	19, // 6: sanitizer.SanitizerRequestPayloads.get_active_errors:type_name -> sanitizer.GetSanitizerActiveErrorsRequestPayload
-	12, // 7: sanitizer.SanitizerResponsePayloads.get_device_information:type_name -> sanitizer.GetSanitizerDeviceInformationResponsePayload
-	14, // 8: sanitizer.SanitizerResponsePayloads.get_status:type_name -> sanitizer.GetSanitizerStatusResponsePayload
-	16, // 9: sanitizer.SanitizerResponsePayloads.get_configuration:type_name -> sanitizer.GetSanitizerConfigurationResponsePayload
-	20, // 10: sanitizer.SanitizerResponsePayloads.get_active_errors:type_name -> sanitizer.GetSanitizerActiveErrorsResponsePayload
-	8,  // 11: sanitizer.SanitizerInfoPayloads.status:type_name -> sanitizer.SanitizerStatus
-	9,  // 12: sanitizer.SanitizerInfoPayloads.configuration:type_name -> sanitizer.SanitizerConfiguration
-	5,  // 13: sanitizer.InfoMessage.payload:type_name -> sanitizer.SanitizerInfoPayloads
-	1,  // 14: sanitizer.SanitizerStatus.flow_sensor_type:type_name -> sanitizer.FlowSensorType
-	0,  // 15: sanitizer.GetSanitizerDeviceInformationResponsePayload.cell_type:type_name -> sanitizer.CellType
-	8,  // 16: sanitizer.GetSanitizerStatusResponsePayload.status:type_name -> sanitizer.SanitizerStatus
-	9,  // 17: sanitizer.GetSanitizerConfigurationResponsePayload.configuration:type_name -> sanitizer.SanitizerConfiguration
-	9,  // 18: sanitizer.SetSanitizerConfigurationRequestPayload.configuration:type_name -> sanitizer.SanitizerConfiguration
-	1,  // 19: sanitizer.OverrideFlowSensorTypeRequestPayload.flow_sensor_type:type_name -> sanitizer.FlowSensorType
-	23, // 20: sanitizer.GetSanitizerActiveErrorsResponsePayload.active_errors:type_name -> sanitizer.ActiveErrors
-	23, // 21: sanitizer.DeviceErrorMessage.active_errors:type_name -> sanitizer.ActiveErrors
-	2,  // 22: sanitizer.SanitizerError.error_code:type_name -> sanitizer.SanitizerErrorCode
-	22, // 23: sanitizer.ActiveErrors.error_list:type_name -> sanitizer.SanitizerError
-	24, // [24:24] is the sub-list for method output_type
-	24, // [24:24] is the sub-list for method input_type
-	24, // [24:24] is the sub-list for extension type_name
-	24, // [24:24] is the sub-list for extension extendee
-	0,  // [0:24] is the sub-list for field type_name
+	12, // 0: sanitizer.SanitizerRequestPayloads.set_sanitizer_output_percentage:type_name -> sanitizer.SetSanitizerTargetPercentageRequestPayload
+	13, // 1: sanitizer.SanitizerRequestPayloads.get_device_information:type_name -> sanitizer.GetSanitizerDeviceInformationRequestPayload
+	15, // 2: sanitizer.SanitizerRequestPayloads.get_status:type_name -> sanitizer.GetSanitizerStatusRequestPayload
+	17, // 3: sanitizer.SanitizerRequestPayloads.get_configuration:type_name -> sanitizer.GetSanitizerConfigurationRequestPayload
+	19, // 4: sanitizer.SanitizerRequestPayloads.set_configuration:type_name -> sanitizer.SetSanitizerConfigurationRequestPayload
+	20, // 5: sanitizer.SanitizerRequestPayloads.override_flow_sensor_type:type_name -> sanitizer.OverrideFlowSensorTypeRequestPayload
+	21, // 6: sanitizer.SanitizerRequestPayloads.get_active_errors:type_name -> sanitizer.GetSanitizerActiveErrorsRequestPayload
+	14, // 7: sanitizer.SanitizerResponsePayloads.get_device_information:type_name -> sanitizer.GetSanitizerDeviceInformationResponsePayload
+	16, // 8: sanitizer.SanitizerResponsePayloads.get_status:type_name -> sanitizer.GetSanitizerStatusResponsePayload
+	18, // 9: sanitizer.SanitizerResponsePayloads.get_configuration:type_name -> sanitizer.GetSanitizerConfigurationResponsePayload
+	22, // 10: sanitizer.SanitizerResponsePayloads.get_active_errors:type_name -> sanitizer.GetSanitizerActiveErrorsResponsePayload
+	10, // 11: sanitizer.SanitizerInfoPayloads.status:type_name -> sanitizer.SanitizerStatus
+	11, // 12: sanitizer.SanitizerInfoPayloads.configuration:type_name -> sanitizer.SanitizerConfiguration
+	26, // 13: sanitizer.CommandRequestMessage.common:type_name -> ned.CommonRequestPayloads
+	3,  // 14: sanitizer.CommandRequestMessage.sanitizer:type_name -> sanitizer.SanitizerRequestPayloads
+	27, // 15: sanitizer.CommandResponseMessage.response_code:type_name -> ned.ResponseCode
+	28, // 16: sanitizer.CommandResponseMessage.common:type_name -> ned.CommonResponsePayloads
+	4,  // 17: sanitizer.CommandResponseMessage.sanitizer:type_name -> sanitizer.SanitizerResponsePayloads
+	5,  // 18: sanitizer.InfoMessage.payload:type_name -> sanitizer.SanitizerInfoPayloads
+	1,  // 19: sanitizer.SanitizerStatus.flow_sensor_type:type_name -> sanitizer.FlowSensorType
+	0,  // 20: sanitizer.GetSanitizerDeviceInformationResponsePayload.cell_type:type_name -> sanitizer.CellType
+	10, // 21: sanitizer.GetSanitizerStatusResponsePayload.status:type_name -> sanitizer.SanitizerStatus
+	11, // 22: sanitizer.GetSanitizerConfigurationResponsePayload.configuration:type_name -> sanitizer.SanitizerConfiguration
+	11, // 23: sanitizer.SetSanitizerConfigurationRequestPayload.configuration:type_name -> sanitizer.SanitizerConfiguration
+	1,  // 24: sanitizer.OverrideFlowSensorTypeRequestPayload.flow_sensor_type:type_name -> sanitizer.FlowSensorType
+	25, // 25: sanitizer.GetSanitizerActiveErrorsResponsePayload.active_errors:type_name -> sanitizer.ActiveErrors
+	25, // 26: sanitizer.DeviceErrorMessage.active_errors:type_name -> sanitizer.ActiveErrors
+	2,  // 27: sanitizer.SanitizerError.error_code:type_name -> sanitizer.SanitizerErrorCode
+	24, // 28: sanitizer.ActiveErrors.error_list:type_name -> sanitizer.SanitizerError
+	29, // [29:29] is the sub-list for method output_type
+	29, // [29:29] is the sub-list for method input_type
+	29, // [29:29] is the sub-list for extension type_name
+	29, // [29:29] is the sub-list for extension extendee
+	0,  // [0:29] is the sub-list for field type_name
 }
 
 func init() { file_sanitizer_proto_init() }
@@ -1572,7 +1786,6 @@ func file_sanitizer_proto_init() {
 	if File_sanitizer_proto != nil {
 		return
 	}
-	file_commonClientMessages_proto_init()
 	file_sanitizer_proto_msgTypes[0].OneofWrappers = []any{
 		(*SanitizerRequestPayloads_SetSanitizerOutputPercentage)(nil),
 		(*SanitizerRequestPayloads_GetDeviceInformation)(nil),
@@ -1592,13 +1805,21 @@ func file_sanitizer_proto_init() {
 		(*SanitizerInfoPayloads_Status)(nil),
 		(*SanitizerInfoPayloads_Configuration)(nil),
 	}
+	file_sanitizer_proto_msgTypes[3].OneofWrappers = []any{
+		(*CommandRequestMessage_Common)(nil),
+		(*CommandRequestMessage_Sanitizer)(nil),
+	}
+	file_sanitizer_proto_msgTypes[4].OneofWrappers = []any{
+		(*CommandResponseMessage_Common)(nil),
+		(*CommandResponseMessage_Sanitizer)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sanitizer_proto_rawDesc), len(file_sanitizer_proto_rawDesc)),
 			NumEnums:      3,
-			NumMessages:   21,
+			NumMessages:   23,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
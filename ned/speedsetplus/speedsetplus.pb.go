@@ -1,5 +1,3 @@
-//go:build exclude_duplicates
-
 //**************************************************************
 //Protobuf Message SpeedSetPlus proto file
 //- command/response definition
@@ -17,9 +15,10 @@
 // 	protoc        v3.12.4
 // source: speedsetplus.proto
 
-package ned
+package speedsetplus
 
 import (
+	ned "NgaSim/ned"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -436,6 +435,17 @@ func (*SpeedsetPlusInfoPayloads_Configuration) isSpeedsetPlusInfoPayloads_Announ
 
 // a command wrapper message with a type field
 // MQTT topic: 'cmd/<category>/<serial number>/req'
+type CommandRequestMessage struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	CommandUuid string                 `protobuf:"bytes,1,opt,name=command_uuid,json=commandUuid,proto3" json:"command_uuid,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*CommandRequestMessage_Common
+	//	*CommandRequestMessage_Speedsetplus
+	Payload       isCommandRequestMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
 func (x *CommandRequestMessage) Reset() {
 	*x = CommandRequestMessage{}
@@ -481,7 +491,7 @@ func (x *CommandRequestMessage) GetPayload() isCommandRequestMessage_Payload {
 	return nil
 }
 
-func (x *CommandRequestMessage) GetCommon() *CommonRequestPayloads {
+func (x *CommandRequestMessage) GetCommon() *ned.CommonRequestPayloads {
 	if x != nil {
 		if x, ok := x.Payload.(*CommandRequestMessage_Common); ok {
 			return x.Common
@@ -504,7 +514,7 @@ type isCommandRequestMessage_Payload interface {
 }
 
 type CommandRequestMessage_Common struct {
-	Common *CommonRequestPayloads `protobuf:"bytes,2,opt,name=common,proto3,oneof"`
+	Common *ned.CommonRequestPayloads `protobuf:"bytes,2,opt,name=common,proto3,oneof"`
 }
 
 type CommandRequestMessage_Speedsetplus struct {
@@ -518,6 +528,19 @@ func (*CommandRequestMessage_Speedsetplus) isCommandRequestMessage_Payload() {}
 // a response wrapper message with a message type field
 // and the required response code
 // MQTT topic: 'cmd/<category>/<serial number>/res'
+type CommandResponseMessage struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	CommandUuid string                 `protobuf:"bytes,1,opt,name=command_uuid,json=commandUuid,proto3" json:"command_uuid,omitempty"`
+	// required
+	ResponseCode ned.ResponseCode `protobuf:"varint,2,opt,name=response_code,json=responseCode,proto3,enum=ned.ResponseCode" json:"response_code,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*CommandResponseMessage_Common
+	//	*CommandResponseMessage_Speedsetplus
+	Payload       isCommandResponseMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
 func (x *CommandResponseMessage) Reset() {
 	*x = CommandResponseMessage{}
@@ -556,11 +579,11 @@ func (x *CommandResponseMessage) GetCommandUuid() string {
 	return ""
 }
 
-func (x *CommandResponseMessage) GetResponseCode() ResponseCode {
+func (x *CommandResponseMessage) GetResponseCode() ned.ResponseCode {
 	if x != nil {
 		return x.ResponseCode
 	}
-	return ResponseCode_RESPONSE_UNKNOWN
+	return ned.ResponseCode_RESPONSE_UNKNOWN
 }
 
 func (x *CommandResponseMessage) GetPayload() isCommandResponseMessage_Payload {
@@ -570,7 +593,7 @@ func (x *CommandResponseMessage) GetPayload() isCommandResponseMessage_Payload {
 	return nil
 }
 
-func (x *CommandResponseMessage) GetCommon() *CommonResponsePayloads {
+func (x *CommandResponseMessage) GetCommon() *ned.CommonResponsePayloads {
 	if x != nil {
 		if x, ok := x.Payload.(*CommandResponseMessage_Common); ok {
 			return x.Common
@@ -593,7 +616,7 @@ type isCommandResponseMessage_Payload interface {
 }
 
 type CommandResponseMessage_Common struct {
-	Common *CommonResponsePayloads `protobuf:"bytes,3,opt,name=common,proto3,oneof"`
+	Common *ned.CommonResponsePayloads `protobuf:"bytes,3,opt,name=common,proto3,oneof"`
 }
 
 type CommandResponseMessage_Speedsetplus struct {
@@ -1623,9 +1646,9 @@ var file_speedsetplus_proto_goTypes = []any{
 	(*DeviceErrorMessage)(nil),                              // 20: speedsetPlus.DeviceErrorMessage
 	(*SpeedsetPlusError)(nil),                               // 21: speedsetPlus.SpeedsetPlusError
 	(*ActiveErrors)(nil),                                    // 22: speedsetPlus.ActiveErrors
-	(*CommonRequestPayloads)(nil),                           // 23: ned.CommonRequestPayloads
-	(ResponseCode)(0),                                       // 24: ned.ResponseCode
-	(*CommonResponsePayloads)(nil),                          // 25: ned.CommonResponsePayloads
+	(*ned.CommonRequestPayloads)(nil),                       // 23: ned.CommonRequestPayloads
+	(ned.ResponseCode)(0),                                   // 24: ned.ResponseCode
+	(*ned.CommonResponsePayloads)(nil),                      // 25: ned.CommonResponsePayloads
 }
 var file_speedsetplus_proto_depIdxs = []int32{
 	10, // 0: speedsetPlus.SpeedsetPlusRequestPayloads.set_vsp_control_command:type_name -> speedsetPlus.SetSpeedsetPlusControlCommandRequestPayload
@@ -1665,7 +1688,6 @@ func file_speedsetplus_proto_init() {
 	if File_speedsetplus_proto != nil {
 		return
 	}
-	file_commonClientMessages_proto_init()
 	file_speedsetplus_proto_msgTypes[0].OneofWrappers = []any{
 		(*SpeedsetPlusRequestPayloads_SetVspControlCommand)(nil),
 		(*SpeedsetPlusRequestPayloads_GetDeviceInformation)(nil),
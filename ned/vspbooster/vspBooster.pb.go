@@ -15,9 +15,10 @@
 // 	protoc        v3.12.4
 // source: vspBooster.proto
 
-package ned
+package vspbooster
 
 import (
+	ned "NgaSim/ned"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -96,6 +97,7 @@ type VspBoosterRequestPayloads struct {
 	//	*VspBoosterRequestPayloads_GetStatus
 	//	*VspBoosterRequestPayloads_GetConfiguration
 	//	*VspBoosterRequestPayloads_SetConfiguration
+	//	*VspBoosterRequestPayloads_GetActiveErrors
 	RequestType   isVspBoosterRequestPayloads_RequestType `protobuf_oneof:"request_type"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -183,6 +185,14 @@ func (x *VspBoosterRequestPayloads) GetSetConfiguration() *SetVspBoosterConfigur
 	return nil
 }
 
+func (x *VspBoosterRequestPayloads) GetGetActiveErrors() *GetVspBoosterActiveErrorsRequestPayload {
+	if x != nil {
+		if x, ok := x.RequestType.(*VspBoosterRequestPayloads_GetActiveErrors); ok {
+			return x.GetActiveErrors
+		}
+	}
+	return nil
+}
 
 type isVspBoosterRequestPayloads_RequestType interface {
 	isVspBoosterRequestPayloads_RequestType()
@@ -208,6 +218,8 @@ type VspBoosterRequestPayloads_SetConfiguration struct {
 	SetConfiguration *SetVspBoosterConfigurationRequestPayload `protobuf:"bytes,5,opt,name=set_configuration,json=setConfiguration,proto3,oneof"`
 }
 
+type VspBoosterRequestPayloads_GetActiveErrors struct {
+	GetActiveErrors *GetVspBoosterActiveErrorsRequestPayload `protobuf:"bytes,7,opt,name=get_active_errors,json=getActiveErrors,proto3,oneof"`
 }
 
 func (*VspBoosterRequestPayloads_SetVspBoosterControlCommand) isVspBoosterRequestPayloads_RequestType() {
@@ -221,6 +233,20 @@ func (*VspBoosterRequestPayloads_GetConfiguration) isVspBoosterRequestPayloads_R
 
 func (*VspBoosterRequestPayloads_SetConfiguration) isVspBoosterRequestPayloads_RequestType() {}
 
+func (*VspBoosterRequestPayloads_GetActiveErrors) isVspBoosterRequestPayloads_RequestType() {}
+
+type VspBoosterResponsePayloads struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to ResponseType:
+	//
+	//	*VspBoosterResponsePayloads_GetDeviceInformation
+	//	*VspBoosterResponsePayloads_GetStatus
+	//	*VspBoosterResponsePayloads_GetConfiguration
+	//	*VspBoosterResponsePayloads_GetActiveErrors
+	ResponseType  isVspBoosterResponsePayloads_ResponseType `protobuf_oneof:"response_type"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
 func (x *VspBoosterResponsePayloads) Reset() {
 	*x = VspBoosterResponsePayloads{}
@@ -286,6 +312,14 @@ func (x *VspBoosterResponsePayloads) GetGetConfiguration() *GetVspBoosterConfigu
 	return nil
 }
 
+func (x *VspBoosterResponsePayloads) GetGetActiveErrors() *GetVspBoosterActiveErrorsResponsePayload {
+	if x != nil {
+		if x, ok := x.ResponseType.(*VspBoosterResponsePayloads_GetActiveErrors); ok {
+			return x.GetActiveErrors
+		}
+	}
+	return nil
+}
 
 type isVspBoosterResponsePayloads_ResponseType interface {
 	isVspBoosterResponsePayloads_ResponseType()
@@ -303,6 +337,8 @@ type VspBoosterResponsePayloads_GetConfiguration struct {
 	GetConfiguration *GetVspBoosterConfigurationResponsePayload `protobuf:"bytes,3,opt,name=get_configuration,json=getConfiguration,proto3,oneof"`
 }
 
+type VspBoosterResponsePayloads_GetActiveErrors struct {
+	GetActiveErrors *GetVspBoosterActiveErrorsResponsePayload `protobuf:"bytes,4,opt,name=get_active_errors,json=getActiveErrors,proto3,oneof"`
 }
 
 func (*VspBoosterResponsePayloads_GetDeviceInformation) isVspBoosterResponsePayloads_ResponseType() {}
@@ -311,6 +347,18 @@ func (*VspBoosterResponsePayloads_GetStatus) isVspBoosterResponsePayloads_Respon
 
 func (*VspBoosterResponsePayloads_GetConfiguration) isVspBoosterResponsePayloads_ResponseType() {}
 
+func (*VspBoosterResponsePayloads_GetActiveErrors) isVspBoosterResponsePayloads_ResponseType() {}
+
+type VspBoosterInfoPayloads struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to AnnounceType:
+	//
+	//	*VspBoosterInfoPayloads_Status
+	//	*VspBoosterInfoPayloads_Configuration
+	AnnounceType  isVspBoosterInfoPayloads_AnnounceType `protobuf_oneof:"announce_type"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
 func (x *VspBoosterInfoPayloads) Reset() {
 	*x = VspBoosterInfoPayloads{}
@@ -385,36 +433,207 @@ func (*VspBoosterInfoPayloads_Configuration) isVspBoosterInfoPayloads_AnnounceTy
 
 // a command wrapper message with a type field
 // MQTT topic: 'cmd/<category>/<serial number>/req'
+type CommandRequestMessage struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	CommandUuid string                 `protobuf:"bytes,1,opt,name=command_uuid,json=commandUuid,proto3" json:"command_uuid,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*CommandRequestMessage_Common
+	//	*CommandRequestMessage_VspBooster
+	Payload       isCommandRequestMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
+func (x *CommandRequestMessage) Reset() {
+	*x = CommandRequestMessage{}
+	mi := &file_vspBooster_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
+func (x *CommandRequestMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
+func (*CommandRequestMessage) ProtoMessage() {}
 
+func (x *CommandRequestMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_vspBooster_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
 
+// Deprecated: Use CommandRequestMessage.ProtoReflect.Descriptor instead.
+func (*CommandRequestMessage) Descriptor() ([]byte, []int) {
+	return file_vspBooster_proto_rawDescGZIP(), []int{3}
+}
 
+func (x *CommandRequestMessage) GetCommandUuid() string {
+	if x != nil {
+		return x.CommandUuid
+	}
+	return ""
+}
 
+func (x *CommandRequestMessage) GetPayload() isCommandRequestMessage_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
 
+func (x *CommandRequestMessage) GetCommon() *ned.CommonRequestPayloads {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandRequestMessage_Common); ok {
+			return x.Common
+		}
+	}
+	return nil
 }
 
-	Common *CommonRequestPayloads `protobuf:"bytes,2,opt,name=common,proto3,oneof"`
+func (x *CommandRequestMessage) GetVspBooster() *VspBoosterRequestPayloads {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandRequestMessage_VspBooster); ok {
+			return x.VspBooster
+		}
+	}
+	return nil
 }
 
+type isCommandRequestMessage_Payload interface {
+	isCommandRequestMessage_Payload()
+}
+
+type CommandRequestMessage_Common struct {
+	Common *ned.CommonRequestPayloads `protobuf:"bytes,2,opt,name=common,proto3,oneof"`
+}
+
+type CommandRequestMessage_VspBooster struct {
 	VspBooster *VspBoosterRequestPayloads `protobuf:"bytes,3,opt,name=vsp_booster,json=vspBooster,proto3,oneof"`
 }
 
+func (*CommandRequestMessage_Common) isCommandRequestMessage_Payload() {}
 
+func (*CommandRequestMessage_VspBooster) isCommandRequestMessage_Payload() {}
 
+// a response wrapper message with a message type field
+// and the required response code
+// MQTT topic: 'cmd/<category>/<serial number>/res'
+type CommandResponseMessage struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	CommandUuid string                 `protobuf:"bytes,1,opt,name=command_uuid,json=commandUuid,proto3" json:"command_uuid,omitempty"`
+	// required
+	ResponseCode ned.ResponseCode `protobuf:"varint,2,opt,name=response_code,json=responseCode,proto3,enum=ned.ResponseCode" json:"response_code,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*CommandResponseMessage_Common
+	//	*CommandResponseMessage_VspBooster
+	Payload       isCommandResponseMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandResponseMessage) Reset() {
+	*x = CommandResponseMessage{}
+	mi := &file_vspBooster_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandResponseMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandResponseMessage) ProtoMessage() {}
+
+func (x *CommandResponseMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_vspBooster_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
 
+// Deprecated: Use CommandResponseMessage.ProtoReflect.Descriptor instead.
+func (*CommandResponseMessage) Descriptor() ([]byte, []int) {
+	return file_vspBooster_proto_rawDescGZIP(), []int{4}
+}
 
+func (x *CommandResponseMessage) GetCommandUuid() string {
+	if x != nil {
+		return x.CommandUuid
+	}
+	return ""
+}
 
+func (x *CommandResponseMessage) GetResponseCode() ned.ResponseCode {
+	if x != nil {
+		return x.ResponseCode
+	}
+	return ned.ResponseCode_RESPONSE_UNKNOWN
+}
 
+func (x *CommandResponseMessage) GetPayload() isCommandResponseMessage_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
 }
 
-	Common *CommonResponsePayloads `protobuf:"bytes,3,opt,name=common,proto3,oneof"`
+func (x *CommandResponseMessage) GetCommon() *ned.CommonResponsePayloads {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandResponseMessage_Common); ok {
+			return x.Common
+		}
+	}
+	return nil
 }
 
+func (x *CommandResponseMessage) GetVspBooster() *VspBoosterResponsePayloads {
+	if x != nil {
+		if x, ok := x.Payload.(*CommandResponseMessage_VspBooster); ok {
+			return x.VspBooster
+		}
+	}
+	return nil
+}
+
+type isCommandResponseMessage_Payload interface {
+	isCommandResponseMessage_Payload()
+}
+
+type CommandResponseMessage_Common struct {
+	Common *ned.CommonResponsePayloads `protobuf:"bytes,3,opt,name=common,proto3,oneof"`
+}
+
+type CommandResponseMessage_VspBooster struct {
 	VspBooster *VspBoosterResponsePayloads `protobuf:"bytes,4,opt,name=vsp_booster,json=vspBooster,proto3,oneof"`
 }
 
+func (*CommandResponseMessage_Common) isCommandResponseMessage_Payload() {}
+
+func (*CommandResponseMessage_VspBooster) isCommandResponseMessage_Payload() {}
+
+// an Info message wrapper
+// MQTT  topic: 'async/<category>/<serial number>/info'
+// Info messages can be a variety of message types
+type InfoMessage struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Payload       *VspBoosterInfoPayloads `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
 func (x *InfoMessage) Reset() {
 	*x = InfoMessage{}
@@ -1033,31 +1252,131 @@ func (x *SetVspBoosterConfigurationRequestPayload) GetConfiguration() *VspBooste
 	return nil
 }
 
+type GetVspBoosterActiveErrorsRequestPayload struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *GetVspBoosterActiveErrorsRequestPayload) Reset() {
+	*x = GetVspBoosterActiveErrorsRequestPayload{}
+	mi := &file_vspBooster_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVspBoosterActiveErrorsRequestPayload) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
+func (*GetVspBoosterActiveErrorsRequestPayload) ProtoMessage() {}
 
+func (x *GetVspBoosterActiveErrorsRequestPayload) ProtoReflect() protoreflect.Message {
+	mi := &file_vspBooster_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
 
+// Deprecated: Use GetVspBoosterActiveErrorsRequestPayload.ProtoReflect.Descriptor instead.
+func (*GetVspBoosterActiveErrorsRequestPayload) Descriptor() ([]byte, []int) {
+	return file_vspBooster_proto_rawDescGZIP(), []int{17}
+}
 
+type GetVspBoosterActiveErrorsResponsePayload struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	ActiveErrors  *ActiveErrors          `protobuf:"bytes,1,opt,name=active_errors,json=activeErrors,proto3" json:"active_errors,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *GetVspBoosterActiveErrorsResponsePayload) Reset() {
+	*x = GetVspBoosterActiveErrorsResponsePayload{}
+	mi := &file_vspBooster_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
+func (x *GetVspBoosterActiveErrorsResponsePayload) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
+func (*GetVspBoosterActiveErrorsResponsePayload) ProtoMessage() {}
 
+func (x *GetVspBoosterActiveErrorsResponsePayload) ProtoReflect() protoreflect.Message {
+	mi := &file_vspBooster_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
 
+// Deprecated: Use GetVspBoosterActiveErrorsResponsePayload.ProtoReflect.Descriptor instead.
+func (*GetVspBoosterActiveErrorsResponsePayload) Descriptor() ([]byte, []int) {
+	return file_vspBooster_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetVspBoosterActiveErrorsResponsePayload) GetActiveErrors() *ActiveErrors {
+	if x != nil {
+		return x.ActiveErrors
+	}
+	return nil
+}
 
 // a device Error message
 // MQTT topic: 'async/<category>/<serial number>/error'
+type DeviceErrorMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ActiveErrors  *ActiveErrors          `protobuf:"bytes,1,opt,name=active_errors,json=activeErrors,proto3" json:"active_errors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
+func (x *DeviceErrorMessage) Reset() {
+	*x = DeviceErrorMessage{}
+	mi := &file_vspBooster_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
+func (x *DeviceErrorMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
+func (*DeviceErrorMessage) ProtoMessage() {}
 
+func (x *DeviceErrorMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_vspBooster_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceErrorMessage.ProtoReflect.Descriptor instead.
+func (*DeviceErrorMessage) Descriptor() ([]byte, []int) {
+	return file_vspBooster_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DeviceErrorMessage) GetActiveErrors() *ActiveErrors {
+	if x != nil {
+		return x.ActiveErrors
+	}
+	return nil
+}
 
 type VspBoosterError struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1111,10 +1430,50 @@ func (x *VspBoosterError) GetErrorMessage() string {
 	return ""
 }
 
+type ActiveErrors struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// List of active errors (empty if back to normal)
+	ErrorList     []*VspBoosterError `protobuf:"bytes,1,rep,name=error_list,json=errorList,proto3" json:"error_list,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
+func (x *ActiveErrors) Reset() {
+	*x = ActiveErrors{}
+	mi := &file_vspBooster_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
+func (x *ActiveErrors) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
+func (*ActiveErrors) ProtoMessage() {}
 
+func (x *ActiveErrors) ProtoReflect() protoreflect.Message {
+	mi := &file_vspBooster_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActiveErrors.ProtoReflect.Descriptor instead.
+func (*ActiveErrors) Descriptor() ([]byte, []int) {
+	return file_vspBooster_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ActiveErrors) GetErrorList() []*VspBoosterError {
+	if x != nil {
+		return x.ErrorList
+	}
+	return nil
+}
 
 var File_vspBooster_proto protoreflect.FileDescriptor
 
@@ -1129,22 +1488,26 @@ const file_vspBooster_proto_rawDesc = "" +
 	"get_status\x18\x03 \x01(\v2-.vspBooster.GetVspBoosterStatusRequestPayloadH\x00R\tgetStatus\x12c\n" +
 	"\x11get_configuration\x18\x04 \x01(\v24.vspBooster.GetVspBoosterConfigurationRequestPayloadH\x00R\x10getConfiguration\x12c\n" +
 	"\x11set_configuration\x18\x05 \x01(\v24.vspBooster.SetVspBoosterConfigurationRequestPayloadH\x00R\x10setConfiguration\x12a\n" +
+	"\x11get_active_errors\x18\a \x01(\v23.vspBooster.GetVspBoosterActiveErrorsRequestPayloadH\x00R\x0fgetActiveErrorsB\x0e\n" +
 	"\frequest_type\"\xbb\x03\n" +
 	"\x1aVspBoosterResponsePayloads\x12q\n" +
 	"\x16get_device_information\x18\x01 \x01(\v29.vspBooster.GetVspBoosterDeviceInformationResponsePayloadH\x00R\x14getDeviceInformation\x12O\n" +
 	"\n" +
 	"get_status\x18\x02 \x01(\v2..vspBooster.GetVspBoosterStatusResponsePayloadH\x00R\tgetStatus\x12d\n" +
 	"\x11get_configuration\x18\x03 \x01(\v25.vspBooster.GetVspBoosterConfigurationResponsePayloadH\x00R\x10getConfiguration\x12b\n" +
+	"\x11get_active_errors\x18\x04 \x01(\v24.vspBooster.GetVspBoosterActiveErrorsResponsePayloadH\x00R\x0fgetActiveErrorsB\x0f\n" +
 	"\rresponse_type\"\xae\x01\n" +
 	"\x16VspBoosterInfoPayloads\x126\n" +
 	"\x06status\x18\x01 \x01(\v2\x1c.vspBooster.VspBoosterStatusH\x00R\x06status\x12K\n" +
 	"\rconfiguration\x18\x02 \x01(\v2#.vspBooster.VspBoosterConfigurationH\x00R\rconfigurationB\x0f\n" +
 	"\rannounce_type\"\xc5\x01\n" +
+	"\x15CommandRequestMessage\x12!\n" +
 	"\fcommand_uuid\x18\x01 \x01(\tR\vcommandUuid\x124\n" +
 	"\x06common\x18\x02 \x01(\v2\x1a.ned.CommonRequestPayloadsH\x00R\x06common\x12H\n" +
 	"\vvsp_booster\x18\x03 \x01(\v2%.vspBooster.VspBoosterRequestPayloadsH\x00R\n" +
 	"vspBoosterB\t\n" +
 	"\apayload\"\x80\x02\n" +
+	"\x16CommandResponseMessage\x12!\n" +
 	"\fcommand_uuid\x18\x01 \x01(\tR\vcommandUuid\x126\n" +
 	"\rresponse_code\x18\x02 \x01(\x0e2\x11.ned.ResponseCodeR\fresponseCode\x125\n" +
 	"\x06common\x18\x03 \x01(\v2\x1b.ned.CommonResponsePayloadsH\x00R\x06common\x12I\n" +
@@ -1189,10 +1552,16 @@ const file_vspBooster_proto_rawDesc = "" +
 	"\rconfiguration\x18\x01 \x01(\v2#.vspBooster.VspBoosterConfigurationR\rconfiguration\"u\n" +
 	"(SetVspBoosterConfigurationRequestPayload\x12I\n" +
 	"\rconfiguration\x18\x01 \x01(\v2#.vspBooster.VspBoosterConfigurationR\rconfiguration\")\n" +
+	"'GetVspBoosterActiveErrorsRequestPayload\"i\n" +
+	"(GetVspBoosterActiveErrorsResponsePayload\x12=\n" +
+	"\ractive_errors\x18\x01 \x01(\v2\x18.vspBooster.ActiveErrorsR\factiveErrors\"S\n" +
+	"\x12DeviceErrorMessage\x12=\n" +
+	"\ractive_errors\x18\x01 \x01(\v2\x18.vspBooster.ActiveErrorsR\factiveErrors\"v\n" +
 	"\x0fVspBoosterError\x12>\n" +
 	"\n" +
 	"error_code\x18\x01 \x01(\x0e2\x1f.vspBooster.VspBoosterErrorCodeR\terrorCode\x12#\n" +
 	"\rerror_message\x18\x02 \x01(\tR\ferrorMessage\"J\n" +
+	"\fActiveErrors\x12:\n" +
 	"\n" +
 	"error_list\x18\x01 \x03(\v2\x1b.vspBooster.VspBoosterErrorR\terrorList*\x9f\x01\n" +
 	"\x13VspBoosterErrorCode\x12\x11\n" +
@@ -1220,6 +1589,8 @@ var file_vspBooster_proto_goTypes = []any{
 	(*VspBoosterRequestPayloads)(nil),                     // 1: vspBooster.VspBoosterRequestPayloads
 	(*VspBoosterResponsePayloads)(nil),                    // 2: vspBooster.VspBoosterResponsePayloads
 	(*VspBoosterInfoPayloads)(nil),                        // 3: vspBooster.VspBoosterInfoPayloads
+	(*CommandRequestMessage)(nil),                         // 4: vspBooster.CommandRequestMessage
+	(*CommandResponseMessage)(nil),                        // 5: vspBooster.CommandResponseMessage
 	(*InfoMessage)(nil),                                   // 6: vspBooster.InfoMessage
 	(*TelemetryMessage)(nil),                              // 7: vspBooster.TelemetryMessage
 	(*VspBoosterStatus)(nil),                              // 8: vspBooster.VspBoosterStatus
@@ -1232,10 +1603,14 @@ var file_vspBooster_proto_goTypes = []any{
 	(*GetVspBoosterConfigurationRequestPayload)(nil),      // 15: vspBooster.GetVspBoosterConfigurationRequestPayload
 	(*GetVspBoosterConfigurationResponsePayload)(nil),     // 16: vspBooster.GetVspBoosterConfigurationResponsePayload
 	(*SetVspBoosterConfigurationRequestPayload)(nil),      // 17: vspBooster.SetVspBoosterConfigurationRequestPayload
+	(*GetVspBoosterActiveErrorsRequestPayload)(nil),       // 18: vspBooster.GetVspBoosterActiveErrorsRequestPayload
+	(*GetVspBoosterActiveErrorsResponsePayload)(nil),      // 19: vspBooster.GetVspBoosterActiveErrorsResponsePayload
+	(*DeviceErrorMessage)(nil),                            // 20: vspBooster.DeviceErrorMessage
 	(*VspBoosterError)(nil),                               // 21: vspBooster.VspBoosterError
-	(*CommonRequestPayloads)(nil),                         // 23: ned.CommonRequestPayloads
-	(ResponseCode)(0),                                     // 24: ned.ResponseCode
-	(*CommonResponsePayloads)(nil),                        // 25: ned.CommonResponsePayloads
+	(*ActiveErrors)(nil),                                  // 22: vspBooster.ActiveErrors
+	(*ned.CommonRequestPayloads)(nil),                     // 23: ned.CommonRequestPayloads
+	(ned.ResponseCode)(0),                                 // 24: ned.ResponseCode
+	(*ned.CommonResponsePayloads)(nil),                    // 25: ned.CommonResponsePayloads
 }
 var file_vspBooster_proto_depIdxs = []int32{
 	10, // 0: vspBooster.VspBoosterRequestPayloads.set_vsp_booster_control_command:type_name -> vspBooster.SetVspBoosterControlCommandRequestPayload
@@ -1243,16 +1618,26 @@ var file_vspBooster_proto_depIdxs = []int32{
 	13, // 2: vspBooster.VspBoosterRequestPayloads.get_status:type_name -> vspBooster.GetVspBoosterStatusRequestPayload
 	15, // 3: vspBooster.VspBoosterRequestPayloads.get_configuration:type_name -> vspBooster.GetVspBoosterConfigurationRequestPayload
 	17, // 4: vspBooster.VspBoosterRequestPayloads.set_configuration:type_name -> vspBooster.SetVspBoosterConfigurationRequestPayload
+	18, // 5: vspBooster.VspBoosterRequestPayloads.get_active_errors:type_name -> vspBooster.GetVspBoosterActiveErrorsRequestPayload
 	12, // 6: vspBooster.VspBoosterResponsePayloads.get_device_information:type_name -> vspBooster.GetVspBoosterDeviceInformationResponsePayload
 	14, // 7: vspBooster.VspBoosterResponsePayloads.get_status:type_name -> vspBooster.GetVspBoosterStatusResponsePayload
 	16, // 8: vspBooster.VspBoosterResponsePayloads.get_configuration:type_name -> vspBooster.GetVspBoosterConfigurationResponsePayload
+	19, // 9: vspBooster.VspBoosterResponsePayloads.get_active_errors:type_name -> vspBooster.GetVspBoosterActiveErrorsResponsePayload
 	8,  // 10: vspBooster.VspBoosterInfoPayloads.status:type_name -> vspBooster.VspBoosterStatus
 	9,  // 11: vspBooster.VspBoosterInfoPayloads.configuration:type_name -> vspBooster.VspBoosterConfiguration
+	23, // 12: vspBooster.CommandRequestMessage.common:type_name -> ned.CommonRequestPayloads
+	1,  // 13: vspBooster.CommandRequestMessage.vsp_booster:type_name -> vspBooster.VspBoosterRequestPayloads
+	24, // 14: vspBooster.CommandResponseMessage.response_code:type_name -> ned.ResponseCode
+	25, // 15: vspBooster.CommandResponseMessage.common:type_name -> ned.CommonResponsePayloads
+	2,  // 16: vspBooster.CommandResponseMessage.vsp_booster:type_name -> vspBooster.VspBoosterResponsePayloads
 	3,  // 17: vspBooster.InfoMessage.payload:type_name -> vspBooster.VspBoosterInfoPayloads
 	8,  // 18: vspBooster.GetVspBoosterStatusResponsePayload.status:type_name -> vspBooster.VspBoosterStatus
 	9,  // 19: vspBooster.GetVspBoosterConfigurationResponsePayload.configuration:type_name -> vspBooster.VspBoosterConfiguration
 	9,  // 20: vspBooster.SetVspBoosterConfigurationRequestPayload.configuration:type_name -> vspBooster.VspBoosterConfiguration
+	22, // 21: vspBooster.GetVspBoosterActiveErrorsResponsePayload.active_errors:type_name -> vspBooster.ActiveErrors
+	22, // 22: vspBooster.DeviceErrorMessage.active_errors:type_name -> vspBooster.ActiveErrors
 	0,  // 23: vspBooster.VspBoosterError.error_code:type_name -> vspBooster.VspBoosterErrorCode
+	21, // 24: vspBooster.ActiveErrors.error_list:type_name -> vspBooster.VspBoosterError
 	25, // [25:25] is the sub-list for method output_type
 	25, // [25:25] is the sub-list for method input_type
 	25, // [25:25] is the sub-list for extension type_name
@@ -1265,26 +1650,31 @@ func file_vspBooster_proto_init() {
 	if File_vspBooster_proto != nil {
 		return
 	}
-	file_commonClientMessages_proto_init()
 	file_vspBooster_proto_msgTypes[0].OneofWrappers = []any{
 		(*VspBoosterRequestPayloads_SetVspBoosterControlCommand)(nil),
 		(*VspBoosterRequestPayloads_GetDeviceInformation)(nil),
 		(*VspBoosterRequestPayloads_GetStatus)(nil),
 		(*VspBoosterRequestPayloads_GetConfiguration)(nil),
 		(*VspBoosterRequestPayloads_SetConfiguration)(nil),
+		(*VspBoosterRequestPayloads_GetActiveErrors)(nil),
 	}
 	file_vspBooster_proto_msgTypes[1].OneofWrappers = []any{
 		(*VspBoosterResponsePayloads_GetDeviceInformation)(nil),
 		(*VspBoosterResponsePayloads_GetStatus)(nil),
 		(*VspBoosterResponsePayloads_GetConfiguration)(nil),
+		(*VspBoosterResponsePayloads_GetActiveErrors)(nil),
 	}
 	file_vspBooster_proto_msgTypes[2].OneofWrappers = []any{
 		(*VspBoosterInfoPayloads_Status)(nil),
 		(*VspBoosterInfoPayloads_Configuration)(nil),
 	}
 	file_vspBooster_proto_msgTypes[3].OneofWrappers = []any{
+		(*CommandRequestMessage_Common)(nil),
+		(*CommandRequestMessage_VspBooster)(nil),
 	}
 	file_vspBooster_proto_msgTypes[4].OneofWrappers = []any{
+		(*CommandResponseMessage_Common)(nil),
+		(*CommandResponseMessage_VspBooster)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
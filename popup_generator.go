@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"google.golang.org/protobuf/proto"
 )
 
 // PopupUIGenerator creates dynamic UI pop-ups based on protobuf reflection
@@ -50,6 +49,7 @@ type CommandExecutionResponse struct {
 	CorrelationID string      `json:"correlation_id"`
 	Timestamp     time.Time   `json:"timestamp"`
 	MessageSent   interface{} `json:"message_sent,omitempty"`
+	Status        string      `json:"status,omitempty"` // PENDING, or QUEUED while MQTT is disconnected
 }
 
 // NewPopupUIGenerator creates a new popup UI generator
@@ -346,8 +346,8 @@ func (pug *PopupUIGenerator) ExecuteProtobufCommand(req CommandExecutionRequest)
 		}, err
 	}
 
-	// Serialize the message
-	msgBytes, err := proto.Marshal(msg)
+	// Wrap the payload in the category CommandRequestMessage and serialize it
+	envelope, msgBytes, err := marshalCommand(req.Category, commandUUID, msg)
 	if err != nil {
 		return &CommandExecutionResponse{
 			Success: false,
@@ -359,11 +359,11 @@ func (pug *PopupUIGenerator) ExecuteProtobufCommand(req CommandExecutionRequest)
 	correlationID := commandUUID
 
 	// Log to terminal
-	pug.terminalLogger.LogProtobufMessage("REQUEST", req.DeviceSerial, "OUTGOING", msg, msgBytes)
+	pug.terminalLogger.LogProtobufMessage("REQUEST", req.DeviceSerial, "OUTGOING", envelope, msgBytes)
 
-	// Send via MQTT if connected, otherwise simulate
+	// Send via MQTT (queued while the broker is unreachable), or simulate without a broker
 	var sendErr error
-	if pug.ngaSim.mqtt != nil && pug.ngaSim.mqtt.IsConnected() {
+	if pug.ngaSim.sendsToBroker() {
 		sendErr = pug.sendMQTTCommand(req.DeviceSerial, req.Category, req.MessageType, msgBytes, correlationID)
 	} else {
 		// Demo mode - simulate response
@@ -374,7 +374,10 @@ func (pug *PopupUIGenerator) ExecuteProtobufCommand(req CommandExecutionRequest)
 		Success:       sendErr == nil,
 		CorrelationID: correlationID,
 		Timestamp:     time.Now(),
-		MessageSent:   msg,
+		MessageSent:   envelope,
+	}
+	if record, exists := pug.ngaSim.commandTracker.Get(commandUUID); exists {
+		response.Status = record.Status
 	}
 
 	if sendErr != nil {
@@ -389,16 +392,17 @@ func (pug *PopupUIGenerator) ExecuteProtobufCommand(req CommandExecutionRequest)
 
 // sendMQTTCommand sends a protobuf command via MQTT
 func (pug *PopupUIGenerator) sendMQTTCommand(deviceSerial, category, messageType string, msgBytes []byte, correlationID string) error {
-	// Construct MQTT topic
-	topic := fmt.Sprintf("async/%s/%s/cmd", category, deviceSerial)
-
-	// Send via MQTT
-	token := pug.ngaSim.mqtt.Publish(topic, 1, false, msgBytes)
-	if token.Wait() && token.Error() != nil {
-		return fmt.Errorf("MQTT publish failed: %v", token.Error())
+	// Publish on cmd/category/serial/req, or hold until the broker comes back
+	queued, err := pug.ngaSim.publishCommand(deviceSerial, category, messageType, correlationID, msgBytes)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("✅ MQTT command sent: %s -> %s (correlation: %s)", deviceSerial, messageType, correlationID)
+	if queued {
+		log.Printf("⏸️ MQTT command queued: %s -> %s (correlation: %s)", deviceSerial, messageType, correlationID)
+	} else {
+		log.Printf("✅ MQTT command sent: %s -> %s (correlation: %s)", deviceSerial, messageType, correlationID)
+	}
 	return nil
 }
 
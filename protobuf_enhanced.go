@@ -190,7 +190,7 @@ func (n *NgaSim) handleProtobufCommandSubmission(w http.ResponseWriter, r *http.
 		}
 
 		// Use existing sanitizer command infrastructure - no duplicate logic!
-		err = n.sendSanitizerCommand(deviceSerial, "sanitizerGen2", percentage)
+		_, err = n.sendSanitizerCommand(deviceSerial, "sanitizerGen2", percentage)
 		if err != nil {
 			log.Printf("❌ Command failed: %v", err)
 			http.Error(w, fmt.Sprintf("Command failed: %v", err), http.StatusInternalServerError)
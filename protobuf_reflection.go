@@ -1,8 +1,12 @@
 package main
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -226,6 +230,9 @@ func (pre *ProtobufReflectionEngine) getCategoryFromName(fullName string) string
 	switch {
 	case strings.Contains(name, "sanitizer"):
 		return "sanitizerGen2"
+	case strings.HasPrefix(name, "vspbooster."):
+		// Checked before "vsp" so booster messages are not lumped in with other pumps
+		return VspBoosterCategory
 	case strings.Contains(name, "pump") || strings.Contains(name, "vsp"):
 		return "VSP"
 	case strings.Contains(name, "light") || strings.Contains(name, "icl"):
@@ -241,33 +248,58 @@ func (pre *ProtobufReflectionEngine) getCategoryFromName(fullName string) string
 	}
 }
 
-// CreateMessage creates a new message instance with default values
+// CreateMessage creates a new message instance with default values. Messages that were not
+// discovered are looked up in the global registry, so the engine works without DiscoverMessages.
 func (pre *ProtobufReflectionEngine) CreateMessage(fullName string) (proto.Message, error) {
 	pre.mutex.RLock()
 	messageType, exists := pre.messageTypes[fullName]
 	pre.mutex.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("message type not found: %s", fullName)
+		var err error
+		messageType, err = protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(fullName))
+		if err != nil {
+			return nil, fmt.Errorf("message type not found: %s", fullName)
+		}
 	}
 
 	return messageType.New().Interface(), nil
 }
 
-// PopulateMessage fills a message with values from a map
+// PopulateMessage fills a message with values from a map. Keys are proto field names (or their
+// JSON names). Nested messages take maps, repeated fields take lists and enums take a value name
+// or number. Text is converted to the field type, since form and command line values are text;
+// empty text leaves a non-string field unset.
 func (pre *ProtobufReflectionEngine) PopulateMessage(msg proto.Message, values map[string]interface{}) error {
-	reflectMsg := msg.ProtoReflect()
-	descriptor := reflectMsg.Descriptor()
+	return pre.populateMessage(msg.ProtoReflect(), values)
+}
 
+// populateMessage is PopulateMessage on a reflected message, used again for nested messages
+func (pre *ProtobufReflectionEngine) populateMessage(msg protoreflect.Message, values map[string]interface{}) error {
+	descriptor := msg.Descriptor()
 	fields := descriptor.Fields()
-	for i := 0; i < fields.Len(); i++ {
-		field := fields.Get(i)
-		fieldName := string(field.Name())
 
-		if value, exists := values[fieldName]; exists && value != nil {
-			if err := pre.setFieldValue(reflectMsg, field, value); err != nil {
-				return fmt.Errorf("failed to set field %s: %v", fieldName, err)
-			}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, fieldName := range names {
+		field := fields.ByName(protoreflect.Name(fieldName))
+		if field == nil {
+			field = fields.ByJSONName(fieldName)
+		}
+		if field == nil {
+			return fmt.Errorf("%s has no field %s (fields: %s)", descriptor.FullName(), fieldName, fieldNames(descriptor))
+		}
+
+		value := values[fieldName]
+		if value == nil || value == "" && field.Kind() != protoreflect.StringKind {
+			continue
+		}
+		if err := pre.setFieldValue(msg, field, value); err != nil {
+			return fmt.Errorf("failed to set field %s: %v", fieldName, err)
 		}
 	}
 
@@ -276,40 +308,135 @@ func (pre *ProtobufReflectionEngine) PopulateMessage(msg proto.Message, values m
 
 // setFieldValue sets a field value with type conversion
 func (pre *ProtobufReflectionEngine) setFieldValue(msg protoreflect.Message, field protoreflect.FieldDescriptor, value interface{}) error {
-	switch field.Kind() {
-	case protoreflect.BoolKind:
-		if v, ok := value.(bool); ok {
-			msg.Set(field, protoreflect.ValueOfBool(v))
+	switch {
+	case field.IsMap():
+		return fmt.Errorf("map fields are not supported")
+
+	case field.IsList():
+		if text, ok := value.(string); ok && strings.HasPrefix(text, "[") {
+			if err := json.Unmarshal([]byte(text), &value); err != nil {
+				return fmt.Errorf("invalid JSON list: %v", err)
+			}
 		}
-	case protoreflect.Int32Kind:
-		if v, ok := value.(float64); ok {
-			msg.Set(field, protoreflect.ValueOfInt32(int32(v)))
+		elements, ok := value.([]interface{})
+		if !ok {
+			elements = []interface{}{value} // A single value for a repeated field
 		}
-	case protoreflect.Int64Kind:
-		if v, ok := value.(float64); ok {
-			msg.Set(field, protoreflect.ValueOfInt64(int64(v)))
+		list := msg.Mutable(field).List()
+		for i, element := range elements {
+			if field.Kind() == protoreflect.MessageKind {
+				item := list.NewElement()
+				if err := pre.populateNested(item.Message(), element); err != nil {
+					return fmt.Errorf("[%d]: %v", i, err)
+				}
+				list.Append(item)
+				continue
+			}
+			item, err := scalarValue(field, element)
+			if err != nil {
+				return fmt.Errorf("[%d]: %v", i, err)
+			}
+			list.Append(item)
 		}
-	case protoreflect.Uint32Kind:
-		if v, ok := value.(float64); ok {
-			msg.Set(field, protoreflect.ValueOfUint32(uint32(v)))
+
+	case field.Kind() == protoreflect.MessageKind:
+		// Mutable also selects the field when it is a oneof member
+		return pre.populateNested(msg.Mutable(field).Message(), value)
+
+	default:
+		v, err := scalarValue(field, value)
+		if err != nil {
+			return err
+		}
+		msg.Set(field, v)
+	}
+
+	return nil
+}
+
+// populateNested fills a nested message from a map, or from a JSON object given as text
+func (pre *ProtobufReflectionEngine) populateNested(msg protoreflect.Message, value interface{}) error {
+	if text, ok := value.(string); ok {
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return fmt.Errorf("%s needs a JSON object: %v", msg.Descriptor().FullName(), err)
 		}
-	case protoreflect.Uint64Kind:
-		if v, ok := value.(float64); ok {
-			msg.Set(field, protoreflect.ValueOfUint64(uint64(v)))
+	}
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s needs an object, got %v", msg.Descriptor().FullName(), value)
+	}
+	return pre.populateMessage(msg, values)
+}
+
+// scalarValue converts a JSON or YAML value, or its text form, to the type of a non-message field
+func scalarValue(field protoreflect.FieldDescriptor, value interface{}) (protoreflect.Value, error) {
+	text, ok := value.(string)
+	if !ok {
+		switch v := value.(type) {
+		case float64:
+			text = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			text = strconv.FormatBool(v)
+		case int, int32, int64, uint, uint32, uint64:
+			text = fmt.Sprint(v)
+		default:
+			return protoreflect.Value{}, fmt.Errorf("unsupported value %v", value)
 		}
+	}
+
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(text)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(text, 0, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(text, 0, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(text, 0, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(text, 0, 64)
+		return protoreflect.ValueOfUint64(v), err
 	case protoreflect.FloatKind:
-		if v, ok := value.(float64); ok {
-			msg.Set(field, protoreflect.ValueOfFloat32(float32(v)))
-		}
+		v, err := strconv.ParseFloat(text, 32)
+		return protoreflect.ValueOfFloat32(float32(v)), err
 	case protoreflect.DoubleKind:
-		if v, ok := value.(float64); ok {
-			msg.Set(field, protoreflect.ValueOfFloat64(v))
-		}
+		v, err := strconv.ParseFloat(text, 64)
+		return protoreflect.ValueOfFloat64(v), err
 	case protoreflect.StringKind:
-		if v, ok := value.(string); ok {
-			msg.Set(field, protoreflect.ValueOfString(v))
+		return protoreflect.ValueOfString(text), nil
+	case protoreflect.BytesKind:
+		v, err := base64.StdEncoding.DecodeString(text)
+		return protoreflect.ValueOfBytes(v), err
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		if enumValue := values.ByName(protoreflect.Name(text)); enumValue != nil {
+			return protoreflect.ValueOfEnum(enumValue.Number()), nil
 		}
+		number, err := strconv.ParseInt(text, 0, 32)
+		if err != nil {
+			var names []string
+			for i := 0; i < values.Len(); i++ {
+				names = append(names, string(values.Get(i).Name()))
+			}
+			return protoreflect.Value{}, fmt.Errorf("unknown %s value %s (values: %s)",
+				field.Enum().FullName(), text, strings.Join(names, ", "))
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(number)), nil
 	}
 
-	return nil
+	return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", field.Kind())
+}
+
+// fieldNames lists the field names of a message for error messages
+func fieldNames(descriptor protoreflect.MessageDescriptor) string {
+	fields := descriptor.Fields()
+	names := make([]string, 0, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		names = append(names, string(fields.Get(i).Name()))
+	}
+	return strings.Join(names, ", ")
 }
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Directions of recorded frames
+const (
+	SessionInbound  = "in"  ///< Frame received from the broker
+	SessionOutbound = "out" ///< Command published by NgaSim
+)
+
+// Session file layout: one JSON header line, then one JSON line per frame
+const (
+	SessionFormat  = "ngasim-session" ///< Header format marker
+	SessionVersion = 1                ///< Header version, bumped on incompatible changes
+	SessionFileExt = ".jsonl"         ///< Extension of session files
+)
+
+// validSessionName matches session file names that can be used in URLs
+var validSessionName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// SessionHeader is the first line of a session file
+type SessionHeader struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	Site      string    `json:"site"`
+	Broker    string    `json:"broker"`
+	NgaSim    string    `json:"ngasim_version"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// SessionFrame is one MQTT message as it crossed the broker connection
+type SessionFrame struct {
+	Time        time.Time `json:"time"`
+	Direction   string    `json:"dir"`
+	Topic       string    `json:"topic"`
+	Payload     []byte    `json:"payload"`                // Base64 in the file
+	Command     string    `json:"command,omitempty"`      // Outbound only
+	CommandUUID string    `json:"command_uuid,omitempty"` // Outbound only
+}
+
+// RecordingStatus describes the recording in progress, or the last one
+type RecordingStatus struct {
+	Active    bool       `json:"active"`
+	File      string     `json:"file,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+	Inbound   int        `json:"inbound"`
+	Outbound  int        `json:"outbound"`
+	Bytes     int64      `json:"bytes"`
+	Error     string     `json:"error,omitempty"` // Write failure that ended the recording
+}
+
+// SessionInfo is a session file in the recording directory
+type SessionInfo struct {
+	Name     string         `json:"name"`
+	Size     int64          `json:"size"`
+	Modified time.Time      `json:"modified"`
+	Header   *SessionHeader `json:"header,omitempty"` // Nil when the file is not a session
+}
+
+// SessionRecorder writes every frame of a site's broker connection to a session file. Each
+// frame is written as it happens, so a capture survives NgaSim being killed.
+type SessionRecorder struct {
+	file   *os.File
+	status RecordingStatus
+	mutex  sync.Mutex
+}
+
+// NewSessionRecorder creates an idle recorder
+func NewSessionRecorder() *SessionRecorder {
+	return &SessionRecorder{}
+}
+
+// Start opens path and writes the session header
+func (r *SessionRecorder) Start(path string, header SessionHeader) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file != nil {
+		return fmt.Errorf("already recording to %s", r.status.File)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create session file: %v", err)
+	}
+
+	r.file = file
+	started := header.StartedAt
+	r.status = RecordingStatus{Active: true, File: path, StartedAt: &started}
+	if err := r.write(header); err != nil {
+		r.closeLocked(err)
+		return fmt.Errorf("failed to write session header: %v", err)
+	}
+	return nil
+}
+
+// Record appends a frame; it does nothing while no recording is active
+func (r *SessionRecorder) Record(frame SessionFrame) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return
+	}
+	if err := r.write(frame); err != nil {
+		log.Printf("❌ Recording to %s stopped: %v", r.status.File, err)
+		r.closeLocked(err)
+		return
+	}
+	if frame.Direction == SessionOutbound {
+		r.status.Outbound++
+	} else {
+		r.status.Inbound++
+	}
+}
+
+// write encodes one line and writes it straight to the file. Caller must hold the mutex.
+func (r *SessionRecorder) write(line interface{}) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	written, err := r.file.Write(append(data, '\n'))
+	r.status.Bytes += int64(written)
+	return err
+}
+
+// Stop closes the session file and returns the final status; stopping twice does nothing
+func (r *SessionRecorder) Stop() RecordingStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.file != nil {
+		r.closeLocked(nil)
+	}
+	return r.status
+}
+
+// closeLocked closes the file, keeping err in the status. Caller must hold the mutex.
+func (r *SessionRecorder) closeLocked(err error) {
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		r.status.Error = err.Error()
+	}
+	stopped := time.Now()
+	r.status.Active = false
+	r.status.StoppedAt = &stopped
+	r.file = nil
+}
+
+// Status returns the recording in progress, or the last one
+func (r *SessionRecorder) Status() RecordingStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.status
+}
+
+// recordFrame passes a frame to the site's recorder
+func (n *NgaSim) recordFrame(direction, topic string, payload []byte, command, commandUUID string) {
+	n.recorder.Record(SessionFrame{
+		Time:        time.Now(),
+		Direction:   direction,
+		Topic:       topic,
+		Payload:     payload,
+		Command:     command,
+		CommandUUID: commandUUID,
+	})
+}
+
+// startRecording starts a session file in the recording directory. An empty name is replaced by
+// the site name and the current time.
+func (n *NgaSim) startRecording(name string) (RecordingStatus, error) {
+	cfg := n.currentConfig()
+	now := time.Now()
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", n.site, now.Format("20060102-150405"))
+	}
+	if !strings.HasSuffix(name, SessionFileExt) {
+		name += SessionFileExt
+	}
+	if !validSessionName.MatchString(name) {
+		return RecordingStatus{}, fmt.Errorf("invalid session name %q", name)
+	}
+
+	path := filepath.Join(cfg.Recording.Dir, name)
+	err := n.recorder.Start(path, SessionHeader{
+		Format:    SessionFormat,
+		Version:   SessionVersion,
+		Site:      n.site,
+		Broker:    cfg.MQTT.Broker,
+		NgaSim:    NgaSimVersion,
+		StartedAt: now,
+	})
+	if err != nil {
+		return RecordingStatus{}, err
+	}
+	log.Printf("⏺️ Recording MQTT traffic to %s", path)
+	return n.recorder.Status(), nil
+}
+
+// stopRecording closes the session file, if one is open
+func (n *NgaSim) stopRecording() RecordingStatus {
+	wasActive := n.recorder.Status().Active
+	status := n.recorder.Stop()
+	if wasActive {
+		log.Printf("⏹️ Recording %s closed: %d inbound, %d outbound frames", status.File, status.Inbound, status.Outbound)
+	}
+	return status
+}
+
+// readSessionHeader reads the first line of a session file
+func readSessionHeader(path string) (*SessionHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	var header SessionHeader
+	if err := json.Unmarshal(line, &header); err != nil || header.Format != SessionFormat {
+		return nil, fmt.Errorf("%s is not an NgaSim session file", path)
+	}
+	if header.Version > SessionVersion {
+		return nil, fmt.Errorf("%s is session version %d, this NgaSim reads up to %d", path, header.Version, SessionVersion)
+	}
+	return &header, nil
+}
+
+// listSessions returns the session files in dir, newest first
+func listSessions(dir string) ([]SessionInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []SessionInfo{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]SessionInfo, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), SessionFileExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		session := SessionInfo{Name: entry.Name(), Size: info.Size(), Modified: info.ModTime()}
+		session.Header, _ = readSessionHeader(filepath.Join(dir, entry.Name()))
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Modified.After(sessions[j].Modified) })
+	return sessions, nil
+}
+
+// sessionPath resolves a session name to a file in the recording directory
+func (n *NgaSim) sessionPath(name string) (string, error) {
+	if !validSessionName.MatchString(name) || strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid session name %q", name)
+	}
+	return filepath.Join(n.currentConfig().Recording.Dir, name), nil
+}
+
+// handleRecording returns the recording status (GET), starts a recording (POST with an optional
+// {"name": ...}) or stops it (DELETE)
+func (n *NgaSim) handleRecording(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	switch r.Method {
+	case http.MethodPost:
+		var request struct {
+			Name string `json:"name"`
+		}
+		if body, err := io.ReadAll(r.Body); err != nil || (len(body) > 0 && json.Unmarshal(body, &request) != nil) {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		status, err := n.startRecording(request.Name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(status)
+
+	case http.MethodDelete:
+		json.NewEncoder(w).Encode(n.stopRecording())
+
+	default:
+		json.NewEncoder(w).Encode(n.recorder.Status())
+	}
+}
+
+// handleSessions lists recorded sessions, or downloads one by name
+func (n *NgaSim) handleSessions(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/sessions"), "/")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if name == "" {
+		sessions, err := listSessions(n.currentConfig().Recording.Dir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sessions)
+		return
+	}
+
+	path, err := n.sessionPath(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		http.Error(w, fmt.Sprintf("Session '%s' not found", name), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeFile(w, r, path)
+}
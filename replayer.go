@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Replay states
+const (
+	ReplayRunning   = "running"   ///< Frames are being fed to the message handler
+	ReplayCompleted = "completed" ///< Every frame was replayed
+	ReplayStopped   = "stopped"   ///< Stopped before the end
+)
+
+// ReplaySpeedStep is the speed setting that replays one frame per step request
+const ReplaySpeedStep = "step"
+
+// SessionReplayStatus describes a replay in progress, or the last one
+type SessionReplayStatus struct {
+	File         string        `json:"file"`
+	Header       SessionHeader `json:"header"`
+	Status       string        `json:"status"`
+	Speed        string        `json:"speed"` // Multiplier, 0 for as fast as possible, or "step"
+	Frames       int           `json:"frames"`
+	Position     int           `json:"position"` // Frames replayed so far
+	PendingSteps int           `json:"pending_steps,omitempty"`
+	SessionTime  *time.Time    `json:"session_time,omitempty"` // Capture time of the last frame replayed
+	NextFrame    *SessionFrame `json:"next_frame,omitempty"`   // Shown while stepping
+	StartedAt    time.Time     `json:"started_at"`
+	EndedAt      *time.Time    `json:"ended_at,omitempty"`
+}
+
+// SessionReplay feeds a recorded session back through a site's message handler, at the
+// recorded pace scaled by a speed multiplier, as fast as possible, or one frame at a time
+type SessionReplay struct {
+	status   SessionReplayStatus
+	frames   []SessionFrame
+	speed    float64
+	stepwise bool
+	steps    int           // Frames released by step requests and not yet replayed
+	wake     chan struct{} // Signalled when the speed changes or steps are added
+	stop     chan struct{}
+	stopping bool
+	mutex    sync.Mutex
+}
+
+// parseReplaySpeed reads a speed setting: "step", or a multiplier such as 1, 10 or 10x where
+// 0 replays as fast as possible
+func parseReplaySpeed(value string) (float64, bool, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == ReplaySpeedStep {
+		return 0, true, nil
+	}
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+	if err != nil || speed < 0 {
+		return 0, false, fmt.Errorf("invalid replay speed %q (a multiplier, 0 or %s)", value, ReplaySpeedStep)
+	}
+	return speed, false, nil
+}
+
+// loadSession reads a session file written by SessionRecorder
+func loadSession(path string) (SessionHeader, []SessionFrame, error) {
+	header, err := readSessionHeader(path)
+	if err != nil {
+		return SessionHeader{}, nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return SessionHeader{}, nil, err
+	}
+	defer file.Close()
+
+	var frames []SessionFrame
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	scanner.Scan() // Header, already read
+	for line := 2; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var frame SessionFrame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return SessionHeader{}, nil, fmt.Errorf("%s line %d: %v", path, line, err)
+		}
+		frames = append(frames, frame)
+	}
+	if err := scanner.Err(); err != nil {
+		return SessionHeader{}, nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return *header, frames, nil
+}
+
+// NewSessionReplay prepares a replay of a session file
+func NewSessionReplay(path, speed string) (*SessionReplay, error) {
+	multiplier, stepwise, err := parseReplaySpeed(speed)
+	if err != nil {
+		return nil, err
+	}
+	header, frames, err := loadSession(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionReplay{
+		status: SessionReplayStatus{
+			File:      path,
+			Header:    header,
+			Status:    ReplayRunning,
+			Frames:    len(frames),
+			StartedAt: time.Now(),
+		},
+		frames:   frames,
+		speed:    multiplier,
+		stepwise: stepwise,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// run replays every frame through deliver, then marks the replay finished
+func (r *SessionReplay) run(deliver func(SessionFrame)) {
+	for i, frame := range r.frames {
+		gap := time.Duration(0)
+		if i > 0 {
+			gap = frame.Time.Sub(r.frames[i-1].Time)
+		}
+		if !r.wait(gap) {
+			r.finish(ReplayStopped)
+			return
+		}
+
+		deliver(frame)
+
+		r.mutex.Lock()
+		r.status.Position = i + 1
+		sessionTime := frame.Time
+		r.status.SessionTime = &sessionTime
+		r.mutex.Unlock()
+	}
+	r.finish(ReplayCompleted)
+}
+
+// wait holds the next frame back for gap scaled by the speed, or until a step releases it.
+// Speed changes take effect immediately. Reports false when the replay was stopped.
+func (r *SessionReplay) wait(gap time.Duration) bool {
+	for {
+		r.mutex.Lock()
+		speed, stepwise := r.speed, r.stepwise
+		if stepwise && r.steps > 0 {
+			r.steps--
+			r.mutex.Unlock()
+			return true
+		}
+		r.mutex.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !stepwise {
+			if speed == 0 || gap <= 0 {
+				select {
+				case <-r.stop:
+					return false
+				default:
+					return true
+				}
+			}
+			timer = time.NewTimer(time.Duration(float64(gap) / speed))
+			timeout = timer.C
+		}
+
+		started := time.Now()
+		select {
+		case <-timeout:
+			return true
+		case <-r.stop:
+			stopTimer(timer)
+			return false
+		case <-r.wake:
+			stopTimer(timer)
+			// Speed changed or steps added; the part of the gap already waited out is kept
+			if !stepwise {
+				gap -= time.Duration(float64(time.Since(started)) * speed)
+			}
+		}
+	}
+}
+
+// stopTimer stops a timer that may not have been started
+func stopTimer(timer *time.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// signal wakes the replay goroutine without blocking
+func (r *SessionReplay) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Step releases count frames of a stepwise replay
+func (r *SessionReplay) Step(count int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.status.Status != ReplayRunning {
+		return fmt.Errorf("replay is %s", r.status.Status)
+	}
+	if !r.stepwise {
+		return fmt.Errorf("replay is not stepwise (speed %s)", r.speedLocked())
+	}
+	r.steps += count
+	r.signal()
+	return nil
+}
+
+// SetSpeed changes the pace of a running replay
+func (r *SessionReplay) SetSpeed(speed string) error {
+	multiplier, stepwise, err := parseReplaySpeed(speed)
+	if err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.speed, r.stepwise, r.steps = multiplier, stepwise, 0
+	r.signal()
+	return nil
+}
+
+// Stop ends the replay; frames already replayed stay applied
+func (r *SessionReplay) Stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.stopping && r.status.Status == ReplayRunning {
+		r.stopping = true
+		close(r.stop)
+	}
+}
+
+// finish records how the replay ended
+func (r *SessionReplay) finish(status string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ended := time.Now()
+	r.status.Status = status
+	r.status.EndedAt = &ended
+	log.Printf("📼 Replay of %s %s after %d of %d frames", r.status.File, status, r.status.Position, r.status.Frames)
+}
+
+// speedLocked formats the current speed. Caller must hold the mutex.
+func (r *SessionReplay) speedLocked() string {
+	if r.stepwise {
+		return ReplaySpeedStep
+	}
+	return strconv.FormatFloat(r.speed, 'g', -1, 64)
+}
+
+// Status returns a snapshot of the replay
+func (r *SessionReplay) Status() SessionReplayStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	status := r.status
+	status.Speed = r.speedLocked()
+	status.PendingSteps = r.steps
+	if r.stepwise && status.Status == ReplayRunning && status.Position < len(r.frames) {
+		next := r.frames[status.Position]
+		status.NextFrame = &next
+	}
+	return status
+}
+
+// startReplay replaces any replay of this site with a replay of path
+func (n *NgaSim) startReplay(path, speed string) (*SessionReplay, error) {
+	replay, err := NewSessionReplay(path, speed)
+	if err != nil {
+		return nil, err
+	}
+
+	n.replayMutex.Lock()
+	if n.replay != nil {
+		n.replay.Stop()
+	}
+	n.replay = replay
+	n.replayMutex.Unlock()
+
+	header := replay.status.Header
+	log.Printf("📼 Replaying %s: %d frames recorded on site %q from %s at speed %s",
+		path, len(replay.frames), header.Site, header.StartedAt.Format(time.RFC3339), replay.Status().Speed)
+	go replay.run(n.replayFrame)
+	return replay, nil
+}
+
+// currentReplay returns the site's replay, nil if none was started
+func (n *NgaSim) currentReplay() *SessionReplay {
+	n.replayMutex.Lock()
+	defer n.replayMutex.Unlock()
+	return n.replay
+}
+
+// replayFrame applies one recorded frame: inbound frames go through the message handler as if
+// the broker had delivered them, outbound commands are tracked so the recorded responses
+// correlate with them
+func (n *NgaSim) replayFrame(frame SessionFrame) {
+	if frame.Direction != SessionOutbound {
+		n.handleMessage(frame.Topic, frame.Payload)
+		return
+	}
+
+	category, serial, _, ok := splitDeviceTopic(frame.Topic)
+	if !ok || frame.CommandUUID == "" {
+		log.Printf("⚠️ Replay: skipping outbound frame on %s", frame.Topic)
+		return
+	}
+	n.recordCommand(serial, category, frame.Command, frame.CommandUUID, frame.Payload)
+	n.addDeviceTerminalEntry(serial, "REPLAY",
+		fmt.Sprintf("📼 Recorded command %s (UUID: %s)", frame.Command, frame.CommandUUID), frame.Payload)
+}
+
+// handleReplay returns the replay status (GET), starts a replay of a recorded session (POST
+// {"session": name, "speed": "10"}) or stops it (DELETE)
+func (n *NgaSim) handleReplay(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	switch r.Method {
+	case http.MethodPost:
+		request := struct {
+			Session string `json:"session"`
+			Speed   string `json:"speed"`
+		}{Speed: n.currentConfig().Replay.Speed}
+		body, err := io.ReadAll(r.Body)
+		if err != nil || json.Unmarshal(body, &request) != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		path, err := n.sessionPath(request.Session)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		replay, err := n.startReplay(path, request.Speed)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(replay.Status())
+
+	case http.MethodDelete:
+		replay := n.currentReplay()
+		if replay == nil {
+			http.Error(w, "No replay has been started", http.StatusNotFound)
+			return
+		}
+		replay.Stop()
+		json.NewEncoder(w).Encode(replay.Status())
+
+	default:
+		replay := n.currentReplay()
+		if replay == nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "idle"})
+			return
+		}
+		json.NewEncoder(w).Encode(replay.Status())
+	}
+}
+
+// handleReplayControl steps a stepwise replay (POST /api/replay/step {"count": n}) or changes
+// its speed (POST /api/replay/speed {"speed": "step"})
+func (n *NgaSim) handleReplayControl(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	replay := n.currentReplay()
+	if replay == nil {
+		http.Error(w, "No replay has been started", http.StatusNotFound)
+		return
+	}
+
+	request := struct {
+		Count int    `json:"count"`
+		Speed string `json:"speed"`
+	}{Count: 1}
+	if body, err := io.ReadAll(r.Body); err != nil || (len(body) > 0 && json.Unmarshal(body, &request) != nil) {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	switch strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/replay"), "/") {
+	case "step":
+		if request.Count < 1 {
+			http.Error(w, "count must be at least 1", http.StatusBadRequest)
+			return
+		}
+		err = replay.Step(request.Count)
+	case "speed":
+		err = replay.SetSpeed(request.Speed)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	json.NewEncoder(w).Encode(replay.Status())
+}
@@ -180,7 +180,7 @@ func (sc *SanitizerController) setPowerLevel(device *SanitizerState, percentage
 
 	// Send actual MQTT command
 	category := "sanitizerGen2" // Default, should come from device registry
-	err := sc.ngaSim.sendSanitizerCommand(device.Serial, category, int(percentage))
+	_, err := sc.ngaSim.sendSanitizerCommand(device.Serial, category, int(percentage))
 	if err != nil {
 		device.ErrorCount++
 		device.Status = "ERROR"
@@ -0,0 +1,545 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v2"
+)
+
+// Scenario run states reported by /api/scenario-runs
+const (
+	ScenarioRunning   = "running"   ///< Working through its steps, or waiting for timed faults to run out
+	ScenarioCompleted = "completed" ///< Every step done and every fault it injected cleared
+	ScenarioStopped   = "stopped"   ///< Stopped from the API; its faults were cleared
+	ScenarioFailed    = "failed"    ///< A step could not be carried out; its faults were cleared
+)
+
+// ScenarioRunHistory is how many finished runs /api/scenario-runs keeps
+const ScenarioRunHistory = 100
+
+// ScenarioStep is one step of a fault-injection scenario
+type ScenarioStep struct {
+	Type         string                 `json:"type" yaml:"type"`                             // "inject", "clear", "wait"
+	DeviceID     string                 `json:"device_id,omitempty" yaml:"device_id"`         // Virtual device serial, e.g. SIMSAN001
+	Category     string                 `json:"category,omitempty" yaml:"category"`           // Or every virtual device of a category
+	Fault        string                 `json:"fault,omitempty" yaml:"fault"`                 // See simulatorFaults; clear without one clears all
+	Parameters   map[string]interface{} `json:"parameters,omitempty" yaml:"parameters"`       // Fault parameters, e.g. ppm: 2200
+	Duration     string                 `json:"duration,omitempty" yaml:"duration"`           // inject: clear automatically after, e.g. "30s"
+	WaitDuration string                 `json:"wait_duration,omitempty" yaml:"wait_duration"` // wait: e.g. "5s", "1m"
+	Description  string                 `json:"description,omitempty" yaml:"description"`     // Shown in the run's event log
+}
+
+// Scenario is a timed sequence of faults driven through virtual devices
+type Scenario struct {
+	ID          string         `json:"id" yaml:"id"`
+	Name        string         `json:"name" yaml:"name"`
+	Description string         `json:"description" yaml:"description"`
+	Tags        []string       `json:"tags" yaml:"tags"`
+	Steps       []ScenarioStep `json:"steps" yaml:"steps"`
+}
+
+// ScenarioEvent is one entry in a run's event log
+type ScenarioEvent struct {
+	Time    time.Time `json:"time"`
+	Step    int       `json:"step"`
+	Message string    `json:"message"`
+}
+
+// ScenarioRun is one execution of a scenario
+type ScenarioRun struct {
+	ID         string          `json:"id"`
+	ScenarioID string          `json:"scenario_id"`
+	Name       string          `json:"name"`
+	Status     string          `json:"status"`
+	Step       int             `json:"step"` // Index of the step being carried out
+	StartedAt  time.Time       `json:"started_at"`
+	EndedAt    *time.Time      `json:"ended_at,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Events     []ScenarioEvent `json:"events"`
+
+	scenario *Scenario
+	stop     chan struct{} // Closed by requestStop
+	stopping bool
+}
+
+// loadScenarios reads scenario files (YAML or JSON lists, like job files) in order
+func loadScenarios(files []string) ([]*Scenario, error) {
+	var scenarios []*Scenario
+	seen := make(map[string]string)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading scenario file: %v", err)
+		}
+
+		var parsed []*Scenario
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			if err := yaml.Unmarshal(data, &parsed); err != nil {
+				return nil, fmt.Errorf("error parsing scenario file %s (tried JSON and YAML): %v", file, err)
+			}
+		}
+
+		for _, scenario := range parsed {
+			if err := scenario.validate(); err != nil {
+				return nil, fmt.Errorf("%s: scenario %q: %v", file, scenario.ID, err)
+			}
+			if previous, exists := seen[scenario.ID]; exists {
+				return nil, fmt.Errorf("%s: scenario %q already defined in %s", file, scenario.ID, previous)
+			}
+			seen[scenario.ID] = file
+			scenarios = append(scenarios, scenario)
+		}
+	}
+	return scenarios, nil
+}
+
+// validate checks a scenario before it is run
+func (sc *Scenario) validate() error {
+	if sc.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if len(sc.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+	for i, step := range sc.Steps {
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("step %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validate checks one step
+func (step ScenarioStep) validate() error {
+	switch step.Type {
+	case "wait":
+		d, err := time.ParseDuration(step.WaitDuration)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("wait needs a positive wait_duration, got %q", step.WaitDuration)
+		}
+		return nil
+
+	case "inject", "clear":
+		if (step.DeviceID == "") == (step.Category == "") {
+			return fmt.Errorf("%s needs exactly one of device_id and category", step.Type)
+		}
+		if step.Type == "inject" && step.Fault == "" {
+			return fmt.Errorf("inject needs a fault")
+		}
+		if _, known := simulatorFaults[step.Fault]; step.Fault != "" && !known {
+			return fmt.Errorf("unknown fault %q", step.Fault)
+		}
+		if step.Duration != "" {
+			if d, err := time.ParseDuration(step.Duration); err != nil || d <= 0 {
+				return fmt.Errorf("bad duration %q", step.Duration)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown step type %q (inject, clear, wait)", step.Type)
+}
+
+// targets returns the virtual devices a step applies to. A device_id without the site suffix
+// matches too, so one scenario file serves every site.
+func (s *Simulator) targets(step ScenarioStep) ([]*VirtualDevice, error) {
+	var devices []*VirtualDevice
+	for _, device := range s.devices {
+		if device.Serial == step.DeviceID || device.Serial == step.DeviceID+s.serialSuffix ||
+			device.Category == step.Category {
+			devices = append(devices, device)
+		}
+	}
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("no virtual device matches device_id %q category %q", step.DeviceID, step.Category)
+	}
+	return devices, nil
+}
+
+// StartScenario validates a scenario against the running devices and starts it
+func (s *Simulator) StartScenario(scenario *Scenario) (*ScenarioRun, error) {
+	if err := scenario.validate(); err != nil {
+		return nil, err
+	}
+	for i, step := range scenario.Steps {
+		if step.Type == "wait" {
+			continue
+		}
+		if _, err := s.targets(step); err != nil {
+			return nil, fmt.Errorf("step %d: %v", i+1, err)
+		}
+	}
+
+	run := &ScenarioRun{
+		ID:         uuid.New().String(),
+		ScenarioID: scenario.ID,
+		Name:       scenario.Name,
+		Status:     ScenarioRunning,
+		StartedAt:  time.Now(),
+		Events:     []ScenarioEvent{},
+		scenario:   scenario,
+		stop:       make(chan struct{}),
+	}
+
+	s.mutex.Lock()
+	s.runs[run.ID] = run
+	s.runOrder = append(s.runOrder, run.ID)
+	s.trimRuns()
+	s.mutex.Unlock()
+
+	log.Printf("🎬 Scenario %s started (run %s)", scenario.ID, run.ID)
+	go s.execute(run)
+	return run, nil
+}
+
+// trimRuns forgets the oldest finished runs beyond ScenarioRunHistory. Caller must hold the
+// simulator mutex.
+func (s *Simulator) trimRuns() {
+	for len(s.runOrder) > ScenarioRunHistory {
+		trimmed := false
+		for i, id := range s.runOrder {
+			if s.runs[id].Status != ScenarioRunning {
+				delete(s.runs, id)
+				s.runOrder = append(s.runOrder[:i], s.runOrder[i+1:]...)
+				trimmed = true
+				break
+			}
+		}
+		if !trimmed {
+			return
+		}
+	}
+}
+
+// StopScenario stops a running scenario; its faults are cleared
+func (s *Simulator) StopScenario(runID string) (ScenarioRun, error) {
+	s.mutex.Lock()
+	run, exists := s.runs[runID]
+	s.mutex.Unlock()
+	if !exists {
+		return ScenarioRun{}, fmt.Errorf("scenario run '%s' not found", runID)
+	}
+	s.mutex.Lock()
+	s.requestStop(run)
+	s.mutex.Unlock()
+	return s.snapshot(run), nil
+}
+
+// requestStop tells a run to stop. Caller must hold the simulator mutex.
+func (s *Simulator) requestStop(run *ScenarioRun) {
+	if !run.stopping {
+		run.stopping = true
+		close(run.stop)
+	}
+}
+
+// event appends to a run's event log
+func (s *Simulator) event(run *ScenarioRun, step int, format string, args ...interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	run.Step = step
+	run.Events = append(run.Events, ScenarioEvent{Time: time.Now(), Step: step + 1, Message: fmt.Sprintf(format, args...)})
+}
+
+// execute carries out a run's steps, then waits for its timed faults to run out and clears
+// whatever it left behind, so every run ends with the devices back to normal
+func (s *Simulator) execute(run *ScenarioRun) {
+	status, failure := ScenarioCompleted, ""
+
+	for i, step := range run.scenario.Steps {
+		if step.Description != "" {
+			s.event(run, i, "%s", step.Description)
+		}
+		if err := s.executeStep(run, i, step); err != nil {
+			if err == errScenarioStopped {
+				status = ScenarioStopped
+			} else {
+				status, failure = ScenarioFailed, err.Error()
+				s.event(run, i, "❌ %v", err)
+			}
+			break
+		}
+	}
+
+	if status == ScenarioCompleted {
+		if until := s.lastTimedFault(run.ID); !until.IsZero() {
+			s.event(run, len(run.scenario.Steps)-1, "waiting for timed faults to clear")
+			select {
+			case <-time.After(time.Until(until) + SimulatorStepInterval):
+			case <-run.stop:
+				status = ScenarioStopped
+			}
+		}
+	}
+
+	for _, device := range s.devices {
+		device.clearRunFaults(run.ID)
+	}
+
+	s.mutex.Lock()
+	now := time.Now()
+	run.Status, run.Error, run.EndedAt = status, failure, &now
+	run.Events = append(run.Events, ScenarioEvent{Time: now, Step: run.Step + 1, Message: "scenario " + status})
+	s.mutex.Unlock()
+	log.Printf("🎬 Scenario %s %s (run %s)", run.ScenarioID, status, run.ID)
+}
+
+// errScenarioStopped ends a run that was stopped from the API
+var errScenarioStopped = fmt.Errorf("scenario stopped")
+
+// executeStep carries out one step
+func (s *Simulator) executeStep(run *ScenarioRun, index int, step ScenarioStep) error {
+	switch step.Type {
+	case "wait":
+		wait, _ := time.ParseDuration(step.WaitDuration)
+		s.event(run, index, "wait %v", wait)
+		select {
+		case <-time.After(wait):
+			return nil
+		case <-run.stop:
+			return errScenarioStopped
+		}
+
+	case "inject":
+		var duration time.Duration
+		if step.Duration != "" {
+			duration, _ = time.ParseDuration(step.Duration)
+		}
+		devices, err := s.targets(step)
+		if err != nil {
+			return err
+		}
+		for _, device := range devices {
+			if err := device.injectFault(step.Fault, faultParams(step.Parameters), duration, run.ID); err != nil {
+				return fmt.Errorf("%s: %v", device.Serial, err)
+			}
+			if duration > 0 {
+				s.event(run, index, "%s: %s injected for %v", device.Serial, step.Fault, duration)
+			} else {
+				s.event(run, index, "%s: %s injected", device.Serial, step.Fault)
+			}
+		}
+
+	case "clear":
+		devices, err := s.targets(step)
+		if err != nil {
+			return err
+		}
+		for _, device := range devices {
+			if step.Fault == "" {
+				device.clearRunFaults(run.ID)
+				s.event(run, index, "%s: faults cleared", device.Serial)
+			} else {
+				device.clearFault(step.Fault)
+				s.event(run, index, "%s: %s cleared", device.Serial, step.Fault)
+			}
+		}
+	}
+
+	select {
+	case <-run.stop:
+		return errScenarioStopped
+	default:
+		return nil
+	}
+}
+
+// lastTimedFault returns when the last fault with a duration injected by a run runs out
+func (s *Simulator) lastTimedFault(runID string) time.Time {
+	var last time.Time
+	for _, device := range s.devices {
+		device.mutex.Lock()
+		for _, fault := range device.faults {
+			if fault.RunID == runID && fault.Until != nil && fault.Until.After(last) {
+				last = *fault.Until
+			}
+		}
+		device.mutex.Unlock()
+	}
+	return last
+}
+
+// snapshot copies a run for the API
+func (s *Simulator) snapshot(run *ScenarioRun) ScenarioRun {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return ScenarioRun{
+		ID:         run.ID,
+		ScenarioID: run.ScenarioID,
+		Name:       run.Name,
+		Status:     run.Status,
+		Step:       run.Step,
+		StartedAt:  run.StartedAt,
+		EndedAt:    run.EndedAt,
+		Error:      run.Error,
+		Events:     append([]ScenarioEvent{}, run.Events...),
+	}
+}
+
+// ScenarioRuns lists runs, newest first
+func (s *Simulator) ScenarioRuns() []ScenarioRun {
+	s.mutex.Lock()
+	runs := make([]*ScenarioRun, 0, len(s.runOrder))
+	for _, id := range s.runOrder {
+		runs = append(runs, s.runs[id])
+	}
+	s.mutex.Unlock()
+
+	snapshots := make([]ScenarioRun, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		snapshots = append(snapshots, s.snapshot(runs[i]))
+	}
+	return snapshots
+}
+
+// ScenarioRun returns one run
+func (s *Simulator) ScenarioRun(runID string) (ScenarioRun, bool) {
+	s.mutex.Lock()
+	run, exists := s.runs[runID]
+	s.mutex.Unlock()
+	if !exists {
+		return ScenarioRun{}, false
+	}
+	return s.snapshot(run), true
+}
+
+// stopScenarios stops every running scenario
+func (s *Simulator) stopScenarios() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, run := range s.runs {
+		s.requestStop(run)
+	}
+}
+
+// handleScenarios lists the scenarios in simulator.scenarios (GET) or starts one (POST).
+// POST takes {"scenario": "<id>"} for a scenario from the files, or {"definition": {...}}
+// for one sent inline.
+func (n *NgaSim) handleScenarios(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	scenarios, loadErr := loadScenarios(n.currentConfig().Simulator.Scenarios)
+
+	if r.Method != http.MethodPost {
+		if scenarios == nil {
+			scenarios = []*Scenario{}
+		}
+		response := map[string]interface{}{
+			"scenarios": scenarios,
+			"faults":    simulatorFaults,
+		}
+		if loadErr != nil {
+			response["error"] = loadErr.Error()
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if n.simulator == nil {
+		http.Error(w, "No virtual devices are running (simulator.devices is empty)", http.StatusConflict)
+		return
+	}
+
+	var request struct {
+		Scenario   string    `json:"scenario"`
+		Definition *Scenario `json:"definition"`
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil || json.Unmarshal(body, &request) != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	scenario := request.Definition
+	if scenario == nil {
+		if loadErr != nil {
+			http.Error(w, loadErr.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, candidate := range scenarios {
+			if candidate.ID == request.Scenario {
+				scenario = candidate
+			}
+		}
+		if scenario == nil {
+			http.Error(w, fmt.Sprintf("Scenario '%s' not found", request.Scenario), http.StatusNotFound)
+			return
+		}
+	}
+
+	run, err := n.simulator.StartScenario(scenario)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(n.simulator.snapshot(run))
+}
+
+// handleScenarioRuns lists runs, returns one by ID, or stops one with DELETE
+func (n *NgaSim) handleScenarioRuns(w http.ResponseWriter, r *http.Request) {
+	runID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/scenario-runs"), "/")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if n.simulator == nil {
+		if runID == "" {
+			json.NewEncoder(w).Encode([]ScenarioRun{})
+			return
+		}
+		http.Error(w, fmt.Sprintf("Scenario run '%s' not found", runID), http.StatusNotFound)
+		return
+	}
+
+	if runID == "" {
+		runs := n.simulator.ScenarioRuns()
+		if status := r.URL.Query().Get("status"); status != "" {
+			filtered := make([]ScenarioRun, 0)
+			for _, run := range runs {
+				if strings.EqualFold(run.Status, status) {
+					filtered = append(filtered, run)
+				}
+			}
+			runs = filtered
+		}
+		json.NewEncoder(w).Encode(runs)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		run, err := n.simulator.StopScenario(runID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(run)
+		return
+	}
+
+	run, exists := n.simulator.ScenarioRun(runID)
+	if !exists {
+		http.Error(w, fmt.Sprintf("Scenario run '%s' not found", runID), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(run)
+}
+
+// scenarioIDs returns the IDs of scenarios, sorted, for log messages
+func scenarioIDs(scenarios []*Scenario) []string {
+	ids := make([]string, 0, len(scenarios))
+	for _, scenario := range scenarios {
+		ids = append(ids, scenario.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
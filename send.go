@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"NgaSim/ned"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// SendMode is the first argument that runs ngasim as a one-shot command sender
+const SendMode = "send"
+
+// messageCategories is the device category a message is sent to when -category is not given,
+// by proto package. Common ned messages go to any category, so they need -category.
+var messageCategories = map[protoreflect.FullName]string{
+	"sanitizer":    "sanitizerGen2",
+	"speedsetPlus": "speedsetPlusGen2",
+	"icl":          DctCategory,
+	"vspBooster":   VspBoosterCategory,
+}
+
+// formatSendMessage renders a message as indented protojson, with enum names. protojson varies
+// its own spacing between runs, so the output is re-indented to stay stable for scripts.
+func formatSendMessage(msg proto.Message) string {
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return fmt.Sprintf("(cannot format %s: %v)", msg.ProtoReflect().Descriptor().FullName(), err)
+	}
+	var indented bytes.Buffer
+	json.Indent(&indented, data, "", "  ")
+	return indented.String()
+}
+
+func sendUsage(fs *flag.FlagSet) func() {
+	return func() {
+		out := fs.Output()
+		fmt.Fprintln(out, "Usage: ngasim send -serial SERIAL -message PACKAGE.MESSAGE [-category CATEGORY] [-json PROTOJSON|@FILE] [-wait 5s] [field=value ...]")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "Builds any registered protobuf message, publishes it to one device in its category")
+		fmt.Fprintln(out, "envelope and prints the correlated response. Nested fields use dots, list items an index:")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "  ngasim send -serial 1234 -message sanitizer.SetSanitizerTargetPercentageRequestPayload target_percentage=50")
+		fmt.Fprintln(out, "  ngasim send -serial 5678 -message icl.SetLightConfigurationRequest \\")
+		fmt.Fprintln(out, "      light_patch.0.address=1 light_patch.0.fields.0.control_type=LIGHT_CONTROL_ON")
+		fmt.Fprintln(out, "  ngasim send -serial 1234 -category sanitizerGen2 -message ned.FindMeCmdRequestPayload -wait 0")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "Broker, credentials and topic prefixes come from the usual configuration:")
+		fs.PrintDefaults()
+	}
+}
+
+// runSend is ngasim send: it builds one command from the command line, publishes it and, unless
+// -wait is 0, waits for the device's response
+func runSend(args []string) error {
+	fs := flag.NewFlagSet("ngasim send", flag.ContinueOnError)
+	serial := fs.String("serial", "", "device serial number")
+	category := fs.String("category", "", "device category (default from the message package)")
+	messageName := fs.String("message", "", "fully qualified message name, e.g. sanitizer.SetSanitizerTargetPercentageRequestPayload")
+	jsonFields := fs.String("json", "", "message fields as protojson, or @file to read them from a file")
+	wait := fs.Duration("wait", 5*time.Second, "how long to wait for the response (0 publishes without waiting)")
+	site := fs.String("site", "", "site whose broker to use when several are configured")
+	fs.Usage = sendUsage(fs)
+
+	cfg, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	if *serial == "" || *messageName == "" {
+		fs.Usage()
+		return fmt.Errorf("-serial and -message are required")
+	}
+	if *site != "" {
+		if cfg, err = cfg.forSite(*site); err != nil {
+			return err
+		}
+	}
+
+	payload, err := buildSendPayload(*messageName, *jsonFields, fs.Args())
+	if err != nil {
+		return err
+	}
+	if *category == "" {
+		*category = messageCategories[payload.ProtoReflect().Descriptor().ParentFile().Package()]
+		if *category == "" {
+			return fmt.Errorf("-category is required for %s", *messageName)
+		}
+	}
+
+	commandUUID := uuid.New().String()
+	envelope, msgBytes, err := marshalCommand(*category, commandUUID, payload)
+	if err != nil {
+		return err
+	}
+
+	mqttConfig := cfg.MQTT
+	requestTopic := mqttConfig.CommandTopic(fmt.Sprintf(TopicCommandFormat, *category, *serial))
+	responseTopic := mqttConfig.CommandTopic(fmt.Sprintf(TopicCommandResponseFormat, *category, *serial))
+
+	client, err := connectSendClient(mqttConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(250)
+
+	// Subscribe before publishing so a fast device cannot answer unseen
+	responses := make(chan []byte, 16)
+	if *wait > 0 {
+		token := client.Subscribe(responseTopic, 1, func(_ mqtt.Client, msg mqtt.Message) {
+			responses <- msg.Payload()
+		})
+		if token.Wait() && token.Error() != nil {
+			return fmt.Errorf("failed to subscribe to %s: %v", responseTopic, token.Error())
+		}
+	}
+
+	log.Printf("📤 %s → %s (%d bytes)", envelope.ProtoReflect().Descriptor().FullName(), requestTopic, len(msgBytes))
+	fmt.Println(formatSendMessage(envelope))
+	token := client.Publish(requestTopic, 1, false, msgBytes)
+	if token.Wait() && token.Error() != nil {
+		return fmt.Errorf("failed to publish to %s: %v", requestTopic, token.Error())
+	}
+	if *wait <= 0 {
+		log.Printf("✅ Published command %s", commandUUID)
+		return nil
+	}
+
+	return awaitSendResponse(*category, commandUUID, responses, *wait)
+}
+
+// buildSendPayload creates the named message and fills it from protojson, then field=value
+// parameters, so parameters can override a JSON template
+func buildSendPayload(messageName, jsonFields string, params []string) (proto.Message, error) {
+	engine := NewProtobufReflectionEngine()
+	payload, err := engine.CreateMessage(messageName)
+	if err != nil {
+		return nil, fmt.Errorf("%v%s", err, similarMessages(messageName))
+	}
+
+	if jsonFields != "" {
+		data := []byte(jsonFields)
+		if strings.HasPrefix(jsonFields, "@") {
+			if data, err = os.ReadFile(jsonFields[1:]); err != nil {
+				return nil, fmt.Errorf("failed to read %s: %v", jsonFields[1:], err)
+			}
+		}
+		if err := protojson.Unmarshal(data, payload); err != nil {
+			return nil, fmt.Errorf("invalid -json for %s: %v", messageName, err)
+		}
+	}
+
+	values, err := parseSendParams(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := engine.PopulateMessage(payload, values); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// parseSendParams turns field=value arguments into the nested maps PopulateMessage takes. Dots
+// separate nested fields and numeric segments index lists, e.g. light_patch.0.address=1.
+func parseSendParams(params []string) (map[string]interface{}, error) {
+	var values interface{} = map[string]interface{}{}
+	for _, param := range params {
+		if strings.HasPrefix(param, "-") {
+			return nil, fmt.Errorf("flag %s must come before the field=value parameters", param)
+		}
+		path, value, ok := strings.Cut(param, "=")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid parameter %q, expected field=value", param)
+		}
+		var err error
+		if values, err = setSendParam(values, strings.Split(path, "."), value); err != nil {
+			return nil, fmt.Errorf("invalid parameter %q: %v", param, err)
+		}
+	}
+	return values.(map[string]interface{}), nil
+}
+
+// setSendParam places value at path below node, creating objects, and lists for numeric segments
+func setSendParam(node interface{}, path []string, value string) (interface{}, error) {
+	if len(path) == 0 {
+		if node != nil {
+			return nil, fmt.Errorf("set twice")
+		}
+		return value, nil
+	}
+
+	if index, err := strconv.Atoi(path[0]); err == nil {
+		list, isList := node.([]interface{})
+		if node != nil && !isList {
+			return nil, fmt.Errorf("%s is not a list index here", path[0])
+		}
+		if index < 0 || index > len(list) {
+			return nil, fmt.Errorf("list index %d skips items, the next is %d", index, len(list))
+		}
+		if index == len(list) {
+			list = append(list, nil)
+		}
+		child, err := setSendParam(list[index], path[1:], value)
+		list[index] = child
+		return list, err
+	}
+
+	object, isObject := node.(map[string]interface{})
+	if node != nil && !isObject {
+		return nil, fmt.Errorf("%s needs an object here", path[0])
+	}
+	if object == nil {
+		object = make(map[string]interface{})
+	}
+	child, err := setSendParam(object[path[0]], path[1:], value)
+	object[path[0]] = child
+	return object, err
+}
+
+// similarMessages suggests registered messages whose name ends like an unknown one
+func similarMessages(messageName string) string {
+	short := messageName[strings.LastIndex(messageName, ".")+1:]
+	var matches []string
+	protoregistry.GlobalTypes.RangeMessages(func(messageType protoreflect.MessageType) bool {
+		if name := messageType.Descriptor().FullName(); strings.EqualFold(string(name.Name()), short) {
+			matches = append(matches, string(name))
+		}
+		return true
+	})
+	if len(matches) == 0 {
+		return ""
+	}
+	return " (did you mean " + strings.Join(matches, " or ") + "?)"
+}
+
+// connectSendClient connects a short-lived client to the configured broker. Its client ID is
+// derived from the configured one so a running NgaSim on the same broker is not disconnected.
+func connectSendClient(c MQTTConfig) (mqtt.Client, error) {
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(c.Broker)
+	opts.SetClientID(fmt.Sprintf("%s-send-%d", c.ClientID, os.Getpid()))
+	opts.SetCleanSession(true)
+	opts.SetConnectTimeout(10 * time.Second)
+
+	if isTLSBroker(c.Broker) {
+		tlsConfig, err := buildTLSConfig(c.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+	username, password, err := mqttCredentials(c)
+	if err != nil {
+		return nil, err
+	}
+	opts.SetUsername(username)
+	opts.SetPassword(password)
+
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		return nil, fmt.Errorf("failed to connect to MQTT broker %s: %v", c.Broker, token.Error())
+	}
+	return client, nil
+}
+
+// awaitSendResponse prints the response correlated with commandUUID. A response code other than
+// OK, or no response in time, is an error.
+func awaitSendResponse(category, commandUUID string, responses <-chan []byte, wait time.Duration) error {
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	start := time.Now()
+
+	for {
+		select {
+		case payload := <-responses:
+			responseUUID, code, _, err := decodeCommandResponse(category, payload)
+			if err != nil {
+				log.Printf("⚠️ %v", err)
+				continue
+			}
+			if responseUUID != commandUUID {
+				log.Printf("⏭️ Ignoring response to another command (%s)", responseUUID)
+				continue
+			}
+
+			response := newCommandResponse(category)
+			proto.Unmarshal(payload, response)
+			log.Printf("📥 %s after %v", responseCodeName(code), time.Since(start).Round(time.Millisecond))
+			fmt.Println(formatSendMessage(response))
+			if code != ned.ResponseCode_RESPONSE_OK {
+				return fmt.Errorf("device answered %s", responseCodeName(code))
+			}
+			return nil
+
+		case <-timeout.C:
+			return fmt.Errorf("no response to command %s within %v", commandUUID, wait)
+		}
+	}
+}
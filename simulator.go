@@ -0,0 +1,531 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"NgaSim/ned"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Virtual device timing
+const (
+	SimulatorStepInterval = 500 * time.Millisecond ///< How often the dynamics of virtual devices are advanced
+	SimReconnectInterval  = 2 * time.Second        ///< Longest wait between reconnect attempts
+)
+
+// simModel is the behaviour of one kind of virtual device. Calls are serialized by the
+// VirtualDevice mutex.
+type simModel interface {
+	information() *ned.GetDeviceInformationResponsePayload // Product details; serial and category are filled in
+	step(dt time.Duration)                                 // Advance the dynamics
+	telemetry(rssi int32) proto.Message                    // Category TelemetryMessage
+	announcements() []proto.Message                        // Category InfoMessages published after connecting
+	statusInfo() proto.Message                             // Category InfoMessage carrying the current status
+	activeErrors() (proto.Message, []string)               // Category DeviceErrorMessage and the active error codes
+	handle(d *VirtualDevice, request proto.Message) (proto.Message, bool)
+	state() map[string]interface{}
+	fault(name string, params faultParams, active bool) error // Start or end a model fault
+}
+
+// simulatedCategory is a category the simulator can run
+type simulatedCategory struct {
+	code     string // Used in generated serial numbers
+	newModel func(index int) simModel
+}
+
+// simulatedCategories lists the device models by NED category
+var simulatedCategories = map[string]simulatedCategory{
+	"sanitizerGen2":         {code: "SAN", newModel: newSimSanitizer},
+	"speedsetPlusGen2":      {code: "SSP", newModel: newSimSpeedsetPlus},
+	"speedsetplus":          {code: "SSP", newModel: newSimSpeedsetPlus},
+	VspBoosterCategory:      {code: "VSB", newModel: newSimVspBooster},
+	"digitalControllerGen2": {code: "DCT", newModel: newSimDct},
+}
+
+// simulatorDeviceSpec is one entry of simulator.devices
+type simulatorDeviceSpec struct {
+	Category string
+	Count    int
+}
+
+// parseSimulatorDevices parses "category" and "category:count" entries
+func parseSimulatorDevices(specs []string) ([]simulatorDeviceSpec, error) {
+	var parsed []simulatorDeviceSpec
+	for _, spec := range specs {
+		category, count := spec, 1
+		if i := strings.LastIndex(spec, ":"); i >= 0 {
+			n, err := strconv.Atoi(spec[i+1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("bad count in %q", spec)
+			}
+			category, count = spec[:i], n
+		}
+		if _, exists := simulatedCategories[category]; !exists {
+			known := make([]string, 0, len(simulatedCategories))
+			for name := range simulatedCategories {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("cannot simulate category %q (known: %s)", category, strings.Join(known, ", "))
+		}
+		parsed = append(parsed, simulatorDeviceSpec{Category: category, Count: count})
+	}
+	return parsed, nil
+}
+
+// VirtualDevice is a simulated device with its own broker connection. It announces itself,
+// publishes telemetry, info and error frames on the async topics and answers command requests,
+// exactly as the hardware does.
+type VirtualDevice struct {
+	Serial   string
+	Category string
+
+	model  simModel
+	client mqtt.Client
+	mqtt   MQTTConfig
+
+	telemetryPeriod  time.Duration
+	telemetryEnabled bool
+	lastTelemetry    time.Time
+	findMeUntil      time.Time
+	errorCodes       string // Active error codes last published, to publish only changes
+	rssi             int32
+
+	faults map[string]*simFault // Injected faults by name
+	conn   *droppableConn       // Current broker socket, failed to simulate a dropped connection
+
+	mutex    sync.Mutex
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// SimulatedDeviceStatus describes a virtual device for /api/simulator
+type SimulatedDeviceStatus struct {
+	Serial           string                 `json:"serial"`
+	Category         string                 `json:"category"`
+	Connected        bool                   `json:"connected"`
+	TelemetryPeriod  string                 `json:"telemetry_period"`
+	TelemetryEnabled bool                   `json:"telemetry_enabled"`
+	FindMeActive     bool                   `json:"find_me_active"`
+	ActiveErrors     []string               `json:"active_errors"`
+	Faults           []simFault             `json:"faults"`
+	State            map[string]interface{} `json:"state"`
+}
+
+// Simulator runs the virtual devices of one site and the fault scenarios driving them
+type Simulator struct {
+	devices      []*VirtualDevice
+	serialSuffix string // "-<site>" when several sites may share a broker, otherwise empty
+
+	runs     map[string]*ScenarioRun // Scenario runs by ID
+	runOrder []string                // Run IDs, oldest first
+	mutex    sync.Mutex              // Protects runs, runOrder and the runs themselves
+}
+
+// NewSimulator creates the virtual devices listed in the configuration. Serial numbers are
+// derived from the category and position so they are stable between runs. With more than one
+// site they end in the site name, so sites sharing a broker do not pick up each other's devices.
+func NewSimulator(cfg *LoadedConfig) (*Simulator, error) {
+	specs, err := parseSimulatorDevices(cfg.Simulator.Devices)
+	if err != nil {
+		return nil, err
+	}
+	period, err := time.ParseDuration(cfg.Simulator.TelemetryPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid simulator.telemetry_period: %v", err)
+	}
+
+	sim := &Simulator{runs: make(map[string]*ScenarioRun)}
+	if len(cfg.Sites) > 1 {
+		sim.serialSuffix = "-" + cfg.Site
+	}
+	counts := make(map[string]int)
+	for _, spec := range specs {
+		kind := simulatedCategories[spec.Category]
+		for i := 0; i < spec.Count; i++ {
+			counts[kind.code]++
+			index := counts[kind.code]
+			sim.devices = append(sim.devices, &VirtualDevice{
+				Serial:           fmt.Sprintf("SIM%s%03d%s", kind.code, index, sim.serialSuffix),
+				Category:         spec.Category,
+				model:            kind.newModel(index),
+				mqtt:             cfg.MQTT,
+				telemetryPeriod:  period,
+				telemetryEnabled: true,
+				rssi:             int32(-40 - rand.Intn(25)),
+				faults:           make(map[string]*simFault),
+				stop:             make(chan struct{}),
+			})
+		}
+	}
+	return sim, nil
+}
+
+// Start connects every virtual device; devices that cannot connect yet keep retrying
+func (s *Simulator) Start() {
+	for _, device := range s.devices {
+		if err := device.start(); err != nil {
+			log.Printf("❌ Virtual device %s: %v", device.Serial, err)
+		}
+	}
+	log.Printf("🤖 Simulator running %d virtual device(s)", len(s.devices))
+}
+
+// Stop ends running scenarios and disconnects every virtual device
+func (s *Simulator) Stop() {
+	s.stopScenarios()
+	for _, device := range s.devices {
+		device.stopOnce.Do(func() {
+			close(device.stop)
+			if device.client != nil {
+				device.client.Disconnect(250)
+			}
+		})
+	}
+}
+
+// Status reports every virtual device
+func (s *Simulator) Status() []SimulatedDeviceStatus {
+	statuses := make([]SimulatedDeviceStatus, 0, len(s.devices))
+	for _, device := range s.devices {
+		statuses = append(statuses, device.status())
+	}
+	return statuses
+}
+
+// asyncTopic returns the topic the device publishes a message type on
+func (d *VirtualDevice) asyncTopic(messageType string) string {
+	return d.mqtt.AsyncTopic(fmt.Sprintf("%s/%s/%s", d.Category, d.Serial, messageType))
+}
+
+// start connects the device to the broker and starts its dynamics
+func (d *VirtualDevice) start() error {
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(d.mqtt.Broker)
+	opts.SetClientID(d.mqtt.ClientID + "-" + d.Serial)
+	opts.SetCleanSession(true)
+	opts.SetAutoReconnect(true)
+	opts.SetConnectRetry(true) // Like hardware, keep trying until the broker is up
+	opts.SetConnectRetryInterval(SimReconnectInterval)
+	opts.SetMaxReconnectInterval(SimReconnectInterval)
+	opts.SetKeepAlive(30 * time.Second)
+	if d.canDropConnection() {
+		opts.SetCustomOpenConnectionFn(d.dial)
+	}
+
+	// Last will: the broker tells the core the device is gone if the connection drops
+	will, _ := proto.Marshal(&ned.DisconnectedMessagePayload{})
+	opts.SetBinaryWill(d.asyncTopic("disconnected"), will, 1, false)
+
+	if isTLSBroker(d.mqtt.Broker) {
+		tlsConfig, err := buildTLSConfig(d.mqtt.TLS)
+		if err != nil {
+			return err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+	mqttConfig := d.mqtt
+	opts.SetCredentialsProvider(func() (string, string) {
+		username, password, _ := mqttCredentials(mqttConfig)
+		return username, password
+	})
+
+	opts.SetOnConnectHandler(func(client mqtt.Client) {
+		requestTopic := d.mqtt.CommandTopic(fmt.Sprintf(TopicCommandFormat, d.Category, d.Serial))
+		client.Subscribe(requestTopic, 1, d.handleRequest)
+		d.announce()
+	})
+
+	d.client = mqtt.NewClient(opts)
+	go d.run()
+
+	token := d.client.Connect()
+	if !token.WaitTimeout(5 * time.Second) {
+		return fmt.Errorf("not connected to %s yet, still trying", d.mqtt.Broker)
+	}
+	if token.Error() != nil {
+		return fmt.Errorf("failed to connect to %s: %v", d.mqtt.Broker, token.Error())
+	}
+	return nil
+}
+
+// publish serializes msg and publishes it on the device's async topic for messageType
+func (d *VirtualDevice) publish(messageType string, msg proto.Message) {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		log.Printf("❌ Virtual device %s: failed to marshal %s: %v", d.Serial, messageType, err)
+		return
+	}
+	var qos byte = 1
+	if messageType == "dt" {
+		qos = 0
+	}
+	d.client.Publish(d.asyncTopic(messageType), qos, false, payload)
+}
+
+// information returns the announce payload
+func (d *VirtualDevice) information() *ned.GetDeviceInformationResponsePayload {
+	info := d.model.information()
+	info.SerialNumber = d.Serial
+	info.Category = d.Category
+	info.AvailableBusTypes = []ned.BusType{ned.BusType_BUS_TYPE_SLIP, ned.BusType_BUS_TYPE_WIFI}
+	info.ActiveBus = &ned.BusDescription{BusType: ned.BusType_BUS_TYPE_WIFI, IpAddress: "169.254.1.100"}
+	return info
+}
+
+// announce publishes the announce frame, the category info messages and any active errors
+func (d *VirtualDevice) announce() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.publish("anc", d.information())
+	for _, info := range d.model.announcements() {
+		d.publish("info", info)
+	}
+	d.errorCodes = ""
+	d.publishErrors()
+	log.Printf("🤖 Virtual %s %s announced", d.Category, d.Serial)
+}
+
+// publishErrors publishes the active error list when it differs from the last one sent.
+// Caller must hold the device mutex.
+func (d *VirtualDevice) publishErrors() {
+	msg, codes := d.model.activeErrors()
+	key := strings.Join(codes, ",")
+	if key == d.errorCodes {
+		return
+	}
+	d.errorCodes = key
+	d.publish("error", msg)
+}
+
+// run advances the dynamics and publishes telemetry until the device is stopped
+func (d *VirtualDevice) run() {
+	ticker := time.NewTicker(SimulatorStepInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case now := <-ticker.C:
+			d.mutex.Lock()
+			d.expireFaults(now)
+			d.model.step(now.Sub(last))
+			if d.client.IsConnectionOpen() {
+				dropout := d.hasFault(FaultTelemetryDropout)
+				if d.telemetryEnabled && !dropout && now.Sub(d.lastTelemetry) >= d.telemetryPeriod {
+					d.rssi = clampInt32(d.rssi+int32(rand.Intn(3)-1), -90, -30)
+					d.publish("dt", d.model.telemetry(d.rssi))
+					d.lastTelemetry = now
+				}
+				d.publishErrors()
+			}
+			d.mutex.Unlock()
+			last = now
+		}
+	}
+}
+
+// handleRequest answers a command request with the category CommandResponseMessage
+func (d *VirtualDevice) handleRequest(client mqtt.Client, msg mqtt.Message) {
+	request := newCommandRequest(d.Category)
+	if err := proto.Unmarshal(msg.Payload(), request); err != nil {
+		log.Printf("⚠️ Virtual device %s: undecodable request: %v", d.Serial, err)
+		return
+	}
+	commandUUID := request.ProtoReflect().Get(request.ProtoReflect().Descriptor().Fields().ByName("command_uuid")).String()
+
+	d.mutex.Lock()
+	if d.disconnectOnCommand() {
+		d.mutex.Unlock()
+		return
+	}
+	response, changed := d.model.handle(d, request)
+	if changed {
+		d.publish("info", d.model.statusInfo())
+	}
+	d.mutex.Unlock()
+
+	reflectResp := response.ProtoReflect()
+	reflectResp.Set(reflectResp.Descriptor().Fields().ByName("command_uuid"), protoreflect.ValueOfString(commandUUID))
+	payload, err := proto.Marshal(response)
+	if err != nil {
+		log.Printf("❌ Virtual device %s: failed to marshal response: %v", d.Serial, err)
+		return
+	}
+
+	log.Printf("🤖 Virtual device %s answered %s: %v", d.Serial, commandUUID,
+		reflectResp.Get(reflectResp.Descriptor().Fields().ByName("response_code")).Enum())
+	responseTopic := d.mqtt.CommandTopic(fmt.Sprintf("%s/%s/res", d.Category, d.Serial))
+	d.client.Publish(responseTopic, 1, false, payload)
+}
+
+// handleCommon answers the requests every device category supports. Caller must hold the
+// device mutex.
+func (d *VirtualDevice) handleCommon(common *ned.CommonRequestPayloads) (*ned.CommonResponsePayloads, ned.ResponseCode) {
+	switch r := common.GetRequestType().(type) {
+	case *ned.CommonRequestPayloads_GetDeviceInformation:
+		return &ned.CommonResponsePayloads{ResponseType: &ned.CommonResponsePayloads_GetDeviceInformationResponse{
+			GetDeviceInformationResponse: d.information(),
+		}}, ned.ResponseCode_RESPONSE_OK
+
+	case *ned.CommonRequestPayloads_GetDeviceConfiguration:
+		return &ned.CommonResponsePayloads{ResponseType: &ned.CommonResponsePayloads_GetDeviceConfigurationResponse{
+			GetDeviceConfigurationResponse: &ned.GetDeviceConfigurationResponsePayload{
+				CoreBssid:      "02:00:00:00:00:01",
+				IsFindMeActive: time.Now().Before(d.findMeUntil),
+			},
+		}}, ned.ResponseCode_RESPONSE_OK
+
+	case *ned.CommonRequestPayloads_GetTelemetryConfiguration:
+		return &ned.CommonResponsePayloads{ResponseType: &ned.CommonResponsePayloads_GetTelemetryConfigurationResponse{
+			GetTelemetryConfigurationResponse: &ned.GetTelemetryConfigurationResponsePayload{
+				TelemetryPeriodValueSeconds: int32(d.telemetryPeriod / time.Second),
+				TelemetryEnabled:            d.telemetryEnabled,
+			},
+		}}, ned.ResponseCode_RESPONSE_OK
+
+	case *ned.CommonRequestPayloads_SetTelemetryConfiguration:
+		period := r.SetTelemetryConfiguration.GetTelemetryPeriodValueSeconds()
+		if period < 1 {
+			return nil, ned.ResponseCode_RESPONSE_BAD_REQUEST
+		}
+		d.telemetryPeriod = time.Duration(period) * time.Second
+		d.telemetryEnabled = r.SetTelemetryConfiguration.GetTelemetryEnabled()
+		return nil, ned.ResponseCode_RESPONSE_OK
+
+	case *ned.CommonRequestPayloads_FindMe:
+		seconds, err := strconv.Atoi(r.FindMe.GetFindMeDurationSeconds())
+		if err != nil || seconds < 0 {
+			return nil, ned.ResponseCode_RESPONSE_BAD_REQUEST
+		}
+		d.findMeUntil = time.Now().Add(time.Duration(seconds) * time.Second)
+		return nil, ned.ResponseCode_RESPONSE_OK
+
+	case *ned.CommonRequestPayloads_FactoryReset, *ned.CommonRequestPayloads_ForgetCore,
+		*ned.CommonRequestPayloads_PairToCore, *ned.CommonRequestPayloads_SetServiceModeStatus:
+		return nil, ned.ResponseCode_RESPONSE_OK
+	}
+	return nil, ned.ResponseCode_RESPONSE_BAD_REQUEST
+}
+
+// status describes the device for the API
+func (d *VirtualDevice) status() SimulatedDeviceStatus {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	_, codes := d.model.activeErrors()
+	if codes == nil {
+		codes = []string{}
+	}
+	faults := make([]simFault, 0, len(d.faults))
+	for _, fault := range d.faults {
+		faults = append(faults, *fault)
+	}
+	sort.Slice(faults, func(i, j int) bool { return faults[i].Name < faults[j].Name })
+	return SimulatedDeviceStatus{
+		Serial:           d.Serial,
+		Category:         d.Category,
+		Connected:        d.client != nil && d.client.IsConnectionOpen(),
+		TelemetryPeriod:  d.telemetryPeriod.String(),
+		TelemetryEnabled: d.telemetryEnabled,
+		FindMeActive:     time.Now().Before(d.findMeUntil),
+		ActiveErrors:     codes,
+		Faults:           faults,
+		State:            d.model.state(),
+	}
+}
+
+// startSimulator starts the configured virtual devices once the site is connected
+func (n *NgaSim) startSimulator() {
+	cfg := n.currentConfig()
+	if len(cfg.Simulator.Devices) == 0 {
+		return
+	}
+
+	simulator, err := NewSimulator(cfg)
+	if err != nil {
+		log.Printf("❌ Simulator not started: %v", err)
+		return
+	}
+	n.simulator = simulator
+	n.simulator.Start()
+
+	if scenarios, err := loadScenarios(cfg.Simulator.Scenarios); err != nil {
+		log.Printf("⚠️ Fault scenarios: %v", err)
+	} else if len(scenarios) > 0 {
+		log.Printf("🎬 Fault scenarios: %s", strings.Join(scenarioIDs(scenarios), ", "))
+	}
+}
+
+// handleSimulator lists the site's virtual devices and their internal state
+func (n *NgaSim) handleSimulator(w http.ResponseWriter, r *http.Request) {
+	devices := make([]SimulatedDeviceStatus, 0)
+	if n.simulator != nil {
+		devices = n.simulator.Status()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"running": n.simulator != nil,
+		"devices": devices,
+	})
+}
+
+// approach moves value toward target by at most rate*dt
+func approach(value, target, rate float64, dt time.Duration) float64 {
+	delta := rate * dt.Seconds()
+	if value < target {
+		return minFloat(value+delta, target)
+	}
+	return maxFloat(value-delta, target)
+}
+
+// settle moves value toward target exponentially with time constant tau
+func settle(value, target float64, tau, dt time.Duration) float64 {
+	return value + (target-value)*minFloat(dt.Seconds()/tau.Seconds(), 1)
+}
+
+// jitter returns value with uniform noise of +/- amount
+func jitter(value, amount float64) float64 {
+	return value + (rand.Float64()*2-1)*amount
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func clampInt32(value, low, high int32) int32 {
+	if value < low {
+		return low
+	}
+	if value > high {
+		return high
+	}
+	return value
+}
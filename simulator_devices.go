@@ -0,0 +1,1132 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"NgaSim/ned"
+	"NgaSim/ned/icl"
+	"NgaSim/ned/sanitizer"
+	"NgaSim/ned/speedsetplus"
+	"NgaSim/ned/vspbooster"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Virtual sanitizer dynamics
+const (
+	SimSanitizerRampRate    = 5.0   ///< Output change, percent per second
+	SimSanitizerSaltUse     = 0.005 ///< Salt consumed at 100% output, ppm per second
+	SimSanitizerLowSalt     = 2700  ///< Below this the cell reports low salt
+	SimSanitizerHighSalt    = 4500  ///< Above this the cell reports high salt
+	SimSanitizerLineVoltage = 240   ///< Nominal supply, V
+	SimSanitizerMaxTilt     = 30    ///< Beyond this angle the cell reports tilted and stops, degrees
+)
+
+// Virtual pump dynamics, shared by SpeedSet Plus and VSP booster
+const (
+	SimPumpMinRPM       = 600              ///< Lowest accepted demand while running
+	SimPumpMaxRPM       = 3450             ///< Highest accepted demand
+	SimPumpAcceleration = 400.0            ///< RPM per second
+	SimPumpDeceleration = 600.0            ///< RPM per second while coasting down
+	SimPumpThermalTau   = 60 * time.Second ///< IPM temperature time constant
+	SimPumpAmbient      = 25.0             ///< Ambient temperature, °C
+	SimPumpDCBus        = 325.0            ///< Nominal DC bus, V
+	SimPumpDCOver       = 400.0            ///< The drive trips above this DC bus voltage
+	SimPumpDCUnder      = 200.0            ///< The drive trips below this DC bus voltage
+)
+
+// Virtual DCT dynamics
+const (
+	SimDctLights          = 4                ///< Lights attached to each virtual DCT
+	SimDctCapacity        = 300              ///< Transformer capacity, W
+	SimDctLightWattage    = 60.0             ///< Light draw at full brightness, W
+	SimDctThermalTau      = 30 * time.Second ///< Light temperature time constant
+	SimDctLightHeatRise   = 55.0             ///< Temperature rise at full brightness, °C
+	SimDctDerateAbove     = 70.0             ///< Lights derate above this temperature, °C
+	SimDctOverTemperature = 75.0             ///< Lights report HIGH_TEMPERATURE_ERROR above this, °C
+)
+
+// ---------------------------------------------------------------------------------------------
+// Sanitizer
+// ---------------------------------------------------------------------------------------------
+
+// simSanitizer is a salt chlorinator: output ramps to the target, salt drifts and is consumed,
+// and the cell reverses polarity periodically
+type simSanitizer struct {
+	index         int
+	target        int32
+	output        float64
+	salt          float64
+	lineVoltage   float64
+	flowSensor    sanitizer.FlowSensorType
+	reversalMins  int32
+	sinceReversal time.Duration
+	reversed      bool
+
+	// Injected faults
+	noFlow       bool
+	saltOverride float64 // Salt reading forced by low_salt/high_salt, 0 when none
+	tilt         float64 // Cell tilt from vertical, degrees
+}
+
+func newSimSanitizer(index int) simModel {
+	return &simSanitizer{
+		index:        index,
+		target:       50,
+		output:       50,
+		salt:         3200 + float64(index%5)*100,
+		lineVoltage:  SimSanitizerLineVoltage,
+		flowSensor:   sanitizer.FlowSensorType_GAS,
+		reversalMins: 180,
+	}
+}
+
+func (m *simSanitizer) information() *ned.GetDeviceInformationResponsePayload {
+	return &ned.GetDeviceInformationResponsePayload{
+		ProductName:     "AquaRite Gen2 (simulated)",
+		ModelId:         "sanitizer-gen2",
+		ModelVersion:    "1",
+		FirmwareVersion: "2.1.3-sim",
+		OtaVersion:      "2.1.3",
+	}
+}
+
+// producing reports whether the cell can generate chlorine
+func (m *simSanitizer) producing() bool {
+	return !m.noFlow && m.tilt <= SimSanitizerMaxTilt
+}
+
+// measuredSalt is the salt reading, which an injected fault may override
+func (m *simSanitizer) measuredSalt() float64 {
+	if m.saltOverride > 0 {
+		return jitter(m.saltOverride, 5)
+	}
+	return m.salt
+}
+
+func (m *simSanitizer) step(dt time.Duration) {
+	target := float64(m.target)
+	if !m.producing() {
+		target = 0
+	}
+	rate := SimSanitizerRampRate
+	if target < m.output {
+		rate *= 4 // Production stops much faster than it builds up
+	}
+	m.output = approach(m.output, target, rate, dt)
+	m.salt = jitter(m.salt, 0.5) - SimSanitizerSaltUse*m.output/100*dt.Seconds()
+	m.lineVoltage = settle(m.lineVoltage, jitter(SimSanitizerLineVoltage, 3), 10*time.Second, dt)
+
+	// Reverse the cell polarity every reversalMins of production; output restarts from zero
+	if m.reversalMins > 0 && m.output > 0 {
+		m.sinceReversal += dt
+		if m.sinceReversal >= time.Duration(m.reversalMins)*time.Minute {
+			m.reversed = !m.reversed
+			m.sinceReversal = 0
+			m.output = 0
+		}
+	}
+}
+
+func (m *simSanitizer) telemetry(rssi int32) proto.Message {
+	tilt := m.tilt * math.Pi / 180
+	return &sanitizer.TelemetryMessage{
+		Rssi:               rssi,
+		PpmSalt:            int32(math.Round(m.measuredSalt())),
+		PercentageOutput:   int32(math.Round(m.output)),
+		AccelerometerX:     int32(jitter(1000*math.Sin(tilt), 8)),
+		AccelerometerY:     int32(jitter(0, 8)),
+		AccelerometerZ:     int32(jitter(1000*math.Cos(tilt), 8)),
+		LineInputVoltage:   int32(math.Round(m.lineVoltage)),
+		IsCellFlowReversed: m.reversed,
+	}
+}
+
+func (m *simSanitizer) status() *sanitizer.SanitizerStatus {
+	return &sanitizer.SanitizerStatus{TargetPercentage: m.target, FlowSensorType: m.flowSensor}
+}
+
+func (m *simSanitizer) configuration() *sanitizer.SanitizerConfiguration {
+	return &sanitizer.SanitizerConfiguration{CellReversalDuration: m.reversalMins}
+}
+
+func (m *simSanitizer) statusInfo() proto.Message {
+	return &sanitizer.InfoMessage{Payload: &sanitizer.SanitizerInfoPayloads{
+		AnnounceType: &sanitizer.SanitizerInfoPayloads_Status{Status: m.status()},
+	}}
+}
+
+func (m *simSanitizer) announcements() []proto.Message {
+	return []proto.Message{
+		m.statusInfo(),
+		&sanitizer.InfoMessage{Payload: &sanitizer.SanitizerInfoPayloads{
+			AnnounceType: &sanitizer.SanitizerInfoPayloads_Configuration{Configuration: m.configuration()},
+		}},
+	}
+}
+
+func (m *simSanitizer) errorList() *sanitizer.ActiveErrors {
+	errors := &sanitizer.ActiveErrors{}
+	if m.noFlow {
+		errors.ErrorList = append(errors.ErrorList, &sanitizer.SanitizerError{
+			ErrorCode:    sanitizer.SanitizerErrorCode_SANITIZER_ERROR_NO_FLOW,
+			ErrorMessage: "No flow detected through the cell",
+		})
+	}
+	salt := m.salt
+	if m.saltOverride > 0 {
+		salt = m.saltOverride
+	}
+	if salt < SimSanitizerLowSalt {
+		errors.ErrorList = append(errors.ErrorList, &sanitizer.SanitizerError{
+			ErrorCode:    sanitizer.SanitizerErrorCode_SANITIZER_ERROR_LOW_SALT,
+			ErrorMessage: fmt.Sprintf("Salt level low: %.0f ppm", salt),
+		})
+	}
+	if salt > SimSanitizerHighSalt {
+		errors.ErrorList = append(errors.ErrorList, &sanitizer.SanitizerError{
+			ErrorCode:    sanitizer.SanitizerErrorCode_SANITIZER_ERROR_HIGH_SALT,
+			ErrorMessage: fmt.Sprintf("Salt level high: %.0f ppm", salt),
+		})
+	}
+	if m.tilt > SimSanitizerMaxTilt {
+		errors.ErrorList = append(errors.ErrorList, &sanitizer.SanitizerError{
+			ErrorCode:    sanitizer.SanitizerErrorCode_SANITIZER_ERROR_CELL_TILTED,
+			ErrorMessage: fmt.Sprintf("Cell tilted %.0f degrees", m.tilt),
+		})
+	}
+	return errors
+}
+
+func (m *simSanitizer) activeErrors() (proto.Message, []string) {
+	errors := m.errorList()
+	var codes []string
+	for _, e := range errors.GetErrorList() {
+		codes = append(codes, e.GetErrorCode().String())
+	}
+	return &sanitizer.DeviceErrorMessage{ActiveErrors: errors}, codes
+}
+
+func (m *simSanitizer) handle(d *VirtualDevice, request proto.Message) (proto.Message, bool) {
+	req := request.(*sanitizer.CommandRequestMessage)
+	resp := &sanitizer.CommandResponseMessage{ResponseCode: ned.ResponseCode_RESPONSE_OK}
+
+	if common := req.GetCommon(); common != nil {
+		payload, code := d.handleCommon(common)
+		resp.ResponseCode = code
+		if payload != nil {
+			resp.Payload = &sanitizer.CommandResponseMessage_Common{Common: payload}
+		}
+		return resp, false
+	}
+
+	var payload *sanitizer.SanitizerResponsePayloads
+	changed := false
+	switch r := req.GetSanitizer().GetRequestType().(type) {
+	case *sanitizer.SanitizerRequestPayloads_SetSanitizerOutputPercentage:
+		target := r.SetSanitizerOutputPercentage.GetTargetPercentage()
+		if target < 0 || target > 100 {
+			resp.ResponseCode = ned.ResponseCode_RESPONSE_BAD_REQUEST
+			break
+		}
+		m.target, changed = target, true
+
+	case *sanitizer.SanitizerRequestPayloads_GetStatus:
+		payload = &sanitizer.SanitizerResponsePayloads{ResponseType: &sanitizer.SanitizerResponsePayloads_GetStatus{
+			GetStatus: &sanitizer.GetSanitizerStatusResponsePayload{Status: m.status()},
+		}}
+
+	case *sanitizer.SanitizerRequestPayloads_GetConfiguration:
+		payload = &sanitizer.SanitizerResponsePayloads{ResponseType: &sanitizer.SanitizerResponsePayloads_GetConfiguration{
+			GetConfiguration: &sanitizer.GetSanitizerConfigurationResponsePayload{Configuration: m.configuration()},
+		}}
+
+	case *sanitizer.SanitizerRequestPayloads_SetConfiguration:
+		duration := r.SetConfiguration.GetConfiguration().GetCellReversalDuration()
+		if duration < 0 {
+			resp.ResponseCode = ned.ResponseCode_RESPONSE_BAD_REQUEST
+			break
+		}
+		m.reversalMins = duration
+
+	case *sanitizer.SanitizerRequestPayloads_OverrideFlowSensorType:
+		m.flowSensor, changed = r.OverrideFlowSensorType.GetFlowSensorType(), true
+
+	case *sanitizer.SanitizerRequestPayloads_GetDeviceInformation:
+		payload = &sanitizer.SanitizerResponsePayloads{ResponseType: &sanitizer.SanitizerResponsePayloads_GetDeviceInformation{
+			GetDeviceInformation: &sanitizer.GetSanitizerDeviceInformationResponsePayload{
+				CellSerialNumber:    fmt.Sprintf("CELL%s", d.Serial),
+				CellFirmwareVersion: "1.4.0-sim",
+				CellType:            sanitizer.CellType_SMART_CELL,
+			},
+		}}
+
+	case *sanitizer.SanitizerRequestPayloads_GetActiveErrors:
+		payload = &sanitizer.SanitizerResponsePayloads{ResponseType: &sanitizer.SanitizerResponsePayloads_GetActiveErrors{
+			GetActiveErrors: &sanitizer.GetSanitizerActiveErrorsResponsePayload{ActiveErrors: m.errorList()},
+		}}
+
+	default:
+		resp.ResponseCode = ned.ResponseCode_RESPONSE_COMMAND_ERROR
+	}
+
+	if payload != nil {
+		resp.Payload = &sanitizer.CommandResponseMessage_Sanitizer{Sanitizer: payload}
+	}
+	return resp, changed
+}
+
+func (m *simSanitizer) state() map[string]interface{} {
+	return map[string]interface{}{
+		"target_percentage":      m.target,
+		"output_percentage":      math.Round(m.output*10) / 10,
+		"ppm_salt":               math.Round(m.measuredSalt()),
+		"line_input_voltage":     math.Round(m.lineVoltage),
+		"flow_sensor_type":       m.flowSensor.String(),
+		"cell_reversal_duration": m.reversalMins,
+		"cell_flow_reversed":     m.reversed,
+		"tilt_degrees":           m.tilt,
+	}
+}
+
+func (m *simSanitizer) fault(name string, params faultParams, active bool) error {
+	switch name {
+	case FaultNoFlow:
+		m.noFlow = active
+
+	case FaultLowSalt, FaultHighSalt:
+		if !active {
+			m.saltOverride = 0
+			return nil
+		}
+		fallback := 2200.0
+		if name == FaultHighSalt {
+			fallback = 5000
+		}
+		ppm, err := params.number("ppm", fallback)
+		if err != nil {
+			return err
+		}
+		if ppm <= 0 {
+			return fmt.Errorf("ppm must be positive")
+		}
+		m.saltOverride = ppm
+
+	case FaultCellTilt:
+		if !active {
+			m.tilt = 0
+			return nil
+		}
+		degrees, err := params.number("degrees", 45)
+		if err != nil {
+			return err
+		}
+		if degrees < 0 || degrees > 180 {
+			return fmt.Errorf("degrees must be between 0 and 180")
+		}
+		m.tilt = degrees
+
+	default:
+		return fmt.Errorf("not supported")
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------------------------
+// Pumps
+// ---------------------------------------------------------------------------------------------
+
+// simMotor is a variable speed pump motor: RPM accelerates to the demand, power follows the
+// affinity laws and the inverter heats with load
+type simMotor struct {
+	power     int32
+	demandRPM int32
+	rpm       float64
+	ipmTemp   float64
+	maxPower  float64 // Shaft power at SimPumpMaxRPM, W
+	dcBus     float64 // DC bus voltage forced by dc_overvoltage/dc_undervoltage, 0 when none
+}
+
+func newSimMotor(maxPower float64) simMotor {
+	return simMotor{demandRPM: 1500, ipmTemp: SimPumpAmbient, maxPower: maxPower}
+}
+
+// dcBusFault reports whether the DC bus is outside the drive's limits
+func (m *simMotor) dcBusFault() (overvoltage, undervoltage bool) {
+	return m.dcBus > SimPumpDCOver, m.dcBus > 0 && m.dcBus < SimPumpDCUnder
+}
+
+func (m *simMotor) step(dt time.Duration) {
+	overvoltage, undervoltage := m.dcBusFault()
+	if m.power == VspBoosterPowerOn && !overvoltage && !undervoltage {
+		rate := SimPumpAcceleration
+		if m.rpm > float64(m.demandRPM) {
+			rate = SimPumpDeceleration
+		}
+		m.rpm = approach(m.rpm, float64(m.demandRPM), rate, dt)
+	} else {
+		m.rpm = approach(m.rpm, 0, SimPumpDeceleration, dt)
+	}
+	m.ipmTemp = settle(m.ipmTemp, SimPumpAmbient+45*m.load(), SimPumpThermalTau, dt)
+}
+
+// load is the fraction of full shaft power at the current speed
+func (m *simMotor) load() float64 {
+	return math.Pow(m.rpm/SimPumpMaxRPM, 3)
+}
+
+// readings returns the telemetry values shared by both pump categories
+func (m *simMotor) readings() (outputPower, inputPower, current, torque, lineVoltage, dcBus float64) {
+	outputPower = m.maxPower * m.load()
+	if m.rpm > 0 {
+		inputPower = outputPower/0.88 + 15
+		torque = outputPower / (m.rpm * 2 * math.Pi / 60)
+	}
+	current = inputPower / 230
+	lineVoltage = 230 * m.rpm / SimPumpMaxRPM
+	dcBus = SimPumpDCBus
+	if m.dcBus > 0 {
+		dcBus = m.dcBus
+	}
+	dcBus = jitter(dcBus, 2)
+	return
+}
+
+// control validates and applies a control command
+func (m *simMotor) control(power, rpm int32) ned.ResponseCode {
+	if power != VspBoosterPowerOff && power != VspBoosterPowerOn {
+		return ned.ResponseCode_RESPONSE_BAD_REQUEST
+	}
+	if power == VspBoosterPowerOn && (rpm < SimPumpMinRPM || rpm > SimPumpMaxRPM) {
+		return ned.ResponseCode_RESPONSE_BAD_REQUEST
+	}
+	m.power = power
+	if rpm > 0 {
+		m.demandRPM = rpm
+	}
+	return ned.ResponseCode_RESPONSE_OK
+}
+
+func (m *simMotor) motorState() map[string]interface{} {
+	outputPower, inputPower, _, _, _, _ := m.readings()
+	return map[string]interface{}{
+		"power":           m.power,
+		"demand_rpm":      m.demandRPM,
+		"motor_rpm":       math.Round(m.rpm),
+		"output_power":    math.Round(outputPower),
+		"input_power":     math.Round(inputPower),
+		"ipm_temperature": math.Round(m.ipmTemp*10) / 10,
+		"dc_bus_fault":    m.dcBus,
+	}
+}
+
+// fault forces the DC bus voltage; out of limits the drive trips until the fault clears
+func (m *simMotor) fault(name string, params faultParams, active bool) error {
+	var fallback float64
+	switch name {
+	case FaultDCOvervoltage:
+		fallback = 420
+	case FaultDCUndervoltage:
+		fallback = 180
+	default:
+		return fmt.Errorf("not supported")
+	}
+	if !active {
+		m.dcBus = 0
+		return nil
+	}
+	voltage, err := params.number("voltage", fallback)
+	if err != nil {
+		return err
+	}
+	if voltage <= 0 {
+		return fmt.Errorf("voltage must be positive")
+	}
+	m.dcBus = voltage
+	return nil
+}
+
+// simSpeedsetPlus is a SpeedSet Plus pump
+type simSpeedsetPlus struct {
+	simMotor
+}
+
+func newSimSpeedsetPlus(index int) simModel {
+	return &simSpeedsetPlus{simMotor: newSimMotor(1850)}
+}
+
+func (m *simSpeedsetPlus) information() *ned.GetDeviceInformationResponsePayload {
+	return &ned.GetDeviceInformationResponsePayload{
+		ProductName:     "SpeedSet Plus (simulated)",
+		ModelId:         "speedsetPlus-gen2",
+		ModelVersion:    "1",
+		FirmwareVersion: "1.8.2-sim",
+		OtaVersion:      "1.8.2",
+	}
+}
+
+func (m *simSpeedsetPlus) telemetry(rssi int32) proto.Message {
+	outputPower, inputPower, current, torque, lineVoltage, dcBus := m.readings()
+	vibration := m.rpm / SimPumpMaxRPM * 40
+	return &speedsetplus.TelemetryMessage{
+		Rssi:               rssi,
+		MotorRpm:           int32(math.Round(m.rpm)),
+		DemandRpm:          m.demandRPM,
+		MotorCurrent:       int32(math.Round(current)),
+		Torque:             int32(math.Round(torque)),
+		InverterInputPower: int32(math.Round(inputPower)),
+		DcBusVoltage:       int32(math.Round(dcBus)),
+		AmbientTemperature: int32(jitter(SimPumpAmbient, 0.3) * 10),
+		OutputPower:        int32(math.Round(outputPower)),
+		MotorLineVoltage:   int32(math.Round(lineVoltage)),
+		MotorInputPower:    int32(math.Round(outputPower / 0.92)),
+		IpmTemperature:     int32(m.ipmTemp * 10),
+		Humidity:           int32(jitter(45, 2)),
+		VibrationX:         int32(jitter(vibration, 3)),
+		VibrationY:         int32(jitter(vibration, 3)),
+		VibrationZ:         int32(jitter(vibration/2, 2)),
+	}
+}
+
+func (m *simSpeedsetPlus) status() *speedsetplus.SpeedsetPlusStatus {
+	return &speedsetplus.SpeedsetPlusStatus{Power: m.power, DemandRpm: m.demandRPM}
+}
+
+func (m *simSpeedsetPlus) statusInfo() proto.Message {
+	return &speedsetplus.InfoMessage{Payload: &speedsetplus.SpeedsetPlusInfoPayloads{
+		AnnounceType: &speedsetplus.SpeedsetPlusInfoPayloads_Status{Status: m.status()},
+	}}
+}
+
+func (m *simSpeedsetPlus) announcements() []proto.Message {
+	return []proto.Message{m.statusInfo()}
+}
+
+func (m *simSpeedsetPlus) errorList() *speedsetplus.ActiveErrors {
+	errors := &speedsetplus.ActiveErrors{}
+	overvoltage, undervoltage := m.dcBusFault()
+	if overvoltage {
+		errors.ErrorList = append(errors.ErrorList, &speedsetplus.SpeedsetPlusError{
+			ErrorCode:    speedsetplus.SpeedsetPlusErrorCode_SPEEDSET_PLUS_ERROR_DC_OVERVOLTAGE,
+			ErrorMessage: fmt.Sprintf("DC bus overvoltage: %.0f V", m.dcBus),
+		})
+	}
+	if undervoltage {
+		errors.ErrorList = append(errors.ErrorList, &speedsetplus.SpeedsetPlusError{
+			ErrorCode:    speedsetplus.SpeedsetPlusErrorCode_SPEEDSET_PLUS_ERROR_DC_UNDERVOLTAGE,
+			ErrorMessage: fmt.Sprintf("DC bus undervoltage: %.0f V", m.dcBus),
+		})
+	}
+	return errors
+}
+
+func (m *simSpeedsetPlus) activeErrors() (proto.Message, []string) {
+	errors := m.errorList()
+	var codes []string
+	for _, e := range errors.GetErrorList() {
+		codes = append(codes, e.GetErrorCode().String())
+	}
+	return &speedsetplus.DeviceErrorMessage{ActiveErrors: errors}, codes
+}
+
+func (m *simSpeedsetPlus) handle(d *VirtualDevice, request proto.Message) (proto.Message, bool) {
+	req := request.(*speedsetplus.CommandRequestMessage)
+	resp := &speedsetplus.CommandResponseMessage{ResponseCode: ned.ResponseCode_RESPONSE_OK}
+
+	if common := req.GetCommon(); common != nil {
+		payload, code := d.handleCommon(common)
+		resp.ResponseCode = code
+		if payload != nil {
+			resp.Payload = &speedsetplus.CommandResponseMessage_Common{Common: payload}
+		}
+		return resp, false
+	}
+
+	var payload *speedsetplus.SpeedsetPlusResponsePayloads
+	changed := false
+	switch r := req.GetSpeedsetplus().GetRequestType().(type) {
+	case *speedsetplus.SpeedsetPlusRequestPayloads_SetVspControlCommand:
+		resp.ResponseCode = m.control(r.SetVspControlCommand.GetPower(), r.SetVspControlCommand.GetSetDemandRpm())
+		changed = resp.ResponseCode == ned.ResponseCode_RESPONSE_OK
+
+	case *speedsetplus.SpeedsetPlusRequestPayloads_GetStatus:
+		payload = &speedsetplus.SpeedsetPlusResponsePayloads{ResponseType: &speedsetplus.SpeedsetPlusResponsePayloads_GetStatus{
+			GetStatus: &speedsetplus.GetSpeedsetPlusStatusResponsePayload{Status: m.status()},
+		}}
+
+	case *speedsetplus.SpeedsetPlusRequestPayloads_GetConfiguration:
+		payload = &speedsetplus.SpeedsetPlusResponsePayloads{ResponseType: &speedsetplus.SpeedsetPlusResponsePayloads_GetConfiguration{
+			GetConfiguration: &speedsetplus.GetSpeedsetPlusConfigurationResponsePayload{Configuration: &speedsetplus.SpeedsetPlusConfiguration{}},
+		}}
+
+	case *speedsetplus.SpeedsetPlusRequestPayloads_SetConfiguration:
+		// The configuration has no fields yet
+
+	case *speedsetplus.SpeedsetPlusRequestPayloads_GetDeviceInformation:
+		payload = &speedsetplus.SpeedsetPlusResponsePayloads{ResponseType: &speedsetplus.SpeedsetPlusResponsePayloads_GetDeviceInformation{
+			GetDeviceInformation: &speedsetplus.GetSpeedsetPlusDeviceInformationResponsePayload{
+				MotorSerialNumber:    fmt.Sprintf("MTR%s", d.Serial),
+				MotorFirmwareVersion: "3.2.0-sim",
+			},
+		}}
+
+	case *speedsetplus.SpeedsetPlusRequestPayloads_GetActiveErrors:
+		payload = &speedsetplus.SpeedsetPlusResponsePayloads{ResponseType: &speedsetplus.SpeedsetPlusResponsePayloads_GetActiveErrors{
+			GetActiveErrors: &speedsetplus.GetSpeedsetPlusActiveErrorsResponsePayload{ActiveErrors: m.errorList()},
+		}}
+
+	default:
+		resp.ResponseCode = ned.ResponseCode_RESPONSE_COMMAND_ERROR
+	}
+
+	if payload != nil {
+		resp.Payload = &speedsetplus.CommandResponseMessage_Speedsetplus{Speedsetplus: payload}
+	}
+	return resp, changed
+}
+
+func (m *simSpeedsetPlus) state() map[string]interface{} {
+	return m.motorState()
+}
+
+// simVspBooster is a VSP booster pump (WP000246)
+type simVspBooster struct {
+	simMotor
+}
+
+func newSimVspBooster(index int) simModel {
+	return &simVspBooster{simMotor: newSimMotor(1100)}
+}
+
+func (m *simVspBooster) information() *ned.GetDeviceInformationResponsePayload {
+	return &ned.GetDeviceInformationResponsePayload{
+		ProductName:     "VSP Booster (simulated)",
+		ModelId:         "vsp-booster-gen2",
+		ModelVersion:    "WP000246",
+		FirmwareVersion: "1.2.0-sim",
+		OtaVersion:      "1.2.0",
+	}
+}
+
+func (m *simVspBooster) telemetry(rssi int32) proto.Message {
+	outputPower, inputPower, current, torque, lineVoltage, dcBus := m.readings()
+	return &vspbooster.TelemetryMessage{
+		Rssi:               rssi,
+		MotorRpm:           int32(math.Round(m.rpm)),
+		DemandRpm:          m.demandRPM,
+		MotorCurrent:       int32(math.Round(current)),
+		Torque:             int32(math.Round(torque)),
+		InverterInputPower: int32(math.Round(inputPower)),
+		DcBusVoltage:       int32(math.Round(dcBus)),
+		AmbientTemperature: int32(jitter(SimPumpAmbient, 0.3) * 10),
+		OutputPower:        int32(math.Round(outputPower)),
+		MotorLineVoltage:   int32(math.Round(lineVoltage)),
+		MotorInputPower:    int32(math.Round(outputPower / 0.92)),
+		IpmTemperature:     int32(m.ipmTemp * 10),
+	}
+}
+
+func (m *simVspBooster) status() *vspbooster.VspBoosterStatus {
+	return &vspbooster.VspBoosterStatus{Power: m.power, DemandRpm: m.demandRPM}
+}
+
+func (m *simVspBooster) statusInfo() proto.Message {
+	return &vspbooster.InfoMessage{Payload: &vspbooster.VspBoosterInfoPayloads{
+		AnnounceType: &vspbooster.VspBoosterInfoPayloads_Status{Status: m.status()},
+	}}
+}
+
+func (m *simVspBooster) announcements() []proto.Message {
+	return []proto.Message{m.statusInfo()}
+}
+
+func (m *simVspBooster) errorList() *vspbooster.ActiveErrors {
+	errors := &vspbooster.ActiveErrors{}
+	overvoltage, undervoltage := m.dcBusFault()
+	if overvoltage {
+		errors.ErrorList = append(errors.ErrorList, &vspbooster.VspBoosterError{
+			ErrorCode:    vspbooster.VspBoosterErrorCode_VSP_BOOSTER_ERROR_DC_OVERVOLTAGE,
+			ErrorMessage: fmt.Sprintf("DC bus overvoltage: %.0f V", m.dcBus),
+		})
+	}
+	if undervoltage {
+		errors.ErrorList = append(errors.ErrorList, &vspbooster.VspBoosterError{
+			ErrorCode:    vspbooster.VspBoosterErrorCode_VSP_BOOSTER_ERROR_DC_UNDERVOLTAGE,
+			ErrorMessage: fmt.Sprintf("DC bus undervoltage: %.0f V", m.dcBus),
+		})
+	}
+	return errors
+}
+
+func (m *simVspBooster) activeErrors() (proto.Message, []string) {
+	errors := m.errorList()
+	var codes []string
+	for _, e := range errors.GetErrorList() {
+		codes = append(codes, e.GetErrorCode().String())
+	}
+	return &vspbooster.DeviceErrorMessage{ActiveErrors: errors}, codes
+}
+
+func (m *simVspBooster) handle(d *VirtualDevice, request proto.Message) (proto.Message, bool) {
+	req := request.(*vspbooster.CommandRequestMessage)
+	resp := &vspbooster.CommandResponseMessage{ResponseCode: ned.ResponseCode_RESPONSE_OK}
+
+	if common := req.GetCommon(); common != nil {
+		payload, code := d.handleCommon(common)
+		resp.ResponseCode = code
+		if payload != nil {
+			resp.Payload = &vspbooster.CommandResponseMessage_Common{Common: payload}
+		}
+		return resp, false
+	}
+
+	var payload *vspbooster.VspBoosterResponsePayloads
+	changed := false
+	switch r := req.GetVspBooster().GetRequestType().(type) {
+	case *vspbooster.VspBoosterRequestPayloads_SetVspBoosterControlCommand:
+		resp.ResponseCode = m.control(r.SetVspBoosterControlCommand.GetPower(), r.SetVspBoosterControlCommand.GetSetDemandRpm())
+		changed = resp.ResponseCode == ned.ResponseCode_RESPONSE_OK
+
+	case *vspbooster.VspBoosterRequestPayloads_GetStatus:
+		payload = &vspbooster.VspBoosterResponsePayloads{ResponseType: &vspbooster.VspBoosterResponsePayloads_GetStatus{
+			GetStatus: &vspbooster.GetVspBoosterStatusResponsePayload{Status: m.status()},
+		}}
+
+	case *vspbooster.VspBoosterRequestPayloads_GetConfiguration:
+		payload = &vspbooster.VspBoosterResponsePayloads{ResponseType: &vspbooster.VspBoosterResponsePayloads_GetConfiguration{
+			GetConfiguration: &vspbooster.GetVspBoosterConfigurationResponsePayload{Configuration: &vspbooster.VspBoosterConfiguration{}},
+		}}
+
+	case *vspbooster.VspBoosterRequestPayloads_SetConfiguration:
+		// The configuration has no fields yet
+
+	case *vspbooster.VspBoosterRequestPayloads_GetDeviceInformation:
+		payload = &vspbooster.VspBoosterResponsePayloads{ResponseType: &vspbooster.VspBoosterResponsePayloads_GetDeviceInformation{
+			GetDeviceInformation: &vspbooster.GetVspBoosterDeviceInformationResponsePayload{
+				MotorSerialNumber:    fmt.Sprintf("MTR%s", d.Serial),
+				MotorFirmwareVersion: "3.2.0-sim",
+			},
+		}}
+
+	case *vspbooster.VspBoosterRequestPayloads_GetActiveErrors:
+		payload = &vspbooster.VspBoosterResponsePayloads{ResponseType: &vspbooster.VspBoosterResponsePayloads_GetActiveErrors{
+			GetActiveErrors: &vspbooster.GetVspBoosterActiveErrorsResponsePayload{ActiveErrors: m.errorList()},
+		}}
+
+	default:
+		resp.ResponseCode = ned.ResponseCode_RESPONSE_COMMAND_ERROR
+	}
+
+	if payload != nil {
+		resp.Payload = &vspbooster.CommandResponseMessage_VspBooster{VspBooster: payload}
+	}
+	return resp, changed
+}
+
+func (m *simVspBooster) state() map[string]interface{} {
+	return m.motorState()
+}
+
+// ---------------------------------------------------------------------------------------------
+// Digital controller (DCT) with lights
+// ---------------------------------------------------------------------------------------------
+
+// simLight is one light on a virtual DCT
+type simLight struct {
+	address       int32
+	serial        string
+	controlType   icl.LightControlType
+	brightness    int32
+	maxBrightness int32
+	driveMode     *icl.LightDriveMode
+	temperature   float64
+	forcedTemp    float64 // Temperature the light heads for under light_overtemperature, 0 when none
+}
+
+// effectiveBrightness is the brightness the light runs at after derating, in percent
+func (l *simLight) effectiveBrightness() float64 {
+	if l.controlType != icl.LightControlType_LIGHT_CONTROL_ON && l.controlType != icl.LightControlType_LIGHT_CONTROL_BLINKING {
+		return 0
+	}
+	brightness := float64(l.brightness) * float64(l.derating()) / 100
+	if l.controlType == icl.LightControlType_LIGHT_CONTROL_BLINKING {
+		brightness /= 2
+	}
+	return brightness
+}
+
+// derating is the percentage of the set brightness the light allows at its temperature
+func (l *simLight) derating() int32 {
+	if l.temperature <= SimDctDerateAbove {
+		return 100
+	}
+	return int32(math.Max(50, 100-(l.temperature-SimDctDerateAbove)*10))
+}
+
+// simDct is a digital controller transformer driving a set of lights; lights heat with
+// brightness and derate when hot
+type simDct struct {
+	lights    map[int32]*simLight
+	mode      icl.DctMode
+	boardTemp float64
+	voltage   float64
+}
+
+func newSimDct(index int) simModel {
+	dct := &simDct{
+		lights:    make(map[int32]*simLight),
+		mode:      icl.DctMode_DCT_MODE_NORMAL,
+		boardTemp: SimPumpAmbient,
+		voltage:   12,
+	}
+	for address := int32(1); address <= SimDctLights; address++ {
+		dct.lights[address] = &simLight{
+			address:       address,
+			serial:        fmt.Sprintf("LGT%02d%03d%d", index, address, address),
+			controlType:   icl.LightControlType_LIGHT_CONTROL_OFF,
+			brightness:    100,
+			maxBrightness: 100,
+			driveMode: &icl.LightDriveMode{LightDriveMode: &icl.LightDriveMode_JandyDrive{
+				JandyDrive: &icl.LightJandyDrive{TargetColor: 1},
+			}},
+			temperature: SimPumpAmbient,
+		}
+	}
+	return dct
+}
+
+// sortedLights returns the lights in address order
+func (m *simDct) sortedLights() []*simLight {
+	lights := make([]*simLight, 0, len(m.lights))
+	for _, light := range m.lights {
+		lights = append(lights, light)
+	}
+	sort.Slice(lights, func(i, j int) bool { return lights[i].address < lights[j].address })
+	return lights
+}
+
+func (m *simDct) information() *ned.GetDeviceInformationResponsePayload {
+	return &ned.GetDeviceInformationResponsePayload{
+		ProductName:     "Infinite WaterColor DCT (simulated)",
+		ModelId:         "dct20",
+		ModelVersion:    "1",
+		FirmwareVersion: "0.9.4-sim",
+		OtaVersion:      "0.9.4",
+	}
+}
+
+// power is the total light draw in watts
+func (m *simDct) power() float64 {
+	total := 0.0
+	for _, light := range m.lights {
+		total += SimDctLightWattage * light.effectiveBrightness() / 100
+	}
+	return total
+}
+
+func (m *simDct) step(dt time.Duration) {
+	for _, light := range m.lights {
+		target := SimPumpAmbient + SimDctLightHeatRise*light.effectiveBrightness()/100
+		if light.forcedTemp > 0 {
+			target = light.forcedTemp
+		}
+		light.temperature = settle(light.temperature, target, SimDctThermalTau, dt)
+	}
+	m.boardTemp = settle(m.boardTemp, SimPumpAmbient+20*m.power()/SimDctCapacity, SimDctThermalTau, dt)
+	m.voltage = settle(m.voltage, jitter(12, 0.2)-m.power()/SimDctCapacity*0.4, 5*time.Second, dt)
+}
+
+func (m *simDct) telemetry(rssi int32) proto.Message {
+	power := m.power()
+	derating := int32(100)
+	if power > SimDctCapacity {
+		derating = int32(SimDctCapacity / power * 100)
+	}
+
+	telemetry := &icl.TelemetryMessage{
+		Power:                      int32(math.Round(power)),
+		Current:                    int32(math.Round(power / m.voltage * 1000)),
+		Voltage:                    math.Round(m.voltage*100) / 100,
+		BoardTemperature:           int32(m.boardTemp * 10),
+		Rssi:                       rssi,
+		DctPowerDeratingPercentage: derating,
+	}
+	for _, light := range m.sortedLights() {
+		telemetry.LightsTelemetry = append(telemetry.LightsTelemetry, &icl.LightTelemetry{
+			Address:                 light.address,
+			LightTemperature:        int32(light.temperature * 10),
+			LightDeratingPercentage: light.derating(),
+		})
+	}
+	return telemetry
+}
+
+func (m *simDct) lightStatus(light *simLight) *icl.LightStatus {
+	return &icl.LightStatus{
+		Address:       light.address,
+		ControlType:   light.controlType,
+		Brightness:    light.brightness,
+		MaxBrightness: light.maxBrightness,
+		DriveMode:     light.driveMode,
+		IsAvailable:   true,
+	}
+}
+
+func (m *simDct) lightInformation(light *simLight) *icl.LightDeviceInformation {
+	return &icl.LightDeviceInformation{
+		Address:         light.address,
+		Model:           "IWC-LED (simulated)",
+		FirmwareVersion: "2.0.1-sim",
+		SerialNumber:    light.serial,
+	}
+}
+
+func (m *simDct) status() *icl.DctStatus {
+	status := &icl.DctStatus{DctWattageCapacity: SimDctCapacity}
+	for _, light := range m.sortedLights() {
+		status.LightsStatus = append(status.LightsStatus, m.lightStatus(light))
+	}
+	return status
+}
+
+func (m *simDct) statusInfo() proto.Message {
+	return &icl.InfoMessage{Payload: &icl.InfiniteWaterColorDCTInfoPayloads{
+		AnnounceType: &icl.InfiniteWaterColorDCTInfoPayloads_DctStatusChanged{
+			DctStatusChanged: &icl.DctStatusChangedInfoPayload{DctStatus: m.status()},
+		},
+	}}
+}
+
+func (m *simDct) announcements() []proto.Message {
+	var messages []proto.Message
+	for _, light := range m.sortedLights() {
+		messages = append(messages, &icl.InfoMessage{Payload: &icl.InfiniteWaterColorDCTInfoPayloads{
+			AnnounceType: &icl.InfiniteWaterColorDCTInfoPayloads_LightAdded{
+				LightAdded: &icl.LightAddedInfoPayload{LightInformation: m.lightInformation(light)},
+			},
+		}})
+	}
+	return append(messages, m.statusInfo())
+}
+
+func (m *simDct) errorList() *icl.ActiveErrors {
+	errors := &icl.ActiveErrors{}
+	for _, light := range m.sortedLights() {
+		if light.temperature > SimDctOverTemperature {
+			errors.LightsErrors = append(errors.LightsErrors, &icl.LightErrors{
+				LightAddress: light.address,
+				LightErrors: []*icl.LightErrorDetails{{
+					ErrorCode:    icl.LightErrorCode_HIGH_TEMPERATURE_ERROR,
+					ErrorMessage: fmt.Sprintf("Light temperature %.1f °C", light.temperature),
+				}},
+			})
+		}
+	}
+	return errors
+}
+
+func (m *simDct) activeErrors() (proto.Message, []string) {
+	errors := m.errorList()
+	var codes []string
+	for _, light := range errors.GetLightsErrors() {
+		for _, e := range light.GetLightErrors() {
+			codes = append(codes, fmt.Sprintf("%s@%d", e.GetErrorCode(), light.GetLightAddress()))
+		}
+	}
+	return errors, codes
+}
+
+// applyPatch applies one SetLightConfiguration patch
+func (m *simDct) applyPatch(patch *icl.LightConfigurationPatch) ned.ResponseCode {
+	light, exists := m.lights[patch.GetAddress()]
+	if !exists {
+		return ned.ResponseCode_RESPONSE_BAD_REQUEST
+	}
+	for _, field := range patch.GetFields() {
+		switch f := field.GetFieldType().(type) {
+		case *icl.LightConfigurationPatch_Field_ControlType:
+			light.controlType = f.ControlType
+		case *icl.LightConfigurationPatch_Field_Brightness:
+			if f.Brightness < 0 || f.Brightness > 100 {
+				return ned.ResponseCode_RESPONSE_BAD_REQUEST
+			}
+			light.brightness = f.Brightness
+			if light.brightness > light.maxBrightness {
+				light.brightness = light.maxBrightness
+			}
+		case *icl.LightConfigurationPatch_Field_DriveMode:
+			light.driveMode = f.DriveMode
+		}
+	}
+	return ned.ResponseCode_RESPONSE_OK
+}
+
+func (m *simDct) handle(d *VirtualDevice, request proto.Message) (proto.Message, bool) {
+	req := request.(*icl.CommandRequestMessage)
+	resp := &icl.CommandResponseMessage{ResponseCode: ned.ResponseCode_RESPONSE_OK}
+
+	if common := req.GetCommon(); common != nil {
+		payload, code := d.handleCommon(common)
+		resp.ResponseCode = code
+		if payload != nil {
+			resp.Payload = &icl.CommandResponseMessage_Common{Common: payload}
+		}
+		return resp, false
+	}
+
+	var payload *icl.InfiniteWaterColorDCTResponsePayloads
+	changed := false
+	switch r := req.GetIcl().GetRequestType().(type) {
+	case *icl.DCTRequests_SetDct20Lights:
+		for _, patch := range r.SetDct20Lights.GetLightPatch() {
+			if code := m.applyPatch(patch); code != ned.ResponseCode_RESPONSE_OK {
+				resp.ResponseCode = code
+				break
+			}
+			changed = true
+		}
+
+	case *icl.DCTRequests_GetDct20Status:
+		payload = &icl.InfiniteWaterColorDCTResponsePayloads{ResponseType: &icl.InfiniteWaterColorDCTResponsePayloads_GetDct20Status{
+			GetDct20Status: &icl.GetDctStatusResponse{DctStatus: m.status()},
+		}}
+
+	case *icl.DCTRequests_GetDct20LightStatus:
+		light, exists := m.lights[r.GetDct20LightStatus.GetLightAddress()]
+		if !exists {
+			resp.ResponseCode = ned.ResponseCode_RESPONSE_BAD_REQUEST
+			break
+		}
+		payload = &icl.InfiniteWaterColorDCTResponsePayloads{ResponseType: &icl.InfiniteWaterColorDCTResponsePayloads_GetDct20LightStatus{
+			GetDct20LightStatus: &icl.GetLightStatusResponse{LightStatus: m.lightStatus(light)},
+		}}
+
+	case *icl.DCTRequests_GetDct20AllLightsInformation:
+		information := &icl.GetDctInformationResponse{}
+		for _, light := range m.sortedLights() {
+			information.LightsInformation = append(information.LightsInformation, m.lightInformation(light))
+		}
+		payload = &icl.InfiniteWaterColorDCTResponsePayloads{ResponseType: &icl.InfiniteWaterColorDCTResponsePayloads_GetDct20AllLightsInformation{
+			GetDct20AllLightsInformation: information,
+		}}
+
+	case *icl.DCTRequests_GetDct20LightInformation:
+		light, exists := m.lights[r.GetDct20LightInformation.GetLightAddress()]
+		if !exists {
+			resp.ResponseCode = ned.ResponseCode_RESPONSE_BAD_REQUEST
+			break
+		}
+		payload = &icl.InfiniteWaterColorDCTResponsePayloads{ResponseType: &icl.InfiniteWaterColorDCTResponsePayloads_GetDct20LightInformation{
+			GetDct20LightInformation: &icl.GetLightInformationResponse{LightInformation: m.lightInformation(light)},
+		}}
+
+	case *icl.DCTRequests_RemoveLight:
+		if _, exists := m.lights[r.RemoveLight.GetLightAddress()]; !exists {
+			resp.ResponseCode = ned.ResponseCode_RESPONSE_BAD_REQUEST
+			break
+		}
+		delete(m.lights, r.RemoveLight.GetLightAddress())
+		changed = true
+
+	case *icl.DCTRequests_SetConfiguration:
+		m.mode = r.SetConfiguration.GetMode()
+
+	case *icl.DCTRequests_GetConfiguration:
+		payload = &icl.InfiniteWaterColorDCTResponsePayloads{ResponseType: &icl.InfiniteWaterColorDCTResponsePayloads_GetConfiguration{
+			GetConfiguration: &icl.GetDctConfigurationResponse{Mode: m.mode},
+		}}
+
+	case *icl.DCTRequests_SetMaxBrightness:
+		for _, max := range r.SetMaxBrightness.GetLightsMaxBrightness() {
+			light, exists := m.lights[max.GetAddress()]
+			if !exists || max.GetMaxBrightness() < 0 || max.GetMaxBrightness() > 100 {
+				resp.ResponseCode = ned.ResponseCode_RESPONSE_BAD_REQUEST
+				break
+			}
+			light.maxBrightness = max.GetMaxBrightness()
+			if light.brightness > light.maxBrightness {
+				light.brightness = light.maxBrightness
+			}
+			changed = true
+		}
+
+	case *icl.DCTRequests_SwapAddresses:
+		a, b := r.SwapAddresses.GetAddressA(), r.SwapAddresses.GetAddressB()
+		lightA, existsA := m.lights[a]
+		lightB, existsB := m.lights[b]
+		if !existsA || !existsB {
+			resp.ResponseCode = ned.ResponseCode_RESPONSE_BAD_REQUEST
+			break
+		}
+		lightA.address, lightB.address = b, a
+		m.lights[a], m.lights[b] = lightB, lightA
+		changed = true
+
+	case *icl.DCTRequests_GetActiveErrors:
+		payload = &icl.InfiniteWaterColorDCTResponsePayloads{ResponseType: &icl.InfiniteWaterColorDCTResponsePayloads_GetActiveErrors{
+			GetActiveErrors: &icl.GetActiveErrorsResponse{ActiveErrors: m.errorList()},
+		}}
+
+	default:
+		resp.ResponseCode = ned.ResponseCode_RESPONSE_COMMAND_ERROR
+	}
+
+	if payload != nil {
+		resp.Payload = &icl.CommandResponseMessage_Icl{Icl: payload}
+	}
+	return resp, changed
+}
+
+func (m *simDct) state() map[string]interface{} {
+	lights := make([]map[string]interface{}, 0, len(m.lights))
+	for _, light := range m.sortedLights() {
+		lights = append(lights, map[string]interface{}{
+			"address":        light.address,
+			"control_type":   light.controlType.String(),
+			"brightness":     light.brightness,
+			"max_brightness": light.maxBrightness,
+			"temperature":    math.Round(light.temperature*10) / 10,
+			"derating":       light.derating(),
+		})
+	}
+	return map[string]interface{}{
+		"mode":              m.mode.String(),
+		"power":             math.Round(m.power()),
+		"board_temperature": math.Round(m.boardTemp*10) / 10,
+		"lights":            lights,
+	}
+}
+
+// fault heats one light, or every light, toward a temperature regardless of brightness
+func (m *simDct) fault(name string, params faultParams, active bool) error {
+	if name != FaultLightOverTemp {
+		return fmt.Errorf("not supported")
+	}
+	address, err := params.number("address", 0)
+	if err != nil {
+		return err
+	}
+	temperature, err := params.number("temperature", 85)
+	if err != nil {
+		return err
+	}
+	if !active {
+		temperature = 0
+	}
+
+	if address == 0 {
+		for _, light := range m.lights {
+			light.forcedTemp = temperature
+		}
+		return nil
+	}
+	light, exists := m.lights[int32(address)]
+	if !exists {
+		return fmt.Errorf("no light at address %.0f", address)
+	}
+	light.forcedTemp = temperature
+	return nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Faults that can be injected into virtual devices
+const (
+	FaultNoFlow              = "no_flow"
+	FaultLowSalt             = "low_salt"
+	FaultHighSalt            = "high_salt"
+	FaultCellTilt            = "cell_tilt"
+	FaultDCOvervoltage       = "dc_overvoltage"
+	FaultDCUndervoltage      = "dc_undervoltage"
+	FaultLightOverTemp       = "light_overtemperature"
+	FaultTelemetryDropout    = "telemetry_dropout"
+	FaultDisconnect          = "disconnect"
+	FaultDisconnectOnCommand = "disconnect_on_command"
+)
+
+// simulatorFaults describes every fault and its parameters, for validation and /api/scenarios
+var simulatorFaults = map[string]string{
+	FaultNoFlow:              "sanitizer: no water through the cell, production stops",
+	FaultLowSalt:             "sanitizer: salt reads ppm (default 2200)",
+	FaultHighSalt:            "sanitizer: salt reads ppm (default 5000)",
+	FaultCellTilt:            "sanitizer: cell tilted by degrees (default 45), CELL_TILTED above 30",
+	FaultDCOvervoltage:       "pumps: DC bus at voltage (default 420), the drive trips",
+	FaultDCUndervoltage:      "pumps: DC bus at voltage (default 180), the drive trips",
+	FaultLightOverTemp:       "DCT: light at address (default every light) heats to temperature (default 85), derating then HIGH_TEMPERATURE_ERROR",
+	FaultTelemetryDropout:    "any: telemetry stops while the connection stays up",
+	FaultDisconnect:          "any: connection dropped without DISCONNECT so the broker sends the will; offline until cleared",
+	FaultDisconnectOnCommand: "any: the next command is not answered and the connection drops; offline for offline (default 10s)",
+}
+
+// SimDisconnectOffline is how long a device stays away after disconnect_on_command fires
+const SimDisconnectOffline = 10 * time.Second
+
+// simFault is a fault active on a virtual device
+type simFault struct {
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Since      time.Time              `json:"since"`
+	Until      *time.Time             `json:"until,omitempty"`  // Cleared automatically at this time
+	RunID      string                 `json:"run_id,omitempty"` // Scenario run that injected it
+}
+
+// faultParams are the parameters of an injected fault, decoded from YAML or JSON
+type faultParams map[string]interface{}
+
+// number returns a numeric parameter, or fallback when it is absent
+func (p faultParams) number(key string, fallback float64) (float64, error) {
+	value, exists := p[key]
+	if !exists {
+		return fallback, nil
+	}
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("parameter %s: %v is not a number", key, value)
+}
+
+// duration returns a duration parameter such as "10s", or fallback when it is absent
+func (p faultParams) duration(key string, fallback time.Duration) (time.Duration, error) {
+	value, exists := p[key]
+	if !exists {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(fmt.Sprint(value))
+	if err != nil {
+		return 0, fmt.Errorf("parameter %s: %v", key, err)
+	}
+	return d, nil
+}
+
+// injectFault starts a fault on the device. A fault already active is replaced. A zero
+// duration keeps the fault until clearFault.
+func (d *VirtualDevice) injectFault(name string, params faultParams, duration time.Duration, runID string) error {
+	if _, known := simulatorFaults[name]; !known {
+		return fmt.Errorf("unknown fault %q", name)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	switch name {
+	case FaultTelemetryDropout:
+	case FaultDisconnect, FaultDisconnectOnCommand:
+		if _, err := params.duration("offline", SimDisconnectOffline); err != nil {
+			return err
+		}
+		if !d.canDropConnection() {
+			return fmt.Errorf("%s needs a tcp or tls broker", name)
+		}
+	default:
+		if err := d.model.fault(name, params, true); err != nil {
+			return fmt.Errorf("%s on %s: %v", name, d.Category, err)
+		}
+	}
+
+	fault := &simFault{Name: name, Parameters: params, Since: time.Now(), RunID: runID}
+	if duration > 0 {
+		until := fault.Since.Add(duration)
+		fault.Until = &until
+	}
+	d.faults[name] = fault
+	log.Printf("💥 Virtual device %s: fault %s injected", d.Serial, name)
+
+	if name == FaultDisconnect {
+		d.dropConnection()
+	}
+	return nil
+}
+
+// clearFault ends a fault; clearing one that is not active does nothing
+func (d *VirtualDevice) clearFault(name string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.clearFaultLocked(name)
+}
+
+// clearFaultLocked ends a fault. Caller must hold the device mutex.
+func (d *VirtualDevice) clearFaultLocked(name string) {
+	fault, active := d.faults[name]
+	if !active {
+		return
+	}
+	delete(d.faults, name)
+
+	switch name {
+	case FaultTelemetryDropout, FaultDisconnect, FaultDisconnectOnCommand:
+		// Telemetry resumes and the next reconnect attempt succeeds
+	default:
+		d.model.fault(name, faultParams(fault.Parameters), false)
+	}
+	log.Printf("🩹 Virtual device %s: fault %s cleared", d.Serial, name)
+}
+
+// clearRunFaults ends every fault injected by a scenario run
+func (d *VirtualDevice) clearRunFaults(runID string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for name, fault := range d.faults {
+		if fault.RunID == runID {
+			d.clearFaultLocked(name)
+		}
+	}
+}
+
+// expireFaults clears faults whose duration has passed. Caller must hold the device mutex.
+func (d *VirtualDevice) expireFaults(now time.Time) {
+	for name, fault := range d.faults {
+		if fault.Until != nil && now.After(*fault.Until) {
+			d.clearFaultLocked(name)
+		}
+	}
+}
+
+// hasFault reports whether a fault is active. Caller must hold the device mutex.
+func (d *VirtualDevice) hasFault(name string) bool {
+	_, active := d.faults[name]
+	return active
+}
+
+// disconnectOnCommand fires a pending disconnect_on_command fault: the connection drops and the
+// device stays away for the configured time. Reports whether the request must go unanswered.
+// Caller must hold the device mutex.
+func (d *VirtualDevice) disconnectOnCommand() bool {
+	fault, pending := d.faults[FaultDisconnectOnCommand]
+	if !pending {
+		return false
+	}
+	offline, _ := faultParams(fault.Parameters).duration("offline", SimDisconnectOffline)
+	delete(d.faults, FaultDisconnectOnCommand)
+
+	until := time.Now().Add(offline)
+	d.faults[FaultDisconnect] = &simFault{Name: FaultDisconnect, Since: time.Now(), Until: &until, RunID: fault.RunID}
+	log.Printf("💥 Virtual device %s: dropping connection mid-command, offline for %v", d.Serial, offline)
+	d.dropConnection()
+	return true
+}
+
+// canDropConnection reports whether the device dials its own connection, which an injected
+// disconnect needs to close without sending DISCONNECT
+func (d *VirtualDevice) canDropConnection() bool {
+	uri, err := url.Parse(d.mqtt.Broker)
+	return err == nil && uri.Scheme != "ws" && uri.Scheme != "wss"
+}
+
+// droppableConn is a broker socket that can fail the way a network outage does. Closing a
+// socket outright is not enough: paho takes "use of closed network connection" to mean it
+// closed the socket itself and never reconnects.
+type droppableConn struct {
+	net.Conn
+	dropped atomic.Bool
+}
+
+// errSimulatedOutage is what the MQTT client sees once a connection is dropped
+var errSimulatedOutage = fmt.Errorf("connection reset (simulated outage)")
+
+func (c *droppableConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if c.dropped.Load() {
+		return 0, errSimulatedOutage
+	}
+	return n, err
+}
+
+func (c *droppableConn) Write(b []byte) (int, error) {
+	if c.dropped.Load() {
+		return 0, errSimulatedOutage
+	}
+	return c.Conn.Write(b)
+}
+
+// dropConnection fails the socket under the MQTT client. The broker sees the connection go
+// away without a DISCONNECT and publishes the last will. Caller must hold the device mutex.
+func (d *VirtualDevice) dropConnection() {
+	if d.conn != nil {
+		d.conn.dropped.Store(true)
+		d.conn.Close()
+		d.conn = nil
+	}
+}
+
+// dial opens the broker connection for the MQTT client, refusing while the device is meant to
+// be offline. Keeping the socket lets dropConnection fail it on demand.
+func (d *VirtualDevice) dial(uri *url.URL, options mqtt.ClientOptions) (net.Conn, error) {
+	d.mutex.Lock()
+	offline := d.hasFault(FaultDisconnect)
+	d.mutex.Unlock()
+	if offline {
+		return nil, fmt.Errorf("%s is offline (injected fault)", d.Serial)
+	}
+
+	dialer := &net.Dialer{Timeout: options.ConnectTimeout}
+	var conn net.Conn
+	var err error
+	if isTLSBroker(uri.String()) {
+		conn, err = tls.DialWithDialer(dialer, "tcp", uri.Host, options.TLSConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", uri.Host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	droppable := &droppableConn{Conn: conn}
+	d.mutex.Lock()
+	d.conn = droppable
+	d.mutex.Unlock()
+	return droppable, nil
+}
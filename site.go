@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// SiteCookie remembers which site a browser is working with, so pages and scripts that use
+// root-relative URLs (/api/devices, /terminal, ...) reach the site they were opened from
+const SiteCookie = "ngasim_site"
+
+// Fleet runs one NgaSim per configured site behind a single web server. Each site has its own
+// broker connection, poller, device namespace, command queue and emergency stop.
+type Fleet struct {
+	sites  []*NgaSim // Configuration order; the first site is the default
+	byName map[string]*NgaSim
+	muxes  map[string]*http.ServeMux
+	config *LoadedConfig
+	server *http.Server
+	broker *EmbeddedBroker // In-process MQTT broker, nil unless broker.embedded is set
+}
+
+// SiteSummary is the fleet view of one site
+type SiteSummary struct {
+	Name           string `json:"name"`
+	Title          string `json:"title,omitempty"`
+	Broker         string `json:"broker"`
+	Connected      bool   `json:"connected"`
+	DemoMode       bool   `json:"demo_mode"` // No broker, devices are simulated
+	Devices        int    `json:"devices"`
+	Online         int    `json:"online"`
+	ActiveFaults   int    `json:"active_faults"`
+	QueuedCommands int    `json:"queued_commands"`
+	URL            string `json:"url"`
+}
+
+// FleetDevice is a device listed across sites
+type FleetDevice struct {
+	Site string `json:"site"`
+	*Device
+}
+
+// NewFleet creates an NgaSim for every site in the configuration. The embedded broker, when
+// enabled, is started first so sites can connect to it.
+func NewFleet(cfg *LoadedConfig) (*Fleet, error) {
+	fleet := &Fleet{
+		byName: make(map[string]*NgaSim),
+		muxes:  make(map[string]*http.ServeMux),
+		config: cfg,
+	}
+
+	if cfg.Broker.Embedded {
+		fleet.broker = NewEmbeddedBroker()
+		if err := fleet.broker.Start(cfg.Broker.Listen); err != nil {
+			return nil, err
+		}
+		cfg.useEmbeddedBroker(fleet.broker.URL())
+	}
+
+	for _, name := range cfg.siteNames() {
+		siteConfig, err := cfg.forSite(name)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("🏊 Creating site %s (broker %s)", name, siteConfig.MQTT.Broker)
+
+		site := NewNgaSim(siteConfig)
+		site.fleet = fleet
+		fleet.sites = append(fleet.sites, site)
+		fleet.byName[name] = site
+	}
+	return fleet, nil
+}
+
+// Start starts every site and then the web server that serves them all
+func (f *Fleet) Start() error {
+	for _, site := range f.sites {
+		if err := site.Start(); err != nil {
+			return fmt.Errorf("failed to start site %s: %v", site.site, err)
+		}
+	}
+
+	// Create and configure the HTTP server
+	// Server struct contains all the HTTP server configuration
+	httpConfig := f.config.HTTP
+	f.server = &http.Server{
+		Addr:    httpConfig.Listen, // Default ":8082" listens on all interfaces, port 8082
+		Handler: f.routes(),        // Fleet routes plus every site's routes
+		// Note: Go's HTTP server has sensible defaults for timeouts, etc.
+	}
+
+	// Start the HTTP server in a goroutine
+	// The goroutine is CRITICAL - without it, ListenAndServe() would block forever
+	// and the main() function would never reach the "select {}" statement
+	go func() {
+		log.Printf("Web server starting on %s", httpConfig.Listen)
+
+		// ListenAndServe() blocks until the server shuts down
+		// It returns http.ErrServerClosed on normal shutdown, or an actual error on failure
+		if err := f.server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Printf("Server error: %v", err)
+		}
+		// When this goroutine exits, the HTTP server has stopped
+	}()
+
+	return nil
+}
+
+// routes mounts each site under /sites/<name>/ and serves root URLs from the selected site
+func (f *Fleet) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	for _, site := range f.sites {
+		siteMux := site.routes()
+		f.muxes[site.site] = siteMux
+
+		prefix := "/sites/" + site.site
+		mux.Handle(prefix+"/", f.rememberSite(site.site, http.StripPrefix(prefix, siteMux)))
+	}
+
+	mux.HandleFunc("/fleet", f.handleFleetView)                             // Aggregate view across sites
+	mux.HandleFunc("/api/sites", f.handleSites)                             // Site list with connection and device counts
+	mux.HandleFunc("/api/fleet", f.handleFleet)                             // Sites plus every device, tagged with its site
+	mux.HandleFunc("/api/fleet/emergency-stop", f.handleFleetEmergencyStop) // Emergency stop on every site
+	mux.HandleFunc("/api/broker", f.handleBroker)                           // Embedded MQTT broker clients and counters
+	mux.HandleFunc("/", f.handleSelectedSite)                               // Everything else goes to the selected site
+
+	return mux
+}
+
+// rememberSite sets the site cookie when a site's main page is opened under /sites/<name>/
+func (f *Fleet) rememberSite(name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sites/"+name+"/" {
+			http.SetCookie(w, &http.Cookie{Name: SiteCookie, Value: name, Path: "/", SameSite: http.SameSiteLaxMode})
+		}
+		w.Header().Set("X-NgaSim-Site", name)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// selectedSite picks the site for a root URL: ?site=<name>, then the site cookie, then the
+// default site
+func (f *Fleet) selectedSite(r *http.Request) (*NgaSim, error) {
+	if name := r.URL.Query().Get("site"); name != "" {
+		site, exists := f.byName[name]
+		if !exists {
+			return nil, fmt.Errorf("site '%s' not found", name)
+		}
+		return site, nil
+	}
+	if cookie, err := r.Cookie(SiteCookie); err == nil {
+		if site, exists := f.byName[cookie.Value]; exists {
+			return site, nil
+		}
+	}
+	return f.sites[0], nil
+}
+
+// handleSelectedSite serves a root URL from the selected site
+func (f *Fleet) handleSelectedSite(w http.ResponseWriter, r *http.Request) {
+	site, err := f.selectedSite(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("X-NgaSim-Site", site.site)
+	f.muxes[site.site].ServeHTTP(w, r)
+}
+
+// summary reports the state of one site for the fleet view
+func (n *NgaSim) summary() SiteSummary {
+	cfg := n.currentConfig()
+	summary := SiteSummary{
+		Name:           n.site,
+		Broker:         cfg.MQTT.Broker,
+		Connected:      n.brokerConnected(),
+		DemoMode:       n.demoMode,
+		QueuedCommands: n.commandQueue.Len(),
+		URL:            "/sites/" + n.site + "/",
+	}
+	for _, site := range cfg.Sites {
+		if site.Name == n.site {
+			summary.Title = site.Title
+		}
+	}
+
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	summary.Devices = len(n.devices)
+	for _, device := range n.devices {
+		if device.Status == DeviceOnline {
+			summary.Online++
+		}
+		summary.ActiveFaults += len(device.ActiveFaults())
+	}
+	return summary
+}
+
+// summaries returns the fleet view of every site, in configuration order
+func (f *Fleet) summaries() []SiteSummary {
+	summaries := make([]SiteSummary, 0, len(f.sites))
+	for _, site := range f.sites {
+		summaries = append(summaries, site.summary())
+	}
+	return summaries
+}
+
+// devices lists the devices of every site, sites in configuration order
+func (f *Fleet) devices() []FleetDevice {
+	devices := make([]FleetDevice, 0)
+	for _, site := range f.sites {
+		for _, device := range site.getSortedDevices() {
+			devices = append(devices, FleetDevice{Site: site.site, Device: device})
+		}
+	}
+	return devices
+}
+
+// handleSites returns the configured sites with their connection state and device counts
+func (f *Fleet) handleSites(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(f.summaries())
+}
+
+// handleFleet returns every site and every device across the fleet
+func (f *Fleet) handleFleet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sites":   f.summaries(),
+		"devices": f.devices(),
+	})
+}
+
+// handleFleetEmergencyStop runs the emergency stop on every site
+func (f *Fleet) handleFleetEmergencyStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("🛑 Fleet emergency stop request received for %d sites", len(f.sites))
+
+	results := make(map[string]interface{})
+	found, stopped := 0, 0
+	for _, site := range f.sites {
+		result := site.emergencyStop()
+		results[site.site] = result
+		found += result["sanitizers_found"].(int)
+		stopped += result["sanitizers_stopped"].(int)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":            stopped > 0,
+		"message":            fmt.Sprintf("Emergency stop executed on %d/%d sanitizers across %d sites", stopped, found, len(f.sites)),
+		"sanitizers_found":   found,
+		"sanitizers_stopped": stopped,
+		"sites":              results,
+	})
+}
+
+// handleFleetView serves the aggregate page listing every site and device
+func (f *Fleet) handleFleetView(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Title   string
+		Version string
+		Sites   []SiteSummary
+		Devices []FleetDevice
+	}{
+		Title:   "NgaSim Fleet",
+		Version: NgaSimVersion,
+		Sites:   f.summaries(),
+		Devices: f.devices(),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := fleetTemplate.Execute(w, data); err != nil {
+		http.Error(w, fmt.Sprintf("Template error: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// siteLinks lists the other sites for the site switcher, empty when there is only one site
+func (n *NgaSim) siteLinks() []SiteSummary {
+	if n.fleet == nil || len(n.fleet.sites) < 2 {
+		return nil
+	}
+	return n.fleet.summaries()
+}
+
+// watchConfigReload reloads the configuration of every site whenever the process receives SIGHUP
+func (f *Fleet) watchConfigReload() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		log.Println("📥 SIGHUP received, reloading configuration...")
+		fresh, err := LoadConfig(f.config.args)
+		if err != nil {
+			log.Printf("❌ Config reload failed, keeping current settings: %v", err)
+			continue
+		}
+		if f.broker != nil {
+			fresh.useEmbeddedBroker(f.broker.URL())
+		}
+		if running, configured := f.config.siteNames(), fresh.siteNames(); strings.Join(running, ",") != strings.Join(configured, ",") {
+			log.Printf("⚠️ Config [[sites]] changed to %v; restart to apply", configured)
+		}
+
+		for _, site := range f.sites {
+			if err := site.reloadConfig(fresh); err != nil {
+				log.Printf("❌ Config reload of site %s failed, keeping current settings: %v", site.site, err)
+			}
+		}
+		log.Println("✅ Configuration reloaded")
+	}
+}
+
+// cleanup stops every site, then the embedded broker
+func (f *Fleet) cleanup() {
+	for _, site := range f.sites {
+		site.cleanup()
+	}
+	if f.broker != nil {
+		f.broker.Stop()
+	}
+}
+
+// usesEmbeddedBroker reports whether the site is connected to the fleet's own broker
+func (n *NgaSim) usesEmbeddedBroker() bool {
+	return n.fleet != nil && n.fleet.broker != nil && n.currentConfig().MQTT.Broker == n.fleet.broker.URL()
+}
+
+// shutdown stops this site, or the whole fleet it belongs to
+func (n *NgaSim) shutdown() {
+	if n.fleet != nil {
+		n.fleet.cleanup()
+		return
+	}
+	n.cleanup()
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"NgaSim/ned/sanitizer"
+	"NgaSim/ned/speedsetplus"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// TelemetryResult is a decoded telemetry payload
+type TelemetryResult struct {
+	Summary string                 // One line for the device terminal
+	Parsed  *ParsedProtobufMessage // Field-by-field view for the terminal, may be nil
+	Apply   func()                 // Typed device update; takes the NgaSim lock itself
+}
+
+// TelemetryDecoder decodes an async/<category>/<serial>/dt payload for one category
+type TelemetryDecoder func(deviceSerial string, payload []byte) (*TelemetryResult, error)
+
+// TelemetryDecoderRegistry maps MQTT categories to their telemetry decoders.
+// Payloads from categories without a decoder, or that their decoder rejects, go to the
+// fallback decoder (legacy JSON).
+type TelemetryDecoderRegistry struct {
+	decoders map[string]TelemetryDecoder
+	fallback TelemetryDecoder
+	mutex    sync.RWMutex
+}
+
+// NewTelemetryDecoderRegistry creates an empty registry
+func NewTelemetryDecoderRegistry() *TelemetryDecoderRegistry {
+	return &TelemetryDecoderRegistry{
+		decoders: make(map[string]TelemetryDecoder),
+	}
+}
+
+// Register sets the decoder for a category, replacing any existing one
+func (r *TelemetryDecoderRegistry) Register(category string, decoder TelemetryDecoder) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.decoders[category] = decoder
+}
+
+// SetFallback sets the decoder used when no category decoder applies
+func (r *TelemetryDecoderRegistry) SetFallback(decoder TelemetryDecoder) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.fallback = decoder
+}
+
+// Categories returns the categories with a registered decoder
+func (r *TelemetryDecoderRegistry) Categories() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	categories := make([]string, 0, len(r.decoders))
+	for category := range r.decoders {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// Decode runs the category decoder, then the fallback if that fails or does not exist
+func (r *TelemetryDecoderRegistry) Decode(category, deviceSerial string, payload []byte) (*TelemetryResult, error) {
+	r.mutex.RLock()
+	decoder, exists := r.decoders[category]
+	fallback := r.fallback
+	r.mutex.RUnlock()
+
+	var decodeErr error
+	if exists {
+		result, err := decoder(deviceSerial, payload)
+		if err == nil {
+			return result, nil
+		}
+		decodeErr = err
+		log.Printf("Failed to decode %s telemetry: %v", category, err)
+	}
+
+	if fallback != nil {
+		if result, err := fallback(deviceSerial, payload); err == nil {
+			return result, nil
+		} else if decodeErr == nil {
+			decodeErr = err
+		}
+	}
+
+	if decodeErr == nil {
+		decodeErr = fmt.Errorf("no telemetry decoder for category %s", category)
+	}
+	return nil, decodeErr
+}
+
+// registerDefaultTelemetryDecoders registers the decoders for every supported product line
+func (sim *NgaSim) registerDefaultTelemetryDecoders(registry *TelemetryDecoderRegistry) {
+	registry.Register("sanitizerGen2", sim.decodeSanitizerTelemetry)
+
+	registry.Register("speedsetplus", sim.decodeSpeedsetPlusTelemetry)
+	registry.Register("speedsetPlusGen2", sim.decodeSpeedsetPlusTelemetry)
+
+	registry.Register(VspBoosterCategory, sim.decodeVspBoosterTelemetry)
+
+	registry.Register("digitalControllerGen2", sim.decodeDctTelemetry)
+
+	registry.SetFallback(sim.decodeJSONTelemetry)
+}
+
+// sanitizerTelemetryUnits annotates sanitizer telemetry fields for display
+var sanitizerTelemetryUnits = map[string]string{
+	"rssi":               "dBm",
+	"ppm_salt":           "ppm",
+	"percentage_output":  "%",
+	"line_input_voltage": "V",
+}
+
+// decodeSanitizerTelemetry decodes sanitizer.TelemetryMessage
+func (sim *NgaSim) decodeSanitizerTelemetry(deviceSerial string, payload []byte) (*TelemetryResult, error) {
+	telemetry := &sanitizer.TelemetryMessage{}
+	if err := proto.Unmarshal(payload, telemetry); err != nil {
+		return nil, fmt.Errorf("failed to parse as sanitizer TelemetryMessage: %v", err)
+	}
+
+	log.Printf("======== Sanitizer Telemetry ========")
+	log.Printf("serial: %s", deviceSerial)
+	log.Printf("rssi: %d dBm", telemetry.GetRssi())
+	log.Printf("ppm_salt: %d ppm", telemetry.GetPpmSalt())
+	log.Printf("percentage_output: %d%%", telemetry.GetPercentageOutput())
+	log.Printf("accelerometer: x=%d, y=%d, z=%d", telemetry.GetAccelerometerX(), telemetry.GetAccelerometerY(), telemetry.GetAccelerometerZ())
+	log.Printf("line_input_voltage: %d V", telemetry.GetLineInputVoltage())
+	log.Printf("is_cell_flow_reversed: %t", telemetry.GetIsCellFlowReversed())
+	log.Printf("========================================")
+
+	// Get device for status info (need device to check PendingPercentage)
+	sim.mutex.RLock()
+	device, exists := sim.devices[deviceSerial]
+	statusInfo := "normal"
+	if exists && device.PendingPercentage != 0 && telemetry.GetPercentageOutput() != device.PendingPercentage {
+		statusInfo = fmt.Sprintf("ramping to %d%%", device.PendingPercentage)
+	}
+	sim.mutex.RUnlock()
+
+	return &TelemetryResult{
+		Summary: fmt.Sprintf("← Current power: %d%% (%s) | Salt: %dppm | RSSI: %ddBm",
+			telemetry.GetPercentageOutput(), statusInfo, telemetry.GetPpmSalt(), telemetry.GetRssi()),
+		Parsed: parseProtoMessage(telemetry, "sanitizerGen2", payload, sanitizerTelemetryUnits),
+		Apply: func() {
+			sim.updateDeviceFromSanitizerTelemetry(deviceSerial, telemetry)
+		},
+	}, nil
+}
+
+// speedsetPlusTelemetryUnits annotates SpeedSet Plus telemetry fields for display
+var speedsetPlusTelemetryUnits = map[string]string{
+	"rssi":                 "dBm",
+	"motor_rpm":            "RPM",
+	"demand_rpm":           "RPM",
+	"motor_current":        "A",
+	"torque":               "Nm",
+	"inverter_input_power": "W",
+	"dc_bus_voltage":       "V",
+	"output_power":         "W",
+	"motor_line_voltage":   "V",
+	"motor_input_power":    "W",
+	"humidity":             "%",
+	"vibration_x":          "mg",
+	"vibration_y":          "mg",
+	"vibration_z":          "mg",
+}
+
+// decodeSpeedsetPlusTelemetry decodes speedsetPlus.TelemetryMessage from a SpeedSet Plus pump
+func (sim *NgaSim) decodeSpeedsetPlusTelemetry(deviceSerial string, payload []byte) (*TelemetryResult, error) {
+	telemetry := &speedsetplus.TelemetryMessage{}
+	if err := proto.Unmarshal(payload, telemetry); err != nil {
+		return nil, fmt.Errorf("failed to parse as speedsetPlus TelemetryMessage: %v", err)
+	}
+
+	pump := &PumpTelemetry{
+		MotorRPM:           telemetry.GetMotorRpm(),
+		DemandRPM:          telemetry.GetDemandRpm(),
+		MotorCurrent:       telemetry.GetMotorCurrent(),
+		Torque:             telemetry.GetTorque(),
+		InverterInputPower: telemetry.GetInverterInputPower(),
+		OutputPower:        telemetry.GetOutputPower(),
+		MotorInputPower:    telemetry.GetMotorInputPower(),
+		DCBusVoltage:       telemetry.GetDcBusVoltage(),
+		MotorLineVoltage:   telemetry.GetMotorLineVoltage(),
+		AmbientTemp:        deciDegrees(telemetry.GetAmbientTemperature()),
+		IPMTemp:            deciDegrees(telemetry.GetIpmTemperature()),
+		Humidity:           telemetry.GetHumidity(),
+		VibrationX:         telemetry.GetVibrationX(),
+		VibrationY:         telemetry.GetVibrationY(),
+		VibrationZ:         telemetry.GetVibrationZ(),
+		TotalFaults:        telemetry.GetTotalFaults(),
+		RSSI:               telemetry.GetRssi(),
+		Updated:            time.Now(),
+	}
+
+	parsed := parseProtoMessage(telemetry, "speedsetplus", payload, speedsetPlusTelemetryUnits)
+	scaleParsedField(parsed, "ambient_temperature", 0.1, "°C")
+	scaleParsedField(parsed, "ipm_temperature", 0.1, "°C")
+
+	return &TelemetryResult{
+		Summary: fmt.Sprintf("← Motor: %d/%d RPM | %dW in | %.1f°C ambient | IPM %.1f°C | RSSI: %ddBm",
+			pump.MotorRPM, pump.DemandRPM, pump.InverterInputPower, pump.AmbientTemp, pump.IPMTemp, pump.RSSI),
+		Parsed: parsed,
+		Apply: func() {
+			sim.updateDeviceFromPumpTelemetry("speedsetplus", "SpeedSetPlus", deviceSerial, pump)
+		},
+	}, nil
+}
+
+// updateDeviceFromPumpTelemetry stores SpeedSet Plus or VSP booster pump telemetry,
+// auto-creating the device as "<name>-<serial>"
+func (sim *NgaSim) updateDeviceFromPumpTelemetry(category, name, deviceSerial string, pump *PumpTelemetry) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	device, exists := sim.devices[deviceSerial]
+	if !exists {
+		device = &Device{
+			ID:       deviceSerial,
+			Serial:   deviceSerial,
+			Name:     fmt.Sprintf("%s-%s", name, deviceSerial),
+			Type:     category,
+			Category: category,
+			Status:   DeviceOnline,
+			LastSeen: time.Now(),
+		}
+		sim.devices[deviceSerial] = device
+		log.Printf("✅ Auto-created %s device from telemetry: %s", name, deviceSerial)
+	}
+
+	device.Pump = pump
+
+	// Keep the generic VSP fields in step for the dashboard and older API clients
+	device.RPM = int(pump.MotorRPM)
+	device.Power = int(pump.InverterInputPower)
+	device.Temp = pump.AmbientTemp
+
+	device.noteTelemetry(time.Now())
+	sim.markDeviceSeen(device)
+}
+
+// deciDegrees converts a deci-degree Celsius reading (0.1 °C units) to degrees Celsius
+func deciDegrees(value int32) float64 {
+	return float64(value) / 10.0
+}
+
+// scaleParsedField rewrites a parsed integer field into display units, e.g. deci-degrees to °C
+func scaleParsedField(parsed *ParsedProtobufMessage, name string, factor float64, unit string) {
+	for i := range parsed.Fields {
+		field := &parsed.Fields[i]
+		if field.Name != name {
+			continue
+		}
+		if raw, ok := field.Value.(int32); ok {
+			scaled := float64(raw) * factor
+			field.Value = scaled
+			field.RawValue = fmt.Sprintf("%.1f", scaled)
+			field.Type = "double"
+		}
+		field.Unit = unit
+	}
+}
+
+// protobufTelemetryDecoder builds a decoder for a category whose telemetry is shown field by
+// field but not yet mapped onto typed Device fields.
+func (sim *NgaSim) protobufTelemetryDecoder(category string, newMessage func() proto.Message, units map[string]string) TelemetryDecoder {
+	return func(deviceSerial string, payload []byte) (*TelemetryResult, error) {
+		telemetry := newMessage()
+		if err := proto.Unmarshal(payload, telemetry); err != nil {
+			return nil, fmt.Errorf("failed to parse as %s: %v", telemetry.ProtoReflect().Descriptor().FullName(), err)
+		}
+
+		parsed := parseProtoMessage(telemetry, category, payload, units)
+		return &TelemetryResult{
+			Summary: fmt.Sprintf("← Telemetry received (%d fields)", len(parsed.Fields)),
+			Parsed:  parsed,
+			Apply: func() {
+				sim.updateDeviceFromProtobufTelemetry(category, deviceSerial)
+			},
+		}, nil
+	}
+}
+
+// decodeJSONTelemetry handles legacy devices that publish telemetry as JSON
+func (sim *NgaSim) decodeJSONTelemetry(deviceSerial string, payload []byte) (*TelemetryResult, error) {
+	var telemetryData map[string]interface{}
+	if err := json.Unmarshal(payload, &telemetryData); err != nil {
+		return nil, fmt.Errorf("not JSON telemetry: %v", err)
+	}
+
+	return &TelemetryResult{
+		Summary: "Telemetry received (JSON)",
+		Apply: func() {
+			sim.updateDeviceFromTelemetry(deviceSerial, telemetryData)
+		},
+	}, nil
+}
+
+// updateDeviceFromProtobufTelemetry records telemetry from a category without typed fields,
+// auto-creating the device like sanitizer telemetry does
+func (sim *NgaSim) updateDeviceFromProtobufTelemetry(category, deviceSerial string) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	device, exists := sim.devices[deviceSerial]
+	if !exists {
+		device = &Device{
+			ID:       deviceSerial,
+			Serial:   deviceSerial,
+			Name:     fmt.Sprintf("%s-%s", category, deviceSerial),
+			Type:     category,
+			Category: category,
+			Status:   DeviceOnline,
+			LastSeen: time.Now(),
+		}
+		sim.devices[deviceSerial] = device
+		log.Printf("✅ Auto-created %s device from telemetry: %s", category, deviceSerial)
+	}
+
+	device.noteTelemetry(time.Now())
+	sim.markDeviceSeen(device)
+}
+
+// parseProtoMessage lists the top-level fields of a decoded message for terminal display.
+// Nested and repeated fields are rendered as JSON.
+func parseProtoMessage(msg proto.Message, category string, raw []byte, units map[string]string) *ParsedProtobufMessage {
+	reflectMsg := msg.ProtoReflect()
+	parsed := &ParsedProtobufMessage{
+		MessageType: string(reflectMsg.Descriptor().FullName()),
+		Category:    category,
+		Timestamp:   time.Now(),
+		RawData:     raw,
+		Fields:      []ParsedProtobufField{},
+		ParsedOK:    true,
+	}
+
+	fields := reflectMsg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		name := string(field.Name())
+		isSet := reflectMsg.Has(field)
+
+		var value interface{}
+		rawValue := ""
+		switch {
+		case field.IsList() || field.IsMap() || field.Kind() == protoreflect.MessageKind:
+			if isSet {
+				rawValue = protoFieldJSON(reflectMsg, field)
+				value = rawValue
+			}
+		case field.Kind() == protoreflect.EnumKind:
+			enumValue := reflectMsg.Get(field).Enum()
+			value = int32(enumValue)
+			rawValue = fmt.Sprintf("%d", enumValue)
+			if enumDesc := field.Enum().Values().ByNumber(enumValue); enumDesc != nil {
+				rawValue = string(enumDesc.Name())
+			}
+		default:
+			value = reflectMsg.Get(field).Interface()
+			rawValue = fmt.Sprintf("%v", value)
+		}
+
+		parsed.Fields = append(parsed.Fields, ParsedProtobufField{
+			Name:     name,
+			Type:     field.Kind().String(),
+			Value:    value,
+			RawValue: rawValue,
+			IsSet:    isSet,
+			Unit:     units[name],
+		})
+	}
+
+	return parsed
+}
+
+// protoFieldJSON renders one composite field of a message as compact JSON
+func protoFieldJSON(msg protoreflect.Message, field protoreflect.FieldDescriptor) string {
+	single := msg.New()
+	single.Set(field, msg.Get(field))
+
+	jsonBytes, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(single.Interface())
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(jsonBytes, &wrapper); err != nil {
+		return string(jsonBytes)
+	}
+	return strings.TrimSpace(string(wrapper[string(field.Name())]))
+}
+
+// storeTelemetry keeps the full decoded telemetry message on the device, so job conditions
+// can test any field of it as telemetry.<field>. JSON telemetry has no parsed message and is
+// not kept.
+func (sim *NgaSim) storeTelemetry(deviceSerial string, parsed *ParsedProtobufMessage) {
+	if parsed == nil {
+		return
+	}
+
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(parsed.MessageType))
+	if err != nil {
+		return
+	}
+	msg := messageType.New().Interface()
+	if err := proto.Unmarshal(parsed.RawData, msg); err != nil {
+		return
+	}
+	telemetry, err := protoMessageToMap(msg)
+	if err != nil {
+		log.Printf("⚠️ Telemetry from %s not kept: %v", deviceSerial, err)
+		return
+	}
+
+	sim.mutex.Lock()
+	if device, exists := sim.devices[deviceSerial]; exists {
+		device.Telemetry = telemetry
+		device.TelemetryUpdated = parsed.Timestamp
+	}
+	sim.mutex.Unlock()
+}
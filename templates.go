@@ -123,6 +123,11 @@ var goDemoTemplateHTML = `
             color: #742a2a;
         }
         
+        .status-stale {
+            background: #f6e05e;
+            color: #744210;
+        }
+        
         .device-info {
             display: grid;
             grid-template-columns: 1fr 1fr;
@@ -533,6 +538,13 @@ var goDemoTemplateHTML = `
                 <a href="/api/devices">📊 API</a>
                 <a href="/goodbye">👋 Exit</a>
             </div>
+            {{if .Sites}}
+            <div class="nav-links">
+                <span>📍 Site: <strong>{{.Site}}</strong></span>
+                {{range .Sites}}<a href="{{.URL}}">{{if eq .Name $.Site}}▶ {{end}}{{.Name}}{{if not .Connected}} ⚠️{{end}}</a>
+                {{end}}<a href="/fleet">🗺️ Fleet</a>
+            </div>
+            {{end}}
         </div>
 
         <!-- Emergency Controls -->
@@ -549,7 +561,7 @@ var goDemoTemplateHTML = `
                 <!-- Device Header -->
                 <div class="device-header">
                     <div class="device-title">{{.Name}}</div>
-                    <div class="status-indicator {{if eq .Status "ONLINE"}}status-online{{else}}status-offline{{end}}">
+                    <div class="status-indicator {{if eq .Status "ONLINE"}}status-online{{else if eq .Status "STALE"}}status-stale{{else}}status-offline{{end}}"{{if .StatusReason}} title="{{.StatusReason}}"{{end}}>
                         {{.Status}}
                     </div>
                 </div>
@@ -582,6 +594,66 @@ var goDemoTemplateHTML = `
                     </div>
                 </div>
 
+                <!-- Device Faults -->
+                {{if .Faults}}
+                <div class="control-group">
+                    <div class="control-label">🚨 Device Faults ({{.ActiveFaultCount}} active)</div>
+                    {{range .Faults}}
+                    {{if .Active}}
+                    <div style="font-size: 0.85em; padding: 4px 8px; margin-bottom: 3px; border-left: 3px solid #e53e3e; background: #fff5f5;">
+                        <strong>{{.Code}}</strong>{{if .Source}} ({{.Source}}){{end}}{{if .Message}} - {{.Message}}{{end}}
+                        <div style="color: #666;">since {{.FirstSeen.Format "15:04:05"}}</div>
+                    </div>
+                    {{else}}
+                    <div style="font-size: 0.85em; padding: 4px 8px; margin-bottom: 3px; border-left: 3px solid #a0aec0; color: #718096;">
+                        {{.Code}}{{if .Source}} ({{.Source}}){{end}} - {{.FirstSeen.Format "15:04:05"}} → cleared {{.ClearedAt.Format "15:04:05"}}
+                    </div>
+                    {{end}}
+                    {{end}}
+                </div>
+                {{end}}
+
+                <!-- Digital Controller Telemetry -->
+                {{with .Dct}}
+                <div class="control-group">
+                    <div class="control-label">🎛️ Light Controller ({{.Power}} W)</div>
+                    <div class="device-info">
+                        <div class="info-item"><span class="info-label">Current:</span><span class="info-value">{{.Current}} mA</span></div>
+                        <div class="info-item"><span class="info-label">Voltage:</span><span class="info-value">{{printf "%.1f" .Voltage}} VAC</span></div>
+                        <div class="info-item"><span class="info-label">Board:</span><span class="info-value">{{printf "%.1f" .BoardTemp}}°C</span></div>
+                        <div class="info-item"><span class="info-label">Derating:</span><span class="info-value">{{.DeratingPercentage}}%</span></div>
+                        <div class="info-item"><span class="info-label">RSSI:</span><span class="info-value">{{.RSSI}} dBm</span></div>
+                        <div class="info-item"><span class="info-label">Updated:</span><span class="info-value">{{.Updated.Format "15:04:05"}}</span></div>
+                    </div>
+                </div>
+                {{end}}
+
+                <!-- Digital Controller Lights -->
+                {{if .Lights}}
+                <div class="control-group">
+                    <div class="control-label">💡 Lights ({{len .Lights}}{{if .DctWattageCapacity}}, {{.DctWattageCapacity}}W capacity{{end}})</div>
+                    {{range .Lights}}
+                    <div style="font-size: 0.85em; padding: 6px 8px; margin-bottom: 5px; border-left: 3px solid {{if .Faults}}#e53e3e{{else if .Available}}#48bb78{{else}}#a0aec0{{end}}; background: #f7fafc;">
+                        <strong>#{{.Address}}{{if .Model}} {{.Model}}{{end}}</strong>{{if not .Available}} <span style="color: #718096;">(unavailable)</span>{{end}}
+                        <div class="device-info">
+                            {{if .ControlType}}
+                            <div class="info-item"><span class="info-label">Control:</span><span class="info-value">{{.ControlType}}</span></div>
+                            <div class="info-item"><span class="info-label">Brightness:</span><span class="info-value">{{.Brightness}}/{{.MaxBrightness}}</span></div>
+                            {{end}}
+                            {{if .DriveMode}}<div class="info-item"><span class="info-label">Drive Mode:</span><span class="info-value">{{range $mode, $value := .DriveMode}}{{$mode}} {{$value}} {{end}}</span></div>{{end}}
+                            {{if not .TelemetryUpdated.IsZero}}
+                            <div class="info-item"><span class="info-label">Temperature:</span><span class="info-value">{{printf "%.1f" .Temperature}}°C</span></div>
+                            <div class="info-item"><span class="info-label">Derating:</span><span class="info-value">{{.DeratingPercentage}}%</span></div>
+                            {{end}}
+                            {{if .FirmwareVersion}}<div class="info-item"><span class="info-label">Firmware:</span><span class="info-value">{{.FirmwareVersion}}</span></div>{{end}}
+                            {{if .SerialNumber}}<div class="info-item"><span class="info-label">Serial:</span><span class="info-value">{{.SerialNumber}}</span></div>{{end}}
+                        </div>
+                        {{range .Faults}}<div style="color: #e53e3e;">🚨 {{.}}</div>{{end}}
+                    </div>
+                    {{end}}
+                </div>
+                {{end}}
+
                 <!-- Device-Specific Controls -->
                 {{if eq .Type "sanitizerGen2"}}
                 <div class="control-group">
@@ -601,6 +673,41 @@ var goDemoTemplateHTML = `
                 </div>
                 {{end}}
 
+                {{if eq .Type "vspBoosterGen2"}}
+                <div class="control-group">
+                    <div class="control-label">🌀 Booster Control (Current: {{.RPM}} RPM)</div>
+                    <div class="controls">
+                        <button class="btn btn-secondary" onclick="sendVspBoosterCommand('{{.Serial}}', 0, 0)">OFF</button>
+                        <button class="btn btn-primary" onclick="sendVspBoosterCommand('{{.Serial}}', 1, 1500)">1500</button>
+                        <button class="btn btn-primary" onclick="sendVspBoosterCommand('{{.Serial}}', 1, 2500)">2500</button>
+                        <button class="btn btn-warning" onclick="sendVspBoosterCommand('{{.Serial}}', 1, 3450)">3450</button>
+                    </div>
+                </div>
+                {{end}}
+
+                <!-- Pump Telemetry (SpeedSet Plus and VSP booster) -->
+                {{with .Pump}}
+                <div class="control-group">
+                    <div class="control-label">🌀 Pump ({{.MotorRPM}} / {{.DemandRPM}} RPM)</div>
+                    <div class="device-info">
+                        <div class="info-item"><span class="info-label">Current:</span><span class="info-value">{{.MotorCurrent}} A</span></div>
+                        <div class="info-item"><span class="info-label">Torque:</span><span class="info-value">{{.Torque}} Nm</span></div>
+                        <div class="info-item"><span class="info-label">Inverter In:</span><span class="info-value">{{.InverterInputPower}} W</span></div>
+                        <div class="info-item"><span class="info-label">Inverter Out:</span><span class="info-value">{{.OutputPower}} W</span></div>
+                        <div class="info-item"><span class="info-label">Motor In:</span><span class="info-value">{{.MotorInputPower}} W</span></div>
+                        <div class="info-item"><span class="info-label">DC Bus:</span><span class="info-value">{{.DCBusVoltage}} V</span></div>
+                        <div class="info-item"><span class="info-label">Motor Line:</span><span class="info-value">{{.MotorLineVoltage}} V</span></div>
+                        <div class="info-item"><span class="info-label">Ambient:</span><span class="info-value">{{printf "%.1f" .AmbientTemp}}°C</span></div>
+                        <div class="info-item"><span class="info-label">IPM:</span><span class="info-value">{{printf "%.1f" .IPMTemp}}°C</span></div>
+                        {{if .Humidity}}<div class="info-item"><span class="info-label">Humidity:</span><span class="info-value">{{.Humidity}}%</span></div>{{end}}
+                        {{if or .VibrationX .VibrationY .VibrationZ}}<div class="info-item"><span class="info-label">Vibration:</span><span class="info-value">{{.VibrationX}}/{{.VibrationY}}/{{.VibrationZ}} mg</span></div>{{end}}
+                        <div class="info-item"><span class="info-label">Total Faults:</span><span class="info-value">{{.TotalFaults}}</span></div>
+                        <div class="info-item"><span class="info-label">RSSI:</span><span class="info-value">{{.RSSI}} dBm</span></div>
+                        <div class="info-item"><span class="info-label">Updated:</span><span class="info-value">{{.Updated.Format "15:04:05"}}</span></div>
+                    </div>
+                </div>
+                {{end}}
+
                 <!-- Dynamic Protobuf Commands -->
                 <div class="control-group">
                     <div class="control-label">🧬 Protobuf Commands</div>
@@ -714,6 +821,31 @@ var goDemoTemplateHTML = `
             }
         }
 
+        async function sendVspBoosterCommand(serial, power, rpm) {
+            console.log('Sending VSP booster command:', serial, power, rpm);
+            
+            try {
+                const response = await fetch('/api/vsp-booster/command', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ serial: serial, power: power, rpm: rpm })
+                });
+                
+                const result = await response.json();
+                
+                if (result.success) {
+                    console.log('✅ Command successful:', result);
+                    setTimeout(() => location.reload(), 1000);
+                } else {
+                    console.error('❌ Command failed:', result.error);
+                    alert('Command failed: ' + result.error);
+                }
+            } catch (error) {
+                console.error('Network error:', error);
+                alert('Network error: ' + error.message);
+            }
+        }
+
         // Show available protobuf commands for a device
         async function showProtobufCommands(deviceSerial, deviceType) {
             console.log('Showing protobuf commands for:', deviceSerial, deviceType);
@@ -1304,3 +1436,67 @@ var goodbyeTemplate = template.Must(template.New("goodbye").Funcs(templateFuncs)
 </body>
 </html>
 `))
+
+// Fleet view: every site with its connection state, plus all devices tagged with their site
+var fleetTemplate = template.Must(template.New("fleet").Funcs(templateFuncs).Parse(`
+<!DOCTYPE html>
+<html>
+<head>
+    <title>{{.Title}}</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 20px; background: #f7fafc; color: #2d3748; }
+        table { border-collapse: collapse; width: 100%; margin-bottom: 30px; background: white; }
+        th, td { padding: 8px 12px; border-bottom: 1px solid #e2e8f0; text-align: left; }
+        th { background: #edf2f7; }
+        .online { color: #38a169; font-weight: bold; }
+        .offline { color: #e53e3e; font-weight: bold; }
+        .btn-danger { background: #e53e3e; color: white; border: none; padding: 10px 20px; border-radius: 5px; cursor: pointer; }
+    </style>
+</head>
+<body>
+    <h1>🗺️ NgaSim Fleet v{{.Version}}</h1>
+    <p><button class="btn-danger" onclick="fleetEmergencyStop()">🛑 EMERGENCY STOP ALL SITES</button></p>
+
+    <h2>Sites</h2>
+    <table>
+        <tr><th>Site</th><th>Broker</th><th>Connection</th><th>Devices</th><th>Online</th><th>Active Faults</th><th>Queued Commands</th></tr>
+        {{range .Sites}}
+        <tr>
+            <td><a href="{{.URL}}">{{.Name}}</a>{{if .Title}} - {{.Title}}{{end}}</td>
+            <td>{{.Broker}}</td>
+            <td>{{if .DemoMode}}🎭 Demo{{else if .Connected}}<span class="online">Connected</span>{{else}}<span class="offline">Disconnected</span>{{end}}</td>
+            <td>{{.Devices}}</td>
+            <td>{{.Online}}</td>
+            <td>{{.ActiveFaults}}</td>
+            <td>{{.QueuedCommands}}</td>
+        </tr>
+        {{end}}
+    </table>
+
+    <h2>Devices</h2>
+    <table>
+        <tr><th>Site</th><th>Name</th><th>Serial</th><th>Category</th><th>Status</th><th>Last Seen</th></tr>
+        {{range .Devices}}
+        <tr>
+            <td><a href="/sites/{{.Site}}/">{{.Site}}</a></td>
+            <td>{{.Name}}</td>
+            <td>{{.Serial}}</td>
+            <td>{{.Category}}</td>
+            <td class="{{if eq .Status "ONLINE"}}online{{else}}offline{{end}}">{{.Status}}</td>
+            <td>{{.LastSeen.Format "15:04:05"}}</td>
+        </tr>
+        {{end}}
+    </table>
+
+    <script>
+        async function fleetEmergencyStop() {
+            if (!confirm('Emergency stop every sanitizer on every site?')) return;
+            const response = await fetch('/api/fleet/emergency-stop', { method: 'POST' });
+            const result = await response.json();
+            alert(result.message);
+            location.reload();
+        }
+    </script>
+</body>
+</html>
+`))
@@ -89,6 +89,20 @@ func (tl *TerminalLogger) LogError(device, message string, err error) {
 	tl.addEntry(entry)
 }
 
+// LogEvent logs a system event about a device, such as an online/offline transition
+func (tl *TerminalLogger) LogEvent(device, eventType, message string) {
+	entry := LogEntry{
+		Timestamp:   time.Now(),
+		Type:        "EVENT",
+		Device:      device,
+		Message:     message,
+		MessageType: eventType,
+		Direction:   "SYSTEM",
+	}
+
+	tl.addEntry(entry)
+}
+
 // addEntry adds an entry to the log with thread safety
 func (tl *TerminalLogger) addEntry(entry LogEntry) {
 	tl.mutex.Lock()
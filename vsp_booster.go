@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"NgaSim/ned/vspbooster"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+// VspBoosterCategory is the NED category of the VSP booster pump (model WP000246)
+const VspBoosterCategory = "vspBoosterGen2"
+
+// VSP booster motor control values for SetVspBoosterControlCommand.power
+const (
+	VspBoosterPowerOff = 0 ///< Motor stopped
+	VspBoosterPowerOn  = 1 ///< Motor running at the demand RPM
+)
+
+func decodeVspBoosterErrors(payload []byte) ([]reportedFault, error) {
+	msg := &vspbooster.DeviceErrorMessage{}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode vspBooster.DeviceErrorMessage: %v", err)
+	}
+	return vspBoosterFaults(msg.GetActiveErrors()), nil
+}
+
+// vspBoosterFaults converts the booster's active error list to reported faults
+func vspBoosterFaults(activeErrors *vspbooster.ActiveErrors) []reportedFault {
+	var faults []reportedFault
+	for _, e := range activeErrors.GetErrorList() {
+		faults = append(faults, reportedFault{
+			Code:      e.GetErrorCode().String(),
+			CodeValue: int32(e.GetErrorCode()),
+			Message:   e.GetErrorMessage(),
+		})
+	}
+	return faults
+}
+
+func decodeVspBoosterInfo(payload []byte) (*infoUpdate, error) {
+	msg := &vspbooster.InfoMessage{}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode vspBooster.InfoMessage: %v", err)
+	}
+
+	info := msg.GetPayload()
+	switch {
+	case info.GetStatus() != nil:
+		return statusInfoUpdate(info.GetStatus())
+	case info.GetConfiguration() != nil:
+		return configurationInfoUpdate(info.GetConfiguration())
+	}
+	return nil, fmt.Errorf("vspBooster.InfoMessage carries no payload")
+}
+
+// vspBoosterTelemetryUnits annotates VSP booster telemetry fields for display
+var vspBoosterTelemetryUnits = map[string]string{
+	"rssi":                 "dBm",
+	"motor_rpm":            "RPM",
+	"demand_rpm":           "RPM",
+	"motor_current":        "A",
+	"torque":               "Nm",
+	"inverter_input_power": "W",
+	"dc_bus_voltage":       "V",
+	"output_power":         "W",
+	"motor_line_voltage":   "V",
+	"motor_input_power":    "W",
+}
+
+// decodeVspBoosterTelemetry decodes vspBooster.TelemetryMessage. The booster reports the same
+// motor values as a SpeedSet Plus pump, minus humidity and vibration.
+func (sim *NgaSim) decodeVspBoosterTelemetry(deviceSerial string, payload []byte) (*TelemetryResult, error) {
+	telemetry := &vspbooster.TelemetryMessage{}
+	if err := proto.Unmarshal(payload, telemetry); err != nil {
+		return nil, fmt.Errorf("failed to parse as vspBooster TelemetryMessage: %v", err)
+	}
+
+	pump := &PumpTelemetry{
+		MotorRPM:           telemetry.GetMotorRpm(),
+		DemandRPM:          telemetry.GetDemandRpm(),
+		MotorCurrent:       telemetry.GetMotorCurrent(),
+		Torque:             telemetry.GetTorque(),
+		InverterInputPower: telemetry.GetInverterInputPower(),
+		OutputPower:        telemetry.GetOutputPower(),
+		MotorInputPower:    telemetry.GetMotorInputPower(),
+		DCBusVoltage:       telemetry.GetDcBusVoltage(),
+		MotorLineVoltage:   telemetry.GetMotorLineVoltage(),
+		AmbientTemp:        deciDegrees(telemetry.GetAmbientTemperature()),
+		IPMTemp:            deciDegrees(telemetry.GetIpmTemperature()),
+		TotalFaults:        telemetry.GetTotalFaults(),
+		RSSI:               telemetry.GetRssi(),
+		Updated:            time.Now(),
+	}
+
+	parsed := parseProtoMessage(telemetry, VspBoosterCategory, payload, vspBoosterTelemetryUnits)
+	scaleParsedField(parsed, "ambient_temperature", 0.1, "°C")
+	scaleParsedField(parsed, "ipm_temperature", 0.1, "°C")
+
+	return &TelemetryResult{
+		Summary: fmt.Sprintf("← Booster: %d/%d RPM | %dW in | %.1f°C ambient | IPM %.1f°C | RSSI: %ddBm",
+			pump.MotorRPM, pump.DemandRPM, pump.InverterInputPower, pump.AmbientTemp, pump.IPMTemp, pump.RSSI),
+		Parsed: parsed,
+		Apply: func() {
+			sim.updateDeviceFromPumpTelemetry(VspBoosterCategory, "VSPBooster", deviceSerial, pump)
+		},
+	}, nil
+}
+
+// sendVspBoosterCommand starts or stops a VSP booster pump at the given demand RPM. It returns the
+// command UUID, or "" when the command is simulated in demo mode.
+func (n *NgaSim) sendVspBoosterCommand(serial string, power, rpm int) (string, error) {
+	log.Printf("🌀 Sending VSP booster command: %s -> power=%d, %d RPM", serial, power, rpm)
+
+	if power != VspBoosterPowerOff && power != VspBoosterPowerOn {
+		return "", fmt.Errorf("invalid power: %d (must be %d or %d)", power, VspBoosterPowerOff, VspBoosterPowerOn)
+	}
+	if rpm < 0 {
+		return "", fmt.Errorf("invalid rpm: %d (must not be negative)", rpm)
+	}
+
+	n.mutex.RLock()
+	device, exists := n.devices[serial]
+	n.mutex.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("device not found: %s", serial)
+	}
+
+	n.mutex.Lock()
+	device.LastCommandTime = time.Now()
+	n.mutex.Unlock()
+
+	n.addDeviceTerminalEntry(serial, "COMMAND",
+		fmt.Sprintf("→ Set booster power=%d at %d RPM", power, rpm),
+		[]byte(fmt.Sprintf(`{"command":"set_vsp_booster_control","power":%d,"rpm":%d}`, power, rpm)))
+
+	if n.sendsToBroker() {
+		return n.sendMQTTVspBoosterCommand(serial, power, rpm)
+	}
+
+	// Demo mode - simulate the motor reaching the demand speed
+	n.addDeviceTerminalEntry(serial, "DEMO",
+		fmt.Sprintf("✅ Demo command completed: power=%d at %d RPM", power, rpm),
+		[]byte(fmt.Sprintf(`{"result":"success","power":%d,"rpm":%d}`, power, rpm)))
+
+	go func() {
+		time.Sleep(2 * time.Second)
+
+		n.mutex.Lock()
+		if device, exists := n.devices[serial]; exists {
+			device.RPM = 0
+			if power == VspBoosterPowerOn {
+				device.RPM = rpm
+			}
+			device.LastCommandTime = time.Time{}
+			device.LastSeen = time.Now()
+			log.Printf("✅ Demo command completed: %s -> %d RPM", serial, device.RPM)
+		}
+		n.mutex.Unlock()
+	}()
+
+	return "", nil
+}
+
+// sendMQTTVspBoosterCommand publishes SetVspBoosterControlCommand in the booster envelope and
+// returns the command UUID
+func (n *NgaSim) sendMQTTVspBoosterCommand(serial string, power, rpm int) (string, error) {
+	commandUUID := uuid.New().String()
+
+	wrapper := &vspbooster.VspBoosterRequestPayloads{
+		RequestType: &vspbooster.VspBoosterRequestPayloads_SetVspBoosterControlCommand{
+			SetVspBoosterControlCommand: &vspbooster.SetVspBoosterControlCommandRequestPayload{
+				Power:        int32(power),
+				SetDemandRpm: int32(rpm),
+			},
+		},
+	}
+
+	_, msgBytes, err := marshalCommand(VspBoosterCategory, commandUUID, wrapper)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal protobuf command: %v", err)
+	}
+
+	queued, err := n.publishCommand(serial, VspBoosterCategory, "SetVspBoosterControlCommand", commandUUID, msgBytes)
+	if err != nil {
+		return "", err
+	}
+	if queued {
+		return commandUUID, nil
+	}
+
+	n.addDeviceTerminalEntry(serial, "MQTT_CMD",
+		fmt.Sprintf("📡 MQTT command sent: Set booster power=%d at %d RPM (UUID: %s)", power, rpm, commandUUID), msgBytes)
+
+	log.Printf("✅ MQTT protobuf command sent successfully: %s -> power=%d, %d RPM (UUID: %s)", serial, power, rpm, commandUUID)
+	return commandUUID, nil
+}